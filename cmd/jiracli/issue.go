@@ -0,0 +1,224 @@
+package jiracli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/trichner/toolbox/pkg/jira"
+)
+
+// issueCommands change issues, either the one of the flags or one per NDJSON record read from stdin, e.g.
+// '{"key": "ARC-1", "status": "Done"}'. The flags are the defaults of the records.
+type issueCommands struct {
+	Create struct {
+		issueFlags
+		Project string `help:"key of the project"`
+		Type    string `help:"name of the issue type, e.g. 'Story'"`
+	} `cmd:"" help:"Create issues and print their keys, reads NDJSON from stdin without --summary."`
+	Update struct {
+		Key string `help:"key of the issue, reads NDJSON from stdin without it"`
+		issueFlags
+	} `cmd:"" help:"Update the fields of issues."`
+	Transition struct {
+		Key    string `help:"key of the issue, reads NDJSON from stdin without it"`
+		Status string `help:"status or name of the transition to move the issues to"`
+	} `cmd:"" help:"Transition issues through their workflow."`
+	Comment struct {
+		Key  string `help:"key of the issue, reads NDJSON from stdin without it"`
+		Body string `help:"text of the comment"`
+	} `cmd:"" help:"Comment on issues."`
+	Assign struct {
+		Key      string `help:"key of the issue, reads NDJSON from stdin without it"`
+		Assignee string `help:"accountId of the assignee, unassigns the issues if empty"`
+	} `cmd:"" help:"Assign issues."`
+}
+
+type issueFlags struct {
+	Summary     string            `help:"summary of the issue"`
+	Description string            `help:"description of the issue"`
+	Labels      []string          `help:"labels of the issue, comma separated" sep:","`
+	FixVersions []string          `help:"names of the fix versions, comma separated" sep:","`
+	Field       map[string]string `help:"custom field as name=value, the value is JSON or else a string" mapsep:"none" placeholder:"NAME=VALUE"`
+}
+
+func (f *issueFlags) fields() jira.IssueFields {
+	return jira.IssueFields{
+		Summary:      f.Summary,
+		Description:  f.Description,
+		Labels:       f.Labels,
+		FixVersions:  f.FixVersions,
+		CustomFields: parseCustomFields(f.Field),
+	}
+}
+
+// issueRecord is an issue to change and how to change it
+type issueRecord struct {
+	Key string `json:"key,omitempty"`
+	jira.IssueFields
+	Status   string  `json:"status,omitempty"`
+	Body     string  `json:"body,omitempty"`
+	Assignee *string `json:"assignee,omitempty"`
+}
+
+// issueService are the changes of jira.JiraService
+type issueService interface {
+	CreateIssue(f *jira.IssueFields) (string, error)
+	UpdateIssue(key string, f *jira.IssueFields) error
+	TransitionIssue(key, status string) error
+	CommentIssue(key, body string) error
+	AssignIssue(key, accountId string) error
+}
+
+// changeIssues runs the issue command on the record of the flags or else on the records read from in
+func changeIssues(command string, cmds *issueCommands, service issueService, in io.Reader, out io.Writer) error {
+	var defaults issueRecord
+	var single bool
+	var change func(r *issueRecord) error
+
+	switch command {
+	case "issue create":
+		c := &cmds.Create
+		defaults = issueRecord{IssueFields: c.fields()}
+		defaults.Project, defaults.Type = c.Project, c.Type
+		single = c.Summary != ""
+		change = func(r *issueRecord) error {
+			key, err := service.CreateIssue(&r.IssueFields)
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintln(out, key)
+			return err
+		}
+	case "issue update":
+		c := &cmds.Update
+		defaults = issueRecord{Key: c.Key, IssueFields: c.fields()}
+		single = c.Key != ""
+		change = func(r *issueRecord) error {
+			if err := service.UpdateIssue(r.Key, &r.IssueFields); err != nil {
+				return err
+			}
+			log.Printf("updated %s", r.Key)
+			return nil
+		}
+	case "issue transition":
+		c := &cmds.Transition
+		defaults = issueRecord{Key: c.Key, Status: c.Status}
+		single = c.Key != ""
+		change = func(r *issueRecord) error {
+			if r.Status == "" {
+				return fmt.Errorf("no status to transition %s to", r.Key)
+			}
+			if err := service.TransitionIssue(r.Key, r.Status); err != nil {
+				return err
+			}
+			log.Printf("transitioned %s to %s", r.Key, r.Status)
+			return nil
+		}
+	case "issue comment":
+		c := &cmds.Comment
+		defaults = issueRecord{Key: c.Key, Body: c.Body}
+		single = c.Key != ""
+		change = func(r *issueRecord) error {
+			if err := service.CommentIssue(r.Key, r.Body); err != nil {
+				return err
+			}
+			log.Printf("commented on %s", r.Key)
+			return nil
+		}
+	case "issue assign":
+		c := &cmds.Assign
+		defaults = issueRecord{Key: c.Key, Assignee: &c.Assignee}
+		single = c.Key != ""
+		change = func(r *issueRecord) error {
+			if err := service.AssignIssue(r.Key, *r.Assignee); err != nil {
+				return err
+			}
+			log.Printf("assigned %s", r.Key)
+			return nil
+		}
+	default:
+		return fmt.Errorf("unknown command %q", command)
+	}
+
+	if single {
+		return change(&defaults)
+	}
+	return readIssueRecords(in, defaults, func(r *issueRecord) error {
+		if r.Key == "" && command != "issue create" {
+			return fmt.Errorf("no key")
+		}
+		return change(r)
+	})
+}
+
+// readIssueRecords calls fn with every NDJSON record of in, the fields a record does not have are those of the
+// defaults
+func readIssueRecords(in io.Reader, defaults issueRecord, fn func(r *issueRecord) error) error {
+	d := json.NewDecoder(in)
+	d.UseNumber()
+	for n := 1; ; n++ {
+		// the slices, maps and pointers of the defaults are not decoded into, that would change them for the
+		// next records
+		r := defaults
+		r.Labels, r.FixVersions, r.CustomFields, r.Assignee = nil, nil, nil, nil
+		err := d.Decode(&r)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("record %d: %w", n, err)
+		}
+		if r.Labels == nil {
+			r.Labels = defaults.Labels
+		}
+		if r.FixVersions == nil {
+			r.FixVersions = defaults.FixVersions
+		}
+		if r.CustomFields == nil {
+			r.CustomFields = defaults.CustomFields
+		}
+		if r.Assignee == nil {
+			r.Assignee = defaults.Assignee
+		}
+
+		if err := fn(&r); err != nil {
+			return fmt.Errorf("record %d: %w", n, err)
+		}
+	}
+}
+
+// parseCustomFields parses the values of the flags as JSON, values which are not JSON are strings. Numbers are
+// kept as they are written, like those of the NDJSON records, e.g. '1.10' or large IDs.
+func parseCustomFields(flags map[string]string) map[string]any {
+	if len(flags) == 0 {
+		return nil
+	}
+
+	fields := make(map[string]any, len(flags))
+	for name, value := range flags {
+		v, ok := parseJSONValue(value)
+		if !ok {
+			v = value
+		}
+		fields[name] = v
+	}
+	return fields
+}
+
+// parseJSONValue parses s if it is a single JSON value
+func parseJSONValue(s string) (any, bool) {
+	d := json.NewDecoder(strings.NewReader(s))
+	d.UseNumber()
+	var v any
+	if err := d.Decode(&v); err != nil {
+		return nil, false
+	}
+	if _, err := d.Token(); !errors.Is(err, io.EOF) {
+		return nil, false
+	}
+	return v, true
+}
@@ -0,0 +1,153 @@
+package jiracli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/kong"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trichner/toolbox/pkg/jira"
+)
+
+// recordingService records the changes as text, issues of the project 'FAIL' cannot be changed
+type recordingService struct {
+	changes []string
+	created int
+}
+
+func (s *recordingService) CreateIssue(f *jira.IssueFields) (string, error) {
+	if f.Project == "FAIL" {
+		return "", fmt.Errorf("cannot create issue")
+	}
+	s.created++
+	fields, _ := json.Marshal(f)
+	s.changes = append(s.changes, "create "+string(fields))
+	return fmt.Sprintf("%s-%d", f.Project, s.created), nil
+}
+
+func (s *recordingService) UpdateIssue(key string, f *jira.IssueFields) error {
+	fields, _ := json.Marshal(f)
+	s.changes = append(s.changes, "update "+key+" "+string(fields))
+	return nil
+}
+
+func (s *recordingService) TransitionIssue(key, status string) error {
+	s.changes = append(s.changes, "transition "+key+" "+status)
+	return nil
+}
+
+func (s *recordingService) CommentIssue(key, body string) error {
+	s.changes = append(s.changes, "comment "+key+" "+body)
+	return nil
+}
+
+func (s *recordingService) AssignIssue(key, accountId string) error {
+	s.changes = append(s.changes, "assign "+key+" "+accountId)
+	return nil
+}
+
+func TestChangeIssues_Create(t *testing.T) {
+	cmds := &issueCommands{}
+	cmds.Create.Project = "ARC"
+	cmds.Create.Type = "Task"
+	cmds.Create.Labels = []string{"release"}
+
+	in := `{"summary": "Release notes", "customFields": {"storyPoints": 2}}
+{"summary": "Tag 1.2", "type": "Story", "labels": ["git"]}
+{"summary": "Announce 1.2"}`
+	s := &recordingService{}
+	var out bytes.Buffer
+	require.NoError(t, changeIssues("issue create", cmds, s, strings.NewReader(in), &out))
+
+	assert.Equal(t, "ARC-1\nARC-2\nARC-3\n", out.String())
+	assert.Equal(t, []string{
+		`create {"project":"ARC","type":"Task","summary":"Release notes","labels":["release"],"customFields":{"storyPoints":2}}`,
+		`create {"project":"ARC","type":"Story","summary":"Tag 1.2","labels":["git"]}`,
+		`create {"project":"ARC","type":"Task","summary":"Announce 1.2","labels":["release"]}`,
+	}, s.changes)
+
+	// a summary creates the single issue of the flags
+	cmds.Create.Summary = "Hotfix"
+	cmds.Create.Field = map[string]string{"storyPoints": "1", "squad": `{"value": "Platform"}`}
+	s = &recordingService{}
+	require.NoError(t, changeIssues("issue create", cmds, s, strings.NewReader(in), &out))
+	assert.Equal(t, []string{
+		`create {"project":"ARC","type":"Task","summary":"Hotfix","labels":["release"],"customFields":{"squad":{"value":"Platform"},"storyPoints":1}}`,
+	}, s.changes)
+}
+
+func TestIssueFlags_Field(t *testing.T) {
+	var cli struct {
+		Issue issueCommands `cmd:""`
+	}
+	parser, err := kong.New(&cli)
+	require.NoError(t, err)
+	_, err = parser.Parse([]string{"issue", "update", "--key=ARC-1",
+		`--field=components=[{"name": "api"}, {"name": "ui"}]`, "--field=note=first; second",
+		"--field=version=1.10", "--field=id=12345678901234567890", "--field=pair=1 2"})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]any{
+		"components": []any{map[string]any{"name": "api"}, map[string]any{"name": "ui"}},
+		"note":       "first; second",
+		"version":    json.Number("1.10"),
+		"id":         json.Number("12345678901234567890"),
+		"pair":       "1 2",
+	}, cli.Issue.Update.fields().CustomFields)
+}
+
+func TestChangeIssues_Bulk(t *testing.T) {
+	for _, tt := range []struct {
+		command string
+		in      string
+		changes []string
+	}{
+		{
+			command: "issue transition",
+			in:      `{"key": "ARC-1"} {"key": "ARC-2", "status": "In Review"}`,
+			changes: []string{"transition ARC-1 Done", "transition ARC-2 In Review"},
+		},
+		{
+			command: "issue update",
+			in:      `{"key": "ARC-1", "fixVersions": ["1.2"]}`,
+			changes: []string{`update ARC-1 {"fixVersions":["1.2"]}`},
+		},
+		{
+			command: "issue comment",
+			in:      `{"key": "ARC-1", "body": "released"}`,
+			changes: []string{"comment ARC-1 released"},
+		},
+		{
+			command: "issue assign",
+			in:      `{"key": "ARC-1", "assignee": "5b10a284"}` + "\n" + `{"key": "ARC-2"}`,
+			changes: []string{"assign ARC-1 5b10a284", "assign ARC-2 "},
+		},
+	} {
+		cmds := &issueCommands{}
+		cmds.Transition.Status = "Done"
+
+		s := &recordingService{}
+		require.NoError(t, changeIssues(tt.command, cmds, s, strings.NewReader(tt.in), &bytes.Buffer{}), tt.command)
+		assert.Equal(t, tt.changes, s.changes, tt.command)
+	}
+}
+
+func TestChangeIssues_Invalid(t *testing.T) {
+	cmds := &issueCommands{}
+
+	err := changeIssues("issue transition", cmds, &recordingService{}, strings.NewReader(`{"key": "ARC-1"}`), &bytes.Buffer{})
+	assert.ErrorContains(t, err, "record 1: no status to transition ARC-1 to")
+
+	err = changeIssues("issue comment", cmds, &recordingService{}, strings.NewReader(`{"key": "ARC-1", "body": "x"} {"body": "y"}`), &bytes.Buffer{})
+	assert.ErrorContains(t, err, "record 2: no key")
+
+	err = changeIssues("issue update", cmds, &recordingService{}, strings.NewReader(`{"key": "ARC-1"`), &bytes.Buffer{})
+	assert.ErrorContains(t, err, "record 1:")
+
+	err = changeIssues("issue create", cmds, &recordingService{}, strings.NewReader(`{"project": "FAIL", "summary": "x"}`), &bytes.Buffer{})
+	assert.ErrorContains(t, err, "record 1: cannot create issue")
+}
@@ -24,8 +24,12 @@ var cli struct {
 		Remove string `help:"Groups to remove, comma separated." required:""`
 	} `cmd:"" help:"Add groups to an existing user."`
 	Issues struct {
-		Query string `help:"search by query" required:""`
+		Query  string   `help:"search by query" required:""`
+		Fields []string `help:"fields of the issues to return, e.g. 'summary,status,storyPoints', all if empty" sep:","`
+		Max    int      `help:"maximum number of issues to return, all if 0"`
 	} `cmd:"" help:"Find or update issues"`
+	Fields struct{}      `cmd:"" help:"List the fields of the issues, e.g. to find the ID of a custom field."`
+	Issue  issueCommands `cmd:"" help:"Create, update, transition, comment on or assign issues."`
 }
 
 func Exec(ctx context.Context, args []string) {
@@ -46,34 +50,64 @@ func Exec(ctx context.Context, args []string) {
 		name := deriveNameFromEmail(email)
 		createUser(name, email, groups)
 	case "issues":
-		queryIssues(cli.Issues.Query)
+		queryIssues(cli.Issues.Query, jira.SearchOptions{Fields: cli.Issues.Fields, MaxResults: cli.Issues.Max})
+	case "fields":
+		listFields()
+	case "issue create", "issue update", "issue transition", "issue comment", "issue assign":
+		if err := changeIssues(kctx.Command(), &cli.Issue, newService(), os.Stdin, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
 	default:
 		panic(kctx.Command())
 	}
 }
 
-func queryIssues(query string) {
-	clientCredentials, err := credentials.FindCredentials()
+func queryIssues(query string, o jira.SearchOptions) {
+	service := newService()
+
+	issues, err := service.Search(query, o)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	service, err := jira.NewJiraService(clientCredentials.Baseurl, clientCredentials.Username, clientCredentials.Token)
+	err = json.NewEncoder(os.Stdout).Encode(issues)
 	if err != nil {
 		log.Fatal(err)
 	}
+}
+
+func listFields() {
+	service := newService()
 
-	issues, err := service.SearchByQuery(query)
+	fields, err := service.Fields()
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	err = json.NewEncoder(os.Stdout).Encode(issues)
+	err = json.NewEncoder(os.Stdout).Encode(fields)
 	if err != nil {
 		log.Fatal(err)
 	}
 }
 
+// newService connects to Jira with the credentials and custom fields of the config
+func newService() *jira.JiraService {
+	clientCredentials, err := credentials.FindCredentials()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	service, err := jira.NewJiraService(clientCredentials.Baseurl, clientCredentials.Username, clientCredentials.Token)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := service.SetCustomFields(clientCredentials.CustomFields); err != nil {
+		log.Fatal(err)
+	}
+	return service
+}
+
 func createUser(name, email string, groups []string) {
 	clientCredentials, err := credentials.FindCredentials()
 	if err != nil {
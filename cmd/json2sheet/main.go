@@ -10,10 +10,19 @@ import (
 	"github.com/alecthomas/kong"
 
 	"github.com/trichner/toolbox/pkg/json2sheet"
+	"github.com/trichner/toolbox/pkg/jsontree"
+	"github.com/trichner/toolbox/pkg/jsontree/jsonpath"
+	"github.com/trichner/toolbox/pkg/jsontree/lexer"
+	"github.com/trichner/toolbox/pkg/sheets"
 )
 
 var cli struct {
 	SpreadsheetUrl string `help:"complete URL to the spreadsheet"`
+	Backend        string `help:"sheets backend, 'local' stores spreadsheets as CSV files for tests and dry runs" enum:"google,local" default:"google"`
+	LocalDir       string `help:"directory of the 'local' backend" default:"." type:"path"`
+	Select         string `help:"JSONPath expression selecting the values to write as rows, e.g. '$.items[*]'"`
+
+	lexer.DialectFlag `embed:""`
 }
 
 func Exec(ctx context.Context, args []string) {
@@ -21,17 +30,35 @@ func Exec(ctx context.Context, args []string) {
 	_, err := parser.Parse(args[1:])
 	parser.FatalIfErrorf(err)
 
+	svc, err := sheets.NewSheetServiceForBackend(ctx, sheets.Backend(cli.Backend), cli.LocalDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	opts := []json2sheet.Option{json2sheet.WithDialect(cli.Dialect)}
+	if cli.Select != "" {
+		q, err := jsonpath.Compile(cli.Select)
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts = append(opts, json2sheet.WithSelect(q))
+	}
+
 	spreadsheetUrl := strings.TrimSpace(cli.SpreadsheetUrl)
 	if spreadsheetUrl != "" {
-		url, err := json2sheet.UpdateSheet(ctx, spreadsheetUrl, os.Stdin)
+		ref, err := sheets.ParseSheetRef(spreadsheetUrl)
 		if err != nil {
 			log.Fatal(err)
 		}
+		url, err := json2sheet.UpdateSheet(svc, ref, os.Stdin, opts...)
+		if err != nil {
+			log.Fatal(jsontree.FormatError(err))
+		}
 		fmt.Println(url)
 	} else {
-		url, err := json2sheet.WriteToNewSheet(ctx, os.Stdin)
+		url, err := json2sheet.WriteToNewSheet(svc, os.Stdin, opts...)
 		if err != nil {
-			log.Fatal(err)
+			log.Fatal(jsontree.FormatError(err))
 		}
 		fmt.Println(url)
 	}
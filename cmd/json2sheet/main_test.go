@@ -2,17 +2,19 @@ package json2sheet
 
 import (
 	"fmt"
-	"log"
-	"os"
+	"strings"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/trichner/toolbox/pkg/json2sheet"
+	"github.com/trichner/toolbox/pkg/sheets"
 )
 
 func TestExec(t *testing.T) {
-	url, err := json2sheet.WriteToNewSheet(os.Stdin)
-	if err != nil {
-		log.Fatal(err)
-	}
+	svc, err := sheets.NewLocalSheetService(t.TempDir())
+	assert.NoError(t, err)
+
+	url, err := json2sheet.WriteToNewSheet(svc, strings.NewReader(`{"a":1}`))
+	assert.NoError(t, err)
 	fmt.Println(url)
 }
@@ -0,0 +1,294 @@
+package json2sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/trichner/toolbox/pkg/jsontree/ast"
+	"github.com/trichner/toolbox/pkg/jsontree/serializer"
+	"github.com/trichner/toolbox/pkg/sqlconn"
+)
+
+// maxArgs keeps statements below the bind parameter limits of the databases, SQLite allows the fewest
+const maxArgs = 32766
+
+// record is a JSON object of the input, records are numbered from 1 within each file
+type record struct {
+	name       string
+	n          int
+	properties []*ast.Property
+}
+
+// loader inserts records into a table in batches of multi-row INSERT statements, records with keys update
+// existing rows instead. A record sets the columns of its properties only, the other columns keep their
+// default when it is inserted and their value when it updates a row. Hence records are batched by the
+// columns they have.
+type loader struct {
+	tx        *sql.Tx
+	driver    sqlconn.Driver
+	table     string
+	columns   []column
+	index     map[string]int
+	keys      []string
+	batchSize int
+
+	// batches are the pending rows by their columns, in the order they were started
+	batches []*batch
+	rows    int
+	pending map[string]bool
+	loaded  int
+}
+
+// batch are rows with values for the same columns
+type batch struct {
+	indexes []int
+	rows    [][]ast.Node
+}
+
+func newLoader(tx *sql.Tx, driver sqlconn.Driver, table string, columns []column, keys []string, batchSize int) (*loader, error) {
+	index := map[string]int{}
+	for i, c := range columns {
+		index[c.name] = i
+	}
+	for _, k := range keys {
+		if _, ok := index[k]; !ok {
+			return nil, fmt.Errorf("key column %q is not a column of table %s", k, table)
+		}
+	}
+
+	// the bind parameters of a full batch must not exceed the limit
+	if len(columns) > 0 && batchSize*len(columns) > maxArgs {
+		batchSize = max(1, maxArgs/len(columns))
+	}
+	return &loader{
+		tx:        tx,
+		driver:    driver,
+		table:     table,
+		columns:   columns,
+		index:     index,
+		keys:      keys,
+		batchSize: batchSize,
+		pending:   map[string]bool{},
+	}, nil
+}
+
+// add queues a record
+func (l *loader) add(ctx context.Context, r record) error {
+	row := make([]ast.Node, len(l.columns))
+	for _, p := range r.properties {
+		i, ok := l.index[p.Name]
+		if !ok {
+			return fmt.Errorf("%s record %d: %q is not a column of table %s", r.name, r.n, p.Name, l.table)
+		}
+		row[i] = p.Value
+	}
+
+	key, err := l.key(row)
+	if err != nil {
+		return fmt.Errorf("%s record %d: %w", r.name, r.n, err)
+	}
+	// a row cannot be updated twice by the same statement, nor before an earlier update of it
+	if l.rows >= l.batchSize || (key != "" && l.pending[key]) {
+		if err := l.flush(ctx); err != nil {
+			return err
+		}
+	}
+	if key != "" {
+		l.pending[key] = true
+	}
+
+	var indexes []int
+	var values []ast.Node
+	for i, v := range row {
+		if v != nil {
+			indexes = append(indexes, i)
+			values = append(values, v)
+		}
+	}
+	b := l.batch(indexes)
+	b.rows = append(b.rows, values)
+	l.rows++
+	return nil
+}
+
+// batch returns the pending batch of rows with values for the columns of the indexes
+func (l *loader) batch(indexes []int) *batch {
+	for _, b := range l.batches {
+		if slices.Equal(b.indexes, indexes) {
+			return b
+		}
+	}
+	b := &batch{indexes: indexes}
+	l.batches = append(l.batches, b)
+	return b
+}
+
+// key identifies the row of a record for upserts
+func (l *loader) key(row []ast.Node) (string, error) {
+	if len(l.keys) == 0 {
+		return "", nil
+	}
+	var b strings.Builder
+	for _, k := range l.keys {
+		v := row[l.index[k]]
+		if v == nil || v.Type() == ast.NodeTypeNull {
+			return "", fmt.Errorf("missing key column %q", k)
+		}
+		text, err := serializer.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		b.Write(text)
+		b.WriteByte(0)
+	}
+	return b.String(), nil
+}
+
+// flush inserts the pending rows
+func (l *loader) flush(ctx context.Context) error {
+	for _, b := range l.batches {
+		if err := l.insert(ctx, b); err != nil {
+			return err
+		}
+	}
+
+	l.loaded += l.rows
+	l.rows = 0
+	l.batches = l.batches[:0]
+	clear(l.pending)
+	return nil
+}
+
+func (l *loader) insert(ctx context.Context, b *batch) error {
+	columns := make([]string, len(b.indexes))
+	for c, i := range b.indexes {
+		columns[c] = l.columns[i].name
+	}
+
+	args := make([]any, 0, len(b.rows)*len(b.indexes))
+	for _, row := range b.rows {
+		for c, i := range b.indexes {
+			arg, err := l.columns[i].typ.arg(row[c])
+			if err != nil {
+				return fmt.Errorf("cannot convert column %q: %w", l.columns[i].name, err)
+			}
+			args = append(args, arg)
+		}
+	}
+
+	stmt := insertStatement(l.driver, l.table, columns, len(b.rows), l.keys)
+	if _, err := l.tx.ExecContext(ctx, stmt, args...); err != nil {
+		return fmt.Errorf("cannot insert into table %s: %w", l.table, err)
+	}
+	return nil
+}
+
+// insertStatement inserts rows of values for the columns, with keys rows having the same key are updated
+func insertStatement(driver sqlconn.Driver, table string, columns []string, rows int, keys []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "INSERT INTO %s (%s) VALUES ", quoteTable(driver, table), quoteColumns(driver, columns))
+	n := 0
+	for r := 0; r < rows; r++ {
+		if r > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString("(")
+		for c := range columns {
+			if c > 0 {
+				b.WriteString(", ")
+			}
+			n++
+			b.WriteString(driver.Placeholder(n))
+		}
+		b.WriteString(")")
+	}
+	if len(keys) == 0 {
+		return b.String()
+	}
+
+	isKey := map[string]bool{}
+	for _, k := range keys {
+		isKey[k] = true
+	}
+	var updates []string
+	for _, c := range columns {
+		if isKey[c] {
+			continue
+		}
+		q := driver.QuoteIdentifier(c)
+		if driver == sqlconn.DriverMySQL {
+			updates = append(updates, fmt.Sprintf("%s = VALUES(%s)", q, q))
+		} else {
+			updates = append(updates, fmt.Sprintf("%s = EXCLUDED.%s", q, q))
+		}
+	}
+
+	if driver == sqlconn.DriverMySQL {
+		if len(updates) == 0 {
+			// MySQL has no way to do nothing on a duplicate key
+			q := driver.QuoteIdentifier(keys[0])
+			updates = append(updates, fmt.Sprintf("%s = %s", q, q))
+		}
+		fmt.Fprintf(&b, " ON DUPLICATE KEY UPDATE %s", strings.Join(updates, ", "))
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, " ON CONFLICT (%s) DO ", quoteColumns(driver, keys))
+	if len(updates) == 0 {
+		b.WriteString("NOTHING")
+	} else {
+		fmt.Fprintf(&b, "UPDATE SET %s", strings.Join(updates, ", "))
+	}
+	return b.String()
+}
+
+// arg converts a value to a bind parameter for a column of the type, nested values are passed as JSON text
+func (t columnType) arg(n ast.Node) (any, error) {
+	if n == nil || n.Type() == ast.NodeTypeNull {
+		return nil, nil
+	}
+	if t == typeJSON {
+		return marshal(n)
+	}
+
+	switch n.Type() {
+	case ast.NodeTypeBoolean:
+		v := n.(ast.BooleanNode).Value()
+		switch t {
+		case typeInteger, typeFloat:
+			if v {
+				return int64(1), nil
+			}
+			return int64(0), nil
+		case typeText:
+			return strconv.FormatBool(v), nil
+		}
+		return v, nil
+	case ast.NodeTypeNumber:
+		literal := n.(ast.NumberNode).Value()
+		switch t {
+		case typeText:
+			// exact, e.g. for DECIMAL columns
+			return literal, nil
+		case typeBoolean:
+			f, err := strconv.ParseFloat(literal, 64)
+			return f != 0, err
+		}
+		if i, err := strconv.ParseInt(literal, 10, 64); err == nil && t != typeFloat {
+			return i, nil
+		}
+		return strconv.ParseFloat(literal, 64)
+	case ast.NodeTypeText:
+		return n.(ast.TextNode).Value(), nil
+	}
+	return marshal(n)
+}
+
+func marshal(n ast.Node) (string, error) {
+	b, err := serializer.Marshal(n)
+	return string(b), err
+}
@@ -0,0 +1,198 @@
+package json2sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/alecthomas/kong"
+	"github.com/trichner/toolbox/pkg/jsontree"
+	"github.com/trichner/toolbox/pkg/jsontree/ast"
+	"github.com/trichner/toolbox/pkg/jsontree/lexer"
+	"github.com/trichner/toolbox/pkg/sqlconn"
+)
+
+type cli struct {
+	DbConnectionUri string `help:"database connection URI, the scheme selects the driver, e.g.: 'mysql://root@127.0.0.1:3306/mydb', 'postgres://postgres@127.0.0.1:5432/mydb' or 'sqlite:///tmp/my.db'" required:"" env:"JSON2SQL_DB_CONNECTION_URI"`
+
+	DbUser     string `help:"user for the database" optional:"" env:"JSON2SQL_DB_USER"`
+	DbPassword string `help:"password for the database" optional:"" env:"JSON2SQL_DB_PASSWORD"`
+	DbName     string `help:"name of the database" optional:"" env:"JSON2SQL_DB_NAME"`
+
+	Table     string   `help:"table to load the records into, e.g. 'orders' or 'scratch.orders'" required:""`
+	Create    bool     `help:"create the table if it does not exist, its columns and their types are inferred from the records"`
+	Key       []string `help:"columns identifying a row, records with the key of an existing row update the columns of their properties rather than being inserted, the primary key of a created table"`
+	BatchSize int      `help:"number of rows inserted per statement" default:"500"`
+
+	lexer.DialectFlag `embed:""`
+
+	Files []string `arg:"" optional:"" help:"NDJSON files of objects to load, top-level arrays are read as a stream of their items, defaults to stdin" type:"existingfile"`
+}
+
+// options of loading records into a table
+type options struct {
+	table     string
+	create    bool
+	keys      []string
+	batchSize int
+}
+
+func Exec(ctx context.Context, args []string) {
+	// kong expects only actual arguments and not the program itself
+	args = args[1:]
+
+	var flags cli
+
+	k, err := kong.New(&flags)
+	if err != nil {
+		log.Fatalf("cannot parse arguments: %v", err)
+	}
+	_, err = k.Parse(args)
+	if err != nil {
+		log.Fatalf("cannot parse arguments: %v", err)
+	}
+	if flags.BatchSize < 1 {
+		log.Fatal("the batch size must be at least 1")
+	}
+
+	cfg, err := sqlconn.Parse(flags.DbConnectionUri, sqlconn.Overrides{
+		User:     flags.DbUser,
+		Password: flags.DbPassword,
+		Database: flags.DbName,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("connecting to database")
+	db, err := cfg.Open(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	o := &options{table: flags.Table, create: flags.Create, keys: flags.Key, batchSize: flags.BatchSize}
+	n, err := load(ctx, db, cfg.Driver, o, func(fn func(r record) error) error {
+		return forEachRecord(flags.Files, flags.Dialect, fn)
+	})
+	if err != nil {
+		log.Fatal(jsontree.FormatError(err))
+	}
+	log.Printf("loaded %d records into %s", n, flags.Table)
+}
+
+// load inserts all records in a single transaction and returns how many it loaded, a created table
+// needs all records to infer its columns before the first one is inserted
+func load(ctx context.Context, db *sql.DB, driver sqlconn.Driver, o *options, records func(fn func(r record) error) error) (int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("cannot begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if o.create {
+		var buffered []record
+		err := records(func(r record) error {
+			buffered = append(buffered, r)
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+		if err := createTable(ctx, tx, driver, o, inferColumns(buffered)); err != nil {
+			return 0, err
+		}
+		records = func(fn func(r record) error) error {
+			for _, r := range buffered {
+				if err := fn(r); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+
+	columns, err := tableColumns(ctx, tx, driver, o.table)
+	if err != nil {
+		return 0, err
+	}
+	l, err := newLoader(tx, driver, o.table, columns, o.keys, o.batchSize)
+	if err != nil {
+		return 0, err
+	}
+	err = records(func(r record) error {
+		return l.add(ctx, r)
+	})
+	if err != nil {
+		return 0, err
+	}
+	if err := l.flush(ctx); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("cannot commit transaction: %w", err)
+	}
+	return l.loaded, nil
+}
+
+// createTable creates the table for the columns unless it exists, without records there are no columns and
+// the table must exist already
+func createTable(ctx context.Context, tx *sql.Tx, driver sqlconn.Driver, o *options, columns []column) error {
+	if len(columns) == 0 {
+		return nil
+	}
+	stmt, err := createTableStatement(driver, o.table, columns, o.keys)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("cannot create table %s: %w", o.table, err)
+	}
+	return nil
+}
+
+// forEachRecord calls fn for every record of the files or stdin
+func forEachRecord(files []string, dialect lexer.Dialect, fn func(r record) error) error {
+	if len(files) == 0 {
+		return readRecords("stdin", os.Stdin, dialect, fn)
+	}
+	for _, name := range files {
+		err := func() error {
+			f, err := os.Open(name)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			return readRecords(name, f, dialect, fn)
+		}()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readRecords reads the objects of NDJSON or of a top-level array
+func readRecords(name string, in io.Reader, dialect lexer.Dialect, fn func(r record) error) error {
+	r := jsontree.NewItemReader(lexer.NewLexer(in, lexer.WithDialect(dialect)))
+	for i := 1; ; i++ {
+		n, err := r.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("cannot read %s: %w", name, err)
+		}
+		o, ok := n.(ast.ObjectNode)
+		if !ok {
+			return fmt.Errorf("%s record %d: expected an object", name, i)
+		}
+		if err := fn(record{name: name, n: i, properties: o.Properties()}); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package json2sql
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trichner/toolbox/pkg/sqlconn"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	db, err := sqlconn.Open(context.Background(), "sqlite://"+filepath.Join(t.TempDir(), "scratch.db"), sqlconn.Overrides{})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// loadString loads NDJSON into the SQLite test database
+func loadString(t *testing.T, db *sql.DB, o *options, input string) (int, error) {
+	if o.batchSize == 0 {
+		o.batchSize = 500
+	}
+	return load(context.Background(), db, sqlconn.DriverSQLite, o, func(fn func(r record) error) error {
+		return readRecords("input", strings.NewReader(input), "json", fn)
+	})
+}
+
+// query returns the rows of a query as lines of '|' separated values
+func query(t *testing.T, db *sql.DB, q string) string {
+	rows, err := db.Query(q)
+	require.NoError(t, err)
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	require.NoError(t, err)
+
+	var lines []string
+	for rows.Next() {
+		values := make([]sql.NullString, len(columns))
+		pointers := make([]any, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		require.NoError(t, rows.Scan(pointers...))
+
+		cells := make([]string, len(values))
+		for i, v := range values {
+			cells[i] = "NULL"
+			if v.Valid {
+				cells[i] = v.String
+			}
+		}
+		lines = append(lines, strings.Join(cells, "|"))
+	}
+	require.NoError(t, rows.Err())
+	return strings.Join(lines, "\n")
+}
+
+func TestLoad_Create(t *testing.T) {
+	db := openTestDB(t)
+	input := `{"id":1,"name":"acme","total":120.5,"paid":true,"tags":["a"],"zip":"8001"}
+{"id":2,"name":"initech","total":80,"paid":false,"note":null,"zip":8002}
+[{"id":3,"name":"globex"}]
+`
+	n, err := loadString(t, db, &options{table: "orders", create: true, keys: []string{"id"}}, input)
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	var schema string
+	require.NoError(t, db.QueryRow("SELECT sql FROM sqlite_master WHERE name = 'orders'").Scan(&schema))
+	assert.Equal(t, `CREATE TABLE "orders" (
+  "id" INTEGER NOT NULL,
+  "name" TEXT,
+  "total" REAL,
+  "paid" BOOLEAN,
+  "tags" TEXT,
+  "zip" TEXT,
+  "note" TEXT,
+  PRIMARY KEY ("id")
+)`, schema)
+
+	assert.Equal(t, `1|acme|120.5|1|["a"]|8001|NULL
+2|initech|80|0|NULL|8002|NULL
+3|globex|NULL|NULL|NULL|NULL|NULL`, query(t, db, "SELECT * FROM orders ORDER BY id"))
+	assert.Equal(t, "integer|real|text", query(t, db, "SELECT typeof(id), typeof(total), typeof(zip) FROM orders WHERE id = 2"))
+}
+
+func TestLoad_Upsert(t *testing.T) {
+	db := openTestDB(t)
+	_, err := db.Exec(`CREATE TABLE stock (sku TEXT, site TEXT, count INTEGER, PRIMARY KEY (sku, site))`)
+	require.NoError(t, err)
+
+	o := &options{table: "stock", keys: []string{"sku", "site"}, batchSize: 2}
+	input := `{"sku":"a","site":"zrh","count":1}
+{"sku":"a","site":"ber","count":2}
+{"sku":"b","site":"zrh","count":3}
+{"sku":"a","site":"zrh","count":4}
+{"sku":"a","site":"zrh","count":5}
+`
+	n, err := loadString(t, db, o, input)
+	require.NoError(t, err)
+	assert.Equal(t, 5, n)
+	assert.Equal(t, "a|ber|2\na|zrh|5\nb|zrh|3", query(t, db, "SELECT * FROM stock ORDER BY sku, site"))
+
+	_, err = loadString(t, db, o, `{"sku":"b","site":"zrh","count":true}`)
+	require.NoError(t, err)
+	assert.Equal(t, "b|zrh|1", query(t, db, "SELECT * FROM stock WHERE sku = 'b'"))
+}
+
+func TestLoad_UpsertPartialRecords(t *testing.T) {
+	db := openTestDB(t)
+	_, err := db.Exec(`CREATE TABLE stock (sku TEXT PRIMARY KEY, count INTEGER, note TEXT DEFAULT 'new')`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO stock VALUES ('a', 1, 'keep me'), ('c', 1, 'clear me')`)
+	require.NoError(t, err)
+
+	// a record updates the columns of its properties only, regardless of the other records of its batch
+	o := &options{table: "stock", keys: []string{"sku"}, batchSize: 10}
+	n, err := loadString(t, db, o, `{"sku":"a","count":2}
+{"sku":"b","note":"y"}
+{"sku":"c","note":null}
+{"sku":"d","count":4}
+{"sku":"a","note":"kept"}`)
+	require.NoError(t, err)
+	assert.Equal(t, 5, n)
+	assert.Equal(t, "a|2|kept\nb|NULL|y\nc|1|NULL\nd|4|new", query(t, db, "SELECT * FROM stock ORDER BY sku"))
+}
+
+func TestLoad_ExistingTable(t *testing.T) {
+	db := openTestDB(t)
+	_, err := db.Exec(`CREATE TABLE people (name TEXT NOT NULL, age INTEGER, meta JSON)`)
+	require.NoError(t, err)
+
+	_, err = loadString(t, db, &options{table: "people"}, `{"name":"ada","meta":{"born":1815}}
+{"name":"alan","age":41}`)
+	require.NoError(t, err)
+	assert.Equal(t, "ada|NULL|{\"born\":1815}\nalan|41|NULL", query(t, db, "SELECT * FROM people ORDER BY name"))
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	db := openTestDB(t)
+	_, err := db.Exec(`CREATE TABLE people (name TEXT NOT NULL, age INTEGER)`)
+	require.NoError(t, err)
+
+	_, err = loadString(t, db, &options{table: "people"}, `{"name":"ada"}
+{"name":"alan","email":"alan@example.com"}`)
+	assert.ErrorContains(t, err, `input record 2: "email" is not a column of table people`)
+
+	_, err = loadString(t, db, &options{table: "people"}, `{"name":"ada"}
+{"age":41}`)
+	assert.ErrorContains(t, err, "NOT NULL constraint failed")
+	// the transaction is rolled back
+	assert.Equal(t, "", query(t, db, "SELECT * FROM people"))
+
+	_, err = loadString(t, db, &options{table: "people"}, `["ada"]`)
+	assert.ErrorContains(t, err, "input record 1: expected an object")
+
+	_, err = loadString(t, db, &options{table: "people", keys: []string{"id"}}, `{"name":"ada"}`)
+	assert.ErrorContains(t, err, `key column "id" is not a column of table people`)
+
+	_, err = loadString(t, db, &options{table: "missing"}, `{"name":"ada"}`)
+	assert.ErrorContains(t, err, "no such table")
+
+	_, err = loadString(t, db, &options{table: "created", create: true, keys: []string{"id"}}, `{"name":"ada"}`)
+	assert.ErrorContains(t, err, `key column "id" is not in any record`)
+}
+
+func TestInferColumns(t *testing.T) {
+	var records []record
+	err := readRecords("input", strings.NewReader(`{"a":1,"b":1,"c":true,"d":null,"e":{}}
+{"a":2,"b":1.5,"c":"yes","d":null,"e":[]}
+{"a":9223372036854775808}`), "json", func(r record) error {
+		records = append(records, r)
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []column{
+		{"a", typeFloat},
+		{"b", typeFloat},
+		{"c", typeText},
+		{"d", typeUnknown},
+		{"e", typeJSON},
+	}, inferColumns(records))
+}
+
+func TestCreateTableStatement(t *testing.T) {
+	columns := []column{{"id", typeText}, {"total", typeFloat}, {"meta", typeJSON}, {"seen", typeUnknown}}
+
+	stmt, err := createTableStatement(sqlconn.DriverMySQL, "scratch.orders", columns, []string{"id"})
+	require.NoError(t, err)
+	assert.Equal(t, "CREATE TABLE IF NOT EXISTS `scratch`.`orders` (\n  `id` VARCHAR(255) NOT NULL,\n  `total` DOUBLE,\n  `meta` JSON,\n  `seen` TEXT,\n  PRIMARY KEY (`id`)\n)", stmt)
+
+	stmt, err = createTableStatement(sqlconn.DriverPostgres, "orders", columns, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "CREATE TABLE IF NOT EXISTS \"orders\" (\n  \"id\" TEXT,\n  \"total\" DOUBLE PRECISION,\n  \"meta\" JSONB,\n  \"seen\" TEXT\n)", stmt)
+}
+
+func TestInsertStatement(t *testing.T) {
+	columns := []string{"id", "name"}
+	assert.Equal(t, `INSERT INTO "t" ("id", "name") VALUES ($1, $2), ($3, $4)`,
+		insertStatement(sqlconn.DriverPostgres, "t", columns, 2, nil))
+	assert.Equal(t, `INSERT INTO "t" ("id", "name") VALUES ($1, $2) ON CONFLICT ("id") DO UPDATE SET "name" = EXCLUDED."name"`,
+		insertStatement(sqlconn.DriverPostgres, "t", columns, 1, []string{"id"}))
+	assert.Equal(t, `INSERT INTO "t" ("id") VALUES (?) ON CONFLICT ("id") DO NOTHING`,
+		insertStatement(sqlconn.DriverSQLite, "t", columns[:1], 1, []string{"id"}))
+	assert.Equal(t, "INSERT INTO `t` (`id`, `name`) VALUES (?, ?) ON DUPLICATE KEY UPDATE `name` = VALUES(`name`)",
+		insertStatement(sqlconn.DriverMySQL, "t", columns, 1, []string{"id"}))
+	assert.Equal(t, "INSERT INTO `t` (`id`) VALUES (?), (?) ON DUPLICATE KEY UPDATE `id` = `id`",
+		insertStatement(sqlconn.DriverMySQL, "t", columns[:1], 2, []string{"id"}))
+}
@@ -0,0 +1,199 @@
+package json2sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/trichner/toolbox/pkg/jsontree/ast"
+	"github.com/trichner/toolbox/pkg/sqlconn"
+)
+
+// columnType tells how JSON values are converted for a column
+type columnType int
+
+const (
+	// typeUnknown takes values as they are, e.g. for columns of only nulls or SQLite columns without a declared type
+	typeUnknown columnType = iota
+	typeBoolean
+	typeInteger
+	typeFloat
+	typeText
+	typeJSON
+)
+
+type column struct {
+	name string
+	typ  columnType
+}
+
+// valueType is the narrowest column type holding a value
+func valueType(n ast.Node) columnType {
+	switch n.Type() {
+	case ast.NodeTypeBoolean:
+		return typeBoolean
+	case ast.NodeTypeNumber:
+		if _, err := strconv.ParseInt(n.(ast.NumberNode).Value(), 10, 64); err == nil {
+			return typeInteger
+		}
+		return typeFloat
+	case ast.NodeTypeText:
+		return typeText
+	case ast.NodeTypeObject, ast.NodeTypeArray:
+		return typeJSON
+	}
+	return typeUnknown
+}
+
+// widen returns a column type holding the values of both types, any value can be stored as text
+func widen(a, b columnType) columnType {
+	switch {
+	case a == b || b == typeUnknown:
+		return a
+	case a == typeUnknown:
+		return b
+	case (a == typeInteger && b == typeFloat) || (a == typeFloat && b == typeInteger):
+		return typeFloat
+	}
+	return typeText
+}
+
+// inferColumns infers the columns of a table holding the records, in the order they first appear
+func inferColumns(records []record) []column {
+	var columns []column
+	index := map[string]int{}
+	for _, r := range records {
+		for _, p := range r.properties {
+			i, ok := index[p.Name]
+			if !ok {
+				i = len(columns)
+				index[p.Name] = i
+				columns = append(columns, column{name: p.Name})
+			}
+			columns[i].typ = widen(columns[i].typ, valueType(p.Value))
+		}
+	}
+	return columns
+}
+
+// databaseType maps the type the database reports for a column, e.g. 'VARCHAR(255)', everything not known
+// otherwise is written as text
+func databaseType(name string) columnType {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	if i := strings.IndexByte(name, '('); i >= 0 {
+		name = strings.TrimSpace(name[:i])
+	}
+	name = strings.TrimPrefix(name, "UNSIGNED ")
+
+	switch name {
+	case "BOOL", "BOOLEAN":
+		return typeBoolean
+	case "TINYINT", "INT", "INTEGER", "SMALLINT", "MEDIUMINT", "BIGINT", "INT2", "INT4", "INT8", "BIG INT",
+		"SERIAL", "BIGSERIAL", "SMALLSERIAL":
+		return typeInteger
+	case "FLOAT", "DOUBLE", "REAL", "FLOAT4", "FLOAT8", "DOUBLE PRECISION":
+		return typeFloat
+	case "JSON", "JSONB":
+		return typeJSON
+	case "":
+		return typeUnknown
+	}
+	return typeText
+}
+
+// sqlType is the type of a created column, MySQL cannot index TEXT columns and hence keys are VARCHAR
+func sqlType(driver sqlconn.Driver, typ columnType, key bool) string {
+	switch typ {
+	case typeBoolean:
+		return "BOOLEAN"
+	case typeInteger:
+		if driver == sqlconn.DriverSQLite {
+			return "INTEGER"
+		}
+		return "BIGINT"
+	case typeFloat:
+		switch driver {
+		case sqlconn.DriverPostgres:
+			return "DOUBLE PRECISION"
+		case sqlconn.DriverSQLite:
+			return "REAL"
+		}
+		return "DOUBLE"
+	case typeJSON:
+		switch driver {
+		case sqlconn.DriverPostgres:
+			return "JSONB"
+		case sqlconn.DriverMySQL:
+			return "JSON"
+		}
+	}
+	if key && driver == sqlconn.DriverMySQL {
+		return "VARCHAR(255)"
+	}
+	return "TEXT"
+}
+
+// createTableStatement creates the table unless it exists, the keys become its primary key
+func createTableStatement(driver sqlconn.Driver, table string, columns []column, keys []string) (string, error) {
+	isKey := map[string]bool{}
+	for _, k := range keys {
+		isKey[k] = true
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "CREATE TABLE IF NOT EXISTS %s (", quoteTable(driver, table))
+	for i, c := range columns {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		fmt.Fprintf(&b, "\n  %s %s", driver.QuoteIdentifier(c.name), sqlType(driver, c.typ, isKey[c.name]))
+		if isKey[c.name] {
+			b.WriteString(" NOT NULL")
+			delete(isKey, c.name)
+		}
+	}
+	for _, k := range keys {
+		if isKey[k] {
+			return "", fmt.Errorf("key column %q is not in any record", k)
+		}
+	}
+	if len(keys) > 0 {
+		fmt.Fprintf(&b, ",\n  PRIMARY KEY (%s)", quoteColumns(driver, keys))
+	}
+	b.WriteString("\n)")
+	return b.String(), nil
+}
+
+// tableColumns returns the columns of an existing table
+func tableColumns(ctx context.Context, tx *sql.Tx, driver sqlconn.Driver, table string) ([]column, error) {
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %s WHERE 1 = 0", quoteTable(driver, table)))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read columns of table %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read columns of table %s: %w", table, err)
+	}
+	columns := make([]column, len(types))
+	for i, t := range types {
+		columns[i] = column{name: t.Name(), typ: databaseType(t.DatabaseTypeName())}
+	}
+	return columns, rows.Err()
+}
+
+// quoteTable quotes a table name which may be qualified, e.g. 'scratch.orders'
+func quoteTable(driver sqlconn.Driver, table string) string {
+	return driver.QuoteIdentifier(strings.Split(table, ".")...)
+}
+
+func quoteColumns(driver sqlconn.Driver, names []string) string {
+	quoted := make([]string, len(names))
+	for i, n := range names {
+		quoted[i] = driver.QuoteIdentifier(n)
+	}
+	return strings.Join(quoted, ", ")
+}
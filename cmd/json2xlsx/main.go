@@ -0,0 +1,80 @@
+package json2xlsx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+
+	"github.com/alecthomas/kong"
+	"github.com/trichner/toolbox/pkg/json2sheet"
+	"github.com/trichner/toolbox/pkg/jsontree"
+	"github.com/trichner/toolbox/pkg/workbook"
+)
+
+var cli struct {
+	File    string `help:"workbook to write, either .xlsx or .ods, created if it does not exist" required:"" type:"path"`
+	Sheet   string `help:"name of the sheet to write, created if missing, defaults to the first sheet"`
+	Append  bool   `help:"append rows to the sheet instead of replacing its contents, objects are mapped onto the existing header row"`
+	Rewrite bool   `help:"allow changing an existing .ods, it is rewritten from the values of its cells and loses all formatting, formulas and styles"`
+}
+
+func Exec(ctx context.Context, args []string) {
+	parser := kong.Must(&cli, kong.Name(args[0]))
+	_, err := parser.Parse(args[1:])
+	parser.FatalIfErrorf(err)
+
+	if err := checkRewrite(cli.File, cli.Rewrite); err != nil {
+		log.Fatal(err)
+	}
+
+	wb, err := workbook.OpenOrCreate(cli.File)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer wb.Close()
+
+	sheet, err := workbook.SheetOrCreate(wb, cli.Sheet)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if cli.Append {
+		err = json2sheet.AppendTo(sheet, os.Stdin)
+	} else {
+		err = sheet.Clear()
+		if err == nil {
+			err = json2sheet.WriteTo(sheet, os.Stdin)
+		}
+	}
+	if err != nil {
+		log.Fatal(jsontree.FormatError(err))
+	}
+
+	err = wb.Save()
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// checkRewrite refuses to change an existing workbook which would lose its formatting unless rewrite is set
+func checkRewrite(file string, rewrite bool) error {
+	format, err := workbook.FormatFromPath(file)
+	if err != nil {
+		return err
+	}
+	if rewrite || format.KeepsFormatting() {
+		return nil
+	}
+
+	_, err = os.Stat(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("%s exists and would lose all formatting, formulas and styles, use --rewrite to write it anyway", file)
+}
@@ -0,0 +1,129 @@
+package jsondiff
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/alecthomas/kong"
+
+	"github.com/trichner/toolbox/pkg/jsontree"
+	"github.com/trichner/toolbox/pkg/jsontree/ast"
+	"github.com/trichner/toolbox/pkg/jsontree/jsonpatch"
+	"github.com/trichner/toolbox/pkg/jsontree/lexer"
+)
+
+type cliArgs struct {
+	Old    string `arg:"" help:"original JSON or NDJSON file" type:"existingfile"`
+	New    string `arg:"" help:"changed JSON or NDJSON file" type:"existingfile"`
+	NDJSON bool   `name:"ndjson" help:"read the files as NDJSON, i.e. as arrays of their records even if there is a single one"`
+	Key    string `help:"pair NDJSON records by this property rather than by position, e.g. 'id', implies --ndjson"`
+	Format string `help:"output format, 'patch' writes an RFC 6902 JSON Patch" enum:"human,patch" default:"human"`
+	Color  string `help:"colorize the human-readable output" enum:"auto,always,never" default:"auto"`
+
+	lexer.DialectFlag `embed:""`
+}
+
+func Exec(ctx context.Context, args []string) {
+	var cli cliArgs
+	parser := kong.Must(&cli, kong.Name(args[0]))
+	_, err := parser.Parse(args[1:])
+	parser.FatalIfErrorf(err)
+
+	from, err := readFile(cli.Old, &cli)
+	if err != nil {
+		log.Fatal(jsontree.FormatError(err))
+	}
+	to, err := readFile(cli.New, &cli)
+	if err != nil {
+		log.Fatal(jsontree.FormatError(err))
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	err = write(w, jsonpatch.Diff(from, to), cli.Format, useColor(cli.Color))
+	if flushErr := w.Flush(); err == nil {
+		err = flushErr
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func readFile(name string, cli *cliArgs) (ast.Node, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	n, err := read(f, cli)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %s: %w", name, err)
+	}
+	return n, nil
+}
+
+// read parses r into a single document, NDJSON records become an array or, with a key, an object of the
+// records by their key. Without either, r must hold a single value.
+func read(r io.Reader, cli *cliArgs) (ast.Node, error) {
+	reader := jsontree.NewValueReader(lexer.NewLexer(r, lexer.WithDialect(cli.Dialect)))
+
+	var values []ast.Node
+	for {
+		n, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, n)
+	}
+
+	if cli.Key != "" {
+		// a single array of records is as good as NDJSON
+		if len(values) == 1 {
+			if arr, ok := values[0].(ast.ArrayNode); ok {
+				values = arr.Items()
+			}
+		}
+		return jsonpatch.IndexBy(values, cli.Key)
+	}
+	if cli.NDJSON {
+		return ast.NewArrayNode(values), nil
+	}
+	if len(values) != 1 {
+		return nil, fmt.Errorf("expected a single JSON value but got %d, use --ndjson for NDJSON records", len(values))
+	}
+	return values[0], nil
+}
+
+func write(w io.Writer, patch jsonpatch.Patch, format string, color bool) error {
+	if format == "human" {
+		return jsonpatch.Format(w, patch, color)
+	}
+
+	b, err := patch.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", b)
+	return err
+}
+
+func useColor(mode string) bool {
+	switch mode {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	fi, err := os.Stdout.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
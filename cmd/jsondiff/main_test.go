@@ -0,0 +1,63 @@
+package jsondiff
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trichner/toolbox/pkg/jsontree/jsonpatch"
+	"github.com/trichner/toolbox/pkg/jsontree/lexer"
+)
+
+func diff(t *testing.T, cli *cliArgs, old, new string) string {
+	from, err := read(strings.NewReader(old), cli)
+	require.NoError(t, err)
+	to, err := read(strings.NewReader(new), cli)
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	require.NoError(t, write(&out, jsonpatch.Diff(from, to), cli.Format, false))
+	return out.String()
+}
+
+func TestDiff(t *testing.T) {
+	cli := &cliArgs{Format: "human", DialectFlag: lexer.DialectFlag{Dialect: lexer.DialectJSON}}
+	out := diff(t, cli, `{"name": "a", "tags": ["x"]}`, `{"name": "b", "tags": ["x", "y"]}`)
+	assert.Equal(t, "~ /name: \"a\" → \"b\"\n+ /tags/1: \"y\"\n", out)
+
+	cli.Format = "patch"
+	out = diff(t, cli, `{"name": "a"}`, `{"name": "a"}`)
+	assert.Equal(t, "[]\n", out)
+}
+
+func TestDiff_Keyed(t *testing.T) {
+	old := "{\"id\": \"a\", \"v\": 1}\n{\"id\": \"b\", \"v\": 2}\n{\"id\": \"c\", \"v\": 3}\n"
+	new := "{\"id\": \"c\", \"v\": 3}\n{\"id\": \"a\", \"v\": 1}\n{\"id\": \"d\", \"v\": 4}\n"
+
+	cli := &cliArgs{Key: "id", Format: "human", DialectFlag: lexer.DialectFlag{Dialect: lexer.DialectJSON}}
+	out := diff(t, cli, old, new)
+	assert.Equal(t, "- /b: {\"id\":\"b\",\"v\":2}\n+ /d: {\"id\":\"d\",\"v\":4}\n", out)
+
+	// without a key the records are aligned by position
+	cli.Key = ""
+	cli.NDJSON = true
+	out = diff(t, cli, old, new)
+	assert.Equal(t, "- /0: {\"id\":\"a\",\"v\":1}\n- /0: {\"id\":\"b\",\"v\":2}\n+ /1: {\"id\":\"a\",\"v\":1}\n+ /2: {\"id\":\"d\",\"v\":4}\n", out)
+}
+
+func TestDiff_NDJSON(t *testing.T) {
+	cli := &cliArgs{NDJSON: true, Format: "human", DialectFlag: lexer.DialectFlag{Dialect: lexer.DialectJSON}}
+
+	// a single record is an array of one record too
+	out := diff(t, cli, `{"id": "a"}`, "{\"id\": \"a\"}\n{\"id\": \"b\"}\n")
+	assert.Equal(t, "+ /1: {\"id\":\"b\"}\n", out)
+
+	out = diff(t, cli, "", `{"id": "a"}`)
+	assert.Equal(t, "+ /0: {\"id\":\"a\"}\n", out)
+
+	cli.NDJSON = false
+	_, err := read(strings.NewReader("{\"id\": \"a\"}\n{\"id\": \"b\"}\n"), cli)
+	assert.ErrorContains(t, err, "expected a single JSON value but got 2, use --ndjson")
+}
@@ -0,0 +1,107 @@
+package jsonfmt
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/alecthomas/kong"
+
+	"github.com/trichner/toolbox/pkg/jsontree"
+	"github.com/trichner/toolbox/pkg/jsontree/lexer"
+	"github.com/trichner/toolbox/pkg/jsontree/serializer"
+)
+
+type cliArgs struct {
+	Files     []string `arg:"" optional:"" help:"files to format, defaults to stdin" type:"existingfile"`
+	Compact   bool     `help:"write every value on a single line, e.g. for NDJSON" short:"c"`
+	Indent    int      `help:"number of spaces to indent with" default:"2"`
+	Tab       bool     `help:"indent with tabs rather than spaces"`
+	SortKeys  bool     `help:"sort properties by name" short:"S"`
+	Canonical bool     `help:"write canonical JSON as of RFC 8785, implies compact and sorted keys"`
+
+	lexer.DialectFlag `embed:""`
+}
+
+func Exec(ctx context.Context, args []string) {
+	var cli cliArgs
+	parser := kong.Must(&cli, kong.Name(args[0]))
+	_, err := parser.Parse(args[1:])
+	parser.FatalIfErrorf(err)
+
+	w := bufio.NewWriter(os.Stdout)
+	if len(cli.Files) == 0 {
+		err = format(&cli, os.Stdin, w)
+	}
+	for _, name := range cli.Files {
+		err = formatFile(&cli, name, w)
+		if err != nil {
+			err = fmt.Errorf("cannot format %s: %w", name, err)
+			break
+		}
+	}
+
+	// keep what was formatted so far
+	if flushErr := w.Flush(); err == nil {
+		err = flushErr
+	}
+	if err != nil {
+		log.Fatal(jsontree.FormatError(err))
+	}
+}
+
+func formatFile(cli *cliArgs, name string, w io.Writer) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return format(cli, f, w)
+}
+
+// format writes every value read from r, each followed by a newline
+func format(cli *cliArgs, r io.Reader, w io.Writer) error {
+	opts := serializerOptions(cli)
+	values := jsontree.NewValueReader(lexer.NewLexer(r, lexer.WithDialect(cli.Dialect)))
+	for {
+		n, err := values.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		err = serializer.Write(w, n, opts...)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, "\n")
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func serializerOptions(cli *cliArgs) []serializer.Option {
+	var opts []serializer.Option
+	if cli.Canonical {
+		return append(opts, serializer.WithCanonical())
+	}
+	if cli.SortKeys {
+		opts = append(opts, serializer.WithSortedKeys())
+	}
+	if cli.Compact {
+		return opts
+	}
+
+	indent := strings.Repeat(" ", cli.Indent)
+	if cli.Tab {
+		indent = "\t"
+	}
+	return append(opts, serializer.WithIndent("", indent))
+}
@@ -0,0 +1,64 @@
+package jsonfmt
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/kong"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func execute(t *testing.T, in string, args ...string) (string, error) {
+	var cli cliArgs
+	parser, err := kong.New(&cli)
+	require.NoError(t, err)
+	_, err = parser.Parse(args)
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	if len(cli.Files) > 0 {
+		err = formatFile(&cli, cli.Files[0], &out)
+	} else {
+		err = format(&cli, strings.NewReader(in), &out)
+	}
+	return out.String(), err
+}
+
+func TestFormat(t *testing.T) {
+	in := `{"b": 1.10, "a": [1e3]} {"c": {}}`
+
+	out, err := execute(t, in)
+	require.NoError(t, err)
+	assert.Equal(t, "{\n  \"b\": 1.10,\n  \"a\": [\n    1e3\n  ]\n}\n{\n  \"c\": {}\n}\n", out)
+
+	out, err = execute(t, in, "--compact", "--sort-keys")
+	require.NoError(t, err)
+	assert.Equal(t, "{\"a\":[1e3],\"b\":1.10}\n{\"c\":{}}\n", out)
+
+	out, err = execute(t, in, "--canonical")
+	require.NoError(t, err)
+	assert.Equal(t, "{\"a\":[1000],\"b\":1.1}\n{\"c\":{}}\n", out)
+
+	out, err = execute(t, `{a: 1, /* c */}`, "--tab", "--dialect=json5")
+	require.NoError(t, err)
+	assert.Equal(t, "{\n\t\"a\": 1\n}\n", out)
+
+	_, err = execute(t, `{a: NaN}`, "--dialect=json5")
+	assert.ErrorContains(t, err, "NaN has no JSON equivalent")
+
+	_, err = execute(t, `{"a": }`)
+	assert.Error(t, err)
+}
+
+func TestFormat_File(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "test.json")
+	require.NoError(t, os.WriteFile(name, []byte(`[1,2]`), 0o644))
+
+	out, err := execute(t, "", "-c", name)
+	require.NoError(t, err)
+	assert.Equal(t, "[1,2]\n", out)
+}
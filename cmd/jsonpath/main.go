@@ -0,0 +1,78 @@
+package jsonpath
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/alecthomas/kong"
+
+	"github.com/trichner/toolbox/pkg/jsontree"
+	"github.com/trichner/toolbox/pkg/jsontree/jsonpath"
+	"github.com/trichner/toolbox/pkg/jsontree/lexer"
+)
+
+type cliArgs struct {
+	Expression string `arg:"" help:"JSONPath expression, e.g. '$.items[*].user.name' or '$..book[?@.price < 10]'"`
+	Paths      bool   `help:"print the locations of the matches rather than their values"`
+
+	lexer.DialectFlag `embed:""`
+}
+
+func Exec(ctx context.Context, args []string) {
+	var cli cliArgs
+	parser := kong.Must(&cli, kong.Name(args[0]))
+	_, err := parser.Parse(args[1:])
+	parser.FatalIfErrorf(err)
+
+	q, err := jsonpath.Compile(cli.Expression)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	err = run(q, cli.Paths, lexer.NewLexer(os.Stdin, lexer.WithDialect(cli.Dialect)), w)
+	if flushErr := w.Flush(); err == nil {
+		err = flushErr
+	}
+	if err != nil {
+		log.Fatal(jsontree.FormatError(err))
+	}
+}
+
+// run writes the matches of the query within every value read from l, one per line
+func run(q *jsonpath.Query, paths bool, l lexer.Lexer, w io.Writer) error {
+	values := jsontree.NewValueReader(l)
+	for {
+		root, err := values.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, m := range q.Find(root) {
+			if paths {
+				_, err = fmt.Fprintln(w, m.Path)
+			} else {
+				err = writeNode(w, m)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func writeNode(w io.Writer, m jsonpath.Match) error {
+	b, err := m.Node.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", b)
+	return err
+}
@@ -0,0 +1,38 @@
+package jsonpath
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trichner/toolbox/pkg/jsontree/jsonpath"
+	"github.com/trichner/toolbox/pkg/jsontree/lexer"
+)
+
+func TestRun(t *testing.T) {
+	in := `{"items": [{"user": {"name": "alice"}}, {"user": {"name": "bob"}}]}
+{"items": [{"user": {"name": "carol", "z": 1, "a": 2}}]}`
+
+	var out bytes.Buffer
+	err := run(jsonpath.MustCompile("$.items[*].user.name"), false, lexer.NewLexer(strings.NewReader(in)), &out)
+	require.NoError(t, err)
+	assert.Equal(t, "\"alice\"\n\"bob\"\n\"carol\"\n", out.String())
+
+	out.Reset()
+	err = run(jsonpath.MustCompile("$.items[?@.user.z]"), false, lexer.NewLexer(strings.NewReader(in)), &out)
+	require.NoError(t, err)
+	assert.Equal(t, `{"user":{"name":"carol","z":1,"a":2}}`+"\n", out.String())
+
+	out.Reset()
+	err = run(jsonpath.MustCompile("$..name"), true, lexer.NewLexer(strings.NewReader(in)), &out)
+	require.NoError(t, err)
+	assert.Equal(t, "$.items[0].user.name\n$.items[1].user.name\n$.items[0].user.name\n", out.String())
+
+	out.Reset()
+	in = "{items: ['x',], /* json5 */}"
+	err = run(jsonpath.MustCompile("$.items[0]"), false, lexer.NewLexer(strings.NewReader(in), lexer.WithDialect(lexer.DialectJSON5)), &out)
+	require.NoError(t, err)
+	assert.Equal(t, "\"x\"\n", out.String())
+}
@@ -0,0 +1,156 @@
+package jsonschema
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/alecthomas/kong"
+
+	"github.com/trichner/toolbox/pkg/jsontree"
+	"github.com/trichner/toolbox/pkg/jsontree/ast"
+	"github.com/trichner/toolbox/pkg/jsontree/jsonschema"
+	"github.com/trichner/toolbox/pkg/jsontree/lexer"
+	"github.com/trichner/toolbox/pkg/jsontree/serializer"
+)
+
+type cliArgs struct {
+	lexer.DialectFlag `embed:""`
+
+	Infer struct {
+		Files  []string `arg:"" optional:"" help:"JSON or NDJSON files to read, defaults to stdin" type:"existingfile"`
+		Counts bool     `help:"annotate the schema with how many values every part was inferred from as 'x-count'"`
+	} `cmd:"" help:"Infer a JSON Schema from records, top-level arrays are read as a stream of their items."`
+	Validate struct {
+		Schema string   `help:"JSON Schema to validate against" required:"" type:"existingfile"`
+		Files  []string `arg:"" optional:"" help:"JSON or NDJSON files to validate, defaults to stdin" type:"existingfile"`
+	} `cmd:"" help:"Validate records against a JSON Schema, top-level arrays are read as a stream of their items."`
+}
+
+func Exec(ctx context.Context, args []string) {
+	var cli cliArgs
+	parser := kong.Must(&cli, kong.Name(args[0]))
+	kctx, err := parser.Parse(args[1:])
+	parser.FatalIfErrorf(err)
+
+	w := bufio.NewWriter(os.Stdout)
+	switch kctx.Command() {
+	case "infer", "infer <files>":
+		err = infer(&cli, w)
+	case "validate", "validate <files>":
+		err = validate(&cli, w)
+	default:
+		err = fmt.Errorf("unknown command: %s", kctx.Command())
+	}
+	if flushErr := w.Flush(); err == nil {
+		err = flushErr
+	}
+	if err != nil {
+		log.Fatal(jsontree.FormatError(err))
+	}
+}
+
+func infer(cli *cliArgs, w io.Writer) error {
+	var opts []jsonschema.InferOption
+	if cli.Infer.Counts {
+		opts = append(opts, jsonschema.WithCounts())
+	}
+	inferrer := jsonschema.NewInferrer(opts...)
+
+	err := forEachRecord(cli.Infer.Files, cli.Dialect, func(_ string, _ int, n ast.Node) error {
+		inferrer.Add(n)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = serializer.Write(w, inferrer.Schema(), serializer.WithIndent("", "  "))
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+// validate writes one line per violation, it fails if any record is invalid
+func validate(cli *cliArgs, w io.Writer) error {
+	schema, err := readSchema(cli.Validate.Schema, cli.Dialect)
+	if err != nil {
+		return err
+	}
+
+	total, invalid := 0, 0
+	err = forEachRecord(cli.Validate.Files, cli.Dialect, func(name string, i int, n ast.Node) error {
+		total++
+		errs := schema.Validate(n)
+		if len(errs) > 0 {
+			invalid++
+		}
+		for _, verr := range errs {
+			if _, err := fmt.Fprintf(w, "%s:%d: %s\n", name, i, verr); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d records are invalid", invalid, total)
+	}
+	return nil
+}
+
+func readSchema(name string, dialect lexer.Dialect) (*jsonschema.Schema, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	n, err := jsontree.Parse(lexer.NewLexer(f, lexer.WithDialect(dialect)))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read schema %s: %w", name, err)
+	}
+	return jsonschema.Compile(n)
+}
+
+// forEachRecord calls fn for every record of the files or stdin, records are numbered from 1 within each file
+func forEachRecord(files []string, dialect lexer.Dialect, fn func(name string, i int, n ast.Node) error) error {
+	each := func(name string, in io.Reader) error {
+		r := jsontree.NewItemReader(lexer.NewLexer(in, lexer.WithDialect(dialect)))
+		for i := 1; ; i++ {
+			n, err := r.Next()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("cannot read %s: %w", name, err)
+			}
+			if err := fn(name, i, n); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(files) == 0 {
+		return each("stdin", os.Stdin)
+	}
+	for _, name := range files {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		err = each(name, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package jsonschema
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trichner/toolbox/pkg/jsontree/lexer"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestInferAndValidate(t *testing.T) {
+	records := writeFile(t, "records.ndjson", "{\"id\": 1, \"name\": \"a\"}\n{\"id\": 2, \"name\": null}\n")
+
+	cli := &cliArgs{DialectFlag: lexer.DialectFlag{Dialect: lexer.DialectJSON}}
+	cli.Infer.Files = []string{records}
+	var schema bytes.Buffer
+	require.NoError(t, infer(cli, &schema))
+	assert.Contains(t, schema.String(), `"required": [`)
+
+	cli.Validate.Schema = writeFile(t, "schema.json", schema.String())
+	cli.Validate.Files = []string{records}
+	var out bytes.Buffer
+	require.NoError(t, validate(cli, &out))
+	assert.Empty(t, out.String())
+
+	// a top-level array is read as a stream of records
+	invalid := writeFile(t, "invalid.json", `[{"id": 3, "name": "c"}, {"id": "4"}]`)
+	cli.Validate.Files = []string{invalid}
+	out.Reset()
+	err := validate(cli, &out)
+	assert.EqualError(t, err, "1 of 2 records are invalid")
+	assert.Equal(t, invalid+`:2: $: missing required property "name"`+"\n"+invalid+`:2: $.id: expected integer but got string`+"\n", out.String())
+}
@@ -4,21 +4,21 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"net/url"
 	"os"
-	"regexp"
-	"strconv"
 
 	"github.com/alecthomas/kong"
 	"github.com/posener/complete/v2"
 	"github.com/posener/complete/v2/predict"
 	"github.com/trichner/toolbox/pkg/sheet2json"
+	"github.com/trichner/toolbox/pkg/sheets"
 )
 
 var cli struct {
 	SpreadsheetID  string `help:"spreadsheet ID"`
-	SheetID        int64  `help:"ID of the sheet within the spreadsheet"`
+	SheetID        int64  `help:"ID of the sheet within the spreadsheet, defaults to the first sheet" default:"-1"`
 	SpreadsheetUrl string `help:"complete URL to the spreadsheet"`
+	Backend        string `help:"sheets backend, 'local' reads spreadsheets from CSV files for tests and dry runs" enum:"google,local" default:"google"`
+	LocalDir       string `help:"directory of the 'local' backend" default:"." type:"path"`
 }
 
 func Completions() complete.Completer {
@@ -39,64 +39,33 @@ func Exec(ctx context.Context, args []string) {
 	_, err := parser.Parse(args[1:])
 	parser.FatalIfErrorf(err)
 
-	var spreadsheetId string
-	var sheetId int64 = -1
-	if cli.SpreadsheetUrl != "" {
-		spreadsheetId, sheetId, err = urlToSpreadsheetID(cli.SpreadsheetUrl)
-		if err != nil {
-			log.Fatal(err)
-		}
-	} else {
-		spreadsheetId = cli.SpreadsheetID
-		sheetId = cli.SheetID
-	}
-
-	if spreadsheetId == "" || sheetId < 0 {
-		log.Fatal(fmt.Errorf("spreadsheetId and sheetId are not set"))
-	}
-
-	err = sheet2json.ReadFromSheet(ctx, spreadsheetId, sheetId, os.Stdout)
+	ref, err := parseSheetRef(cli.SpreadsheetUrl, cli.SpreadsheetID, cli.SheetID)
 	if err != nil {
 		log.Fatal(err)
 	}
-}
 
-// urlToSpreadsheetID parses a URL to a spreadsheet such as: https://docs.google.com/spreadsheets/d/1dAN8MO9NDVPqVIoOxC9H_j4Ir5c1viQ97igGdXOyXsU/edit#gid=886605725
-func urlToSpreadsheetID(u string) (string, int64, error) {
-	parsed, err := url.Parse(u)
+	svc, err := sheets.NewSheetServiceForBackend(ctx, sheets.Backend(cli.Backend), cli.LocalDir)
 	if err != nil {
-		return "", -1, err
-	}
-
-	const googleDocsHost = "docs.google.com"
-	if parsed.Host != googleDocsHost {
-		return "", -1, fmt.Errorf("unexpected host '%s', expected '%s'", parsed.Host, googleDocsHost)
+		log.Fatal(err)
 	}
 
-	const httpsScheme = "https"
-	if parsed.Scheme != "https" {
-		return "", -1, fmt.Errorf("unexpected scheme '%s', expected '%s'", parsed.Scheme, httpsScheme)
+	err = sheet2json.ReadFromSheet(svc, ref, os.Stdout)
+	if err != nil {
+		log.Fatal(err)
 	}
+}
 
-	pathPattern := regexp.MustCompile("^/spreadsheets/d/([^/]+)/edit$")
-	matches := pathPattern.FindStringSubmatch(parsed.Path)
-	if matches == nil {
-		return "", -1, fmt.Errorf("can't find spreadsheetId in path: '%s'", parsed.Path)
+func parseSheetRef(spreadsheetUrl, spreadsheetId string, sheetId int64) (*sheets.SheetRef, error) {
+	if spreadsheetUrl != "" {
+		return sheets.ParseSheetRef(spreadsheetUrl)
 	}
-	spreadsheetId := matches[1]
 
-	q, err := url.ParseQuery(parsed.Fragment)
-	if err != nil {
-		return "", -1, fmt.Errorf("can't parse fragment '%s': %w", parsed.Fragment, err)
+	if spreadsheetId == "" {
+		return nil, fmt.Errorf("neither spreadsheet URL nor spreadsheetId are set")
 	}
 
-	const queryParamGid = "gid"
-	rawSheetId := q.Get(queryParamGid)
-	if rawSheetId == "" {
-		return "", -1, fmt.Errorf("can't find '%s' in '%s'", queryParamGid, parsed.Fragment)
+	if sheetId < 0 {
+		sheetId = sheets.NoSheetId
 	}
-
-	sheetId, err := strconv.ParseInt(rawSheetId, 10, 64)
-
-	return spreadsheetId, sheetId, err
+	return &sheets.SheetRef{SpreadsheetId: spreadsheetId, SheetId: sheetId}, nil
 }
@@ -1,20 +1,30 @@
 package sheet2json
 
 import (
-	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/trichner/toolbox/pkg/sheets"
 )
 
-func TestUrlToSpreadsheet(t *testing.T) {
+func TestParseSheetRef_Url(t *testing.T) {
 	u := "https://docs.google.com/spreadsheets/d/1dAN8MO9NDVPqVIoOxC9H_j4Ir5c1viQ97igGdXOyXsU/edit#gid=886605725"
-	spreadsheetId, sheetId, err := urlToSpreadsheetID(u)
+	ref, err := parseSheetRef(u, "", sheets.NoSheetId)
 
 	assert.NoError(t, err)
-	fmt.Printf("%s / %d", spreadsheetId, sheetId)
+	assert.Equal(t, "1dAN8MO9NDVPqVIoOxC9H_j4Ir5c1viQ97igGdXOyXsU", ref.SpreadsheetId)
+	assert.Equal(t, int64(886605725), ref.SheetId)
 }
 
-func TestExec(t *testing.T) {
-	Exec([]string{"sheet2json", "--spreadsheet-id=https://docs.google.com/spreadsheets/d/1dAN8MO9NDVPqVIoOxC9H_j4Ir5c1viQ97igGdXOyXsU/edit#gid=886605725"})
+func TestParseSheetRef_Id(t *testing.T) {
+	ref, err := parseSheetRef("", "1dAN8MO9NDVPqVIoOxC9H_j4Ir5c1viQ97igGdXOyXsU", sheets.NoSheetId)
+
+	assert.NoError(t, err)
+	assert.False(t, ref.HasSheetId())
+}
+
+func TestParseSheetRef_Missing(t *testing.T) {
+	_, err := parseSheetRef("", "", 3)
+
+	assert.Error(t, err)
 }
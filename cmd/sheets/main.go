@@ -0,0 +1,344 @@
+package sheets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/alecthomas/kong"
+	"github.com/trichner/toolbox/pkg/sheets"
+)
+
+type tabFlag struct {
+	Tab string `help:"title of the tab, defaults to the tab of the URL's gid"`
+}
+
+type cliArgs struct {
+	Backend  string `help:"sheets backend, 'local' stores spreadsheets as CSV files for tests and dry runs" enum:"google,local" default:"google"`
+	LocalDir string `help:"directory of the 'local' backend" default:"." type:"path"`
+	Output   string `help:"output format" short:"o" enum:"table,json" default:"table"`
+
+	Ls struct {
+		Spreadsheet string `arg:"" help:"spreadsheet ID or URL"`
+	} `cmd:"" help:"List the tabs of a spreadsheet."`
+	Create struct {
+		Title string `arg:"" help:"title of the new spreadsheet"`
+	} `cmd:"" help:"Create a new spreadsheet."`
+	AddTab struct {
+		Spreadsheet string `arg:"" help:"spreadsheet ID or URL"`
+		Title       string `arg:"" help:"title of the new tab"`
+	} `cmd:"" help:"Add a new tab to a spreadsheet."`
+	RenameTab struct {
+		Spreadsheet string `arg:"" help:"spreadsheet ID or URL"`
+		Title       string `arg:"" help:"new title of the tab"`
+		tabFlag
+	} `cmd:"" help:"Rename a tab, the tab must be selected explicitly."`
+	DeleteTab struct {
+		Spreadsheet string `arg:"" help:"spreadsheet ID or URL"`
+		tabFlag
+	} `cmd:"" help:"Delete a tab, the tab must be selected explicitly."`
+	CopyTab struct {
+		Spreadsheet string `arg:"" help:"spreadsheet ID or URL"`
+		Title       string `help:"title of the copy, only applies to copies within the same spreadsheet"`
+		To          string `help:"ID or URL of the spreadsheet to copy to, defaults to the same spreadsheet"`
+		tabFlag
+	} `cmd:"" help:"Copy a tab within the spreadsheet or to another one, defaults to the first tab."`
+	Clear struct {
+		Spreadsheet string `arg:"" help:"spreadsheet ID or URL"`
+		tabFlag
+	} `cmd:"" help:"Remove all values of a tab, the tab must be selected explicitly."`
+	Share struct {
+		Spreadsheet string `arg:"" help:"spreadsheet ID or URL"`
+		Email       string `help:"email of the user or group to share with" xor:"grantee" required:""`
+		Anyone      bool   `help:"share with anyone having the link" xor:"grantee" required:""`
+		Role        string `help:"role to grant" enum:"reader,commenter,writer" default:"reader"`
+		Notify      bool   `help:"send a notification email"`
+	} `cmd:"" help:"Share a spreadsheet with a user, a group or anyone having the link."`
+}
+
+// spreadsheetInfo is the output of commands acting on a whole spreadsheet
+type spreadsheetInfo struct {
+	*sheets.SpreadSheet
+	Url string `json:"url"`
+}
+
+// sheetInfo is the output of commands acting on a single tab
+type sheetInfo struct {
+	SpreadsheetId string `json:"spreadsheetId"`
+	*sheets.Sheet
+	Url string `json:"url"`
+}
+
+type shareInfo struct {
+	SpreadsheetId string `json:"spreadsheetId"`
+	Email         string `json:"email,omitempty"`
+	Anyone        bool   `json:"anyone,omitempty"`
+	Role          string `json:"role"`
+}
+
+func Exec(ctx context.Context, args []string) {
+	var cli cliArgs
+	parser := kong.Must(&cli, kong.Name(args[0]))
+	kctx, err := parser.Parse(args[1:])
+	parser.FatalIfErrorf(err)
+
+	svc, err := sheets.NewSheetServiceForBackend(ctx, sheets.Backend(cli.Backend), cli.LocalDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := run(svc, &cli, kctx.Command(), os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(svc sheets.SheetsService, cli *cliArgs, command string, w io.Writer) error {
+	var res any
+	var err error
+	switch command {
+	case "ls <spreadsheet>":
+		res, err = listTabs(svc, cli.Ls.Spreadsheet)
+	case "create <title>":
+		res, err = createSpreadsheet(svc, cli.Create.Title)
+	case "add-tab <spreadsheet> <title>":
+		res, err = addTab(svc, cli.AddTab.Spreadsheet, cli.AddTab.Title)
+	case "rename-tab <spreadsheet> <title>":
+		res, err = renameTab(svc, cli.RenameTab.Spreadsheet, cli.RenameTab.Tab, cli.RenameTab.Title)
+	case "delete-tab <spreadsheet>":
+		res, err = deleteTab(svc, cli.DeleteTab.Spreadsheet, cli.DeleteTab.Tab)
+	case "copy-tab <spreadsheet>":
+		res, err = copyTab(svc, cli.CopyTab.Spreadsheet, cli.CopyTab.Tab, cli.CopyTab.Title, cli.CopyTab.To)
+	case "clear <spreadsheet>":
+		res, err = clearTab(svc, cli.Clear.Spreadsheet, cli.Clear.Tab)
+	case "share <spreadsheet>":
+		res, err = share(svc, cli.Share.Spreadsheet, &sheets.ShareOptions{
+			Email:  cli.Share.Email,
+			Anyone: cli.Share.Anyone,
+			Role:   sheets.ShareRole(cli.Share.Role),
+			Notify: cli.Share.Notify,
+		})
+	default:
+		return fmt.Errorf("unknown command %q", command)
+	}
+	if err != nil {
+		return err
+	}
+
+	if cli.Output == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(res)
+	}
+	return printTable(w, res)
+}
+
+func listTabs(svc sheets.SheetsService, spreadsheet string) (*spreadsheetInfo, error) {
+	ss, _, err := openSpreadsheet(svc, spreadsheet)
+	if err != nil {
+		return nil, err
+	}
+	return describeSpreadsheet(ss)
+}
+
+func createSpreadsheet(svc sheets.SheetsService, title string) (*spreadsheetInfo, error) {
+	ss, err := svc.CreateSpreadSheet(title)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create spreadsheet %q: %w", title, err)
+	}
+	return describeSpreadsheet(ss)
+}
+
+func addTab(svc sheets.SheetsService, spreadsheet, title string) (*sheetInfo, error) {
+	ss, ref, err := openSpreadsheet(svc, spreadsheet)
+	if err != nil {
+		return nil, err
+	}
+
+	sheet, err := ss.CreateSheet(&sheets.CreateSheetOptions{Title: title})
+	if err != nil {
+		return nil, err
+	}
+	return describeSheet(ref.SpreadsheetId, sheet)
+}
+
+func renameTab(svc sheets.SheetsService, spreadsheet, tab, title string) (*sheetInfo, error) {
+	ref, sheet, err := openTab(svc, spreadsheet, tab, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := sheet.Rename(title); err != nil {
+		return nil, err
+	}
+	return describeSheet(ref.SpreadsheetId, sheet)
+}
+
+func deleteTab(svc sheets.SheetsService, spreadsheet, tab string) (*sheetInfo, error) {
+	ref, sheet, err := openTab(svc, spreadsheet, tab, true)
+	if err != nil {
+		return nil, err
+	}
+
+	// describe it upfront, there is nothing left to describe afterwards
+	info, err := describeSheet(ref.SpreadsheetId, sheet)
+	if err != nil {
+		return nil, err
+	}
+	if err := sheet.Delete(); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func copyTab(svc sheets.SheetsService, spreadsheet, tab, title, to string) (*sheetInfo, error) {
+	ref, sheet, err := openTab(svc, spreadsheet, tab, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if to == "" {
+		copied, err := sheet.Duplicate(title)
+		if err != nil {
+			return nil, err
+		}
+		return describeSheet(ref.SpreadsheetId, copied)
+	}
+
+	if title != "" {
+		return nil, fmt.Errorf("a title for the copy is only supported within the same spreadsheet")
+	}
+
+	dest, err := sheets.ParseSheetRef(to)
+	if err != nil {
+		return nil, fmt.Errorf("invalid destination spreadsheet: %w", err)
+	}
+	copied, err := sheet.CopyTo(dest.SpreadsheetId)
+	if err != nil {
+		return nil, err
+	}
+	return newSheetInfo(dest.SpreadsheetId, copied), nil
+}
+
+func clearTab(svc sheets.SheetsService, spreadsheet, tab string) (*sheetInfo, error) {
+	ref, sheet, err := openTab(svc, spreadsheet, tab, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := sheet.Clear(); err != nil {
+		return nil, err
+	}
+	return describeSheet(ref.SpreadsheetId, sheet)
+}
+
+func share(svc sheets.SheetsService, spreadsheet string, opts *sheets.ShareOptions) (*shareInfo, error) {
+	ss, ref, err := openSpreadsheet(svc, spreadsheet)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ss.Share(opts); err != nil {
+		return nil, err
+	}
+
+	info := &shareInfo{SpreadsheetId: ref.SpreadsheetId, Anyone: opts.Anyone, Role: string(opts.Role)}
+	if !opts.Anyone {
+		info.Email = opts.Email
+	}
+	return info, nil
+}
+
+func openSpreadsheet(svc sheets.SheetsService, spreadsheet string) (sheets.SpreadsheetOps, *sheets.SheetRef, error) {
+	ref, err := sheets.ParseSheetRef(spreadsheet)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid spreadsheet: %w", err)
+	}
+
+	ss, err := svc.GetSpreadSheet(ref.SpreadsheetId)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot open spreadsheet %q: %w", ref.SpreadsheetId, err)
+	}
+	return ss, ref, nil
+}
+
+// openTab selects a tab by its title or by the gid of the spreadsheet URL. Unless the tab is required to be
+// selected explicitly, it falls back to the first tab.
+func openTab(svc sheets.SheetsService, spreadsheet, tab string, explicit bool) (*sheets.SheetRef, sheets.SheetOps, error) {
+	ss, ref, err := openSpreadsheet(svc, spreadsheet)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var sheet sheets.SheetOps
+	switch {
+	case tab != "":
+		sheet, err = ss.SheetByTitle(tab)
+	case ref.HasSheetId():
+		sheet, err = ss.SheetById(ref.SheetId)
+	case explicit:
+		return nil, nil, fmt.Errorf("no tab selected, either pass --tab or a spreadsheet URL with a gid")
+	default:
+		sheet, err = ss.FirstSheet()
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot open tab of spreadsheet %q: %w", ref.SpreadsheetId, err)
+	}
+	return ref, sheet, nil
+}
+
+func describeSpreadsheet(ss sheets.SpreadsheetOps) (*spreadsheetInfo, error) {
+	info, err := ss.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	ref := &sheets.SheetRef{SpreadsheetId: info.Id, SheetId: sheets.NoSheetId}
+	return &spreadsheetInfo{SpreadSheet: info, Url: ref.URL()}, nil
+}
+
+func describeSheet(spreadsheetId string, sheet sheets.SheetOps) (*sheetInfo, error) {
+	info, err := sheet.Get()
+	if err != nil {
+		return nil, err
+	}
+	return newSheetInfo(spreadsheetId, info), nil
+}
+
+func newSheetInfo(spreadsheetId string, sheet *sheets.Sheet) *sheetInfo {
+	ref := &sheets.SheetRef{SpreadsheetId: spreadsheetId, SheetId: sheet.Id}
+	return &sheetInfo{SpreadsheetId: spreadsheetId, Sheet: sheet, Url: ref.URL()}
+}
+
+func printTable(w io.Writer, res any) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	switch r := res.(type) {
+	case *spreadsheetInfo:
+		fmt.Fprintf(tw, "ID:\t%s\n", r.Id)
+		fmt.Fprintf(tw, "Title:\t%s\n", r.Title)
+		fmt.Fprintf(tw, "URL:\t%s\n\n", r.Url)
+		printSheetRows(tw, r.Sheets...)
+	case *sheetInfo:
+		printSheetRows(tw, r.Sheet)
+	case *shareInfo:
+		grantee := r.Email
+		if r.Anyone {
+			grantee = "anyone with the link"
+		}
+		fmt.Fprintf(tw, "SPREADSHEET\tGRANTEE\tROLE\n")
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", r.SpreadsheetId, grantee, r.Role)
+	default:
+		panic(fmt.Errorf("unhandled result %T", res))
+	}
+	return tw.Flush()
+}
+
+func printSheetRows(w io.Writer, sts ...*sheets.Sheet) {
+	fmt.Fprintf(w, "INDEX\tID\tTITLE\tROWS\tCOLUMNS\n")
+	for _, s := range sts {
+		fmt.Fprintf(w, "%d\t%d\t%s\t%d\t%d\n", s.Index, s.Id, s.Title, s.RowCount, s.ColumnCount)
+	}
+}
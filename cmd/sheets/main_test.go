@@ -0,0 +1,83 @@
+package sheets
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/alecthomas/kong"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trichner/toolbox/pkg/sheets"
+)
+
+func execute(t *testing.T, svc sheets.SheetsService, args ...string) (string, error) {
+	var cli cliArgs
+	parser, err := kong.New(&cli)
+	require.NoError(t, err)
+	kctx, err := parser.Parse(args)
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	err = run(svc, &cli, kctx.Command(), &out)
+	return out.String(), err
+}
+
+func TestSheets(t *testing.T) {
+	svc, err := sheets.NewLocalSheetService(t.TempDir())
+	require.NoError(t, err)
+
+	out, err := execute(t, svc, "--output=json", "create", "report")
+	require.NoError(t, err)
+
+	var created spreadsheetInfo
+	require.NoError(t, json.Unmarshal([]byte(out), &created))
+	assert.Equal(t, "report", created.Title)
+	require.Len(t, created.Sheets, 1)
+	id := created.Id
+
+	_, err = execute(t, svc, "add-tab", id, "data")
+	require.NoError(t, err)
+
+	_, err = execute(t, svc, "copy-tab", id, "--tab=data", "--title=backup")
+	require.NoError(t, err)
+
+	// destructive operations need an explicit tab
+	_, err = execute(t, svc, "delete-tab", id)
+	assert.Error(t, err)
+
+	_, err = execute(t, svc, "delete-tab", id, "--tab=Sheet1")
+	require.NoError(t, err)
+
+	_, err = execute(t, svc, "rename-tab", id, "archive", "--tab=backup")
+	require.NoError(t, err)
+
+	out, err = execute(t, svc, "ls", id)
+	require.NoError(t, err)
+	assert.Contains(t, out, "INDEX  ID")
+	assert.Contains(t, out, "archive")
+	assert.NotContains(t, out, "Sheet1")
+
+	out, err = execute(t, svc, "-o", "json", "ls", id)
+	require.NoError(t, err)
+	var listed spreadsheetInfo
+	require.NoError(t, json.Unmarshal([]byte(out), &listed))
+	require.Len(t, listed.Sheets, 2)
+	assert.Equal(t, "data", listed.Sheets[0].Title)
+	assert.Equal(t, "archive", listed.Sheets[1].Title)
+
+	url := (&sheets.SheetRef{SpreadsheetId: id, SheetId: listed.Sheets[1].Id}).URL()
+	_, err = execute(t, svc, "clear", url)
+	require.NoError(t, err)
+
+	out, err = execute(t, svc, "share", id, "--anyone")
+	require.NoError(t, err)
+	assert.Contains(t, out, "anyone with the link")
+
+	_, err = execute(t, svc, "ls", "doesnotexist")
+	assert.ErrorIs(t, err, sheets.ErrNotFound)
+
+	var cli cliArgs
+	err = run(svc, &cli, "unknown", &bytes.Buffer{})
+	assert.ErrorContains(t, err, `unknown command "unknown"`)
+}
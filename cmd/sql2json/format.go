@@ -0,0 +1,246 @@
+package sql2json
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/trichner/toolbox/pkg/jsontree/ast"
+	"github.com/trichner/toolbox/pkg/jsontree/serializer"
+)
+
+// resultWriter writes the result sets of the statements in one of the output formats
+type resultWriter interface {
+	// Begin starts the result set of a statement, statements are numbered from 1 if there are multiple and 0
+	// otherwise
+	Begin(statement int, columns []string) error
+	Row(values []ast.Node) error
+	// Close ends the output, it does not close the underlying writer
+	Close() error
+}
+
+func newResultWriter(format string, w io.Writer) (resultWriter, error) {
+	switch format {
+	case "ndjson", "":
+		return &objectWriter{w: w}, nil
+	case "json":
+		return &objectWriter{w: w, array: true}, nil
+	case "arrays":
+		return &arrayWriter{w: w}, nil
+	case "csv":
+		return &csvWriter{w: csv.NewWriter(w)}, nil
+	case "tsv":
+		cw := csv.NewWriter(w)
+		cw.Comma = '\t'
+		return &csvWriter{w: cw}, nil
+	case "table":
+		return &tableWriter{w: w}, nil
+	}
+	return nil, fmt.Errorf("unknown format %q, expected one of: ndjson, json, csv, tsv, arrays, table", format)
+}
+
+// objectWriter writes rows as objects, either as NDJSON or wrapped in a single JSON array. The rows of multiple
+// statements are tagged with their statement, i.e. '{"statement":1,"row":{...}}'.
+type objectWriter struct {
+	w     io.Writer
+	array bool
+
+	statement ast.Node
+	columns   []string
+	rows      int
+}
+
+func (o *objectWriter) Begin(statement int, columns []string) error {
+	o.statement = nil
+	if statement > 0 {
+		o.statement = ast.NewNumberNode(strconv.Itoa(statement))
+	}
+	o.columns = columns
+	return nil
+}
+
+func (o *objectWriter) Row(values []ast.Node) error {
+	row := ast.NewObject()
+	for i, v := range values {
+		row.Set(o.columns[i], v)
+	}
+
+	var n ast.Node = row
+	if o.statement != nil {
+		n = ast.NewObject().Set("statement", o.statement).Set("row", row)
+	}
+
+	if o.array {
+		sep := ",\n"
+		if o.rows == 0 {
+			sep = "[\n"
+		}
+		if _, err := io.WriteString(o.w, sep); err != nil {
+			return err
+		}
+	}
+	o.rows++
+
+	if err := serializer.Write(o.w, n); err != nil {
+		return err
+	}
+	if o.array {
+		return nil
+	}
+	_, err := io.WriteString(o.w, "\n")
+	return err
+}
+
+func (o *objectWriter) Close() error {
+	if !o.array {
+		return nil
+	}
+	end := "\n]\n"
+	if o.rows == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(o.w, end)
+	return err
+}
+
+// arrayWriter writes NDJSON arrays, every result set starts with an array of its column names
+type arrayWriter struct {
+	w io.Writer
+}
+
+func (a *arrayWriter) Begin(_ int, columns []string) error {
+	header := ast.NewArray()
+	for _, c := range columns {
+		header.Append(ast.NewTextNode(c))
+	}
+	return a.write(header)
+}
+
+func (a *arrayWriter) Row(values []ast.Node) error {
+	return a.write(ast.NewArray(values...))
+}
+
+func (a *arrayWriter) write(n ast.Node) error {
+	if err := serializer.Write(a.w, n); err != nil {
+		return err
+	}
+	_, err := io.WriteString(a.w, "\n")
+	return err
+}
+
+func (a *arrayWriter) Close() error {
+	return nil
+}
+
+// csvWriter writes CSV or TSV with a header row, multiple result sets are separated by an empty line
+type csvWriter struct {
+	w    *csv.Writer
+	sets int
+}
+
+func (c *csvWriter) Begin(_ int, columns []string) error {
+	if c.sets > 0 {
+		if err := c.w.Write(nil); err != nil {
+			return err
+		}
+	}
+	c.sets++
+	return c.w.Write(columns)
+}
+
+func (c *csvWriter) Row(values []ast.Node) error {
+	record := make([]string, len(values))
+	for i, v := range values {
+		s, err := cellValue(v)
+		if err != nil {
+			return err
+		}
+		record[i] = s
+	}
+	return c.w.Write(record)
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// tableWriter aligns the columns of each result set for humans, NULL is written as such
+type tableWriter struct {
+	w    io.Writer
+	tw   *tabwriter.Writer
+	sets int
+}
+
+func (t *tableWriter) Begin(_ int, columns []string) error {
+	if err := t.flush(); err != nil {
+		return err
+	}
+	if t.sets > 0 {
+		if _, err := io.WriteString(t.w, "\n"); err != nil {
+			return err
+		}
+	}
+	t.sets++
+
+	t.tw = tabwriter.NewWriter(t.w, 0, 4, 2, ' ', 0)
+	return t.writeLine(columns)
+}
+
+func (t *tableWriter) Row(values []ast.Node) error {
+	cells := make([]string, len(values))
+	for i, v := range values {
+		if v.Type() == ast.NodeTypeNull {
+			cells[i] = "NULL"
+			continue
+		}
+		s, err := cellValue(v)
+		if err != nil {
+			return err
+		}
+		cells[i] = s
+	}
+	return t.writeLine(cells)
+}
+
+// tableEscaper keeps cells on a single line and in their column
+var tableEscaper = strings.NewReplacer("\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+func (t *tableWriter) writeLine(cells []string) error {
+	escaped := make([]string, len(cells))
+	for i, c := range cells {
+		escaped[i] = tableEscaper.Replace(c)
+	}
+	_, err := io.WriteString(t.tw, strings.Join(escaped, "\t")+"\n")
+	return err
+}
+
+func (t *tableWriter) flush() error {
+	if t.tw == nil {
+		return nil
+	}
+	return t.tw.Flush()
+}
+
+func (t *tableWriter) Close() error {
+	return t.flush()
+}
+
+// cellValue is the text of a value in a cell, NULL is empty
+func cellValue(n ast.Node) (string, error) {
+	switch n.Type() {
+	case ast.NodeTypeNull:
+		return "", nil
+	case ast.NodeTypeText:
+		return n.(ast.TextNode).Value(), nil
+	case ast.NodeTypeNumber:
+		return n.(ast.NumberNode).Value(), nil
+	case ast.NodeTypeBoolean:
+		return strconv.FormatBool(n.(ast.BooleanNode).Value()), nil
+	}
+	b, err := serializer.Marshal(n)
+	return string(b), err
+}
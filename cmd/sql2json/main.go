@@ -1,24 +1,120 @@
 package sql2json
 
 import (
+	"bufio"
 	"context"
 	"database/sql"
-	"encoding/json"
 	"fmt"
+	"io"
 	"log"
+	"net/url"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/alecthomas/kong"
-	"github.com/go-sql-driver/mysql"
+	"github.com/posener/complete/v2"
+	"github.com/posener/complete/v2/predict"
+	"github.com/trichner/toolbox/pkg/json2sheet"
+	"github.com/trichner/toolbox/pkg/jsontree/ast"
+	"github.com/trichner/toolbox/pkg/keyring"
+	"github.com/trichner/toolbox/pkg/sheets"
+	"github.com/trichner/toolbox/pkg/sqlconn"
 )
 
 type cli struct {
-	DbConnectionUri string `help:"database connection URI, e.g.: 'root@tcp(127.0.0.1:3306)/mydb'" required:"" env:"SQL2JSON_DB_CONNECTION_URI"`
+	DbConnectionUri string `help:"database connection URI, the scheme selects the driver, e.g.: 'mysql://root@127.0.0.1:3306/mydb', 'postgres://postgres@127.0.0.1:5432/mydb' or 'sqlite:///tmp/my.db'" env:"SQL2JSON_DB_CONNECTION_URI"`
 
 	DbUser     string `help:"user for the database" optional:"" env:"SQL2JSON_DB_USER"`
-	DbPassword string `help:"password for the database" optional:"" env:"SQL2JSON_DB_PASSWORD"`
+	DbPassword string `help:"password for the database, prefer --db-password-file or --connection as it leaks into the shell history" optional:"" xor:"password" env:"SQL2JSON_DB_PASSWORD"`
 	DbName     string `help:"name of the database" optional:"" env:"SQL2JSON_DB_NAME"`
-	Query      string `help:"a sql query fetching the results" required:"" env:"SQL2JSON_QUERY"`
+
+	DbPasswordFile string `help:"file with the password for the database" optional:"" xor:"password" type:"existingfile" env:"SQL2JSON_DB_PASSWORD_FILE"`
+	Connection     string `help:"name of a saved connection or of a connection whose password is stored in the keyring, the password is prompted for and stored if missing" optional:"" env:"SQL2JSON_CONNECTION"`
+	SavePassword   bool   `help:"store the password of --connection in the keyring, it is prompted for unless given"`
+
+	SshHost                  string `help:"SSH server to tunnel the connection through, e.g. a bastion, the host of the connection URI is resolved by the SSH server" placeholder:"[USER@]HOST[:PORT]" env:"SQL2JSON_SSH_HOST"`
+	SshKey                   string `help:"private key for the SSH server, the keys of the SSH agent are used if not given" type:"path" env:"SQL2JSON_SSH_KEY"`
+	SshKnownHosts            string `help:"known hosts file verifying the key of the SSH server" default:"~/.ssh/known_hosts" type:"path"`
+	SshInsecureIgnoreHostKey bool   `help:"accept any key of the SSH server"`
+
+	TlsCa         string `help:"PEM file of the certificate authorities verifying the database server, e.g. a private CA" type:"existingfile" env:"SQL2JSON_TLS_CA"`
+	TlsCert       string `help:"PEM file of a client certificate" type:"existingfile"`
+	TlsKey        string `help:"PEM file of the key of the client certificate" type:"existingfile"`
+	TlsServerName string `help:"name verifying the certificate of the database server, defaults to the host of the connection URI"`
+	TlsSkipVerify bool   `help:"accept any certificate of the database server"`
+
+	Query      string `help:"a sql query fetching the results, multiple statements are separated by ';'" xor:"query" env:"SQL2JSON_QUERY"`
+	QueryFile  string `help:"a file with the sql query, e.g. a script of multiple statements" xor:"query" type:"existingfile"`
+	SavedQuery string `help:"name of a saved query, its parameters default to the saved ones and it runs on its saved connection unless --connection is given" xor:"query" placeholder:"NAME"`
+	Registry   string `help:"file of the saved connections and queries" default:"${registry}" type:"path" env:"SQL2JSON_REGISTRY"`
+
+	Param   map[string]string `help:"a bind parameter as 'name=value' referred to as ':name' in the query, numeric names are positions starting at 1 for the '?' or '$1' placeholders of a single statement" mapsep:"none" placeholder:"NAME=VALUE"`
+	Params  string            `help:"bind parameters as a JSON object of named or a JSON array of positional parameters, e.g.: '{\"id\":42}'" placeholder:"JSON"`
+	Timeout time.Duration     `help:"cancel a statement taking longer, e.g.: '30s', no timeout if zero" default:"0s"`
+
+	Decimals      string `help:"how to write DECIMAL and NUMERIC values, as exact strings or as JSON numbers" enum:"string,number" default:"string"`
+	TinyintAsBool bool   `help:"write MySQL TINYINT values of 0 and 1, e.g. of BOOLEAN columns, as booleans"`
+
+	Format         string `help:"output format, 'arrays' writes an array of the column names followed by an array of values per row" enum:"ndjson,json,csv,tsv,arrays,table" default:"ndjson"`
+	ToSheet        bool   `help:"write the result to a new spreadsheet or the one of --spreadsheet-url rather than to stdout"`
+	SpreadsheetUrl string `help:"complete URL of the spreadsheet to write to with --to-sheet"`
+	SheetsBackend  string `help:"sheets backend of --to-sheet, 'local' stores spreadsheets as CSV files for tests and dry runs" enum:"google,local" default:"google"`
+	SheetsLocalDir string `help:"directory of the 'local' sheets backend" default:"." type:"path"`
+
+	Run  struct{} `cmd:"" default:"1" help:"Run the query and write its results, the default command."`
+	List struct{} `cmd:"" help:"List the saved connections and queries."`
+	Show struct {
+		Name string `arg:"" help:"name of a saved connection or query"`
+	} `cmd:"" help:"Show a saved connection or query as JSON."`
+}
+
+func newParser(flags *cli) (*kong.Kong, error) {
+	return kong.New(flags, kong.Vars{"registry": defaultRegistryPath()})
+}
+
+// Completions completes the names of the saved connections and queries
+func Completions() complete.Completer {
+	var flags cli
+	parser, err := newParser(&flags)
+	if err != nil {
+		return &complete.Command{}
+	}
+
+	names := func(list func(r *registry) []string) complete.Predictor {
+		return complete.PredictFunc(func(string) []string {
+			path := os.Getenv("SQL2JSON_REGISTRY")
+			if path == "" {
+				path = defaultRegistryPath()
+			}
+			r, err := loadRegistry(path)
+			if err != nil {
+				return nil
+			}
+			return list(r)
+		})
+	}
+	connections := names((*registry).connectionNames)
+	queries := names((*registry).queryNames)
+
+	// the flags belong to the default command, hence the other commands are completed as arguments for the
+	// flags to be completed too
+	var commands predict.Set
+	for _, c := range parser.Model.Children {
+		if c.Name != "run" {
+			commands = append(commands, c.Name)
+		}
+	}
+	cmd := &complete.Command{
+		Flags: map[string]complete.Predictor{},
+		Args:  predict.Or(commands, connections, queries),
+	}
+	for _, f := range parser.Model.Flags {
+		cmd.Flags[f.Name] = predict.Something
+	}
+	cmd.Flags["connection"] = connections
+	cmd.Flags["saved-query"] = queries
+	return cmd
 }
 
 func Exec(ctx context.Context, args []string) {
@@ -27,42 +123,309 @@ func Exec(ctx context.Context, args []string) {
 
 	var flags cli
 
-	k, err := kong.New(&flags)
+	k, err := newParser(&flags)
 	if err != nil {
 		log.Fatalf("cannot parse arguments: %v", err)
 	}
-	_, err = k.Parse(args)
+	kctx, err := k.Parse(args)
 	if err != nil {
 		log.Fatalf("cannot parse arguments: %v", err)
 	}
 
-	dsn, err := parseDsnConfig(&flags)
+	reg, err := loadRegistry(flags.Registry)
+	if err != nil {
+		log.Fatal(err)
+	}
+	switch kctx.Command() {
+	case "list":
+		err = reg.list(os.Stdout)
+	case "show <name>":
+		err = reg.show(os.Stdout, flags.Show.Name)
+	}
 	if err != nil {
 		log.Fatal(err)
 	}
+	if kctx.Command() != "run" {
+		return
+	}
 
-	log.Printf("connecting to database")
-	db, err := sql.Open("mysql", dsn)
+	script, defaults, err := resolveQuery(&flags, reg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	p, err := parseParams(flags.Param, flags.Params)
+	if err == nil {
+		err = p.withDefaults(defaults)
+	}
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	pingErr := db.Ping()
-	if pingErr != nil {
-		log.Fatal(pingErr)
+	if err := resolveConnection(&flags, reg); err != nil {
+		log.Fatal(err)
 	}
 
-	encoder := json.NewEncoder(os.Stdout)
-	err = execQuery(db, flags.Query, encoder)
+	pw := &passwords{connection: flags.Connection, prompt: terminalPrompt()}
+	if flags.Connection != "" {
+		pw.secrets, err = keyring.Open(keyringServiceName)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	cfg, err := parseConnection(&flags, pw)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// the quoting rules of literals depend on the database
+	statements := splitStatements(script, cfg.Driver)
+	if len(statements) == 0 {
+		log.Fatal("no query given")
+	}
+	if flags.ToSheet && len(statements) > 1 {
+		log.Fatal("cannot write the results of multiple statements to a sheet")
+	}
+
+	cfg.TLS, err = sqlconn.NewTLSConfig(sqlconn.TLSOptions{
+		CAFile:     flags.TlsCa,
+		CertFile:   flags.TlsCert,
+		KeyFile:    flags.TlsKey,
+		ServerName: flags.TlsServerName,
+		SkipVerify: flags.TlsSkipVerify,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if flags.SshHost != "" {
+		log.Printf("opening SSH tunnel through %s", flags.SshHost)
+		tunnel, err := sqlconn.DialSSH(ctx, sqlconn.SSHOptions{
+			Host:                  flags.SshHost,
+			KeyFile:               flags.SshKey,
+			KnownHostsFile:        flags.SshKnownHosts,
+			InsecureIgnoreHostKey: flags.SshInsecureIgnoreHostKey,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer tunnel.Close()
+		cfg.Dial = tunnel.DialContext
+	}
+
+	log.Printf("connecting to %s", cfg.Redacted())
+	db, err := cfg.Open(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	r := &runner{
+		db:        db,
+		driver:    cfg.Driver,
+		params:    p,
+		timeout:   flags.Timeout,
+		converter: &converter{decimalsAsNumbers: flags.Decimals == "number", tinyintAsBool: flags.TinyintAsBool},
+	}
+	if flags.ToSheet {
+		url, err := writeToSheet(ctx, r, statements, &flags)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(url)
+		return
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	rw, err := newResultWriter(flags.Format, w)
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = r.run(ctx, statements, rw)
 	if err != nil {
 		log.Fatal(err)
 	}
+	if err := w.Flush(); err != nil {
+		log.Fatal(err)
+	}
 }
 
-func execQuery(db *sql.DB, query string, writer *json.Encoder) error {
-	var err error
+// resolveQuery returns the script of the flags and the default parameters of a saved query, a saved query
+// runs on its saved connection unless another one is given
+func resolveQuery(flags *cli, reg *registry) (string, map[string]any, error) {
+	switch {
+	case flags.SavedQuery != "":
+		q, err := reg.query(flags.SavedQuery)
+		if err != nil {
+			return "", nil, err
+		}
+		if flags.Connection == "" {
+			flags.Connection = q.Connection
+		}
+		return q.SQL, q.Params, nil
+	case flags.QueryFile != "":
+		content, err := os.ReadFile(flags.QueryFile)
+		if err != nil {
+			return "", nil, fmt.Errorf("cannot read query file: %w", err)
+		}
+		return string(content), nil, nil
+	}
+	return flags.Query, nil, nil
+}
+
+// resolveConnection replaces the connection URI with the one of a saved connection, its database is the
+// default of --db-name
+func resolveConnection(flags *cli, reg *registry) error {
+	if flags.Connection != "" {
+		c, ok, err := reg.connection(flags.Connection)
+		if err != nil {
+			return err
+		}
+		if ok {
+			flags.DbConnectionUri = c.DSN
+			if flags.DbName == "" {
+				flags.DbName = c.Database
+			}
+		}
+	}
+	if flags.DbConnectionUri == "" {
+		return fmt.Errorf("no connection given, use --db-connection-uri or a saved --connection")
+	}
+	return nil
+}
+
+// parseConnection parses the connection URI with the password of the flags, the password file, the keyring
+// or a prompt, in this order
+func parseConnection(flags *cli, p *passwords) (*sqlconn.Config, error) {
+	o := sqlconn.Overrides{
+		User:     flags.DbUser,
+		Password: flags.DbPassword,
+		Database: flags.DbName,
+	}
+	if flags.DbPasswordFile != "" {
+		password, err := readPasswordFile(flags.DbPasswordFile)
+		if err != nil {
+			return nil, err
+		}
+		o.Password = password
+	}
+
+	if flags.SavePassword {
+		if o.Password == "" {
+			if p.prompt == nil {
+				return nil, fmt.Errorf("no password to save, give it with --db-password-file or on a terminal")
+			}
+			password, err := p.prompt(fmt.Sprintf("password for connection %q: ", p.connection))
+			if err != nil {
+				return nil, fmt.Errorf("cannot read password: %w", err)
+			}
+			o.Password = password
+		}
+		if err := p.save(o.Password); err != nil {
+			return nil, err
+		}
+	}
+
+	cfg, err := sqlconn.Parse(flags.DbConnectionUri, o)
+	if err != nil {
+		return nil, err
+	}
+	password, ok, err := p.lookup(cfg)
+	if err != nil || !ok {
+		return cfg, err
+	}
+	o.Password = password
+	return sqlconn.Parse(flags.DbConnectionUri, o)
+}
+
+// writeToSheet streams the rows as NDJSON objects through json2sheet
+func writeToSheet(ctx context.Context, r *runner, statements []string, flags *cli) (*url.URL, error) {
+	svc, err := sheets.NewSheetServiceForBackend(ctx, sheets.Backend(flags.SheetsBackend), flags.SheetsLocalDir)
+	if err != nil {
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		bw := bufio.NewWriter(pw)
+		err := r.run(ctx, statements, &objectWriter{w: bw})
+		if err == nil {
+			err = bw.Flush()
+		}
+		pw.CloseWithError(err)
+		done <- err
+	}()
+
+	var u *url.URL
+	spreadsheetUrl := strings.TrimSpace(flags.SpreadsheetUrl)
+	if spreadsheetUrl != "" {
+		ref, err := sheets.ParseSheetRef(spreadsheetUrl)
+		if err != nil {
+			pr.CloseWithError(err)
+			<-done
+			return nil, err
+		}
+		u, err = json2sheet.UpdateSheet(svc, ref, pr)
+	} else {
+		u, err = json2sheet.WriteToNewSheet(svc, pr)
+	}
+
+	// unblock the query if the sheet failed
+	pr.CloseWithError(err)
+	if runErr := <-done; runErr != nil {
+		return nil, runErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+type runner struct {
+	db        *sql.DB
+	driver    sqlconn.Driver
+	params    *params
+	timeout   time.Duration
+	converter *converter
+}
+
+// run executes the statements one after the other and writes their result sets
+func (r *runner) run(ctx context.Context, statements []string, w resultWriter) error {
+	// the placeholders of a statement cannot tell which of the positional parameters are its own
+	if len(statements) > 1 && len(r.params.positional) > 0 {
+		return fmt.Errorf("positional parameters cannot be used with multiple statements, use named parameters instead")
+	}
+	for i, stmt := range statements {
+		query, args, err := r.params.bind(stmt, r.driver)
+		if err != nil {
+			return fmt.Errorf("statement %d: %w", i+1, err)
+		}
+
+		statement := 0
+		if len(statements) > 1 {
+			statement = i + 1
+		}
+		err = r.execQuery(ctx, query, args, statement, w)
+		if err != nil {
+			if statement > 0 {
+				return fmt.Errorf("statement %d: %w", statement, err)
+			}
+			return err
+		}
+	}
+	return w.Close()
+}
 
-	rows, err := db.Query(query)
+// execQuery writes the result set of a query, statements without a result like an UPDATE write nothing
+func (r *runner) execQuery(ctx context.Context, query string, args []any, statement int, w resultWriter) error {
+	if r.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+
+	c := r.converter
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return fmt.Errorf("cannot execute query: %w", err)
 	}
@@ -73,10 +436,23 @@ func execQuery(db *sql.DB, query string, writer *json.Encoder) error {
 		return fmt.Errorf("cannot get result column names: %w", err)
 	}
 
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return fmt.Errorf("cannot get result column types: %w", err)
+	}
+	kinds := c.columnKinds(types)
+
+	if len(header) == 0 {
+		return rows.Err()
+	}
+	if err := w.Begin(statement, header); err != nil {
+		return fmt.Errorf("cannot write result: %w", err)
+	}
+
 	length := len(header)
 	for rows.Next() {
 		pointers := make([]interface{}, length)
-		row := make([]*string, length)
+		row := make([]any, length)
 
 		for i := range pointers {
 			pointers[i] = &row[i]
@@ -86,7 +462,11 @@ func execQuery(db *sql.DB, query string, writer *json.Encoder) error {
 			return fmt.Errorf("cannot scan row: %w", err)
 		}
 
-		if err := writer.Encode(mapRow(header, row)); err != nil {
+		values, err := mapRow(header, kinds, row, c)
+		if err != nil {
+			return err
+		}
+		if err := w.Row(values); err != nil {
 			return fmt.Errorf("cannot write row: %w", err)
 		}
 	}
@@ -98,38 +478,15 @@ func execQuery(db *sql.DB, query string, writer *json.Encoder) error {
 	return nil
 }
 
-func mapRow(headers []string, r []*string) map[string]string {
-	mapped := make(map[string]string)
+func mapRow(headers []string, kinds []columnKind, r []any, c *converter) ([]ast.Node, error) {
+	mapped := make([]ast.Node, len(r))
 
 	for i := range r {
-		v := r[i]
-		if v != nil {
-			mapped[headers[i]] = *v
+		v, err := c.toNode(r[i], kinds[i])
+		if err != nil {
+			return nil, fmt.Errorf("cannot convert column %q: %w", headers[i], err)
 		}
+		mapped[i] = v
 	}
-	return mapped
-}
-
-func parseDsnConfig(args *cli) (string, error) {
-	cfg, err := mysql.ParseDSN(args.DbConnectionUri)
-	if err != nil {
-		return "", fmt.Errorf("invalid mysql connection URI: %w", err)
-	}
-
-	dbName := args.DbName
-	if dbName != "" {
-		cfg.DBName = dbName
-	}
-
-	dbUser := args.DbUser
-	if dbUser != "" {
-		cfg.User = dbUser
-	}
-
-	dbPassword := args.DbPassword
-	if dbPassword != "" {
-		cfg.Passwd = dbPassword
-	}
-
-	return cfg.FormatDSN(), nil
+	return mapped, nil
 }
@@ -0,0 +1,99 @@
+package sql2json
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+
+	"github.com/trichner/toolbox/pkg/keyring"
+	"github.com/trichner/toolbox/pkg/sqlconn"
+)
+
+// keyringServiceName is the keyring service the passwords of named connections are stored with
+const keyringServiceName = "toolbox sql2json"
+
+// secretStore is where the passwords of named connections are kept, i.e. a keyring.Ring
+type secretStore interface {
+	Get(name string) (*keyring.Item, error)
+	Put(name string, item *keyring.Item) error
+}
+
+// passwords finds the password of a connection whose URI has none
+type passwords struct {
+	// connection is the name the password is stored with in the secrets
+	connection string
+	secrets    secretStore
+
+	// prompt asks for the password, it is nil without a terminal
+	prompt func(label string) (string, error)
+}
+
+// lookup returns the password of the connection from the keyring or prompts for it, a prompted password of a
+// named connection is stored in the keyring. It returns false if the URI has a password already or the
+// password cannot be found.
+func (p *passwords) lookup(cfg *sqlconn.Config) (string, bool, error) {
+	if !cfg.MissingPassword() {
+		return "", false, nil
+	}
+
+	if p.connection != "" {
+		item, err := p.secrets.Get(p.connection)
+		if err == nil {
+			return item.Secret, true, nil
+		}
+		if !errors.Is(err, keyring.ErrNotFound) {
+			return "", false, fmt.Errorf("cannot read password of connection %q: %w", p.connection, err)
+		}
+	}
+
+	if p.prompt == nil {
+		return "", false, nil
+	}
+	password, err := p.prompt(fmt.Sprintf("password for %s: ", cfg.Redacted()))
+	if err != nil {
+		return "", false, fmt.Errorf("cannot read password: %w", err)
+	}
+	if p.connection != "" {
+		if err := p.save(password); err != nil {
+			return "", false, err
+		}
+	}
+	return password, true, nil
+}
+
+// save stores the password of the named connection in the keyring
+func (p *passwords) save(password string) error {
+	if p.connection == "" {
+		return fmt.Errorf("a password can only be saved for a named connection")
+	}
+	if err := p.secrets.Put(p.connection, &keyring.Item{Secret: password}); err != nil {
+		return fmt.Errorf("cannot save password of connection %q: %w", p.connection, err)
+	}
+	return nil
+}
+
+// readPasswordFile reads a password from a file, a trailing line break is not part of it
+func readPasswordFile(name string) (string, error) {
+	content, err := os.ReadFile(name)
+	if err != nil {
+		return "", fmt.Errorf("cannot read password file: %w", err)
+	}
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+// terminalPrompt asks for a password without echoing it, it is nil if stdin is not a terminal
+func terminalPrompt() func(label string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil
+	}
+	return func(label string) (string, error) {
+		fmt.Fprint(os.Stderr, label)
+		password, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		return string(password), err
+	}
+}
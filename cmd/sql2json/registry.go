@@ -0,0 +1,192 @@
+package sql2json
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/trichner/toolbox/pkg/jsontree/ast"
+	"github.com/trichner/toolbox/pkg/sqlconn"
+	"github.com/trichner/toolbox/pkg/tbconfig"
+)
+
+// registryFileName is the file of the saved connections and queries in the tb config dir
+const registryFileName = "sql2json.json"
+
+// registry are the saved connections and queries, e.g.:
+//
+//	{
+//	  "connections": {
+//	    "reporting-prod": {"driver": "postgres", "dsn": "postgres://reporting@db.example.com/shop"}
+//	  },
+//	  "queries": {
+//	    "weekly-signups": {
+//	      "connection": "reporting-prod",
+//	      "sql": "SELECT country, COUNT(*) AS signups FROM users WHERE created >= :since GROUP BY country",
+//	      "params": {"since": "2024-01-01"}
+//	    }
+//	  }
+//	}
+type registry struct {
+	Connections map[string]*savedConnection `json:"connections,omitempty"`
+	Queries     map[string]*savedQuery      `json:"queries,omitempty"`
+}
+
+// savedConnection is a connection without its password, it is read from the keyring by the name of the
+// connection
+type savedConnection struct {
+	Driver      string `json:"driver,omitempty"`
+	DSN         string `json:"dsn"`
+	Database    string `json:"database,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// savedQuery is a script with the defaults of its parameters and optionally the connection to run it on
+type savedQuery struct {
+	Connection  string         `json:"connection,omitempty"`
+	SQL         string         `json:"sql"`
+	Params      map[string]any `json:"params,omitempty"`
+	Description string         `json:"description,omitempty"`
+}
+
+// defaultRegistryPath is the registry in the tb config dir, if there is one
+func defaultRegistryPath() string {
+	path, err := tbconfig.Path(registryFileName)
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+// loadRegistry reads the saved connections and queries, a missing file is an empty registry
+func loadRegistry(path string) (*registry, error) {
+	r := &registry{}
+	if path == "" {
+		return r, nil
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read saved connections and queries: %w", err)
+	}
+	defer f.Close()
+
+	d := json.NewDecoder(f)
+	d.UseNumber()
+	if err := d.Decode(r); err != nil {
+		return nil, fmt.Errorf("invalid saved connections and queries in %s: %w", path, err)
+	}
+	return r, nil
+}
+
+// connection returns the saved connection of the name, it must not have a password
+func (r *registry) connection(name string) (*savedConnection, bool, error) {
+	c, ok := r.Connections[name]
+	if !ok {
+		return nil, false, nil
+	}
+
+	cfg, err := sqlconn.Parse(c.DSN, sqlconn.Overrides{})
+	if err != nil {
+		return nil, false, fmt.Errorf("saved connection %q: %w", name, err)
+	}
+	if c.Driver != "" && sqlconn.Driver(c.Driver) != cfg.Driver {
+		return nil, false, fmt.Errorf("saved connection %q: driver %s does not match its %s DSN", name, c.Driver, cfg.Driver)
+	}
+	if cfg.HasPassword() {
+		return nil, false, fmt.Errorf("saved connection %q: the DSN has a password, store it in the keyring with --save-password instead", name)
+	}
+	return c, true, nil
+}
+
+// query returns the saved query of the name
+func (r *registry) query(name string) (*savedQuery, error) {
+	q, ok := r.Queries[name]
+	if !ok {
+		return nil, fmt.Errorf("no saved query %q", name)
+	}
+	return q, nil
+}
+
+// connectionNames and queryNames are sorted, e.g. for completions
+func (r *registry) connectionNames() []string {
+	return sortedKeys(r.Connections)
+}
+
+func (r *registry) queryNames() []string {
+	return sortedKeys(r.Queries)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// list writes a table of the saved connections and one of the saved queries
+func (r *registry) list(w io.Writer) error {
+	tw := &tableWriter{w: w}
+	if err := tw.Begin(0, []string{"connection", "driver", "database", "dsn", "description"}); err != nil {
+		return err
+	}
+	for _, name := range r.connectionNames() {
+		c := r.Connections[name]
+		driver := c.Driver
+		dsn := c.DSN
+		if cfg, err := sqlconn.Parse(c.DSN, sqlconn.Overrides{}); err == nil {
+			driver = string(cfg.Driver)
+			dsn = cfg.Redacted()
+		}
+		if err := tw.Row(textNodes(name, driver, c.Database, dsn, c.Description)); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Begin(0, []string{"query", "connection", "params", "description"}); err != nil {
+		return err
+	}
+	for _, name := range r.queryNames() {
+		q := r.Queries[name]
+		params := strings.Join(sortedKeys(q.Params), ",")
+		if err := tw.Row(textNodes(name, q.Connection, params, q.Description)); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+// show writes the saved connections and queries of the name as JSON
+func (r *registry) show(w io.Writer, name string) error {
+	found := &registry{}
+	if c, ok := r.Connections[name]; ok {
+		found.Connections = map[string]*savedConnection{name: c}
+	}
+	if q, ok := r.Queries[name]; ok {
+		found.Queries = map[string]*savedQuery{name: q}
+	}
+	if found.Connections == nil && found.Queries == nil {
+		return fmt.Errorf("no saved connection or query %q", name)
+	}
+
+	e := json.NewEncoder(w)
+	e.SetEscapeHTML(false)
+	e.SetIndent("", "  ")
+	return e.Encode(found)
+}
+
+func textNodes(values ...string) []ast.Node {
+	nodes := make([]ast.Node, len(values))
+	for i, v := range values {
+		nodes[i] = ast.NewTextNode(v)
+	}
+	return nodes
+}
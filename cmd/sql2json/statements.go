@@ -0,0 +1,250 @@
+package sql2json
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/trichner/toolbox/pkg/sqlconn"
+)
+
+// splitStatements splits a script into its ';' separated statements, separators within literals, quoted
+// identifiers and comments are ignored
+func splitStatements(script string, driver sqlconn.Driver) []string {
+	var statements []string
+	start := 0
+	scanSQL(script, driver, func(i int) {
+		if script[i] == ';' {
+			statements = appendStatement(statements, script[start:i])
+			start = i + 1
+		}
+	}, nil)
+	return appendStatement(statements, script[start:])
+}
+
+func appendStatement(statements []string, stmt string) []string {
+	stmt = strings.TrimSpace(stmt)
+	if stmt == "" {
+		return statements
+	}
+	return append(statements, stmt)
+}
+
+// scanSQL calls code for every byte of a statement which is not part of a literal, a quoted identifier or a
+// comment and named for every named parameter, e.g. ':id'
+func scanSQL(s string, driver sqlconn.Driver, code func(i int), named func(start, end int)) {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			// quotes are escaped by doubling them, which is the same as two adjacent literals
+			end := closingQuote(s, i, driver)
+			if end < 0 {
+				return
+			}
+			i = end
+		case c == '-' && strings.HasPrefix(s[i:], "--"):
+			end := strings.IndexByte(s[i:], '\n')
+			if end < 0 {
+				return
+			}
+			i += end
+		case c == '/' && strings.HasPrefix(s[i:], "/*"):
+			end := strings.Index(s[i+2:], "*/")
+			if end < 0 {
+				return
+			}
+			i += end + 3
+		case c == ':' && strings.HasPrefix(s[i:], "::"):
+			// a Postgres type cast
+			i++
+		case c == ':' && i+1 < len(s) && isNameStart(s[i+1]):
+			end := i + 2
+			for end < len(s) && isNamePart(s[end]) {
+				end++
+			}
+			if named != nil {
+				named(i, end)
+			}
+			i = end - 1
+		default:
+			if code != nil {
+				code(i)
+			}
+		}
+	}
+}
+
+// closingQuote returns the index of the quote closing the one at start, MySQL additionally escapes quotes of
+// literals with a backslash, e.g. 'it\'s'
+func closingQuote(s string, start int, driver sqlconn.Driver) int {
+	c := s[start]
+	backslashes := driver == sqlconn.DriverMySQL && c != '`'
+	for i := start + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if backslashes {
+				i++
+			}
+		case c:
+			return i
+		}
+	}
+	return -1
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+func isNamePart(c byte) bool {
+	return isNameStart(c) || ('0' <= c && c <= '9')
+}
+
+// params are the bind parameters of the statements, either named or positional
+type params struct {
+	named      map[string]any
+	positional []any
+}
+
+// parseParams merges the parameters given as JSON, i.e. an object of named or an array of positional ones,
+// with those given as 'name=value' pairs. Numeric names, e.g. '1=value', are positions starting at 1.
+func parseParams(pairs map[string]string, raw string) (*params, error) {
+	p := &params{named: map[string]any{}}
+	if raw != "" {
+		d := json.NewDecoder(strings.NewReader(raw))
+		d.UseNumber()
+		var v any
+		if err := d.Decode(&v); err != nil {
+			return nil, fmt.Errorf("invalid parameters, expected a JSON object or array: %w", err)
+		}
+		switch v := v.(type) {
+		case map[string]any:
+			for name, value := range v {
+				arg, err := paramValue(value)
+				if err != nil {
+					return nil, fmt.Errorf("invalid parameter %q: %w", name, err)
+				}
+				p.named[name] = arg
+			}
+		case []any:
+			for i, value := range v {
+				arg, err := paramValue(value)
+				if err != nil {
+					return nil, fmt.Errorf("invalid parameter %d: %w", i+1, err)
+				}
+				p.positional = append(p.positional, arg)
+			}
+		default:
+			return nil, fmt.Errorf("invalid parameters, expected a JSON object or array but got: %s", raw)
+		}
+	}
+
+	var positions []int
+	for name, value := range pairs {
+		if n, err := strconv.Atoi(name); err == nil {
+			if n < 1 {
+				return nil, fmt.Errorf("invalid parameter position %d, positions start at 1", n)
+			}
+			positions = append(positions, n)
+			continue
+		}
+		p.named[name] = value
+	}
+	sort.Ints(positions)
+	for _, n := range positions {
+		for len(p.positional) < n {
+			p.positional = append(p.positional, nil)
+		}
+		p.positional[n-1] = pairs[strconv.Itoa(n)]
+	}
+
+	if len(p.named) > 0 && len(p.positional) > 0 {
+		return nil, fmt.Errorf("cannot mix named and positional parameters")
+	}
+	return p, nil
+}
+
+// withDefaults adds the named parameters which are not given otherwise, e.g. those of a saved query
+func (p *params) withDefaults(defaults map[string]any) error {
+	if len(defaults) == 0 {
+		return nil
+	}
+	if len(p.positional) > 0 {
+		return fmt.Errorf("cannot mix named and positional parameters")
+	}
+	for name, value := range defaults {
+		if _, ok := p.named[name]; ok {
+			continue
+		}
+		arg, err := paramValue(value)
+		if err != nil {
+			return fmt.Errorf("invalid parameter %q: %w", name, err)
+		}
+		p.named[name] = arg
+	}
+	return nil
+}
+
+// paramValue converts a JSON value to a bind parameter, nested values are passed as JSON text
+func paramValue(v any) (any, error) {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i, nil
+		}
+		return v.Float64()
+	case map[string]any, []any:
+		var buf bytes.Buffer
+		e := json.NewEncoder(&buf)
+		e.SetEscapeHTML(false)
+		if err := e.Encode(v); err != nil {
+			return nil, err
+		}
+		return strings.TrimSuffix(buf.String(), "\n"), nil
+	}
+	return v, nil
+}
+
+// bind replaces the named parameters of a statement with the placeholders of the driver and returns the
+// arguments in order, positional parameters are passed as is and hence only to a single statement
+func (p *params) bind(stmt string, driver sqlconn.Driver) (string, []any, error) {
+	if len(p.named) == 0 {
+		return stmt, p.positional, nil
+	}
+
+	var b strings.Builder
+	var args []any
+	positions := map[string]int{}
+	last := 0
+	var err error
+	scanSQL(stmt, driver, nil, func(start, end int) {
+		name := stmt[start+1 : end]
+		value, ok := p.named[name]
+		if !ok {
+			if err == nil {
+				err = fmt.Errorf("missing parameter %q", name)
+			}
+			return
+		}
+
+		b.WriteString(stmt[last:start])
+		last = end
+
+		// Postgres refers to the same argument again, the others take it once per placeholder
+		n, ok := positions[name]
+		if !ok || driver != sqlconn.DriverPostgres {
+			args = append(args, value)
+			n = len(args)
+			positions[name] = n
+		}
+		b.WriteString(driver.Placeholder(n))
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	b.WriteString(stmt[last:])
+	return b.String(), args, nil
+}
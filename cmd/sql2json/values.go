@@ -0,0 +1,180 @@
+package sql2json
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/trichner/toolbox/pkg/jsontree/ast"
+)
+
+// columnKind tells how the values of a column are converted to JSON
+type columnKind int
+
+const (
+	// kindAuto converts values by their Go type, e.g. for expressions in SQLite which have no declared type
+	kindAuto columnKind = iota
+	kindInteger
+	kindFloat
+	kindDecimal
+	kindBoolean
+	kindDateTime
+	kindDate
+	kindBinary
+	kindText
+)
+
+// mysqlDateTime is the text format of DATETIME and TIMESTAMP values in MySQL
+const mysqlDateTime = "2006-01-02 15:04:05.999999999"
+
+type converter struct {
+	// decimalsAsNumbers writes DECIMAL and NUMERIC values as JSON numbers, they are exact strings otherwise
+	decimalsAsNumbers bool
+
+	// tinyintAsBool writes TINYINT values of 0 and 1 as booleans, MySQL does not report the display width and
+	// hence TINYINT(1) cannot be told from other TINYINT columns. Other values stay numbers.
+	tinyintAsBool bool
+}
+
+// columnKinds derives the conversion of each column from the type the database reports for it
+func (c *converter) columnKinds(types []*sql.ColumnType) []columnKind {
+	kinds := make([]columnKind, len(types))
+	for i, t := range types {
+		kinds[i] = c.columnKind(t.DatabaseTypeName())
+	}
+	return kinds
+}
+
+func (c *converter) columnKind(databaseType string) columnKind {
+	name := strings.ToUpper(strings.TrimSpace(databaseType))
+	if i := strings.IndexByte(name, '('); i >= 0 {
+		name = strings.TrimSpace(name[:i])
+	}
+	name = strings.TrimPrefix(name, "UNSIGNED ")
+
+	switch name {
+	case "TINYINT":
+		if c.tinyintAsBool {
+			return kindBoolean
+		}
+		return kindInteger
+	case "INT", "INTEGER", "SMALLINT", "MEDIUMINT", "BIGINT", "INT2", "INT4", "INT8", "BIG INT", "YEAR",
+		"SERIAL", "BIGSERIAL", "SMALLSERIAL":
+		return kindInteger
+	case "FLOAT", "DOUBLE", "REAL", "FLOAT4", "FLOAT8", "DOUBLE PRECISION":
+		return kindFloat
+	case "DECIMAL", "NUMERIC":
+		return kindDecimal
+	case "BOOL", "BOOLEAN":
+		return kindBoolean
+	case "DATETIME", "TIMESTAMP", "TIMESTAMPTZ", "TIMESTAMP WITH TIME ZONE", "TIMESTAMP WITHOUT TIME ZONE":
+		return kindDateTime
+	case "DATE":
+		return kindDate
+	case "BLOB", "TINYBLOB", "MEDIUMBLOB", "LONGBLOB", "BINARY", "VARBINARY", "BYTEA", "BIT", "GEOMETRY":
+		return kindBinary
+	case "":
+		return kindAuto
+	}
+	return kindText
+}
+
+// toNode converts a value scanned into an any to JSON, NULL becomes null
+func (c *converter) toNode(v any, kind columnKind) (ast.Node, error) {
+	switch v := v.(type) {
+	case nil:
+		return ast.NewNullNode(), nil
+	case bool:
+		return ast.NewBooleanNode(v), nil
+	case int64:
+		switch kind {
+		case kindBoolean:
+			if v == 0 || v == 1 {
+				return ast.NewBooleanNode(v == 1), nil
+			}
+		case kindDecimal:
+			return c.decimal(strconv.FormatInt(v, 10)), nil
+		}
+		return ast.NewNumberNode(strconv.FormatInt(v, 10)), nil
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return ast.NewTextNode(strconv.FormatFloat(v, 'g', -1, 64)), nil
+		}
+		literal := strconv.FormatFloat(v, 'g', -1, 64)
+		if kind == kindDecimal {
+			return c.decimal(literal), nil
+		}
+		return ast.NewNumberNode(literal), nil
+	case time.Time:
+		if kind == kindDate {
+			return ast.NewTextNode(v.Format(time.DateOnly)), nil
+		}
+		return ast.NewTextNode(v.Format(time.RFC3339Nano)), nil
+	case []byte:
+		if kind == kindBinary || (kind == kindAuto && !utf8.Valid(v)) {
+			return ast.NewTextNode(base64.StdEncoding.EncodeToString(v)), nil
+		}
+		return c.textToNode(string(v), kind), nil
+	case string:
+		if kind == kindBinary {
+			return ast.NewTextNode(base64.StdEncoding.EncodeToString([]byte(v))), nil
+		}
+		return c.textToNode(v, kind), nil
+	}
+
+	// driver specific types
+	n, err := ast.Encode(v)
+	if err != nil {
+		return nil, fmt.Errorf("cannot convert %T to JSON: %w", v, err)
+	}
+	return n, nil
+}
+
+// textToNode converts the text representation of a value, e.g. as MySQL returns all values of plain queries
+func (c *converter) textToNode(s string, kind columnKind) ast.Node {
+	switch kind {
+	case kindInteger, kindFloat:
+		if isNumber(s) {
+			return ast.NewNumberNode(s)
+		}
+	case kindDecimal:
+		if isNumber(s) {
+			return c.decimal(s)
+		}
+	case kindBoolean:
+		switch strings.ToLower(s) {
+		case "1", "t", "true":
+			return ast.NewBooleanNode(true)
+		case "0", "f", "false":
+			return ast.NewBooleanNode(false)
+		}
+		// e.g. a TINYINT which is not a boolean after all
+		if isNumber(s) {
+			return ast.NewNumberNode(s)
+		}
+	case kindDateTime:
+		// zero dates like '0000-00-00 00:00:00' are kept as is
+		if t, err := time.ParseInLocation(mysqlDateTime, s, time.UTC); err == nil {
+			return ast.NewTextNode(t.Format(time.RFC3339Nano))
+		}
+	}
+	return ast.NewTextNode(s)
+}
+
+func (c *converter) decimal(literal string) ast.Node {
+	if c.decimalsAsNumbers {
+		return ast.NewNumberNode(literal)
+	}
+	return ast.NewTextNode(literal)
+}
+
+// isNumber tells whether s is a valid JSON number literal
+func isNumber(s string) bool {
+	return s != "" && (s[0] == '-' || ('0' <= s[0] && s[0] <= '9')) && json.Valid([]byte(s))
+}
@@ -4,8 +4,9 @@ import (
 	"fmt"
 	"os"
 	"path"
-	"path/filepath"
 	"strings"
+
+	"github.com/trichner/toolbox/pkg/tbconfig"
 )
 
 type config struct{}
@@ -15,7 +16,10 @@ func (c *config) Getenv(name string) string {
 }
 
 func (c *config) ReadFile(name string) ([]byte, error) {
-	basePath := c.determineCommandConfigPath()
+	basePath, err := tbconfig.Dir()
+	if err != nil {
+		return nil, err
+	}
 	name = path.Clean(name)
 	if path.IsAbs(name) {
 		return nil, fmt.Errorf("expected relative path but was absolute: %s", name)
@@ -27,23 +31,3 @@ func (c *config) ReadFile(name string) ([]byte, error) {
 
 	return os.ReadFile(p)
 }
-
-func (c *config) determineCommandConfigPath() string {
-	dir := c.determineConfigPath()
-	return path.Join(dir, "tb")
-}
-
-func (c *config) determineConfigPath() string {
-	dir := c.Getenv("XDG_CONFIG")
-	if dir != "" {
-		return dir
-	}
-
-	dir = c.Getenv("HOME")
-	if dir != "" {
-		panic(fmt.Errorf("cannot determine $HOME directory, env variable not set"))
-	}
-
-	// default XDG_CONFIG
-	return filepath.Join(dir, ".config")
-}
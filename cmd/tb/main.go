@@ -6,12 +6,20 @@ import (
 
 	"github.com/trichner/toolbox/cmd/csv2json"
 	"github.com/trichner/toolbox/cmd/sheet2json"
+	"github.com/trichner/toolbox/cmd/sheets"
 	"github.com/trichner/toolbox/pkg/cmdreg"
 
 	"github.com/trichner/toolbox/cmd/sql2json"
+	"github.com/trichner/toolbox/cmd/xlsx2json"
 
 	"github.com/trichner/toolbox/cmd/jiracli"
 	"github.com/trichner/toolbox/cmd/json2sheet"
+	"github.com/trichner/toolbox/cmd/json2sql"
+	"github.com/trichner/toolbox/cmd/json2xlsx"
+	"github.com/trichner/toolbox/cmd/jsondiff"
+	"github.com/trichner/toolbox/cmd/jsonfmt"
+	"github.com/trichner/toolbox/cmd/jsonpath"
+	"github.com/trichner/toolbox/cmd/jsonschema"
 	"github.com/trichner/toolbox/cmd/kraki"
 )
 
@@ -21,9 +29,17 @@ func main() {
 	r.RegisterFunc("csv2json", csv2json.Exec)
 	r.RegisterFunc("jiracli", jiracli.Exec)
 	r.RegisterFunc("json2sheet", json2sheet.Exec)
+	r.RegisterFunc("json2sql", json2sql.Exec)
+	r.RegisterFunc("json2xlsx", json2xlsx.Exec)
+	r.RegisterFunc("jsondiff", jsondiff.Exec)
+	r.RegisterFunc("jsonfmt", jsonfmt.Exec)
+	r.RegisterFunc("jsonpath", jsonpath.Exec)
+	r.RegisterFunc("jsonschema", jsonschema.Exec)
 	r.RegisterFunc("kraki", kraki.Exec)
 	r.RegisterFunc("sheet2json", sheet2json.Exec, cmdreg.WithCompletion(sheet2json.Completions()))
-	r.RegisterFunc("sql2json", sql2json.Exec)
+	r.RegisterFunc("sheets", sheets.Exec)
+	r.RegisterFunc("sql2json", sql2json.Exec, cmdreg.WithCompletion(sql2json.Completions()))
+	r.RegisterFunc("xlsx2json", xlsx2json.Exec)
 
 	r.RegisterFunc("help", help(r))
 
@@ -0,0 +1,43 @@
+package xlsx2json
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"github.com/alecthomas/kong"
+	"github.com/trichner/toolbox/pkg/sheet2json"
+	"github.com/trichner/toolbox/pkg/workbook"
+)
+
+var cli struct {
+	File  string `help:"workbook to read, either .xlsx or .ods" required:"" type:"existingfile"`
+	Sheet string `help:"name of the sheet to read, defaults to the first sheet"`
+}
+
+func Exec(ctx context.Context, args []string) {
+	parser := kong.Must(&cli, kong.Name(args[0]))
+	_, err := parser.Parse(args[1:])
+	parser.FatalIfErrorf(err)
+
+	wb, err := workbook.Open(cli.File)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer wb.Close()
+
+	var sheet workbook.Sheet
+	if cli.Sheet != "" {
+		sheet, err = wb.SheetByName(cli.Sheet)
+	} else {
+		sheet, err = wb.FirstSheet()
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = sheet2json.ReadObjectsFrom(sheet, os.Stdout)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -1,6 +1,7 @@
 package credentials
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -12,6 +13,70 @@ type Credentials struct {
 	Baseurl  string `json:"baseurl"`
 	Username string `json:"username"`
 	Token    string `json:"token"`
+
+	// CustomFields are the custom fields of the issues by their names, i.e. their ID or their name in Jira. They
+	// are DefaultCustomFields if the credentials have none.
+	CustomFields CustomFields `json:"customFields,omitempty"`
+}
+
+// DefaultCustomFields are the custom fields issues had before they were configurable
+var DefaultCustomFields = CustomFields{
+	{Name: "squad", Field: "customfield_10951"},
+	{Name: "storyPoints", Field: "customfield_10004"},
+}
+
+// CustomFields name custom fields in the order of the config, e.g. {"squad": "customfield_10951"}
+type CustomFields []CustomField
+
+// CustomField is a custom field by its name and its ID or name in Jira
+type CustomField struct {
+	Name  string
+	Field string
+}
+
+func (f *CustomFields) UnmarshalJSON(b []byte) error {
+	d := json.NewDecoder(bytes.NewReader(b))
+	if tkn, err := d.Token(); err != nil || tkn != json.Delim('{') {
+		return fmt.Errorf("custom fields must be an object of names and fields")
+	}
+
+	fields := CustomFields{}
+	for d.More() {
+		tkn, err := d.Token()
+		if err != nil {
+			return err
+		}
+		var field string
+		if err := d.Decode(&field); err != nil {
+			return fmt.Errorf("custom field %q: %w", tkn, err)
+		}
+		fields = append(fields, CustomField{Name: tkn.(string), Field: field})
+	}
+	*f = fields
+	return nil
+}
+
+func (f CustomFields) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, field := range f {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		name, err := json.Marshal(field.Name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(field.Field)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(name)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
 }
 
 func FindCredentials() (*Credentials, error) {
@@ -41,6 +106,9 @@ func FindCredentials() (*Credentials, error) {
 	if err := json.Unmarshal(contents, &clientCredentials); err != nil {
 		return nil, fmt.Errorf("failed to parse credentials: %w", err)
 	}
+	if clientCredentials.CustomFields == nil {
+		clientCredentials.CustomFields = DefaultCustomFields
+	}
 
 	return &clientCredentials, nil
 }
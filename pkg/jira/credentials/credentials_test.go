@@ -0,0 +1,32 @@
+package credentials
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCustomFields_JSON(t *testing.T) {
+	raw := `{"token":"s0meT0kn","customFields":{"storyPoints":"Story Points","squad":"customfield_10951"}}`
+
+	var c Credentials
+	require.NoError(t, json.Unmarshal([]byte(raw), &c))
+	assert.Equal(t, CustomFields{
+		{Name: "storyPoints", Field: "Story Points"},
+		{Name: "squad", Field: "customfield_10951"},
+	}, c.CustomFields)
+
+	out, err := json.Marshal(c.CustomFields)
+	require.NoError(t, err)
+	assert.Equal(t, `{"storyPoints":"Story Points","squad":"customfield_10951"}`, string(out))
+
+	// no custom fields at all rather than the defaults
+	require.NoError(t, json.Unmarshal([]byte(`{"customFields":{}}`), &c))
+	assert.NotNil(t, c.CustomFields)
+	assert.Empty(t, c.CustomFields)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"customFields":["squad"]}`), &c))
+	assert.Error(t, json.Unmarshal([]byte(`{"customFields":{"squad":1}}`), &c))
+}
@@ -0,0 +1,229 @@
+package jira
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/trichner/toolbox/pkg/jira/credentials"
+)
+
+// issueFields maps the names of the Issue fields to the Jira fields they are read from, the key is returned
+// for every issue
+var issueFields = map[string]string{
+	"key":        "",
+	"summary":    "summary",
+	"type":       "issuetype",
+	"assignee":   "assignee",
+	"fixVersion": "fixVersions",
+	"status":     "status",
+	"labels":     "labels",
+	"issueLinks": "issuelinks",
+}
+
+// Field is a field of the issues of a Jira instance, e.g. to find the ID of a custom field
+type Field struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Custom bool   `json:"custom"`
+	Type   string `json:"type,omitempty"`
+}
+
+// Fields lists all fields of the Jira instance
+func (j *JiraService) Fields() ([]Field, error) {
+	fields, _, err := j.client.Field.GetList()
+	if err != nil {
+		return nil, fmt.Errorf("cannot list fields: %w", err)
+	}
+
+	mapped := make([]Field, 0, len(fields))
+	for _, f := range fields {
+		mapped = append(mapped, Field{ID: f.ID, Name: f.Name, Custom: f.Custom, Type: f.Schema.Type})
+	}
+	sort.Slice(mapped, func(i, k int) bool {
+		return mapped[i].ID < mapped[k].ID
+	})
+	return mapped, nil
+}
+
+// SetCustomFields configures the custom fields of the issues by their names, e.g.
+// {"squad": "customfield_10951", "storyPoints": "Story Points"}. A field is either given by its ID or by its
+// name as listed by Fields. Issues are written with their custom fields in this order.
+func (j *JiraService) SetCustomFields(fields credentials.CustomFields) error {
+	resolved := make(map[string]string, len(fields))
+	names := make([]string, 0, len(fields))
+	var byName map[string]string
+	for _, f := range fields {
+		name, field := f.Name, f.Field
+		if _, ok := resolved[name]; ok {
+			return fmt.Errorf("custom field %q is configured twice", name)
+		}
+		names = append(names, name)
+		if _, ok := issueFields[name]; ok {
+			return fmt.Errorf("custom field %q clashes with the issue field of the same name", name)
+		}
+		if strings.HasPrefix(field, "customfield_") {
+			resolved[name] = field
+			continue
+		}
+
+		if byName == nil {
+			all, err := j.Fields()
+			if err != nil {
+				return err
+			}
+			byName = make(map[string]string, len(all))
+			for _, f := range all {
+				byName[f.Name] = f.ID
+			}
+		}
+		id, ok := byName[field]
+		if !ok {
+			return fmt.Errorf("custom field %q: no field named %q", name, field)
+		}
+		resolved[name] = id
+	}
+	j.customFields = resolved
+	j.customFieldNames = names
+	return nil
+}
+
+// jiraFields returns the Jira fields to request for the names of Issue fields, all navigable fields if there
+// are none
+func (j *JiraService) jiraFields(names []string) ([]string, error) {
+	if len(names) == 0 {
+		return []string{"*navigable"}, nil
+	}
+
+	var fields []string
+	for _, name := range names {
+		field, ok := issueFields[name]
+		if !ok {
+			field, ok = j.customFields[name]
+		}
+		if !ok {
+			return nil, fmt.Errorf("unknown field %q, expected one of %s", name, strings.Join(j.fieldNames(), ", "))
+		}
+		if field != "" {
+			fields = append(fields, field)
+		}
+	}
+	if len(fields) == 0 {
+		// no fields at all means the default ones, the key is part of every issue anyway
+		fields = append(fields, "summary")
+	}
+	return fields, nil
+}
+
+func (j *JiraService) fieldNames() []string {
+	names := make([]string, 0, len(issueFields)+len(j.customFields))
+	for name := range issueFields {
+		names = append(names, name)
+	}
+	for name := range j.customFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// customFieldValue simplifies the values of custom fields, e.g. an option to its value or a user to its name
+func customFieldValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for _, key := range []string{"value", "displayName", "name"} {
+			if s, ok := v[key]; ok {
+				return s
+			}
+		}
+		return v
+	case []any:
+		values := make([]any, len(v))
+		for i, e := range v {
+			values[i] = customFieldValue(e)
+		}
+		return values
+	}
+	return v
+}
+
+// MarshalJSON writes the custom fields after the other fields in the order they are configured, only the
+// selected fields are written if the issue was searched for some fields
+func (i Issue) MarshalJSON() ([]byte, error) {
+	type issue Issue
+	if len(i.selected) == 0 {
+		raw, err := json.Marshal(issue(i))
+		if err != nil || len(i.CustomFields) == 0 {
+			return raw, err
+		}
+
+		b := bytes.NewBuffer(raw[:len(raw)-1])
+		for _, name := range i.customFieldOrder() {
+			if err := writeField(b, name, i.CustomFields[name]); err != nil {
+				return nil, err
+			}
+		}
+		b.WriteByte('}')
+		return b.Bytes(), nil
+	}
+
+	var b bytes.Buffer
+	b.WriteByte('{')
+	v := reflect.ValueOf(i)
+	for k := 0; k < v.NumField(); k++ {
+		f := v.Type().Field(k)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if !f.IsExported() || name == "-" || (name != "key" && !slices.Contains(i.selected, name)) {
+			continue
+		}
+		if err := writeField(&b, name, v.Field(k).Interface()); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range i.customFieldOrder() {
+		if !slices.Contains(i.selected, name) {
+			continue
+		}
+		if err := writeField(&b, name, i.CustomFields[name]); err != nil {
+			return nil, err
+		}
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// customFieldOrder are the names of the custom fields in the order they are configured, sorted if unknown
+func (i Issue) customFieldOrder() []string {
+	if i.customFieldNames != nil {
+		return i.customFieldNames
+	}
+	names := make([]string, 0, len(i.CustomFields))
+	for name := range i.CustomFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// writeField writes a property of a JSON object, separated by a comma from a previous one
+func writeField(b *bytes.Buffer, name string, value any) error {
+	if b.Len() > 1 {
+		b.WriteByte(',')
+	}
+	raw, err := json.Marshal(name)
+	if err != nil {
+		return err
+	}
+	b.Write(raw)
+	b.WriteByte(':')
+	raw, err = json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	b.Write(raw)
+	return nil
+}
@@ -0,0 +1,160 @@
+package jira
+
+import (
+	"fmt"
+	"strings"
+
+	gojira "gopkg.in/andygrunwald/go-jira.v1"
+)
+
+// IssueFields are the fields to create or update an issue with, empty fields are left as they are
+type IssueFields struct {
+	// Project and Type are the key of the project and the name of the issue type, they are required to create
+	// an issue and cannot be updated
+	Project string `json:"project,omitempty"`
+	Type    string `json:"type,omitempty"`
+
+	Summary     string   `json:"summary,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Labels      []string `json:"labels,omitempty"`
+	FixVersions []string `json:"fixVersions,omitempty"`
+
+	// CustomFields are the values of custom fields by their configured names or IDs, the values are passed as
+	// Jira expects them, e.g. {"squad": {"value": "Platform"}, "storyPoints": 3}
+	CustomFields map[string]any `json:"customFields,omitempty"`
+}
+
+// CreateIssue creates an issue and returns its key
+func (j *JiraService) CreateIssue(f *IssueFields) (string, error) {
+	if f.Project == "" || f.Type == "" || f.Summary == "" {
+		return "", fmt.Errorf("an issue needs a project, a type and a summary")
+	}
+
+	fields, err := j.jiraIssueFields(f)
+	if err != nil {
+		return "", err
+	}
+	fields["project"] = map[string]any{"key": f.Project}
+	fields["issuetype"] = map[string]any{"name": f.Type}
+
+	req, err := j.client.NewRequest("POST", "rest/api/2/issue", map[string]any{"fields": fields})
+	if err != nil {
+		return "", err
+	}
+
+	var created struct {
+		Key string `json:"key"`
+	}
+	resp, err := j.client.Do(req, &created)
+	if err != nil {
+		return "", fmt.Errorf("cannot create issue: %w", gojira.NewJiraError(resp, err))
+	}
+	return created.Key, nil
+}
+
+// UpdateIssue sets the non-empty fields of an issue
+func (j *JiraService) UpdateIssue(key string, f *IssueFields) error {
+	if f.Project != "" || f.Type != "" {
+		return fmt.Errorf("cannot change the project or type of %s", key)
+	}
+
+	fields, err := j.jiraIssueFields(f)
+	if err != nil {
+		return err
+	}
+	if len(fields) == 0 {
+		return fmt.Errorf("no fields to update %s with", key)
+	}
+
+	resp, err := j.client.Issue.UpdateIssue(key, map[string]any{"fields": fields})
+	if err != nil {
+		return fmt.Errorf("cannot update %s: %w", key, gojira.NewJiraError(resp, err))
+	}
+	return nil
+}
+
+// TransitionIssue moves an issue to a status of its workflow, the status is matched by the name of the
+// transition or of its target status
+func (j *JiraService) TransitionIssue(key, status string) error {
+	transitions, _, err := j.client.Issue.GetTransitions(key)
+	if err != nil {
+		return fmt.Errorf("cannot get transitions of %s: %w", key, err)
+	}
+
+	var names []string
+	for _, t := range transitions {
+		if strings.EqualFold(t.To.Name, status) || strings.EqualFold(t.Name, status) {
+			// unlike UpdateIssue, go-jira reads the error of the response already
+			if _, err := j.client.Issue.DoTransition(key, t.ID); err != nil {
+				return fmt.Errorf("cannot transition %s to %q: %w", key, status, err)
+			}
+			return nil
+		}
+		names = append(names, t.To.Name)
+	}
+	return fmt.Errorf("cannot transition %s to %q, expected one of %s", key, status, strings.Join(names, ", "))
+}
+
+// CommentIssue adds a comment to an issue
+func (j *JiraService) CommentIssue(key, body string) error {
+	if body == "" {
+		return fmt.Errorf("no comment for %s", key)
+	}
+	if _, _, err := j.client.Issue.AddComment(key, &gojira.Comment{Body: body}); err != nil {
+		return fmt.Errorf("cannot comment on %s: %w", key, err)
+	}
+	return nil
+}
+
+// AssignIssue assigns an issue to the user of the accountId, an empty accountId unassigns it
+func (j *JiraService) AssignIssue(key, accountId string) error {
+	var assignee struct {
+		AccountId *string `json:"accountId"`
+	}
+	if accountId != "" {
+		assignee.AccountId = &accountId
+	}
+
+	req, err := j.client.NewRequest("PUT", fmt.Sprintf("rest/api/2/issue/%s/assignee", key), &assignee)
+	if err != nil {
+		return err
+	}
+	resp, err := j.client.Do(req, nil)
+	if err != nil {
+		return fmt.Errorf("cannot assign %s: %w", key, gojira.NewJiraError(resp, err))
+	}
+	return nil
+}
+
+// jiraIssueFields maps the fields to the ones of the Jira API
+func (j *JiraService) jiraIssueFields(f *IssueFields) (map[string]any, error) {
+	fields := map[string]any{}
+	if f.Summary != "" {
+		fields["summary"] = f.Summary
+	}
+	if f.Description != "" {
+		fields["description"] = f.Description
+	}
+	if f.Labels != nil {
+		fields["labels"] = f.Labels
+	}
+	if f.FixVersions != nil {
+		versions := make([]map[string]any, len(f.FixVersions))
+		for i, v := range f.FixVersions {
+			versions[i] = map[string]any{"name": v}
+		}
+		fields["fixVersions"] = versions
+	}
+
+	for name, value := range f.CustomFields {
+		id, ok := j.customFields[name]
+		if !ok && !strings.HasPrefix(name, "customfield_") {
+			return nil, fmt.Errorf("unknown custom field %q", name)
+		}
+		if !ok {
+			id = name
+		}
+		fields[id] = value
+	}
+	return fields, nil
+}
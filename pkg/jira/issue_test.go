@@ -0,0 +1,159 @@
+package jira
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// request is a request received by the recorder
+type request struct {
+	method string
+	path   string
+	body   map[string]any
+}
+
+// newRecordingService returns a service whose requests are recorded and answered with the responses by method
+// and path or by path only, a status code is answered with an error
+func newRecordingService(t *testing.T, responses map[string]any) (*JiraService, *[]request) {
+	var requests []request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := request{method: r.Method, path: r.URL.Path}
+		json.NewDecoder(r.Body).Decode(&req.body)
+		requests = append(requests, req)
+
+		response, ok := responses[r.Method+" "+r.URL.Path]
+		if !ok {
+			response, ok = responses[r.URL.Path]
+		}
+		if !ok {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if status, ok := response.(int); ok {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			w.Write([]byte(`{"errorMessages": ["not allowed"]}`))
+			return
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(server.Close)
+
+	svc, err := NewJiraService(server.URL, "octo@example.com", "s0meT0kn")
+	require.NoError(t, err)
+	svc.customFields = map[string]string{"storyPoints": "customfield_10004"}
+	return svc, &requests
+}
+
+func TestJiraService_CreateIssue(t *testing.T) {
+	svc, requests := newRecordingService(t, map[string]any{"/rest/api/2/issue": map[string]any{"id": "1", "key": "ARC-7"}})
+
+	key, err := svc.CreateIssue(&IssueFields{
+		Project:      "ARC",
+		Type:         "Story",
+		Summary:      "Release 1.2",
+		Labels:       []string{"release"},
+		FixVersions:  []string{"1.2"},
+		CustomFields: map[string]any{"storyPoints": 3, "customfield_10951": map[string]any{"value": "Platform"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "ARC-7", key)
+
+	require.Len(t, *requests, 1)
+	assert.Equal(t, "POST", (*requests)[0].method)
+	assert.Equal(t, map[string]any{"fields": map[string]any{
+		"project":           map[string]any{"key": "ARC"},
+		"issuetype":         map[string]any{"name": "Story"},
+		"summary":           "Release 1.2",
+		"labels":            []any{"release"},
+		"fixVersions":       []any{map[string]any{"name": "1.2"}},
+		"customfield_10004": 3.0,
+		"customfield_10951": map[string]any{"value": "Platform"},
+	}}, (*requests)[0].body)
+
+	_, err = svc.CreateIssue(&IssueFields{Project: "ARC", Summary: "no type"})
+	assert.ErrorContains(t, err, "needs a project, a type and a summary")
+
+	_, err = svc.CreateIssue(&IssueFields{Project: "ARC", Type: "Story", Summary: "x", CustomFields: map[string]any{"squad": "Platform"}})
+	assert.ErrorContains(t, err, `unknown custom field "squad"`)
+}
+
+func TestJiraService_UpdateIssue(t *testing.T) {
+	svc, requests := newRecordingService(t, map[string]any{"/rest/api/2/issue/ARC-8": http.StatusForbidden})
+
+	require.NoError(t, svc.UpdateIssue("ARC-7", &IssueFields{FixVersions: []string{"1.3"}}))
+	require.Len(t, *requests, 1)
+	assert.Equal(t, "PUT", (*requests)[0].method)
+	assert.Equal(t, "/rest/api/2/issue/ARC-7", (*requests)[0].path)
+	assert.Equal(t, map[string]any{"fields": map[string]any{"fixVersions": []any{map[string]any{"name": "1.3"}}}}, (*requests)[0].body)
+
+	err := svc.UpdateIssue("ARC-8", &IssueFields{Summary: "x"})
+	assert.ErrorContains(t, err, "cannot update ARC-8")
+
+	assert.ErrorContains(t, svc.UpdateIssue("ARC-7", &IssueFields{}), "no fields")
+	assert.ErrorContains(t, svc.UpdateIssue("ARC-7", &IssueFields{Project: "OPS"}), "cannot change the project")
+}
+
+func TestJiraService_TransitionIssue(t *testing.T) {
+	svc, requests := newRecordingService(t, map[string]any{"/rest/api/2/issue/ARC-7/transitions": map[string]any{
+		"transitions": []map[string]any{
+			{"id": "11", "name": "Start", "to": map[string]any{"name": "In Progress"}},
+			{"id": "31", "name": "Finish", "to": map[string]any{"name": "Done"}},
+		},
+	}})
+
+	require.NoError(t, svc.TransitionIssue("ARC-7", "done"))
+	require.Len(t, *requests, 2)
+	assert.Equal(t, "POST", (*requests)[1].method)
+	assert.Equal(t, map[string]any{"id": "31"}, (*requests)[1].body["transition"])
+
+	require.NoError(t, svc.TransitionIssue("ARC-7", "Start"))
+	assert.Equal(t, map[string]any{"id": "11"}, (*requests)[3].body["transition"])
+
+	err := svc.TransitionIssue("ARC-7", "Closed")
+	assert.ErrorContains(t, err, `cannot transition ARC-7 to "Closed", expected one of In Progress, Done`)
+}
+
+func TestJiraService_CommentAndAssignIssue(t *testing.T) {
+	svc, requests := newRecordingService(t, map[string]any{"/rest/api/2/issue/ARC-7/comment": map[string]any{"id": "1"}})
+
+	require.NoError(t, svc.CommentIssue("ARC-7", "released in 1.2"))
+	require.NoError(t, svc.AssignIssue("ARC-7", "5b10a2844c20165700ede21g"))
+	require.NoError(t, svc.AssignIssue("ARC-7", ""))
+
+	require.Len(t, *requests, 3)
+	assert.Equal(t, "released in 1.2", (*requests)[0].body["body"])
+	assert.Equal(t, "/rest/api/2/issue/ARC-7/assignee", (*requests)[1].path)
+	assert.Equal(t, map[string]any{"accountId": "5b10a2844c20165700ede21g"}, (*requests)[1].body)
+	assert.Equal(t, map[string]any{"accountId": nil}, (*requests)[2].body)
+
+	assert.ErrorContains(t, svc.CommentIssue("ARC-7", ""), "no comment")
+}
+
+func TestJiraService_TransitionAndCommentIssue_Errors(t *testing.T) {
+	svc, _ := newRecordingService(t, map[string]any{
+		"GET /rest/api/2/issue/ARC-7/transitions": map[string]any{
+			"transitions": []map[string]any{{"id": "31", "name": "Finish", "to": map[string]any{"name": "Done"}}},
+		},
+		"POST /rest/api/2/issue/ARC-7/transitions": http.StatusBadRequest,
+		"/rest/api/2/issue/ARC-7/comment":          http.StatusForbidden,
+		"/rest/api/2/issue/ARC-7/assignee":         http.StatusNotFound,
+	})
+
+	// the message of Jira is reported rather than a failure to read it
+	err := svc.TransitionIssue("ARC-7", "Done")
+	assert.ErrorContains(t, err, `cannot transition ARC-7 to "Done": not allowed`)
+	assert.NotContains(t, err.Error(), "closed")
+
+	err = svc.CommentIssue("ARC-7", "released")
+	assert.ErrorContains(t, err, "cannot comment on ARC-7: not allowed")
+	assert.NotContains(t, err.Error(), "closed")
+
+	err = svc.AssignIssue("ARC-7", "")
+	assert.ErrorContains(t, err, "cannot assign ARC-7: not allowed")
+}
@@ -1,6 +1,7 @@
 package jira
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/trichner/toolbox/pkg/jira/credentials"
@@ -8,18 +9,41 @@ import (
 )
 
 type Issue struct {
-	Key         string       `json:"key"`
-	Summary     string       `json:"summary"`
-	Type        string       `json:"type"`
-	Assignee    *string      `json:"assignee"`
-	Squad       *string      `json:"squad"`
-	FixVersion  *string      `json:"fixVersion"`
-	Status      *string      `json:"status"`
-	Labels      []string     `json:"labels"`
-	StoryPoints *int         `json:"storyPoints"`
-	IssueLinks  []*IssueLink `json:"issueLinks"`
+	Key        string       `json:"key"`
+	Summary    string       `json:"summary"`
+	Type       string       `json:"type"`
+	Assignee   *string      `json:"assignee"`
+	FixVersion *string      `json:"fixVersion"`
+	Status     *string      `json:"status"`
+	Labels     []string     `json:"labels"`
+	IssueLinks []*IssueLink `json:"issueLinks"`
+
+	// CustomFields are the values of the configured custom fields by their names, see
+	// JiraService.SetCustomFields
+	CustomFields map[string]any `json:"-"`
+
+	// selected are the fields the issue was searched for
+	selected []string
+
+	// customFieldNames are the names of the custom fields in the order they are configured
+	customFieldNames []string
 }
 
+// SearchOptions select the fields and the number of the issues to search for
+type SearchOptions struct {
+	// Fields are the names of the Issue fields or the configured custom fields, all if empty
+	Fields []string
+
+	// MaxResults caps the number of issues, all are returned if it is zero
+	MaxResults int
+}
+
+// searchPageSize is the maximum number of issues Jira returns per request
+const searchPageSize = 100
+
+// errSearchDone stops searching for more pages
+var errSearchDone = errors.New("search done")
+
 type Version struct {
 	Name        string `json:"name"`
 	ReleaseDate string `json:"releaseDate"`
@@ -43,6 +67,10 @@ type IssueLink struct {
 
 type JiraService struct {
 	client *gojira.Client
+
+	// customFields are the IDs of the custom fields by their names, customFieldNames their names in order
+	customFields     map[string]string
+	customFieldNames []string
 }
 
 func NewJiraServiceWithDefaultCredentials(baseUrl string) (*JiraService, error) {
@@ -95,7 +123,7 @@ func (j *JiraService) GetByKey(key string) (*Issue, error) {
 	if len(issues) == 0 {
 		return nil, nil
 	}
-	issueVos := mapJiraListToVoList(issues)
+	issueVos := j.mapJiraListToVoList(issues, nil)
 	if len(issueVos) == 1 {
 		return &issueVos[0], nil
 	}
@@ -119,11 +147,34 @@ func (j *JiraService) GetVersion(projectId, version string) (*Version, error) {
 }
 
 func (j *JiraService) SearchByQuery(query string) ([]Issue, error) {
-	issues, _, err := j.client.Issue.Search(query, nil)
+	return j.Search(query, SearchOptions{})
+}
+
+// Search finds the issues of a JQL query, it pages through the results until all or the maximum number of
+// issues are found
+func (j *JiraService) Search(query string, o SearchOptions) ([]Issue, error) {
+	fields, err := j.jiraFields(o.Fields)
 	if err != nil {
 		return nil, err
 	}
-	return mapJiraListToVoList(issues), nil
+
+	pageSize := searchPageSize
+	if o.MaxResults > 0 && o.MaxResults < pageSize {
+		pageSize = o.MaxResults
+	}
+
+	issues := []Issue{}
+	err = j.client.Issue.SearchPages(query, &gojira.SearchOptions{MaxResults: pageSize, Fields: fields}, func(issue gojira.Issue) error {
+		issues = append(issues, j.mapJiraToVo(issue, o.Fields))
+		if o.MaxResults > 0 && len(issues) >= o.MaxResults {
+			return errSearchDone
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errSearchDone) {
+		return nil, fmt.Errorf("cannot search issues: %w", err)
+	}
+	return issues, nil
 }
 
 func findVersion(version string, versions []gojira.Version) *gojira.Version {
@@ -143,7 +194,11 @@ func mapJiraVersion(v *gojira.Version) *Version {
 	}
 }
 
-func mapJiraToVo(issue gojira.Issue) Issue {
+func (j *JiraService) mapJiraToVo(issue gojira.Issue, selected []string) Issue {
+	if issue.Fields == nil {
+		return Issue{Key: issue.Key, selected: selected}
+	}
+
 	var fixVersion *string
 	if len(issue.Fields.FixVersions) > 0 {
 		fixVersion = &issue.Fields.FixVersions[0].Name
@@ -154,19 +209,18 @@ func mapJiraToVo(issue gojira.Issue) Issue {
 		status = &issue.Fields.Status.Name
 	}
 
-	squad := parseSquadField(issue.Fields)
-
 	return Issue{
-		Key:         issue.Key,
-		Summary:     issue.Fields.Summary,
-		Type:        issue.Fields.Type.Name,
-		FixVersion:  fixVersion,
-		Assignee:    mapAssignee(issue),
-		Squad:       squad,
-		Status:      status,
-		Labels:      issue.Fields.Labels,
-		StoryPoints: parseStoryPointsField(issue.Fields),
-		IssueLinks:  mapIssueLinks(issue.Fields.IssueLinks),
+		Key:              issue.Key,
+		Summary:          issue.Fields.Summary,
+		Type:             issue.Fields.Type.Name,
+		FixVersion:       fixVersion,
+		Assignee:         mapAssignee(issue),
+		Status:           status,
+		Labels:           issue.Fields.Labels,
+		IssueLinks:       mapIssueLinks(issue.Fields.IssueLinks),
+		CustomFields:     j.mapCustomFields(issue.Fields),
+		selected:         selected,
+		customFieldNames: j.customFieldNames,
 	}
 }
 
@@ -210,49 +264,22 @@ func mapAssignee(issue gojira.Issue) *string {
 	return &issue.Fields.Assignee.DisplayName
 }
 
-func parseSquadField(fields *gojira.IssueFields) *string {
-	customSquadField, ok := fields.Unknowns["customfield_10951"]
-	if !ok || customSquadField == nil {
+func (j *JiraService) mapCustomFields(fields *gojira.IssueFields) map[string]any {
+	if len(j.customFields) == 0 {
 		return nil
 	}
 
-	squadField, ok := customSquadField.(map[string]interface{})
-	if !ok || squadField == nil {
-		return nil
-	}
-
-	squadNameUntyped, ok := squadField["value"]
-	if !ok || squadNameUntyped == nil {
-		return nil
-	}
-
-	squadName, ok := squadNameUntyped.(string)
-	if !ok {
-		return nil
-	}
-
-	return &squadName
-}
-
-func parseStoryPointsField(fields *gojira.IssueFields) *int {
-	customStoryPointField, ok := fields.Unknowns["customfield_10004"]
-
-	if !ok || customStoryPointField == nil {
-		return nil
-	}
-
-	floatStoryPointField, ok := customStoryPointField.(float64)
-	if !ok {
-		return nil
+	values := make(map[string]any, len(j.customFields))
+	for name, id := range j.customFields {
+		values[name] = customFieldValue(fields.Unknowns[id])
 	}
-	intStoryPointField := int(floatStoryPointField)
-	return &intStoryPointField
+	return values
 }
 
-func mapJiraListToVoList(issues []gojira.Issue) []Issue {
+func (j *JiraService) mapJiraListToVoList(issues []gojira.Issue, selected []string) []Issue {
 	jiraIssues := []Issue{}
 	for _, issue := range issues {
-		jiraIssue := mapJiraToVo(issue)
+		jiraIssue := j.mapJiraToVo(issue, selected)
 		jiraIssues = append(jiraIssues, jiraIssue)
 	}
 	return jiraIssues
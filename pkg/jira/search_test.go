@@ -0,0 +1,148 @@
+package jira
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trichner/toolbox/pkg/jira/credentials"
+)
+
+// fakeJira serves a number of issues page by page and the fields of the issues
+type fakeJira struct {
+	issues   int
+	searches []string
+}
+
+func (f *fakeJira) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/rest/api/2/field":
+		json.NewEncoder(w).Encode([]map[string]any{
+			{"id": "summary", "name": "Summary"},
+			{"id": "customfield_10004", "name": "Story Points", "custom": true, "schema": map[string]any{"type": "number"}},
+			{"id": "customfield_10951", "name": "Squad", "custom": true, "schema": map[string]any{"type": "option"}},
+		})
+	case "/rest/api/2/search":
+		f.searches = append(f.searches, r.URL.RawQuery)
+		startAt, _ := strconv.Atoi(r.URL.Query().Get("startAt"))
+		maxResults, _ := strconv.Atoi(r.URL.Query().Get("maxResults"))
+
+		issues := []map[string]any{}
+		for i := startAt; i < f.issues && i < startAt+maxResults; i++ {
+			issues = append(issues, map[string]any{
+				"key": fmt.Sprintf("ARC-%d", i+1),
+				"fields": map[string]any{
+					"summary":           fmt.Sprintf("issue %d", i+1),
+					"issuetype":         map[string]any{"name": "Story"},
+					"status":            map[string]any{"name": "Done"},
+					"customfield_10004": 3.0,
+					"customfield_10951": map[string]any{"value": "Platform", "id": "10"},
+				},
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"issues":     issues,
+			"startAt":    startAt,
+			"maxResults": maxResults,
+			"total":      f.issues,
+		})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newFakeService(t *testing.T, issues int) (*JiraService, *fakeJira) {
+	fake := &fakeJira{issues: issues}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	svc, err := NewJiraService(server.URL, "octo@example.com", "s0meT0kn")
+	require.NoError(t, err)
+	return svc, fake
+}
+
+func TestJiraService_Search_Pages(t *testing.T) {
+	svc, fake := newFakeService(t, 230)
+
+	issues, err := svc.SearchByQuery("project = ARC")
+	require.NoError(t, err)
+	assert.Len(t, issues, 230)
+	assert.Equal(t, "ARC-230", issues[229].Key)
+	assert.Len(t, fake.searches, 3)
+
+	fake.searches = nil
+	issues, err = svc.Search("project = ARC", SearchOptions{MaxResults: 120})
+	require.NoError(t, err)
+	assert.Len(t, issues, 120)
+	assert.Len(t, fake.searches, 2)
+
+	fake.searches = nil
+	issues, err = svc.Search("project = ARC", SearchOptions{MaxResults: 10})
+	require.NoError(t, err)
+	assert.Len(t, issues, 10)
+	require.Len(t, fake.searches, 1)
+	assert.Contains(t, fake.searches[0], "maxResults=10")
+}
+
+func TestJiraService_Search_Fields(t *testing.T) {
+	svc, fake := newFakeService(t, 1)
+	require.NoError(t, svc.SetCustomFields(credentials.CustomFields{
+		{Name: "squad", Field: "customfield_10951"},
+		{Name: "storyPoints", Field: "Story Points"},
+	}))
+
+	issues, err := svc.Search("key = ARC-1", SearchOptions{Fields: []string{"summary", "storyPoints", "squad"}})
+	require.NoError(t, err)
+	require.Len(t, fake.searches, 1)
+	assert.Contains(t, fake.searches[0], "fields=summary,customfield_10004,customfield_10951")
+
+	out, err := json.Marshal(issues)
+	require.NoError(t, err)
+	// the fields of the struct first, then the custom fields in the order they are configured
+	assert.Equal(t, `[{"key":"ARC-1","summary":"issue 1","squad":"Platform","storyPoints":3}]`, string(out))
+
+	issues, err = svc.SearchByQuery("key = ARC-1")
+	require.NoError(t, err)
+	out, err = json.Marshal(issues[0])
+	require.NoError(t, err)
+	assert.Equal(t, `{"key":"ARC-1","summary":"issue 1","type":"Story","assignee":null,"fixVersion":null,`+
+		`"status":"Done","labels":null,"issueLinks":[],"squad":"Platform","storyPoints":3}`, string(out))
+
+	_, err = svc.Search("key = ARC-1", SearchOptions{Fields: []string{"estimate"}})
+	assert.ErrorContains(t, err, `unknown field "estimate"`)
+}
+
+func TestJiraService_SetCustomFields_Invalid(t *testing.T) {
+	svc, _ := newFakeService(t, 0)
+
+	err := svc.SetCustomFields(credentials.CustomFields{{Name: "status", Field: "customfield_10001"}})
+	assert.ErrorContains(t, err, "clashes")
+
+	err = svc.SetCustomFields(credentials.CustomFields{{Name: "team", Field: "Team"}})
+	assert.ErrorContains(t, err, `no field named "Team"`)
+
+	err = svc.SetCustomFields(credentials.CustomFields{{Name: "team", Field: "customfield_1"}, {Name: "team", Field: "customfield_2"}})
+	assert.ErrorContains(t, err, "configured twice")
+}
+
+func TestJiraService_Fields(t *testing.T) {
+	svc, _ := newFakeService(t, 0)
+
+	fields, err := svc.Fields()
+	require.NoError(t, err)
+	require.Len(t, fields, 3)
+	assert.Equal(t, Field{ID: "customfield_10004", Name: "Story Points", Custom: true, Type: "number"}, fields[0])
+}
+
+func TestCustomFieldValue(t *testing.T) {
+	assert.Equal(t, "Platform", customFieldValue(map[string]any{"value": "Platform", "id": "10"}))
+	assert.Equal(t, []any{"Jane Doe", "x"}, customFieldValue([]any{map[string]any{"displayName": "Jane Doe"}, "x"}))
+	assert.Equal(t, 3.0, customFieldValue(3.0))
+	assert.Nil(t, customFieldValue(nil))
+	assert.Equal(t, map[string]any{"other": 1.0}, customFieldValue(map[string]any{"other": 1.0}))
+}
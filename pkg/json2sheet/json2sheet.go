@@ -2,11 +2,14 @@ package json2sheet
 
 import (
 	"bufio"
-	"context"
-	"fmt"
+	"bytes"
 	"io"
 	"net/url"
 
+	"github.com/trichner/toolbox/pkg/jsontree"
+	"github.com/trichner/toolbox/pkg/jsontree/ast"
+	"github.com/trichner/toolbox/pkg/jsontree/jsonpath"
+	"github.com/trichner/toolbox/pkg/jsontree/lexer"
 	"github.com/trichner/toolbox/pkg/sheets"
 )
 
@@ -16,6 +19,40 @@ const (
 	streamTypeArrays
 )
 
+type options struct {
+	query   *jsonpath.Query
+	dialect lexer.Dialect
+}
+
+type Option func(o *options)
+
+// WithDialect accepts lenient JSON like JSONC or JSON5 rather than strict JSON
+func WithDialect(d lexer.Dialect) Option {
+	return func(o *options) {
+		o.dialect = d
+	}
+}
+
+// WithSelect maps the nodes selected by the query rather than the top-level values to rows, scalars become
+// rows with a single cell
+func WithSelect(q *jsonpath.Query) Option {
+	return func(o *options) {
+		o.query = q
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{dialect: lexer.DialectJSON}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+func (o *options) newLexer(r io.Reader) lexer.Lexer {
+	return lexer.NewLexer(r, lexer.WithDialect(o.dialect))
+}
+
 type SheetUpdater interface {
 	UpdateValues(data [][]string) error
 }
@@ -24,89 +61,161 @@ type SheetAppender interface {
 	AppendValues(data [][]string) error
 }
 
-func UpdateSheet(ctx context.Context, spreadsheetUrl string, r io.Reader) (*url.URL, error) {
+func UpdateSheet(svc sheets.SheetsService, ref *sheets.SheetRef, r io.Reader, opts ...Option) (*url.URL, error) {
+	sheet, err := sheets.OpenSheet(svc, ref)
+	if err != nil {
+		return nil, err
+	}
 
-	svc, err := sheets.NewSheetService(ctx)
+	err = WriteObjectsTo(sheet, r, opts...)
 	if err != nil {
 		return nil, err
 	}
 
-	spreadsheetID, sheetID, err := sheets.ParseSpreadsheetUrl(spreadsheetUrl)
+	return url.Parse(ref.URL())
+}
+
+func WriteToNewSheet(svc sheets.SheetsService, r io.Reader, opts ...Option) (*url.URL, error) {
+	ss, err := svc.CreateSpreadSheet("json2sheet")
 	if err != nil {
 		return nil, err
 	}
 
-	ss, err := svc.GetSpreadSheet(spreadsheetID)
+	sheet, err := ss.FirstSheet()
 	if err != nil {
 		return nil, err
 	}
 
-	sheet, err := ss.SheetById(sheetID)
+	// using append makes chunking easier and auto-extends the range
+	err = AppendTo(sheet, r, opts...)
 	if err != nil {
 		return nil, err
 	}
 
-	err = WriteObjectsTo(sheet, r)
+	info, err := ss.Get()
 	if err != nil {
 		return nil, err
 	}
 
-	return url.Parse(spreadsheetUrl)
+	ref := &sheets.SheetRef{SpreadsheetId: info.Id, SheetId: sheets.NoSheetId}
+	if len(info.Sheets) > 0 {
+		ref.SheetId = info.Sheets[0].Id
+	}
+	return url.Parse(ref.URL())
 }
 
-func WriteToNewSheet(ctx context.Context, r io.Reader) (*url.URL, error) {
-
-	svc, err := sheets.NewSheetService(ctx)
+// WriteTo writes a stream of either JSON objects or JSON arrays to the sheet, objects are mapped to rows
+// with a header row
+func WriteTo(to SheetUpdater, r io.Reader, opts ...Option) error {
+	rows, _, err := mapToRows(r, newOptions(opts), nil)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	return to.UpdateValues(rows)
+}
 
-	ss, err := svc.CreateSpreadSheet("json2sheet")
+// AppendTo appends a stream of either JSON objects or JSON arrays to the sheet, objects are mapped to rows
+// with a header row. Objects appended to a sheet with values already are mapped onto its first row instead,
+// new properties extend it.
+func AppendTo(to SheetAppender, r io.Reader, opts ...Option) error {
+	existing, err := readHeader(to)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	sheet, err := ss.FirstSheet()
+	rows, objects, err := mapToRows(r, newOptions(opts), existing)
 	if err != nil {
-		return nil, err
+		return err
 	}
-
-	br := bufio.NewReader(r)
-
-	streamType := streamTypeUnknown
-	peek, err := br.Peek(2)
-	if err == nil {
-		streamType = guessJsonStreamType(peek)
+	if !objects {
+		return to.AppendValues(rows)
 	}
+	return appendObjectRows(to, rows, existing)
+}
 
-	if streamType == streamTypeArrays {
-		// using append makes chunking easier and auto-extends the range
-		err = AppendArraysTo(sheet, br)
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		err = AppendObjectsTo(sheet, br)
-		if err != nil {
-			return nil, err
+// mapToRows maps either objects or arrays to rows, guessed from the start of the input or, if selecting, from
+// the first selected node. The rows of objects start with a header row continuing the existing one.
+func mapToRows(r io.Reader, o *options, existing []string) ([][]string, bool, error) {
+	if o.query != nil {
+		nodes := &peekingReader{nodes: jsonpath.NewReader(o.query, jsontree.NewValueReader(o.newLexer(r)))}
+		first, err := nodes.Peek()
+		if err == nil && first.Type() == ast.NodeTypeObject {
+			rows, err := mapObjectsToRows(nodes, existing)
+			return rows, true, err
 		}
+		rows, err := mapArraysToRows(scalarRows{nodes})
+		return rows, false, err
 	}
 
-	info, err := ss.Get()
-	if err != nil {
-		return nil, err
+	br := bufio.NewReader(r)
+	if guessStreamType(br) == streamTypeArrays {
+		rows, err := mapArraysToRows(newArrayReader(br, o))
+		return rows, false, err
 	}
+	rows, err := mapObjectsToRows(newObjectReader(br, o), existing)
+	return rows, true, err
+}
+
+// peekSize bounds how far ahead the stream type is guessed, leading whitespace included
+const peekSize = 512
 
-	raw := fmt.Sprintf("https://docs.google.com/spreadsheets/d/%s/edit#gid=0", info.Id)
-	return url.Parse(raw)
+func guessStreamType(br *bufio.Reader) int {
+	peeked, _ := br.Peek(peekSize)
+	return guessJsonStreamType(peeked)
 }
 
+// guessJsonStreamType guesses the type of rows from the start of the input, a top-level array wrapping objects
+// is a stream of objects
 func guessJsonStreamType(peeked []byte) int {
-	if peeked[0] == '[' {
-		return streamTypeArrays
+	peeked = skipWhitespace(peeked)
+	if len(peeked) == 0 {
+		return streamTypeUnknown
 	}
 	if peeked[0] == '{' {
 		return streamTypeObjects
 	}
-	return streamTypeUnknown
+	if peeked[0] != '[' {
+		return streamTypeUnknown
+	}
+
+	next := skipWhitespace(peeked[1:])
+	if len(next) > 0 && next[0] == '{' {
+		return streamTypeObjects
+	}
+	return streamTypeArrays
+}
+
+// isWrappedArrays reports whether the input is a top-level array of arrays rather than a stream of arrays
+func isWrappedArrays(peeked []byte) bool {
+	peeked = skipWhitespace(peeked)
+	if len(peeked) == 0 || peeked[0] != '[' {
+		return false
+	}
+	next := skipWhitespace(peeked[1:])
+	return len(next) > 0 && next[0] == '['
+}
+
+// skipWhitespace skips whitespace as well as comments, which lenient dialects allow
+func skipWhitespace(b []byte) []byte {
+	for len(b) > 0 {
+		switch {
+		case b[0] == ' ' || b[0] == '\t' || b[0] == '\n' || b[0] == '\r':
+			b = b[1:]
+		case bytes.HasPrefix(b, []byte("//")):
+			end := bytes.IndexByte(b, '\n')
+			if end < 0 {
+				return nil
+			}
+			b = b[end+1:]
+		case bytes.HasPrefix(b, []byte("/*")):
+			end := bytes.Index(b[2:], []byte("*/"))
+			if end < 0 {
+				return nil
+			}
+			b = b[end+4:]
+		default:
+			return b
+		}
+	}
+	return b
 }
@@ -1,20 +1,100 @@
 package json2sheet
 
 import (
-	"fmt"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trichner/toolbox/pkg/sheets"
+	"github.com/trichner/toolbox/pkg/workbook"
 )
 
 func TestWriteToNewSheet(t *testing.T) {
+	svc, err := sheets.NewLocalSheetService(t.TempDir())
+	require.NoError(t, err)
+
 	buf := strings.NewReader(`
 	{"a":"hello","b":"world"}
 	{"b":2,"a":1,"c":3}
 	{"d":4,"a":1,"c":3}
 	`)
-	url, err := WriteToNewSheet(buf)
-	fmt.Println(url)
+	url, err := WriteToNewSheet(svc, buf)
 	assert.NoError(t, err)
+
+	ref, err := sheets.ParseSheetRef(url.String())
+	require.NoError(t, err)
+
+	sheet, err := sheets.OpenSheet(svc, ref)
+	require.NoError(t, err)
+
+	values, err := sheet.Values()
+	require.NoError(t, err)
+	assert.Equal(t, [][]any{
+		{"a", "b", "c", "d"},
+		{"hello", "world"},
+		{"1", "2", "3"},
+		{"1", "", "3", "4"},
+	}, values)
+}
+
+func TestUpdateSheet(t *testing.T) {
+	svc, err := sheets.NewLocalSheetService(t.TempDir())
+	require.NoError(t, err)
+
+	ss, err := svc.CreateSpreadSheet("test")
+	require.NoError(t, err)
+	info, err := ss.Get()
+	require.NoError(t, err)
+
+	ref := &sheets.SheetRef{SpreadsheetId: info.Id, SheetId: sheets.NoSheetId}
+	_, err = UpdateSheet(svc, ref, strings.NewReader(`{"a":true,"b":null}`))
+	require.NoError(t, err)
+
+	sheet, err := ss.FirstSheet()
+	require.NoError(t, err)
+	values, err := sheet.Values()
+	require.NoError(t, err)
+	assert.Equal(t, [][]any{{"a", "b"}, {"TRUE", ""}}, values)
+}
+
+func TestAppendTo_Twice(t *testing.T) {
+	wb, err := workbook.OpenOrCreate(filepath.Join(t.TempDir(), "test.xlsx"))
+	require.NoError(t, err)
+	defer wb.Close()
+	sheet, err := wb.FirstSheet()
+	require.NoError(t, err)
+
+	require.NoError(t, AppendTo(sheet, strings.NewReader(`{"a":1,"b":2}`)))
+	require.NoError(t, AppendTo(sheet, strings.NewReader(`{"b":3,"a":4}
+{"c":5,"a":6}`)))
+	require.NoError(t, AppendTo(sheet, strings.NewReader(`["x","y"]`)))
+
+	values, err := sheet.Values()
+	require.NoError(t, err)
+	assert.Equal(t, [][]any{
+		{"a", "b", "c"},
+		{"1", "2"},
+		{"4", "3"},
+		{"6", "", "5"},
+		{"x", "y"},
+	}, values)
+}
+
+func TestAppendTo_ExistingHeader(t *testing.T) {
+	svc, err := sheets.NewLocalSheetService(t.TempDir())
+	require.NoError(t, err)
+	ss, err := svc.CreateSpreadSheet("test")
+	require.NoError(t, err)
+	sheet, err := ss.FirstSheet()
+	require.NoError(t, err)
+
+	// a column without a name is kept
+	require.NoError(t, sheet.UpdateValues([][]string{{"id", "", "name"}, {"1", "note", "ada"}}))
+	require.NoError(t, AppendTo(sheet, strings.NewReader(`{"name":"alan","id":2}`)))
+
+	values, err := sheet.Values()
+	require.NoError(t, err)
+	assert.Equal(t, [][]any{{"id", "", "name"}, {"1", "note", "ada"}, {"2", "", "alan"}}, values)
 }
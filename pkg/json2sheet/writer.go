@@ -1,17 +1,21 @@
 package json2sheet
 
 import (
+	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"slices"
 
 	"github.com/trichner/toolbox/pkg/jsontree"
 	"github.com/trichner/toolbox/pkg/jsontree/ast"
-	"github.com/trichner/toolbox/pkg/jsontree/lexer"
+	"github.com/trichner/toolbox/pkg/jsontree/jsonpath"
+	"github.com/trichner/toolbox/pkg/sheets"
 )
 
-func WriteArraysTo(to SheetUpdater, from io.Reader) error {
-	rows, err := mapArraysToRows(from)
+func WriteArraysTo(to SheetUpdater, from io.Reader, opts ...Option) error {
+	rows, err := mapArraysToRows(newArrayReader(from, newOptions(opts)))
 	if err != nil {
 		return err
 	}
@@ -19,8 +23,8 @@ func WriteArraysTo(to SheetUpdater, from io.Reader) error {
 	return to.UpdateValues(rows)
 }
 
-func WriteObjectsTo(to SheetUpdater, from io.Reader) error {
-	rows, err := mapObjectsToRows(from)
+func WriteObjectsTo(to SheetUpdater, from io.Reader, opts ...Option) error {
+	rows, err := mapObjectsToRows(newObjectReader(from, newOptions(opts)), nil)
 	if err != nil {
 		return err
 	}
@@ -28,8 +32,8 @@ func WriteObjectsTo(to SheetUpdater, from io.Reader) error {
 	return to.UpdateValues(rows)
 }
 
-func AppendArraysTo(to SheetAppender, from io.Reader) error {
-	rows, err := mapArraysToRows(from)
+func AppendArraysTo(to SheetAppender, from io.Reader, opts ...Option) error {
+	rows, err := mapArraysToRows(newArrayReader(from, newOptions(opts)))
 	if err != nil {
 		return err
 	}
@@ -37,27 +41,79 @@ func AppendArraysTo(to SheetAppender, from io.Reader) error {
 	return to.AppendValues(rows)
 }
 
-func AppendObjectsTo(to SheetAppender, from io.Reader) error {
-	rows, err := mapObjectsToRows(from)
+func AppendObjectsTo(to SheetAppender, from io.Reader, opts ...Option) error {
+	existing, err := readHeader(to)
 	if err != nil {
 		return err
 	}
 
-	return to.AppendValues(rows)
+	rows, err := mapObjectsToRows(newObjectReader(from, newOptions(opts)), existing)
+	if err != nil {
+		return err
+	}
+
+	return appendObjectRows(to, rows, existing)
 }
 
-func mapObjectsToRows(from io.Reader) ([][]string, error) {
-	var rows [][]string
+// sheetReadUpdater is a sheet whose header row can be read and updated, e.g. to append objects below it
+type sheetReadUpdater interface {
+	SheetUpdater
+	HeaderValues() ([]any, error)
+}
+
+// readHeader returns the first row of a sheet, it is nil if the sheet is empty or cannot be read. Only the first
+// row is read rather than all values of the sheet.
+func readHeader(to SheetAppender) ([]string, error) {
+	sheet, ok := to.(sheetReadUpdater)
+	if !ok {
+		return nil, nil
+	}
+	values, err := sheet.HeaderValues()
+	if errors.Is(err, sheets.ErrEmptySheet) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	header := make([]string, len(values))
+	for i, v := range values {
+		header[i] = fmt.Sprint(v)
+	}
+	return header, nil
+}
+
+// appendObjectRows appends the rows of objects, below the existing header row if there is one. The header is
+// updated if the objects have new properties.
+func appendObjectRows(to SheetAppender, rows [][]string, existing []string) error {
+	if existing == nil {
+		return to.AppendValues(rows)
+	}
+
+	if len(rows[0]) > len(existing) {
+		// only a sheet with a readable header has an existing one
+		if err := to.(sheetReadUpdater).UpdateValues(rows[:1]); err != nil {
+			return err
+		}
+	}
+	return to.AppendValues(rows[1:])
+}
 
-	l := lexer.NewLexer(from)
+// mapObjectsToRows maps objects to a header row followed by their rows, the header continues the existing one
+// of a sheet, if any
+func mapObjectsToRows(nodes jsontree.NodeReader, existing []string) ([][]string, error) {
+	var rows [][]string
 
 	// write empty header row for a start
 	rows = append(rows, []string{})
 
-	headers := map[string]int{}
+	headers := newHeader(existing)
 
 	for {
-		root, err := jsontree.Parse(l)
+		root, err := nodes.Next()
 		if err == io.EOF {
 			break
 		}
@@ -66,26 +122,25 @@ func mapObjectsToRows(from io.Reader) ([][]string, error) {
 		}
 
 		if root.Type() != ast.NodeTypeObject {
-			return nil, fmt.Errorf("json is not an object: %s", root.Type())
+			return nil, fmt.Errorf("json %sis not an object: %s", describeLocation(root), root.Type())
 		}
 
 		node := root.(ast.ObjectNode)
 
-		headers = appendToHeaderMap(node, headers)
+		headers.add(node)
 
-		row := toRow(node, headers)
+		row := headers.toRow(node)
 		rows = append(rows, row)
 	}
 
-	rows[0] = headersToRow(headers)
+	rows[0] = slices.Clone(headers.names)
 	return rows, nil
 }
 
-func mapArraysToRows(from io.Reader) ([][]string, error) {
+func mapArraysToRows(nodes jsontree.NodeReader) ([][]string, error) {
 	var rows [][]string
-	l := lexer.NewLexer(from)
 	for {
-		root, err := jsontree.Parse(l)
+		root, err := nodes.Next()
 		if err == io.EOF {
 			break
 		}
@@ -94,7 +149,7 @@ func mapArraysToRows(from io.Reader) ([][]string, error) {
 		}
 
 		if root.Type() != ast.NodeTypeArray {
-			return nil, fmt.Errorf("json object is not an array: %s", root.Type())
+			return nil, fmt.Errorf("json %sis not an array: %s", describeLocation(root), root.Type())
 		}
 
 		node := root.(ast.ArrayNode)
@@ -107,28 +162,107 @@ func mapArraysToRows(from io.Reader) ([][]string, error) {
 	return rows, nil
 }
 
-func headersToRow(headers map[string]int) []string {
-	row := make([]string, len(headers))
-	for k, v := range headers {
-		row[v] = k
+// newObjectReader reads objects either streamed or wrapped in a top-level array
+func newObjectReader(from io.Reader, o *options) jsontree.NodeReader {
+	if o.query != nil {
+		return jsonpath.NewReader(o.query, jsontree.NewValueReader(o.newLexer(from)))
 	}
-	return row
+	return jsontree.NewItemReader(o.newLexer(from))
+}
+
+// newArrayReader reads rows either from a stream of arrays or from a top-level array of arrays
+func newArrayReader(from io.Reader, o *options) jsontree.NodeReader {
+	if o.query != nil {
+		return scalarRows{jsonpath.NewReader(o.query, jsontree.NewValueReader(o.newLexer(from)))}
+	}
+
+	br := bufio.NewReader(from)
+	peeked, _ := br.Peek(peekSize)
+	if isWrappedArrays(peeked) {
+		return jsontree.NewItemReader(o.newLexer(br))
+	}
+	return jsontree.NewValueReader(o.newLexer(br))
+}
+
+// scalarRows turns scalars into rows with a single cell
+type scalarRows struct {
+	nodes jsontree.NodeReader
+}
+
+func (r scalarRows) Next() (ast.Node, error) {
+	n, err := r.nodes.Next()
+	if err != nil {
+		return nil, err
+	}
+	switch n.Type() {
+	case ast.NodeTypeArray, ast.NodeTypeObject:
+		return n, nil
+	}
+	return ast.NewArrayNode([]ast.Node{n}), nil
+}
+
+// peekingReader allows to look at the first node before deciding how to map the nodes
+type peekingReader struct {
+	nodes  jsontree.NodeReader
+	peeked bool
+	node   ast.Node
+	err    error
+}
+
+func (r *peekingReader) Peek() (ast.Node, error) {
+	if !r.peeked {
+		r.node, r.err = r.nodes.Next()
+		r.peeked = true
+	}
+	return r.node, r.err
+}
+
+func (r *peekingReader) Next() (ast.Node, error) {
+	if r.peeked {
+		r.peeked = false
+		return r.node, r.err
+	}
+	return r.nodes.Next()
+}
+
+func describeLocation(n ast.Node) string {
+	span, ok := ast.SpanOf(n)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("at %s ", span.Start)
+}
+
+// header maps the names of properties to their columns, unnamed columns of an existing header are kept
+type header struct {
+	columns map[string]int
+	names   []string
+}
+
+func newHeader(existing []string) *header {
+	h := &header{columns: map[string]int{}, names: slices.Clone(existing)}
+	for i, name := range existing {
+		if _, ok := h.columns[name]; !ok && name != "" {
+			h.columns[name] = i
+		}
+	}
+	return h
 }
 
-func appendToHeaderMap(root ast.ObjectNode, headers map[string]int) map[string]int {
+func (h *header) add(root ast.ObjectNode) {
 	for _, v := range root.Properties() {
-		_, ok := headers[v.Name]
+		_, ok := h.columns[v.Name]
 		if !ok {
-			headers[v.Name] = len(headers)
+			h.columns[v.Name] = len(h.names)
+			h.names = append(h.names, v.Name)
 		}
 	}
-	return headers
 }
 
-func toRow(root ast.ObjectNode, headers map[string]int) []string {
-	row := make([]string, len(headers))
+func (h *header) toRow(root ast.ObjectNode) []string {
+	row := make([]string, len(h.names))
 	for _, v := range root.Properties() {
-		idx, ok := headers[v.Name]
+		idx, ok := h.columns[v.Name]
 		if !ok {
 			panic(fmt.Errorf("unexpected header name: %s", v.Name))
 		}
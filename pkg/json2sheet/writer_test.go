@@ -5,6 +5,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/trichner/toolbox/pkg/jsontree/jsonpath"
+	"github.com/trichner/toolbox/pkg/jsontree/lexer"
 )
 
 type mockSheetWriter struct {
@@ -44,3 +46,78 @@ func TestWriteObjectsTo(t *testing.T) {
 	assert.Equal(t, 4, len(rows))
 	assert.Equal(t, 4, len(rows[0]))
 }
+
+func TestWriteArraysTo_Unescapes(t *testing.T) {
+	src := `["café", "line\nbreak", "\"quoted\""]`
+	m := &mockSheetWriter{}
+	assert.NoError(t, WriteArraysTo(m, strings.NewReader(src)))
+
+	assert.Equal(t, [][]string{{"café", "line\nbreak", `"quoted"`}}, m.invocations[0])
+}
+
+func TestWriteObjectsTo_TopLevelArray(t *testing.T) {
+	src := `[
+		{"a":"hello","b":"world"},
+		{"b":2,"a":1,"c":3}
+	]`
+	m := &mockSheetWriter{}
+	assert.NoError(t, WriteObjectsTo(m, strings.NewReader(src)))
+
+	assert.Equal(t, [][]string{
+		{"a", "b", "c"},
+		{"hello", "world"},
+		{"1", "2", "3"},
+	}, m.invocations[0])
+}
+
+func TestWriteArraysTo_TopLevelArray(t *testing.T) {
+	src := ` [["a", "b"], [1, [2]]]`
+	m := &mockSheetWriter{}
+	assert.NoError(t, WriteArraysTo(m, strings.NewReader(src)))
+
+	assert.Equal(t, [][]string{{"a", "b"}, {"1", "[2]"}}, m.invocations[0])
+}
+
+func TestGuessJsonStreamType(t *testing.T) {
+	assert.Equal(t, streamTypeObjects, guessJsonStreamType([]byte(`{"a":1}`)))
+	assert.Equal(t, streamTypeObjects, guessJsonStreamType([]byte("\n [\n\t{")))
+	assert.Equal(t, streamTypeArrays, guessJsonStreamType([]byte(`[1, 2]`)))
+	assert.Equal(t, streamTypeArrays, guessJsonStreamType([]byte(`[`)))
+	assert.Equal(t, streamTypeUnknown, guessJsonStreamType([]byte(`  `)))
+	assert.Equal(t, streamTypeUnknown, guessJsonStreamType(nil))
+	assert.Equal(t, streamTypeObjects, guessJsonStreamType([]byte("// c\n[ /* c */ {")))
+	assert.Equal(t, streamTypeUnknown, guessJsonStreamType([]byte("/* open")))
+}
+
+func TestWriteTo_Select(t *testing.T) {
+	src := `{"data": {"items": [{"id": 1, "tags": ["a"]}, {"id": 2, "name": "two"}]}}
+	{"data": {"items": [{"id": 3}]}}`
+
+	m := &mockSheetWriter{}
+	assert.NoError(t, WriteTo(m, strings.NewReader(src), WithSelect(jsonpath.MustCompile(`$.data.items[*]`))))
+	assert.Equal(t, [][]string{
+		{"id", "tags", "name"},
+		{"1", `["a"]`},
+		{"2", "", "two"},
+		{"3", "", ""},
+	}, m.invocations[0])
+
+	m = &mockSheetWriter{}
+	assert.NoError(t, WriteTo(m, strings.NewReader(src), WithSelect(jsonpath.MustCompile(`$..id`))))
+	assert.Equal(t, [][]string{{"1"}, {"2"}, {"3"}}, m.invocations[0])
+}
+
+func TestWriteTo_Dialect(t *testing.T) {
+	src := `// exported with comments
+	[
+		[1, 'two',], // trailing commas
+		[3, 0x10],
+	]`
+
+	m := &mockSheetWriter{}
+	assert.NoError(t, WriteTo(m, strings.NewReader(src), WithDialect(lexer.DialectJSON5)))
+	assert.Equal(t, [][]string{{"1", "two"}, {"3", "16"}}, m.invocations[0])
+
+	m = &mockSheetWriter{}
+	assert.Error(t, WriteTo(m, strings.NewReader(src)))
+}
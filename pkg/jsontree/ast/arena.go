@@ -0,0 +1,133 @@
+package ast
+
+const (
+	minBlockSize = 16
+	maxBlockSize = 512
+)
+
+// Arena allocates nodes in blocks rather than one at a time, which takes most of the load off the garbage
+// collector when parsing many values. Nodes are used like any other node, but a block is only freed once none
+// of its nodes is referenced anymore. Retaining a single node of a large document hence retains a good part of
+// the memory of the whole document, deep copy nodes to be kept for long. An Arena is not safe for concurrent use.
+type Arena struct {
+	nulls      block[nullNode]
+	booleans   block[boolValueNode]
+	texts      block[textNode]
+	numbers    block[numberValueNode]
+	arrays     block[arrayNode]
+	objects    block[objectNode]
+	spans      block[Span]
+	properties block[Property]
+
+	items        block[Node]
+	propertyRefs block[*Property]
+}
+
+func NewArena() *Arena {
+	return &Arena{}
+}
+
+// NewNull allocates a null node, a zero span means it has none like any other node of the arena
+func (a *Arena) NewNull(span Span) NullNode {
+	n := a.nulls.alloc()
+	n.node = a.node(NodeTypeNull, span)
+	return n
+}
+
+func (a *Arena) NewBoolean(value bool, span Span) BooleanNode {
+	n := a.booleans.alloc()
+	n.node = a.node(NodeTypeBoolean, span)
+	n.value = value
+	return n
+}
+
+func (a *Arena) NewText(value string, span Span) TextNode {
+	n := a.texts.alloc()
+	n.node = a.node(NodeTypeText, span)
+	n.value = value
+	return n
+}
+
+func (a *Arena) NewNumber(value string, span Span) NumberNode {
+	n := a.numbers.alloc()
+	n.node = a.node(NodeTypeNumber, span)
+	n.value = value
+	return n
+}
+
+// NewArray allocates an array node holding a copy of items, the caller may reuse the slice
+func (a *Arena) NewArray(items []Node, span Span) ArrayNode {
+	n := a.arrays.alloc()
+	n.node = a.node(NodeTypeArray, span)
+	n.items = a.items.copy(items)
+	return n
+}
+
+// NewObject allocates an object node holding a copy of properties, the caller may reuse the slice
+func (a *Arena) NewObject(properties []Property, span Span) ObjectNode {
+	n := a.objects.alloc()
+	n.node = a.node(NodeTypeObject, span)
+	if len(properties) > 0 {
+		refs := a.propertyRefs.slice(len(properties))
+		for i := range properties {
+			p := a.properties.alloc()
+			*p = properties[i]
+			refs[i] = p
+		}
+		n.properties = refs
+	}
+	return n
+}
+
+func (a *Arena) node(nodeType NodeType, span Span) node {
+	n := node{nodeType: nodeType}
+	if span != (Span{}) {
+		n.span = a.spans.alloc()
+		*n.span = span
+	}
+	return n
+}
+
+// block hands out values of a slab, slabs double in size up to maxBlockSize
+type block[T any] struct {
+	free []T
+	size int
+}
+
+func (b *block[T]) alloc() *T {
+	if len(b.free) == 0 {
+		b.grow(1)
+	}
+	v := &b.free[0]
+	b.free = b.free[1:]
+	return v
+}
+
+// slice returns n consecutive values, its capacity is capped so appending to it never touches other values
+func (b *block[T]) slice(n int) []T {
+	if n == 0 {
+		return nil
+	}
+	if len(b.free) < n {
+		b.grow(n)
+	}
+	s := b.free[:n:n]
+	b.free = b.free[n:]
+	return s
+}
+
+func (b *block[T]) copy(src []T) []T {
+	s := b.slice(len(src))
+	copy(s, src)
+	return s
+}
+
+func (b *block[T]) grow(n int) {
+	b.size = min(max(b.size*2, minBlockSize), maxBlockSize)
+	if n > b.size {
+		// large slices get a slab of their own
+		b.free = make([]T, n)
+		return
+	}
+	b.free = make([]T, b.size)
+}
@@ -62,53 +62,69 @@ type NullNode interface {
 	Node
 }
 
-func NewNullNode() NullNode {
-	return NullNode(&nullNode{node: node{nodeType: NodeTypeNull}})
+func NewNullNode(opts ...NodeOption) NullNode {
+	return NullNode(&nullNode{node: newNode(NodeTypeNull, opts)})
 }
 
-func NewBooleanNode(value bool) BooleanNode {
+func NewBooleanNode(value bool, opts ...NodeOption) BooleanNode {
 	return BooleanNode(&boolValueNode{
-		node:  node{nodeType: NodeTypeBoolean},
+		node:  newNode(NodeTypeBoolean, opts),
 		value: value,
 	})
 }
 
-func NewTextNode(value string) TextNode {
+func NewTextNode(value string, opts ...NodeOption) TextNode {
 	return TextNode(&textNode{
-		node:  node{nodeType: NodeTypeText},
+		node:  newNode(NodeTypeText, opts),
 		value: value,
 	})
 }
 
-func NewArrayNode(items []Node) ArrayNode {
+func NewArrayNode(items []Node, opts ...NodeOption) ArrayNode {
 	return ArrayNode(&arrayNode{
-		node:  node{nodeType: NodeTypeArray},
+		node:  newNode(NodeTypeArray, opts),
 		items: items,
 	})
 }
 
-func NewNumberNode(value string) NumberNode {
+func NewNumberNode(value string, opts ...NodeOption) NumberNode {
 	return NumberNode(&numberValueNode{
-		node:  node{nodeType: NodeTypeNumber},
+		node:  newNode(NodeTypeNumber, opts),
 		value: value,
 	})
 }
 
-func NewObjectNode(properties []*Property) ObjectNode {
+func NewObjectNode(properties []*Property, opts ...NodeOption) ObjectNode {
 	return ObjectNode(&objectNode{
-		node:       node{nodeType: NodeTypeObject},
+		node:       newNode(NodeTypeObject, opts),
 		properties: properties,
 	})
 }
 
 type node struct {
 	nodeType NodeType
+	span     *Span
+}
+
+func newNode(nodeType NodeType, opts []NodeOption) node {
+	n := node{nodeType: nodeType}
+	for _, opt := range opts {
+		opt(&n)
+	}
+	return n
 }
 
 func (n *node) Type() NodeType {
 	return n.nodeType
 }
 
+func (n *node) Span() (Span, bool) {
+	if n.span == nil {
+		return Span{}, false
+	}
+	return *n.span, true
+}
+
 type nullNode struct {
 	node
 }
@@ -198,9 +214,8 @@ func (o *objectNode) MarshalJSON() ([]byte, error) {
 		if i != 0 {
 			buf.WriteByte(',')
 		}
-		buf.WriteString(`"`)
-		buf.WriteString(p.Name)
-		buf.WriteString(`":`)
+		writeEscaped(&buf, p.Name)
+		buf.WriteByte(':')
 
 		if p.Value == nil {
 			buf.WriteString("null")
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/trichner/toolbox/pkg/jsontree/lexer"
 )
 
 func TestMarshallJSON(t *testing.T) {
@@ -15,3 +16,121 @@ func TestMarshallJSON(t *testing.T) {
 
 	assert.Equal(t, `{"a":"hello","b":null,"c":[2,4,8]}`, string(txt))
 }
+
+func TestMarshallJSON_EscapesNames(t *testing.T) {
+	n := NewObjectNode([]*Property{{Name: `say "hi"`, Value: NewTextNode("a\\b")}, {Name: "line\nbreak", Value: NewBooleanNode(true)}})
+
+	txt, err := n.MarshalJSON()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"say \"hi\"":"a\\b","line\nbreak":true}`, string(txt))
+}
+
+func TestEqual(t *testing.T) {
+	a := NewObjectNode([]*Property{{Name: "n", Value: NewNumberNode("1")}, {Name: "l", Value: NewArrayNode([]Node{NewTextNode("x")})}})
+	b := NewObjectNode([]*Property{{Name: "l", Value: NewArrayNode([]Node{NewTextNode("x")})}, {Name: "n", Value: NewNumberNode("1.0e0")}})
+
+	assert.True(t, Equal(a, b))
+	assert.True(t, Equal(nil, nil))
+	assert.False(t, Equal(a, nil))
+	assert.False(t, Equal(NewNumberNode("1"), NewTextNode("1")))
+	assert.False(t, Equal(NewArrayNode([]Node{NewNullNode()}), NewArrayNode(nil)))
+	assert.False(t, Equal(NewNumberNode("12345678901234567890"), NewNumberNode("12345678901234567891")))
+
+	// the non-finite literals of JSON5
+	assert.True(t, Equal(NewNumberNode("Infinity"), NewNumberNode("Infinity")))
+	assert.True(t, Equal(NewNumberNode("Infinity"), NewNumberNode("+Infinity")))
+	assert.True(t, Equal(NewNumberNode("NaN"), NewNumberNode("NaN")))
+	assert.False(t, Equal(NewNumberNode("Infinity"), NewNumberNode("-Infinity")))
+	assert.False(t, Equal(NewNumberNode("NaN"), NewNumberNode("1")))
+	cmp, ok := CompareNumbers(NewNumberNode("-Infinity"), NewNumberNode("-1e308"))
+	assert.True(t, ok)
+	assert.Equal(t, -1, cmp)
+}
+
+func TestObject(t *testing.T) {
+	o := NewObject(&Property{Name: "a", Value: NewNumberNode("1")})
+	o.Set("b", NewTextNode("x")).Set("a", NewNumberNode("2")).Set("c", NewNullNode())
+
+	txt, _ := o.MarshalJSON()
+	assert.Equal(t, `{"a":2,"b":"x","c":null}`, string(txt))
+
+	v, ok := o.Get("b")
+	assert.True(t, ok)
+	assert.Equal(t, NewTextNode("x"), v)
+
+	assert.True(t, o.Delete("a"))
+	assert.False(t, o.Delete("a"))
+	assert.Equal(t, 2, o.Len())
+
+	txt, _ = o.MarshalJSON()
+	assert.Equal(t, `{"b":"x","c":null}`, string(txt))
+}
+
+func TestArray(t *testing.T) {
+	a := NewArray(NewNumberNode("1"), NewNumberNode("3"))
+	a.Insert(1, NewNumberNode("2")).Append(NewNumberNode("4")).Insert(0, NewNumberNode("0"))
+
+	txt, _ := a.MarshalJSON()
+	assert.Equal(t, `[0,1,2,3,4]`, string(txt))
+
+	removed := a.Remove(2)
+	assert.Equal(t, "2", removed.(NumberNode).Value())
+	a.Set(0, NewTextNode("zero"))
+
+	txt, _ = a.MarshalJSON()
+	assert.Equal(t, `["zero",1,3,4]`, string(txt))
+	assert.Equal(t, 4, a.Len())
+	assert.Panics(t, func() { a.Get(4) })
+}
+
+func TestDeepCopy(t *testing.T) {
+	original := NewObjectNode([]*Property{
+		{Name: "list", Value: NewArrayNode([]Node{NewNumberNode("1"), NewBooleanNode(true)})},
+		{Name: "name", Value: NewTextNode("a")},
+	})
+
+	c := DeepCopy(original).(*Object)
+	list, _ := c.Get("list")
+	list.(*Array).Append(NewNullNode())
+	c.Set("name", NewTextNode("b"))
+
+	txt, _ := original.MarshalJSON()
+	assert.Equal(t, `{"list":[1,true],"name":"a"}`, string(txt))
+	txt, _ = c.MarshalJSON()
+	assert.Equal(t, `{"list":[1,true,null],"name":"b"}`, string(txt))
+	assert.Nil(t, DeepCopy(nil))
+}
+
+func TestArena(t *testing.T) {
+	a := NewArena()
+	span := Span{Start: lexer.Position{Line: 1, Column: 1}, End: lexer.Position{Offset: 4, Line: 1, Column: 5}}
+
+	items := []Node{a.NewNumber("1", span), a.NewNull(Span{}), a.NewBoolean(true, span)}
+	arr := a.NewArray(items, span)
+	items[0] = a.NewText("clobbered", Span{})
+
+	obj := a.NewObject([]Property{{Name: "a", Value: arr}, {Name: "b", Value: a.NewText("x", Span{})}}, Span{})
+	txt, _ := obj.MarshalJSON()
+	assert.Equal(t, `{"a":[1,null,true],"b":"x"}`, string(txt))
+	assert.True(t, Equal(NewObject().Set("a", NewArray(NewNumberNode("1"), NewNullNode(), NewBooleanNode(true))).Set("b", NewTextNode("x")), obj))
+
+	got, ok := SpanOf(arr)
+	assert.True(t, ok)
+	assert.Equal(t, span, got)
+	_, ok = SpanOf(arr.Items()[1])
+	assert.False(t, ok)
+
+	// appending must not overwrite nodes allocated later
+	next := a.NewArray([]Node{a.NewNumber("2", Span{})}, Span{})
+	_ = append(arr.Items(), NewNullNode())
+	assert.Equal(t, "2", next.Items()[0].(NumberNode).Value())
+
+	for i := 0; i < 2*maxBlockSize; i++ {
+		a.NewText("many", Span{})
+	}
+	large := make([]Node, 3*maxBlockSize)
+	for i := range large {
+		large[i] = NewNumberNode("0")
+	}
+	assert.Len(t, a.NewArray(large, Span{}).Items(), len(large))
+}
@@ -0,0 +1,91 @@
+package ast
+
+import "math/big"
+
+// Equal compares nodes structurally, numbers by their value, e.g. 1 and 1.0e0 are equal, and objects regardless
+// of the order of their properties. A nil node, e.g. an absent value, is only equal to nil.
+func Equal(a, b Node) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if a.Type() != b.Type() {
+		return false
+	}
+
+	switch a.Type() {
+	case NodeTypeNull:
+		return true
+	case NodeTypeBoolean:
+		return a.(BooleanNode).Value() == b.(BooleanNode).Value()
+	case NodeTypeText:
+		return a.(TextNode).Value() == b.(TextNode).Value()
+	case NodeTypeNumber:
+		cmp, ok := CompareNumbers(a.(NumberNode), b.(NumberNode))
+		return ok && cmp == 0
+	case NodeTypeArray:
+		x, y := a.(ArrayNode).Items(), b.(ArrayNode).Items()
+		if len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !Equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case NodeTypeObject:
+		x, y := a.(ObjectNode).Properties(), b.(ObjectNode).Properties()
+		if len(x) != len(y) {
+			return false
+		}
+		for _, p := range x {
+			other, ok := lookup(y, p.Name)
+			if !ok || !Equal(p.Value, other) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// CompareNumbers compares arbitrary precision numbers, Infinity and -Infinity included. It fails for NaN unless
+// both literals are the same, hence a NaN equals itself.
+func CompareNumbers(a, b NumberNode) (int, bool) {
+	if a.Value() == b.Value() {
+		return 0, true
+	}
+	x, ok := parseNumber(a.Value())
+	if !ok {
+		return 0, false
+	}
+	y, ok := parseNumber(b.Value())
+	if !ok {
+		return 0, false
+	}
+	return x.Cmp(y), true
+}
+
+func parseNumber(s string) (*big.Float, bool) {
+	// the non-finite literals of JSON5, big.ParseFloat only knows 'Inf'
+	switch s {
+	case "Infinity", "+Infinity":
+		return new(big.Float).SetInf(false), true
+	case "-Infinity":
+		return new(big.Float).SetInf(true), true
+	}
+	f, _, err := big.ParseFloat(s, 10, 256, big.ToNearestEven)
+	if err != nil {
+		return nil, false
+	}
+	return f, true
+}
+
+func lookup(properties []*Property, name string) (Node, bool) {
+	for _, p := range properties {
+		if p.Name == name {
+			return p.Value, true
+		}
+	}
+	return nil, false
+}
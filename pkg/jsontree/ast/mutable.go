@@ -0,0 +1,126 @@
+package ast
+
+import "slices"
+
+// Object is an ObjectNode that can be changed in place, its properties keep their order. Nodes returned by the
+// parser are read-only, use DeepCopy to obtain a mutable tree from them.
+type Object struct {
+	objectNode
+}
+
+// NewObject creates a mutable object from the given properties
+func NewObject(properties ...*Property) *Object {
+	return &Object{objectNode{node: node{nodeType: NodeTypeObject}, properties: properties}}
+}
+
+// Len returns the number of properties
+func (o *Object) Len() int {
+	return len(o.properties)
+}
+
+// Get returns the value of the first property with the given name
+func (o *Object) Get(name string) (Node, bool) {
+	return lookup(o.properties, name)
+}
+
+// Set replaces the value of an existing property in place or appends a new property
+func (o *Object) Set(name string, value Node) *Object {
+	for _, p := range o.properties {
+		if p.Name == name {
+			p.Value = value
+			return o
+		}
+	}
+	o.properties = append(o.properties, &Property{Name: name, Value: value})
+	return o
+}
+
+// Delete removes all properties with the given name and reports whether there were any
+func (o *Object) Delete(name string) bool {
+	kept := o.properties[:0]
+	for _, p := range o.properties {
+		if p.Name != name {
+			kept = append(kept, p)
+		}
+	}
+	deleted := len(kept) != len(o.properties)
+	clear(o.properties[len(kept):])
+	o.properties = kept
+	return deleted
+}
+
+// Array is an ArrayNode that can be changed in place. Like with slices, indices out of range panic.
+type Array struct {
+	arrayNode
+}
+
+// NewArray creates a mutable array from the given items
+func NewArray(items ...Node) *Array {
+	return &Array{arrayNode{node: node{nodeType: NodeTypeArray}, items: items}}
+}
+
+// Len returns the number of items
+func (a *Array) Len() int {
+	return len(a.items)
+}
+
+// Get returns the item at index i
+func (a *Array) Get(i int) Node {
+	return a.items[i]
+}
+
+// Set replaces the item at index i
+func (a *Array) Set(i int, value Node) *Array {
+	a.items[i] = value
+	return a
+}
+
+// Append adds items to the end of the array
+func (a *Array) Append(items ...Node) *Array {
+	a.items = append(a.items, items...)
+	return a
+}
+
+// Insert inserts items before index i, with i == Len() it appends
+func (a *Array) Insert(i int, items ...Node) *Array {
+	a.items = slices.Insert(a.items, i, items...)
+	return a
+}
+
+// Remove removes the item at index i and returns it
+func (a *Array) Remove(i int) Node {
+	removed := a.items[i]
+	a.items = slices.Delete(a.items, i, i+1)
+	// drop the reference left behind in the backing array
+	a.items[:len(a.items)+1][len(a.items)] = nil
+	return removed
+}
+
+// DeepCopy copies a tree, objects and arrays of the copy are mutable, i.e. an *Object or an *Array. Source spans
+// are not retained.
+func DeepCopy(n Node) Node {
+	if n == nil {
+		return nil
+	}
+	switch n.Type() {
+	case NodeTypeObject:
+		properties := make([]*Property, len(n.(ObjectNode).Properties()))
+		for i, p := range n.(ObjectNode).Properties() {
+			properties[i] = &Property{Name: p.Name, Value: DeepCopy(p.Value)}
+		}
+		return NewObject(properties...)
+	case NodeTypeArray:
+		items := make([]Node, len(n.(ArrayNode).Items()))
+		for i, item := range n.(ArrayNode).Items() {
+			items[i] = DeepCopy(item)
+		}
+		return NewArray(items...)
+	case NodeTypeText:
+		return NewTextNode(n.(TextNode).Value())
+	case NodeTypeNumber:
+		return NewNumberNode(n.(NumberNode).Value())
+	case NodeTypeBoolean:
+		return NewBooleanNode(n.(BooleanNode).Value())
+	}
+	return NewNullNode()
+}
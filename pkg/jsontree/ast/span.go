@@ -0,0 +1,27 @@
+package ast
+
+import "github.com/trichner/toolbox/pkg/jsontree/lexer"
+
+// Span is the location of a node in the source, End is the position right after the node
+type Span struct {
+	Start lexer.Position
+	End   lexer.Position
+}
+
+type NodeOption func(n *node)
+
+// WithSpan records where the node is located in the source
+func WithSpan(span Span) NodeOption {
+	return func(n *node) {
+		n.span = &span
+	}
+}
+
+// SpanOf returns the location of a node in the source, only nodes created by a parser have one
+func SpanOf(n Node) (Span, bool) {
+	s, ok := n.(interface{ Span() (Span, bool) })
+	if !ok {
+		return Span{}, false
+	}
+	return s.Span()
+}
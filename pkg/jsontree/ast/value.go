@@ -0,0 +1,584 @@
+package ast
+
+import (
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	nodeType            = reflect.TypeOf((*Node)(nil)).Elem()
+	objectType          = reflect.TypeOf((*Object)(nil))
+	arrayType           = reflect.TypeOf((*Array)(nil))
+	numberType          = reflect.TypeOf(json.Number(""))
+	bigIntType          = reflect.TypeOf(big.Int{})
+	bigFloatType        = reflect.TypeOf(big.Float{})
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// Encode converts a Go value into a tree, much like encoding/json would marshal it:
+//   - structs become objects with the properties in field order, the `json` tag is honored including omitempty
+//   - maps become objects sorted by key, slices and arrays become arrays, []byte becomes base64 text
+//   - json.Number, big.Int and big.Float keep their exact value
+//   - nodes, including *Object and *Array, are taken as is
+//   - encoding.TextMarshaler implementations become text, e.g. time.Time
+func Encode(v any) (Node, error) {
+	return encode(reflect.ValueOf(v))
+}
+
+func encode(v reflect.Value) (Node, error) {
+	if !v.IsValid() {
+		return NewNullNode(), nil
+	}
+
+	t := v.Type()
+	if t.Implements(nodeType) {
+		if k := t.Kind(); (k == reflect.Pointer || k == reflect.Interface) && v.IsNil() {
+			return NewNullNode(), nil
+		}
+		return v.Interface().(Node), nil
+	}
+	switch t {
+	case numberType:
+		if v.String() == "" {
+			return NewNumberNode("0"), nil
+		}
+		return NewNumberNode(v.String()), nil
+	case bigIntType:
+		i := v.Interface().(big.Int)
+		return NewNumberNode(i.String()), nil
+	case bigFloatType:
+		f := v.Interface().(big.Float)
+		if f.IsInf() {
+			return nil, fmt.Errorf("unsupported value: %s", f.String())
+		}
+		// like for floats, plain notation unless very small or large, i.e. about 1e-6 and 1e21
+		format := byte('f')
+		if exp := f.MantExp(nil); f.Sign() != 0 && (exp < -19 || exp > 70) {
+			format = 'e'
+		}
+		return NewNumberNode(f.Text(format, -1)), nil
+	}
+	if k := t.Kind(); k != reflect.Pointer && k != reflect.Interface && t.Implements(textMarshalerType) {
+		return encodeText(v)
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return NewNullNode(), nil
+		}
+		if t.Kind() == reflect.Pointer && t.Elem() != bigIntType && t.Elem() != bigFloatType && t.Implements(textMarshalerType) {
+			return encodeText(v)
+		}
+		return encode(v.Elem())
+	case reflect.Bool:
+		return NewBooleanNode(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return NewNumberNode(strconv.FormatInt(v.Int(), 10)), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return NewNumberNode(strconv.FormatUint(v.Uint(), 10)), nil
+	case reflect.Float32, reflect.Float64:
+		return encodeFloat(v.Float(), t.Bits())
+	case reflect.String:
+		return NewTextNode(v.String()), nil
+	case reflect.Slice:
+		if v.IsNil() {
+			return NewNullNode(), nil
+		}
+		if t.Elem().Kind() == reflect.Uint8 {
+			return NewTextNode(base64.StdEncoding.EncodeToString(v.Bytes())), nil
+		}
+		return encodeItems(v)
+	case reflect.Array:
+		return encodeItems(v)
+	case reflect.Map:
+		if v.IsNil() {
+			return NewNullNode(), nil
+		}
+		return encodeMap(v)
+	case reflect.Struct:
+		return encodeStruct(v)
+	}
+	return nil, fmt.Errorf("unsupported type: %s", t)
+}
+
+func encodeText(v reflect.Value) (Node, error) {
+	b, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return NewTextNode(string(b)), nil
+}
+
+// encodeFloat formats like encoding/json, i.e. like ES6 for float64
+func encodeFloat(f float64, bits int) (Node, error) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return nil, fmt.Errorf("unsupported value: %s", strconv.FormatFloat(f, 'g', -1, bits))
+	}
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		format = 'e'
+	}
+	return NewNumberNode(strconv.FormatFloat(f, format, -1, bits)), nil
+}
+
+func encodeItems(v reflect.Value) (Node, error) {
+	items := make([]Node, v.Len())
+	for i := range items {
+		item, err := encode(v.Index(i))
+		if err != nil {
+			return nil, fmt.Errorf("item %d: %w", i, err)
+		}
+		items[i] = item
+	}
+	return NewArrayNode(items), nil
+}
+
+func encodeMap(v reflect.Value) (Node, error) {
+	properties := make([]*Property, 0, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		name, err := encodeKey(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		value, err := encode(iter.Value())
+		if err != nil {
+			return nil, fmt.Errorf("property %q: %w", name, err)
+		}
+		properties = append(properties, &Property{Name: name, Value: value})
+	}
+	slices.SortFunc(properties, func(a, b *Property) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return NewObjectNode(properties), nil
+}
+
+func encodeKey(k reflect.Value) (string, error) {
+	if k.Kind() == reflect.String {
+		return k.String(), nil
+	}
+	if m, ok := k.Interface().(encoding.TextMarshaler); ok {
+		b, err := m.MarshalText()
+		return string(b), err
+	}
+	switch k.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(k.Uint(), 10), nil
+	}
+	return "", fmt.Errorf("unsupported map key type: %s", k.Type())
+}
+
+func encodeStruct(v reflect.Value) (Node, error) {
+	fields := fieldsOf(v.Type())
+	properties := make([]*Property, 0, len(fields))
+	for _, f := range fields {
+		fv, ok := fieldByIndex(v, f.index)
+		if !ok || (f.omitEmpty && isEmpty(fv)) {
+			continue
+		}
+		value, err := encode(fv)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", f.name, err)
+		}
+		properties = append(properties, &Property{Name: f.name, Value: value})
+	}
+	return NewObjectNode(properties), nil
+}
+
+// isEmpty tells whether omitempty omits a value, like in encoding/json structs are never empty
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Struct:
+		return false
+	}
+	return v.IsZero()
+}
+
+// fieldByIndex is like reflect.Value.FieldByIndex but stops at nil embedded pointers
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// Decode stores a tree in the value pointed to by v, much like encoding/json would unmarshal it:
+//   - objects decode into structs by the `json` tag or a case-insensitive field name, or into maps
+//   - numbers decode into integers only if they fit exactly, json.Number, big.Int and big.Float keep their precision
+//   - into an empty interface, objects become map[string]any, arrays []any and numbers json.Number
+//   - into *Object or *Array, a mutable copy is stored which keeps the order of properties
+//   - into Node interfaces, the node is stored as is
+//   - encoding.TextUnmarshaler implementations are decoded from text
+//
+// Unknown properties are ignored and null leaves values other than pointers, maps, slices and interfaces unchanged.
+func Decode(n Node, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("cannot decode into non-pointer %T", v)
+	}
+	return decode(n, rv.Elem())
+}
+
+func decode(n Node, v reflect.Value) error {
+	if n == nil {
+		n = NewNullNode()
+	}
+
+	t := v.Type()
+	switch {
+	case t == objectType && n.Type() == NodeTypeObject, t == arrayType && n.Type() == NodeTypeArray:
+		v.Set(reflect.ValueOf(DeepCopy(n)))
+		return nil
+	case t.Kind() == reflect.Interface && t.NumMethod() > 0 && t.Implements(nodeType):
+		if reflect.TypeOf(n).Implements(t) {
+			v.Set(reflect.ValueOf(n))
+			return nil
+		}
+		if n.Type() == NodeTypeNull {
+			v.SetZero()
+			return nil
+		}
+		return decodeError(n, t)
+	}
+
+	if n.Type() == NodeTypeNull {
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Interface:
+			v.SetZero()
+		}
+		return nil
+	}
+
+	if t.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(t.Elem()))
+		}
+		return decode(n, v.Elem())
+	}
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) && n.Type() == NodeTypeText {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(n.(TextNode).Value()))
+	}
+
+	switch n.Type() {
+	case NodeTypeObject:
+		return decodeObject(n.(ObjectNode), v)
+	case NodeTypeArray:
+		return decodeArray(n.(ArrayNode), v)
+	case NodeTypeNumber:
+		return decodeNumber(n.(NumberNode), v)
+	case NodeTypeText:
+		s := n.(TextNode).Value()
+		switch {
+		case t.Kind() == reflect.String && t != numberType:
+			v.SetString(s)
+		case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+			b, err := base64.StdEncoding.DecodeString(s)
+			if err != nil {
+				return err
+			}
+			v.SetBytes(b)
+		case t.Kind() == reflect.Interface && t.NumMethod() == 0:
+			v.Set(reflect.ValueOf(s))
+		default:
+			return decodeError(n, t)
+		}
+		return nil
+	case NodeTypeBoolean:
+		b := n.(BooleanNode).Value()
+		switch {
+		case t.Kind() == reflect.Bool:
+			v.SetBool(b)
+		case t.Kind() == reflect.Interface && t.NumMethod() == 0:
+			v.Set(reflect.ValueOf(b))
+		default:
+			return decodeError(n, t)
+		}
+		return nil
+	}
+	return decodeError(n, t)
+}
+
+func decodeObject(obj ObjectNode, v reflect.Value) error {
+	t := v.Type()
+	switch t.Kind() {
+	case reflect.Interface:
+		if t.NumMethod() != 0 {
+			return decodeError(obj, t)
+		}
+		m := make(map[string]any, len(obj.Properties()))
+		for _, p := range obj.Properties() {
+			var value any
+			if err := decode(p.Value, reflect.ValueOf(&value).Elem()); err != nil {
+				return fmt.Errorf("property %q: %w", p.Name, err)
+			}
+			m[p.Name] = value
+		}
+		v.Set(reflect.ValueOf(m))
+		return nil
+	case reflect.Map:
+		if v.IsNil() {
+			v.Set(reflect.MakeMapWithSize(t, len(obj.Properties())))
+		}
+		for _, p := range obj.Properties() {
+			key, err := decodeKey(p.Name, t.Key())
+			if err != nil {
+				return err
+			}
+			value := reflect.New(t.Elem()).Elem()
+			if err := decode(p.Value, value); err != nil {
+				return fmt.Errorf("property %q: %w", p.Name, err)
+			}
+			v.SetMapIndex(key, value)
+		}
+		return nil
+	case reflect.Struct:
+		fields := fieldsOf(t)
+		for _, p := range obj.Properties() {
+			f, ok := findField(fields, p.Name)
+			if !ok {
+				continue
+			}
+			fv := v
+			for i, x := range f.index {
+				if i > 0 && fv.Kind() == reflect.Pointer {
+					if fv.IsNil() {
+						fv.Set(reflect.New(fv.Type().Elem()))
+					}
+					fv = fv.Elem()
+				}
+				fv = fv.Field(x)
+			}
+			if err := decode(p.Value, fv); err != nil {
+				return fmt.Errorf("field %s: %w", f.name, err)
+			}
+		}
+		return nil
+	}
+	return decodeError(obj, t)
+}
+
+func decodeKey(name string, t reflect.Type) (reflect.Value, error) {
+	key := reflect.New(t).Elem()
+	if t.Kind() == reflect.String {
+		key.SetString(name)
+		return key, nil
+	}
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		err := key.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(name))
+		return key, err
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(name, 10, t.Bits())
+		if err != nil {
+			return key, fmt.Errorf("invalid map key %q: %w", name, err)
+		}
+		key.SetInt(i)
+		return key, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u, err := strconv.ParseUint(name, 10, t.Bits())
+		if err != nil {
+			return key, fmt.Errorf("invalid map key %q: %w", name, err)
+		}
+		key.SetUint(u)
+		return key, nil
+	}
+	return key, fmt.Errorf("unsupported map key type: %s", t)
+}
+
+func decodeArray(arr ArrayNode, v reflect.Value) error {
+	items := arr.Items()
+	t := v.Type()
+	switch t.Kind() {
+	case reflect.Interface:
+		if t.NumMethod() != 0 {
+			return decodeError(arr, t)
+		}
+		values := make([]any, len(items))
+		for i, item := range items {
+			if err := decode(item, reflect.ValueOf(&values[i]).Elem()); err != nil {
+				return fmt.Errorf("item %d: %w", i, err)
+			}
+		}
+		v.Set(reflect.ValueOf(values))
+		return nil
+	case reflect.Slice:
+		s := reflect.MakeSlice(t, len(items), len(items))
+		for i, item := range items {
+			if err := decode(item, s.Index(i)); err != nil {
+				return fmt.Errorf("item %d: %w", i, err)
+			}
+		}
+		v.Set(s)
+		return nil
+	case reflect.Array:
+		v.SetZero()
+		for i, item := range items {
+			if i >= v.Len() {
+				break
+			}
+			if err := decode(item, v.Index(i)); err != nil {
+				return fmt.Errorf("item %d: %w", i, err)
+			}
+		}
+		return nil
+	}
+	return decodeError(arr, t)
+}
+
+func decodeNumber(n NumberNode, v reflect.Value) error {
+	t := v.Type()
+	s := n.Value()
+	switch t {
+	case numberType:
+		v.SetString(s)
+		return nil
+	case bigIntType:
+		i, ok := new(big.Int).SetString(s, 10)
+		if !ok {
+			f, ok := parseNumber(s)
+			if !ok || !f.IsInt() {
+				return fmt.Errorf("cannot decode %s into %s", s, t)
+			}
+			i, _ = f.Int(nil)
+		}
+		v.Set(reflect.ValueOf(i).Elem())
+		return nil
+	case bigFloatType:
+		f, ok := parseNumber(s)
+		if !ok {
+			return fmt.Errorf("cannot decode %s into %s", s, t)
+		}
+		v.Set(reflect.ValueOf(f).Elem())
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Interface:
+		if t.NumMethod() != 0 {
+			return decodeError(n, t)
+		}
+		v.Set(reflect.ValueOf(json.Number(s)))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, t.Bits())
+		if err != nil {
+			return fmt.Errorf("cannot decode %s into %s", s, t)
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u, err := strconv.ParseUint(s, 10, t.Bits())
+		if err != nil {
+			return fmt.Errorf("cannot decode %s into %s", s, t)
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, t.Bits())
+		if err != nil {
+			return fmt.Errorf("cannot decode %s into %s", s, t)
+		}
+		v.SetFloat(f)
+	default:
+		return decodeError(n, t)
+	}
+	return nil
+}
+
+func decodeError(n Node, t reflect.Type) error {
+	return fmt.Errorf("cannot decode %s into %s", n.Type(), t)
+}
+
+type field struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+var fieldCache sync.Map // map[reflect.Type][]field
+
+// fieldsOf lists the fields of a struct in declaration order, fields of embedded structs are promoted unless a
+// shallower field has the same name
+func fieldsOf(t reflect.Type) []field {
+	if cached, ok := fieldCache.Load(t); ok {
+		return cached.([]field)
+	}
+
+	var fields []field
+	depths := make(map[string]int)
+	var collect func(t reflect.Type, index []int, embedding []reflect.Type)
+	collect = func(t reflect.Type, index []int, embedding []reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			tag := sf.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name, opts, _ := strings.Cut(tag, ",")
+
+			fieldIndex := append(slices.Clone(index), i)
+			ft := sf.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+				if !slices.Contains(embedding, ft) {
+					collect(ft, fieldIndex, append(embedding, t))
+				}
+				continue
+			}
+			if !sf.IsExported() {
+				continue
+			}
+
+			if name == "" {
+				name = sf.Name
+			}
+			if d, ok := depths[name]; ok {
+				if d <= len(index) {
+					continue
+				}
+				fields = slices.DeleteFunc(fields, func(f field) bool { return f.name == name })
+			}
+			depths[name] = len(index)
+			fields = append(fields, field{name: name, index: fieldIndex, omitEmpty: strings.Contains(opts, "omitempty")})
+		}
+	}
+	collect(t, nil, nil)
+
+	fieldCache.Store(t, fields)
+	return fields
+}
+
+func findField(fields []field, name string) (field, bool) {
+	for _, f := range fields {
+		if f.name == name {
+			return f, true
+		}
+	}
+	for _, f := range fields {
+		if strings.EqualFold(f.name, name) {
+			return f, true
+		}
+	}
+	return field{}, false
+}
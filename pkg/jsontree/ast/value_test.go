@@ -0,0 +1,113 @@
+package ast
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type address struct {
+	City string `json:"city"`
+	Zip  string `json:"zip,omitempty"`
+}
+
+type base struct {
+	ID      int    `json:"id"`
+	Comment string `json:"-"`
+}
+
+type customer struct {
+	base
+	Name     string            `json:"name"`
+	Balance  *big.Float        `json:"balance"`
+	Ref      json.Number       `json:"ref"`
+	Tags     []string          `json:"tags,omitempty"`
+	Address  *address          `json:"address,omitempty"`
+	Extra    *Object           `json:"extra,omitempty"`
+	Raw      Node              `json:"raw,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Since    time.Time         `json:"since"`
+	internal string
+}
+
+func TestEncode(t *testing.T) {
+	balance, _, _ := big.ParseFloat("12345678901234567890.125", 10, 128, big.ToNearestEven)
+	c := customer{
+		base:    base{ID: 7, Comment: "hidden"},
+		Name:    "Ada",
+		Balance: balance,
+		Ref:     "123456789012345678901234567890",
+		Address: &address{City: "Zurich"},
+		Extra:   NewObject().Set("z", NewNumberNode("1")).Set("a", NewNumberNode("2")),
+		Labels:  map[string]string{"b": "2", "a": "1"},
+		Since:   time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	n, err := Encode(c)
+	require.NoError(t, err)
+	txt, _ := n.MarshalJSON()
+	assert.Equal(t, `{"id":7,"name":"Ada","balance":12345678901234567890.125,"ref":123456789012345678901234567890,`+
+		`"address":{"city":"Zurich"},"extra":{"z":1,"a":2},"labels":{"a":"1","b":"2"},"since":"2024-01-02T03:04:05Z"}`, string(txt))
+
+	n, err = Encode([]any{nil, true, 1.5, 1e21, uint8(3), []byte("hi"), [2]int{1, 2}, map[int]bool{2: true}})
+	require.NoError(t, err)
+	txt, _ = n.MarshalJSON()
+	assert.Equal(t, `[null,true,1.5,1e+21,3,"aGk=",[1,2],{"2":true}]`, string(txt))
+
+	_, err = Encode(map[string]any{"f": func() {}})
+	assert.Error(t, err)
+}
+
+func TestDecode(t *testing.T) {
+	n := NewObjectNode([]*Property{
+		{Name: "id", Value: NewNumberNode("7")},
+		{Name: "NAME", Value: NewTextNode("Ada")},
+		{Name: "balance", Value: NewNumberNode("12345678901234567890.125")},
+		{Name: "ref", Value: NewNumberNode("123456789012345678901234567890")},
+		{Name: "tags", Value: NewArrayNode([]Node{NewTextNode("a"), NewTextNode("b")})},
+		{Name: "address", Value: NewObjectNode([]*Property{{Name: "city", Value: NewTextNode("Zurich")}})},
+		{Name: "extra", Value: NewObjectNode([]*Property{{Name: "z", Value: NewNumberNode("1")}, {Name: "a", Value: NewNullNode()}})},
+		{Name: "raw", Value: NewArrayNode(nil)},
+		{Name: "since", Value: NewTextNode("2024-01-02T03:04:05Z")},
+		{Name: "unknown", Value: NewBooleanNode(true)},
+	})
+
+	var c customer
+	require.NoError(t, Decode(n, &c))
+	assert.Equal(t, 7, c.ID)
+	assert.Equal(t, "Ada", c.Name)
+	assert.Equal(t, "12345678901234567890.125", c.Balance.Text('f', 3))
+	assert.Equal(t, json.Number("123456789012345678901234567890"), c.Ref)
+	assert.Equal(t, []string{"a", "b"}, c.Tags)
+	assert.Equal(t, &address{City: "Zurich"}, c.Address)
+	txt, _ := c.Extra.MarshalJSON()
+	assert.Equal(t, `{"z":1,"a":null}`, string(txt))
+	assert.Equal(t, NodeTypeArray, c.Raw.Type())
+	assert.Equal(t, 2024, c.Since.Year())
+
+	var v any
+	require.NoError(t, Decode(n, &v))
+	assert.Equal(t, json.Number("7"), v.(map[string]any)["id"])
+	assert.Equal(t, []any{"a", "b"}, v.(map[string]any)["tags"])
+
+	var i big.Int
+	require.NoError(t, Decode(NewNumberNode("123456789012345678901234567890"), &i))
+	assert.Equal(t, "123456789012345678901234567890", i.String())
+}
+
+func TestDecode_Errors(t *testing.T) {
+	var i8 int8
+	assert.Error(t, Decode(NewNumberNode("300"), &i8))
+	var i int
+	assert.Error(t, Decode(NewNumberNode("1.5"), &i))
+	assert.Error(t, Decode(NewTextNode("1"), &i))
+	assert.Error(t, Decode(NewTextNode("1"), i))
+
+	var c customer
+	err := Decode(NewObjectNode([]*Property{{Name: "tags", Value: NewArrayNode([]Node{NewNumberNode("1")})}}), &c)
+	assert.ErrorContains(t, err, "field tags: item 0: cannot decode NodeTypeNumber into string")
+}
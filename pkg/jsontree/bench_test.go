@@ -0,0 +1,218 @@
+package jsontree
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"testing"
+
+	"github.com/trichner/toolbox/pkg/jsontree/ast"
+	"github.com/trichner/toolbox/pkg/jsontree/lexer"
+)
+
+// benchmarkInputs are representative NDJSON inputs, set JSONTREE_BENCH_FILE to additionally benchmark a file
+func benchmarkInputs(b *testing.B) map[string][]byte {
+	inputs := map[string][]byte{
+		"records": generateRecords(2000),
+		"escaped": generateEscaped(2000),
+		"numeric": generateNumeric(2000),
+	}
+	if name := os.Getenv("JSONTREE_BENCH_FILE"); name != "" {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			b.Fatal(err)
+		}
+		inputs["file"] = data
+	}
+	return inputs
+}
+
+// generateRecords creates records like a typical API export, e.g. of orders
+func generateRecords(n int) []byte {
+	rnd := rand.New(rand.NewSource(1))
+	var buf bytes.Buffer
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&buf, `{"id":%d,"customer":{"name":"customer %d","email":"c%d@example.com","vip":%t},`, i, rnd.Intn(1000), i, rnd.Intn(2) == 0)
+		fmt.Fprintf(&buf, `"status":"%s","total":%d.%02d,"currency":"CHF","created":"2024-01-%02dT10:%02d:00Z",`,
+			[]string{"open", "shipped", "cancelled"}[rnd.Intn(3)], rnd.Intn(1000), rnd.Intn(100), 1+rnd.Intn(28), rnd.Intn(60))
+		buf.WriteString(`"items":[`)
+		for j := 0; j < 1+rnd.Intn(5); j++ {
+			if j > 0 {
+				buf.WriteByte(',')
+			}
+			fmt.Fprintf(&buf, `{"sku":"SKU-%05d","qty":%d,"price":%d.95,"tags":["a","b"],"discount":null}`, rnd.Intn(100000), 1+rnd.Intn(9), rnd.Intn(100))
+		}
+		buf.WriteString("]}\n")
+	}
+	return buf.Bytes()
+}
+
+// generateEscaped creates records dominated by text with escapes and non-ASCII characters
+func generateEscaped(n int) []byte {
+	var buf bytes.Buffer
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&buf, `{"title":"Gr\u00fc\u00dfe aus Z\u00fcrich %d","body":"line one\nline \"two\"\t\u2713 — ümlauts and emoji 😀","path":"C:\\temp\\%d"}`+"\n", i, i)
+	}
+	return buf.Bytes()
+}
+
+// generateNumeric creates records dominated by numbers, e.g. measurements
+func generateNumeric(n int) []byte {
+	rnd := rand.New(rand.NewSource(2))
+	var buf bytes.Buffer
+	for i := 0; i < n; i++ {
+		buf.WriteByte('[')
+		for j := 0; j < 20; j++ {
+			if j > 0 {
+				buf.WriteByte(',')
+			}
+			fmt.Fprintf(&buf, "%g", rnd.NormFloat64()*1e3)
+		}
+		buf.WriteString("]\n")
+	}
+	return buf.Bytes()
+}
+
+func BenchmarkLexer(b *testing.B) {
+	for name, data := range benchmarkInputs(b) {
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				l := lexer.NewLexer(bytes.NewReader(data))
+				for {
+					tkn, err := l.Token()
+					if err != nil {
+						b.Fatal(err)
+					}
+					if tkn.Type == lexer.TokenTypeEOF {
+						break
+					}
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	for name, data := range benchmarkInputs(b) {
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				r := NewValueReader(lexer.NewLexer(bytes.NewReader(data)))
+				for {
+					_, err := r.Next()
+					if err == io.EOF {
+						break
+					}
+					if err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkParseRecords compares parsing NDJSON record by record with Parse, i.e. a new arena per record, to a
+// reader sharing one arena
+func BenchmarkParseRecords(b *testing.B) {
+	data := generateRecords(2000)
+	for name, next := range map[string]func(l lexer.Lexer) func() (ast.Node, error){
+		"Parse": func(l lexer.Lexer) func() (ast.Node, error) {
+			return func() (ast.Node, error) { return Parse(l) }
+		},
+		"ValueReader": func(l lexer.Lexer) func() (ast.Node, error) {
+			return NewValueReader(l).Next
+		},
+	} {
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				next := next(lexer.NewLexer(bytes.NewReader(data)))
+				for {
+					_, err := next()
+					if err == io.EOF {
+						break
+					}
+					if err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkDecoder(b *testing.B) {
+	for name, data := range benchmarkInputs(b) {
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				d := NewDecoder(lexer.NewLexer(bytes.NewReader(data)))
+				for {
+					_, err := d.Next()
+					if err == io.EOF {
+						break
+					}
+					if err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkEncodingJSON decodes the same inputs with encoding/json as a baseline
+func BenchmarkEncodingJSON(b *testing.B) {
+	for name, data := range benchmarkInputs(b) {
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				d := json.NewDecoder(bytes.NewReader(data))
+				d.UseNumber()
+				for {
+					var v any
+					err := d.Decode(&v)
+					if err == io.EOF {
+						break
+					}
+					if err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkEncodingJSONTokens tokenizes the same inputs with encoding/json as a baseline for the Decoder
+func BenchmarkEncodingJSONTokens(b *testing.B) {
+	for name, data := range benchmarkInputs(b) {
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				d := json.NewDecoder(bytes.NewReader(data))
+				d.UseNumber()
+				for {
+					_, err := d.Token()
+					if err == io.EOF {
+						break
+					}
+					if err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
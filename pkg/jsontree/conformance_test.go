@@ -0,0 +1,91 @@
+package jsontree
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trichner/toolbox/pkg/jsontree/ast"
+	"github.com/trichner/toolbox/pkg/jsontree/lexer"
+)
+
+// TestJSONTestSuite runs a selection of the parsing tests of https://github.com/nst/JSONTestSuite, files
+// prefixed with 'y_' must be accepted, 'n_' must be rejected and 'i_' are up to the implementation.
+func TestJSONTestSuite(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "JSONTestSuite", "*.json"))
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	for _, f := range files {
+		name := filepath.Base(f)
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(f)
+			require.NoError(t, err)
+
+			n, err := parseDocument(data)
+			switch {
+			case strings.HasPrefix(name, "y_"):
+				require.NoError(t, err)
+				assertSameAsEncodingJson(t, data, n)
+			case strings.HasPrefix(name, "n_"):
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+// parseDocument parses exactly one value, anything but whitespace after it is an error
+func parseDocument(data []byte) (ast.Node, error) {
+	l := lexer.NewLexer(bytes.NewReader(data))
+	n, err := Parse(l)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = Parse(l)
+	if !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("expected a single value: %v", err)
+	}
+	return n, nil
+}
+
+func assertSameAsEncodingJson(t *testing.T, data []byte, n ast.Node) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+
+	var expected any
+	require.NoError(t, d.Decode(&expected))
+	assert.Equal(t, expected, toValue(n))
+}
+
+func toValue(n ast.Node) any {
+	switch n.Type() {
+	case ast.NodeTypeObject:
+		m := map[string]any{}
+		for _, p := range n.(ast.ObjectNode).Properties() {
+			m[p.Name] = toValue(p.Value)
+		}
+		return m
+	case ast.NodeTypeArray:
+		items := []any{}
+		for _, item := range n.(ast.ArrayNode).Items() {
+			items = append(items, toValue(item))
+		}
+		return items
+	case ast.NodeTypeText:
+		return n.(ast.TextNode).Value()
+	case ast.NodeTypeNumber:
+		return json.Number(n.(ast.NumberNode).Value())
+	case ast.NodeTypeBoolean:
+		return n.(ast.BooleanNode).Value()
+	}
+	return nil
+}
@@ -0,0 +1,311 @@
+package jsontree
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/trichner/toolbox/pkg/jsontree/ast"
+	"github.com/trichner/toolbox/pkg/jsontree/lexer"
+)
+
+//go:generate stringer -type=EventType
+type EventType int
+
+const (
+	EventTypeUnknown EventType = iota
+	EventTypeStartObject
+	EventTypeEndObject
+	EventTypeStartArray
+	EventTypeEndArray
+
+	// EventTypeKey is the name of an object property, its value follows as the next event
+	EventTypeKey
+
+	EventTypeText
+	EventTypeNumber
+	EventTypeBoolean
+	EventTypeNull
+)
+
+type Event struct {
+	Type EventType
+	// Value holds the property name of a key, the unescaped text or the literal of a number, boolean or null
+	Value string
+	Pos   lexer.Position
+}
+
+type frameState int
+
+const (
+	// stateFirst expects the first property or item, or the end of the container
+	stateFirst frameState = iota
+	// stateNext expects a property or item after a comma
+	stateNext
+	// stateValue expects the value of a property
+	stateValue
+	// stateAfter expects a comma or the end of the container after a property or item
+	stateAfter
+)
+
+type frame struct {
+	object bool
+	state  frameState
+	key    string
+	index  int
+}
+
+// Decoder reads JSON as a stream of events without materializing values, hence it can process inputs of any
+// size. Like Parse, it reads a stream of top-level values, e.g. NDJSON.
+type Decoder struct {
+	l     lexer.Lexer
+	p     *parser
+	stack []frame
+
+	// pathDepth is the number of frames making up the path of the last event
+	pathDepth int
+}
+
+func NewDecoder(l lexer.Lexer) *Decoder {
+	return &Decoder{l: l, p: newParser(l)}
+}
+
+// Next reads the next event, at the end of the input it returns io.EOF. Invalid input results in a *SyntaxError.
+func (d *Decoder) Next() (Event, error) {
+	ev, err := d.next()
+	if err != nil && err != io.EOF {
+		return Event{}, newSyntaxError(d.l, err)
+	}
+	return ev, err
+}
+
+// Path returns the location of the last event within its top-level value, it is only valid until the next
+// call to the Decoder.
+func (d *Decoder) Path() Path {
+	path := make(Path, 0, d.pathDepth)
+	for _, f := range d.stack[:d.pathDepth] {
+		if f.object {
+			path = append(path, PathElement{Key: f.key, Index: -1})
+		} else {
+			path = append(path, PathElement{Index: f.index})
+		}
+	}
+	return path
+}
+
+// Depth is the number of objects and arrays currently open
+func (d *Decoder) Depth() int {
+	return len(d.stack)
+}
+
+// More reports whether there is another property or item in the current object or array, or another
+// top-level value if none is open.
+func (d *Decoder) More() (bool, error) {
+	more, err := d.more()
+	if err != nil {
+		return false, newSyntaxError(d.l, err)
+	}
+	return more, nil
+}
+
+// Node reads the next value as a whole, e.g. to materialize the items of a huge array one at a time. It must
+// only be called when a value is expected, i.e. at the top-level, after a key or if More reports another item.
+func (d *Decoder) Node() (ast.Node, error) {
+	if len(d.stack) == 0 {
+		return d.p.parse()
+	}
+
+	top := d.top()
+	if top.object && top.state != stateValue {
+		return nil, fmt.Errorf("no value to read, expected a property name")
+	}
+	if top.state == stateAfter {
+		return nil, fmt.Errorf("no value to read, expected a separator")
+	}
+	top.state = stateAfter
+
+	n, err := d.p.parseValue()
+	if err != nil {
+		return nil, newSyntaxError(d.l, err)
+	}
+	d.pathDepth = len(d.stack)
+	return n, nil
+}
+
+func (d *Decoder) next() (Event, error) {
+	if len(d.stack) == 0 {
+		tkn, err := d.l.Peek()
+		if err != nil {
+			return Event{}, err
+		}
+		if tkn.Type == lexer.TokenTypeEOF {
+			return Event{}, io.EOF
+		}
+		return d.value()
+	}
+
+	top := d.top()
+	switch top.state {
+	case stateFirst:
+		tkn, err := d.l.Peek()
+		if err != nil {
+			return Event{}, err
+		}
+		if isClosing(top, tkn) {
+			return d.end()
+		}
+		if top.object {
+			return d.key()
+		}
+		return d.value()
+	case stateNext:
+		if top.object {
+			return d.key()
+		}
+		return d.value()
+	case stateValue:
+		return d.value()
+	}
+
+	// stateAfter
+	tkn, err := d.l.Peek()
+	if err != nil {
+		return Event{}, err
+	}
+	if isClosing(top, tkn) {
+		return d.end()
+	}
+	if tkn.Type != lexer.TokenTypeComma {
+		return Event{}, errorAt(tkn, fmt.Errorf("unexpected token, expected %q or the end of the %s but got: %v", lexer.TokenTypeComma, top.kind(), tkn.Type))
+	}
+	d.skipComma(top)
+	return d.next()
+}
+
+func (d *Decoder) more() (bool, error) {
+	tkn, err := d.l.Peek()
+	if err != nil {
+		return false, err
+	}
+	if len(d.stack) == 0 {
+		return tkn.Type != lexer.TokenTypeEOF, nil
+	}
+
+	top := d.top()
+	switch top.state {
+	case stateFirst:
+		return !isClosing(top, tkn), nil
+	case stateNext, stateValue:
+		return true, nil
+	}
+
+	// stateAfter
+	if isClosing(top, tkn) {
+		return false, nil
+	}
+	if tkn.Type != lexer.TokenTypeComma {
+		return false, errorAt(tkn, fmt.Errorf("unexpected token, expected %q or the end of the %s but got: %v", lexer.TokenTypeComma, top.kind(), tkn.Type))
+	}
+	d.skipComma(top)
+	return true, nil
+}
+
+func (d *Decoder) skipComma(top *frame) {
+	_, _ = d.l.Token()
+	top.state = stateNext
+	if !top.object {
+		top.index++
+	}
+}
+
+func (d *Decoder) key() (Event, error) {
+	top := d.top()
+	tkn, err := d.l.Token()
+	if err != nil {
+		return Event{}, err
+	}
+	if tkn.Type != lexer.TokenTypeText {
+		return Event{}, errorAt(tkn, fmt.Errorf("unexpected token parsing object, expected %q but got: %q", lexer.TokenTypeText, tkn.Type))
+	}
+	if _, err := skipToken(d.l, lexer.TokenTypeColon); err != nil {
+		return Event{}, err
+	}
+
+	top.key = tkn.Value
+	top.state = stateValue
+	d.pathDepth = len(d.stack)
+	return Event{Type: EventTypeKey, Value: tkn.Value, Pos: tkn.Pos}, nil
+}
+
+func (d *Decoder) value() (Event, error) {
+	if len(d.stack) > 0 {
+		d.top().state = stateAfter
+	}
+	d.pathDepth = len(d.stack)
+
+	tkn, err := d.l.Token()
+	if err != nil {
+		return Event{}, err
+	}
+
+	ev := Event{Pos: tkn.Pos, Value: tkn.Value}
+	switch tkn.Type {
+	case lexer.TokenTypeOpeningBrace:
+		d.stack = append(d.stack, frame{object: true})
+		ev.Type = EventTypeStartObject
+	case lexer.TokenTypeOpeningBracket:
+		d.stack = append(d.stack, frame{})
+		ev.Type = EventTypeStartArray
+	case lexer.TokenTypeText:
+		ev.Type = EventTypeText
+	case lexer.TokenTypePrimitiveNumber:
+		ev.Type = EventTypeNumber
+	case lexer.TokenTypePrimitiveText:
+		switch tkn.Value {
+		case "true", "false":
+			ev.Type = EventTypeBoolean
+		case "null":
+			ev.Type = EventTypeNull
+		default:
+			return Event{}, errorAt(tkn, fmt.Errorf("unrecognized literal: %q", tkn.Value))
+		}
+	case lexer.TokenTypeEOF:
+		return Event{}, errorAt(tkn, io.ErrUnexpectedEOF)
+	default:
+		return Event{}, errorAt(tkn, fmt.Errorf("unexpected token: %v", tkn.Type))
+	}
+	return ev, nil
+}
+
+func (d *Decoder) end() (Event, error) {
+	tkn, err := d.l.Token()
+	if err != nil {
+		return Event{}, err
+	}
+
+	top := d.stack[len(d.stack)-1]
+	d.stack = d.stack[:len(d.stack)-1]
+	d.pathDepth = len(d.stack)
+
+	if top.object {
+		return Event{Type: EventTypeEndObject, Pos: tkn.Pos}, nil
+	}
+	return Event{Type: EventTypeEndArray, Pos: tkn.Pos}, nil
+}
+
+func (d *Decoder) top() *frame {
+	return &d.stack[len(d.stack)-1]
+}
+
+func isClosing(f *frame, tkn lexer.Token) bool {
+	if f.object {
+		return tkn.Type == lexer.TokenTypeClosingBrace
+	}
+	return tkn.Type == lexer.TokenTypeClosingBracket
+}
+
+func (f *frame) kind() string {
+	if f.object {
+		return "object"
+	}
+	return "array"
+}
@@ -0,0 +1,174 @@
+package jsontree
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trichner/toolbox/pkg/jsontree/ast"
+	"github.com/trichner/toolbox/pkg/jsontree/lexer"
+)
+
+func TestDecoder_Next(t *testing.T) {
+	d := NewDecoder(lexer.NewLexer(strings.NewReader(`{"a": [1, "x", {"b c": null}], "d": true} 7`)))
+
+	type step struct {
+		Type  EventType
+		Value string
+		Path  string
+	}
+	var steps []step
+	for {
+		ev, err := d.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		steps = append(steps, step{ev.Type, ev.Value, d.Path().String()})
+	}
+
+	assert.Equal(t, []step{
+		{EventTypeStartObject, "", "$"},
+		{EventTypeKey, "a", "$.a"},
+		{EventTypeStartArray, "", "$.a"},
+		{EventTypeNumber, "1", "$.a[0]"},
+		{EventTypeText, "x", "$.a[1]"},
+		{EventTypeStartObject, "", "$.a[2]"},
+		{EventTypeKey, "b c", `$.a[2]["b c"]`},
+		{EventTypeNull, "null", `$.a[2]["b c"]`},
+		{EventTypeEndObject, "", "$.a[2]"},
+		{EventTypeEndArray, "", "$.a"},
+		{EventTypeKey, "d", "$.d"},
+		{EventTypeBoolean, "true", "$.d"},
+		{EventTypeEndObject, "", "$"},
+		{EventTypeNumber, "7", "$"},
+	}, steps)
+}
+
+func TestDecoder_Next_SyntaxError(t *testing.T) {
+	tests := []string{`[1 2]`, `{"a" 1}`, `{"a": 1,}`, `[1,]`, `[1`, `{1: 2}`}
+	for _, raw := range tests {
+		t.Run(raw, func(t *testing.T) {
+			d := NewDecoder(lexer.NewLexer(strings.NewReader(raw)))
+			var err error
+			for err == nil {
+				_, err = d.Next()
+			}
+			var serr *SyntaxError
+			assert.ErrorAs(t, err, &serr)
+		})
+	}
+}
+
+func TestDecoder_Node(t *testing.T) {
+	d := NewDecoder(lexer.NewLexer(strings.NewReader(`{"items": [{"a": 1}, [2], 3], "n": 4}`)))
+
+	ev, err := d.Next()
+	require.NoError(t, err)
+	assert.Equal(t, EventTypeStartObject, ev.Type)
+	ev, err = d.Next()
+	require.NoError(t, err)
+	assert.Equal(t, EventTypeKey, ev.Type)
+	ev, err = d.Next()
+	require.NoError(t, err)
+	assert.Equal(t, EventTypeStartArray, ev.Type)
+
+	var items []ast.Node
+	for {
+		more, err := d.More()
+		require.NoError(t, err)
+		if !more {
+			break
+		}
+		n, err := d.Node()
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("$.items[%d]", len(items)), d.Path().String())
+		items = append(items, n)
+	}
+	require.Len(t, items, 3)
+	assert.Equal(t, ast.NodeTypeObject, items[0].Type())
+	assert.Equal(t, ast.NodeTypeArray, items[1].Type())
+	assert.Equal(t, "3", items[2].(ast.NumberNode).Value())
+
+	ev, err = d.Next()
+	require.NoError(t, err)
+	assert.Equal(t, EventTypeEndArray, ev.Type)
+
+	ev, err = d.Next()
+	require.NoError(t, err)
+	assert.Equal(t, EventTypeKey, ev.Type)
+	n, err := d.Node()
+	require.NoError(t, err)
+	assert.Equal(t, "4", n.(ast.NumberNode).Value())
+
+	_, err = d.Node()
+	assert.Error(t, err)
+}
+
+func TestItemReader(t *testing.T) {
+	raw := `[{"a": 1}, {"a": 2}] {"a": 3} [] [{"a": 4}]`
+	r := NewItemReader(lexer.NewLexer(strings.NewReader(raw)))
+
+	var values []string
+	for {
+		n, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		values = append(values, n.(ast.ObjectNode).Properties()[0].Value.(ast.NumberNode).Value())
+	}
+	assert.Equal(t, []string{"1", "2", "3", "4"}, values)
+}
+
+func TestItemReader_LargeArray(t *testing.T) {
+	const count = 100_000
+
+	pr, pw := io.Pipe()
+	go func() {
+		fmt.Fprint(pw, "[")
+		for i := 0; i < count; i++ {
+			if i > 0 {
+				fmt.Fprint(pw, ",\n")
+			}
+			fmt.Fprintf(pw, `{"id": %d, "name": "item %d"}`, i, i)
+		}
+		fmt.Fprint(pw, "]")
+		pw.Close()
+	}()
+
+	r := NewItemReader(lexer.NewLexer(pr))
+	i := 0
+	for {
+		n, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		require.Equal(t, fmt.Sprint(i), n.(ast.ObjectNode).Properties()[0].Value.(ast.NumberNode).Value())
+		i++
+	}
+	assert.Equal(t, count, i)
+}
+
+func TestItemReader_SyntaxError(t *testing.T) {
+	r := NewItemReader(lexer.NewLexer(strings.NewReader(`[{"a": 1} {"a": 2}]`)))
+
+	_, err := r.Next()
+	require.NoError(t, err)
+
+	_, err = r.Next()
+	var serr *SyntaxError
+	require.ErrorAs(t, err, &serr)
+	assert.Equal(t, 11, serr.Pos.Column)
+}
+
+func TestPath_String(t *testing.T) {
+	p := Path{{Key: "a", Index: -1}, {Index: 3}, {Key: "first name", Index: -1}, {Key: "ok_1", Index: -1}, {Key: "a\"b\n", Index: -1}}
+	assert.Equal(t, `$.a[3]["first name"].ok_1["a\"b\n"]`, p.String())
+	assert.Equal(t, "$", Path{}.String())
+}
@@ -0,0 +1,89 @@
+package jsontree
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/trichner/toolbox/pkg/jsontree/lexer"
+)
+
+// SyntaxError describes invalid JSON input and where it was found
+type SyntaxError struct {
+	Msg string
+	Pos lexer.Position
+
+	// line is an excerpt of the source line at Pos and column the zero based column of Pos within it, if available
+	line   string
+	column int
+	err    error
+}
+
+func newSyntaxError(l lexer.Lexer, err error) error {
+	pos, ok := findPosition(err)
+	if !ok {
+		// not a syntax error, e.g. failing to read the input
+		return err
+	}
+
+	serr := &SyntaxError{Msg: err.Error(), Pos: pos, column: -1, err: err}
+	if e, ok := l.(lexer.Excerpter); ok {
+		if line, column, ok := e.Excerpt(pos); ok {
+			serr.line, serr.column = line, column
+		}
+	}
+	return serr
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at %s: %s", e.Pos, e.Msg)
+}
+
+func (e *SyntaxError) Unwrap() error {
+	return e.err
+}
+
+// Snippet renders the offending source line with a caret pointing at the error, it is empty if the line is
+// not available anymore.
+//
+//	3 | {"a": 1,, "b": 2}
+//	  |         ^
+func (e *SyntaxError) Snippet() string {
+	if e.column < 0 {
+		return ""
+	}
+
+	number := strconv.Itoa(e.Pos.Line)
+	gutter := strings.Repeat(" ", len(number))
+
+	var caret strings.Builder
+	for i, r := range []rune(e.line) {
+		if i >= e.column {
+			break
+		}
+		// keep tabs to stay aligned with the line above
+		if r == '\t' {
+			caret.WriteRune('\t')
+		} else {
+			caret.WriteRune(' ')
+		}
+	}
+	caret.WriteRune('^')
+
+	return fmt.Sprintf("%s | %s\n%s | %s", number, e.line, gutter, caret.String())
+}
+
+// FormatError renders an error for humans, syntax errors include a snippet of the offending source line
+func FormatError(err error) string {
+	var serr *SyntaxError
+	if !errors.As(err, &serr) {
+		return err.Error()
+	}
+
+	snippet := serr.Snippet()
+	if snippet == "" {
+		return err.Error()
+	}
+	return err.Error() + "\n" + snippet
+}
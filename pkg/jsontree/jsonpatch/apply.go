@@ -0,0 +1,223 @@
+package jsonpatch
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/trichner/toolbox/pkg/jsontree"
+	"github.com/trichner/toolbox/pkg/jsontree/ast"
+)
+
+// Apply applies the patch to a document and returns the patched document, the original remains untouched.
+// Either all operations apply or an error is returned.
+func Apply(doc ast.Node, patch Patch) (ast.Node, error) {
+	var err error
+	for i, op := range patch {
+		doc, err = applyOperation(doc, op)
+		if err != nil {
+			return nil, fmt.Errorf("cannot apply operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+	return doc, nil
+}
+
+func applyOperation(doc ast.Node, op Operation) (ast.Node, error) {
+	path, err := jsontree.ParsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case OpAdd:
+		return add(doc, path, op.Value)
+	case OpRemove:
+		return remove(doc, path)
+	case OpReplace:
+		return replace(doc, path, op.Value)
+	case OpMove, OpCopy:
+		from, err := jsontree.ParsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err := get(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		if op.Op == OpMove {
+			if op.Path != op.From && strings.HasPrefix(op.Path, op.From+"/") {
+				return nil, fmt.Errorf("cannot move %s into itself", op.From)
+			}
+			doc, err = remove(doc, from)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return add(doc, path, value)
+	case OpTest:
+		value, err := get(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !ast.Equal(value, op.Value) {
+			return nil, fmt.Errorf("test failed, values differ")
+		}
+		return doc, nil
+	}
+	return nil, fmt.Errorf("unknown op: %q", op.Op)
+}
+
+func get(n ast.Node, path []string) (ast.Node, error) {
+	for _, token := range path {
+		switch typed := n.(type) {
+		case ast.ObjectNode:
+			v, ok := lookup(typed.Properties(), token)
+			if !ok {
+				return nil, fmt.Errorf("property %q not found", token)
+			}
+			n = v
+		case ast.ArrayNode:
+			i, err := parseIndex(token, len(typed.Items()), false)
+			if err != nil {
+				return nil, err
+			}
+			n = typed.Items()[i]
+		default:
+			return nil, fmt.Errorf("cannot resolve %q in %s", token, describe(n))
+		}
+	}
+	return n, nil
+}
+
+func add(doc ast.Node, path []string, value ast.Node) (ast.Node, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return modify(doc, path, func(parent ast.Node, token string) (ast.Node, error) {
+		switch typed := parent.(type) {
+		case ast.ObjectNode:
+			return setProperty(typed, token, value), nil
+		case ast.ArrayNode:
+			items := typed.Items()
+			i, err := parseIndex(token, len(items), true)
+			if err != nil {
+				return nil, err
+			}
+			updated := make([]ast.Node, 0, len(items)+1)
+			updated = append(append(append(updated, items[:i]...), value), items[i:]...)
+			return ast.NewArrayNode(updated), nil
+		}
+		return nil, fmt.Errorf("cannot add %q to %s", token, describe(parent))
+	})
+}
+
+func remove(doc ast.Node, path []string) (ast.Node, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("cannot remove the whole document")
+	}
+	return modify(doc, path, func(parent ast.Node, token string) (ast.Node, error) {
+		switch typed := parent.(type) {
+		case ast.ObjectNode:
+			if _, ok := lookup(typed.Properties(), token); !ok {
+				return nil, fmt.Errorf("property %q not found", token)
+			}
+			return deleteProperty(typed, token), nil
+		case ast.ArrayNode:
+			items := typed.Items()
+			i, err := parseIndex(token, len(items), false)
+			if err != nil {
+				return nil, err
+			}
+			updated := make([]ast.Node, 0, len(items)-1)
+			updated = append(append(updated, items[:i]...), items[i+1:]...)
+			return ast.NewArrayNode(updated), nil
+		}
+		return nil, fmt.Errorf("cannot remove %q from %s", token, describe(parent))
+	})
+}
+
+// replace swaps the value at path, properties keep their position
+func replace(doc ast.Node, path []string, value ast.Node) (ast.Node, error) {
+	if _, err := get(doc, path); err != nil {
+		return nil, err
+	}
+	if len(path) == 0 {
+		return value, nil
+	}
+	return modify(doc, path, func(parent ast.Node, token string) (ast.Node, error) {
+		switch typed := parent.(type) {
+		case ast.ObjectNode:
+			return setProperty(typed, token, value), nil
+		case ast.ArrayNode:
+			items := make([]ast.Node, len(typed.Items()))
+			copy(items, typed.Items())
+			i, _ := parseIndex(token, len(items), false)
+			items[i] = value
+			return ast.NewArrayNode(items), nil
+		}
+		return nil, fmt.Errorf("cannot replace %q in %s", token, describe(parent))
+	})
+}
+
+// modify rebuilds the nodes along the path, fn returns the updated parent of the last token
+func modify(n ast.Node, path []string, fn func(parent ast.Node, token string) (ast.Node, error)) (ast.Node, error) {
+	if len(path) == 1 {
+		return fn(n, path[0])
+	}
+
+	child, err := get(n, path[:1])
+	if err != nil {
+		return nil, err
+	}
+	updated, err := modify(child, path[1:], fn)
+	if err != nil {
+		return nil, err
+	}
+
+	switch typed := n.(type) {
+	case ast.ObjectNode:
+		return setProperty(typed, path[0], updated), nil
+	case ast.ArrayNode:
+		items := make([]ast.Node, len(typed.Items()))
+		copy(items, typed.Items())
+		i, _ := parseIndex(path[0], len(items), false)
+		items[i] = updated
+		return ast.NewArrayNode(items), nil
+	}
+	return nil, fmt.Errorf("cannot resolve %q in %s", path[0], describe(n))
+}
+
+// setProperty replaces the value of an existing property in place or appends a new one
+func setProperty(obj ast.ObjectNode, name string, value ast.Node) ast.ObjectNode {
+	properties := make([]*ast.Property, 0, len(obj.Properties())+1)
+	replaced := false
+	for _, p := range obj.Properties() {
+		if p.Name == name && !replaced {
+			properties = append(properties, &ast.Property{Name: name, Value: value})
+			replaced = true
+			continue
+		}
+		properties = append(properties, p)
+	}
+	if !replaced {
+		properties = append(properties, &ast.Property{Name: name, Value: value})
+	}
+	return ast.NewObjectNode(properties)
+}
+
+func deleteProperty(obj ast.ObjectNode, name string) ast.ObjectNode {
+	properties := make([]*ast.Property, 0, len(obj.Properties()))
+	for _, p := range obj.Properties() {
+		if p.Name != name {
+			properties = append(properties, p)
+		}
+	}
+	return ast.NewObjectNode(properties)
+}
+
+// describe names the type of n for errors, mutable trees may hold nil values
+func describe(n ast.Node) string {
+	if n == nil {
+		return "a missing value"
+	}
+	return n.Type().String()
+}
@@ -0,0 +1,193 @@
+package jsonpatch
+
+import (
+	"strconv"
+
+	"github.com/trichner/toolbox/pkg/jsontree"
+	"github.com/trichner/toolbox/pkg/jsontree/ast"
+)
+
+// maxLCSCells bounds the effort of aligning array items, longer arrays are compared item by item
+const maxLCSCells = 4_000_000
+
+// Diff computes a patch transforming one tree into another. Objects are compared property by property, array
+// items are aligned such that inserting or removing items does not show up as changes of all following ones.
+// Numbers are compared by value, hence 1.0 and 1 are no change.
+func Diff(from, to ast.Node) Patch {
+	d := &differ{}
+	d.diff("", from, to)
+	return d.patch
+}
+
+type differ struct {
+	patch Patch
+}
+
+func (d *differ) diff(path string, a, b ast.Node) {
+	if ast.Equal(a, b) {
+		return
+	}
+
+	if a != nil && b != nil && a.Type() == b.Type() {
+		switch a.Type() {
+		case ast.NodeTypeObject:
+			d.diffObjects(path, a.(ast.ObjectNode), b.(ast.ObjectNode))
+			return
+		case ast.NodeTypeArray:
+			d.diffArrays(path, a.(ast.ArrayNode).Items(), b.(ast.ArrayNode).Items())
+			return
+		}
+	}
+	d.patch = append(d.patch, Operation{Op: OpReplace, Path: path, Value: b, Old: a})
+}
+
+func (d *differ) diffObjects(path string, a, b ast.ObjectNode) {
+	for _, p := range a.Properties() {
+		other, ok := lookup(b.Properties(), p.Name)
+		if !ok {
+			d.patch = append(d.patch, Operation{Op: OpRemove, Path: jsontree.AppendPointer(path, p.Name), Old: p.Value})
+			continue
+		}
+		d.diff(jsontree.AppendPointer(path, p.Name), p.Value, other)
+	}
+	for _, p := range b.Properties() {
+		if _, ok := lookup(a.Properties(), p.Name); !ok {
+			d.patch = append(d.patch, Operation{Op: OpAdd, Path: jsontree.AppendPointer(path, p.Name), Value: p.Value})
+		}
+	}
+}
+
+type edit int
+
+const (
+	editKeep edit = iota
+	editRemove
+	editAdd
+)
+
+func (d *differ) diffArrays(path string, a, b []ast.Node) {
+	script := align(a, b)
+
+	// i is the index within the array as patched so far, x and y the ones within a and b
+	i, x, y := 0, 0, 0
+	for k := 0; k < len(script); {
+		if script[k] == editKeep {
+			i, x, y, k = i+1, x+1, y+1, k+1
+			continue
+		}
+
+		// a run of changes, removed and added items pair up as changes of the item in place
+		removed, added := 0, 0
+		for ; k < len(script) && script[k] != editKeep; k++ {
+			if script[k] == editRemove {
+				removed++
+			} else {
+				added++
+			}
+		}
+		for ; removed > 0 && added > 0; removed, added = removed-1, added-1 {
+			d.diff(jsontree.AppendPointer(path, strconv.Itoa(i)), a[x], b[y])
+			i, x, y = i+1, x+1, y+1
+		}
+		for ; removed > 0; removed-- {
+			d.patch = append(d.patch, Operation{Op: OpRemove, Path: jsontree.AppendPointer(path, strconv.Itoa(i)), Old: a[x]})
+			x++
+		}
+		for ; added > 0; added-- {
+			d.patch = append(d.patch, Operation{Op: OpAdd, Path: jsontree.AppendPointer(path, strconv.Itoa(i)), Value: b[y]})
+			i, y = i+1, y+1
+		}
+	}
+}
+
+// align computes an edit script turning a into b based on their longest common subsequence
+func align(a, b []ast.Node) []edit {
+	var script []edit
+
+	// common prefix and suffix are cheap to find and usually the bulk of the items
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && ast.Equal(a[prefix], b[prefix]) {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && ast.Equal(a[len(a)-1-suffix], b[len(b)-1-suffix]) {
+		suffix++
+	}
+	for k := 0; k < prefix; k++ {
+		script = append(script, editKeep)
+	}
+
+	x, y := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if len(x)*len(y) > maxLCSCells {
+		script = append(script, alignByIndex(len(x), len(y))...)
+	} else {
+		script = append(script, alignLCS(x, y)...)
+	}
+
+	for k := 0; k < suffix; k++ {
+		script = append(script, editKeep)
+	}
+	return script
+}
+
+func alignByIndex(n, m int) []edit {
+	var script []edit
+	for k := 0; k < n; k++ {
+		script = append(script, editRemove)
+	}
+	for k := 0; k < m; k++ {
+		script = append(script, editAdd)
+	}
+	return script
+}
+
+func alignLCS(a, b []ast.Node) []edit {
+	n, m := len(a), len(b)
+
+	// lengths[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lengths := make([][]int, n+1)
+	for i := range lengths {
+		lengths[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if ast.Equal(a[i], b[j]) {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+
+	var script []edit
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case ast.Equal(a[i], b[j]):
+			script = append(script, editKeep)
+			i, j = i+1, j+1
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			script = append(script, editRemove)
+			i++
+		default:
+			script = append(script, editAdd)
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		script = append(script, editRemove)
+	}
+	for ; j < m; j++ {
+		script = append(script, editAdd)
+	}
+	return script
+}
+
+func lookup(properties []*ast.Property, name string) (ast.Node, bool) {
+	for _, p := range properties {
+		if p.Name == name {
+			return p.Value, true
+		}
+	}
+	return nil, false
+}
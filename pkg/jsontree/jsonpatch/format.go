@@ -0,0 +1,73 @@
+package jsonpatch
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/trichner/toolbox/pkg/jsontree/ast"
+)
+
+const (
+	colorReset  = "\033[0m"
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+	colorCyan   = "\033[36m"
+)
+
+// maxValueLength truncates long values in the human-readable form
+const maxValueLength = 120
+
+// Format writes the patch in a human-readable form, one line per operation, e.g.
+//
+//	~ /items/0/price: 8.95 → 9.95
+//	+ /items/2: {"id":3}
+//	- /items/4: {"id":5}
+func Format(w io.Writer, patch Patch, color bool) error {
+	for _, op := range patch {
+		var line, c string
+		switch op.Op {
+		case OpAdd:
+			line, c = fmt.Sprintf("+ %s: %s", op.Path, formatValue(op.Value)), colorGreen
+		case OpRemove:
+			line, c = fmt.Sprintf("- %s", op.Path), colorRed
+			if op.Old != nil {
+				line += ": " + formatValue(op.Old)
+			}
+		case OpReplace:
+			line, c = fmt.Sprintf("~ %s: ", op.Path), colorYellow
+			if op.Old != nil {
+				line += formatValue(op.Old) + " → "
+			}
+			line += formatValue(op.Value)
+		case OpMove, OpCopy:
+			line, c = fmt.Sprintf("> %s %s → %s", op.Op, op.From, op.Path), colorCyan
+		case OpTest:
+			line, c = fmt.Sprintf("? %s: %s", op.Path, formatValue(op.Value)), colorCyan
+		}
+
+		if color {
+			line = c + line + colorReset
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func formatValue(n ast.Node) string {
+	if n == nil {
+		return "null"
+	}
+	b, err := n.MarshalJSON()
+	if err != nil {
+		return fmt.Sprintf("<%s>", n.Type())
+	}
+
+	s := []rune(string(b))
+	if len(s) > maxValueLength {
+		return string(s[:maxValueLength-1]) + "…"
+	}
+	return string(s)
+}
@@ -0,0 +1,206 @@
+package jsonpatch
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trichner/toolbox/pkg/jsontree"
+	"github.com/trichner/toolbox/pkg/jsontree/ast"
+	"github.com/trichner/toolbox/pkg/jsontree/lexer"
+)
+
+func parse(t *testing.T, raw string) ast.Node {
+	n, err := jsontree.Parse(lexer.NewLexer(strings.NewReader(raw)))
+	require.NoError(t, err)
+	return n
+}
+
+func marshal(t *testing.T, n interface{ MarshalJSON() ([]byte, error) }) string {
+	b, err := n.MarshalJSON()
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to string
+		expected string
+	}{
+		{"equal", `{"a": [1, {"b": 1.0}]}`, `{"a": [1, {"b": 1}]}`, `[]`},
+		{"replace root", `1`, `"x"`, `[{"op":"replace","path":"","value":"x"}]`},
+		{"properties", `{"a": 1, "b": 2, "c/d": 3}`, `{"b": 3, "c/d": 3, "e~": 4}`,
+			`[{"op":"remove","path":"/a"},{"op":"replace","path":"/b","value":3},{"op":"add","path":"/e~0","value":4}]`},
+		{"insert item", `[1, 2, 3, 4]`, `[1, 2, 9, 3, 4]`, `[{"op":"add","path":"/2","value":9}]`},
+		{"remove items", `[1, 2, 3, 4]`, `[2, 4]`, `[{"op":"remove","path":"/0"},{"op":"remove","path":"/1"}]`},
+		{"change item in place", `[{"id": 1, "v": "a"}, {"id": 2, "v": "b"}]`, `[{"id": 1, "v": "a"}, {"id": 2, "v": "c"}]`,
+			`[{"op":"replace","path":"/1/v","value":"c"}]`},
+		{"type change", `{"a": [1]}`, `{"a": {"0": 1}}`, `[{"op":"replace","path":"/a","value":{"0":1}}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to := parse(t, tt.from), parse(t, tt.to)
+			patch := Diff(from, to)
+			if len(patch) == 0 {
+				assert.Equal(t, tt.expected, "[]")
+				return
+			}
+			assert.Equal(t, tt.expected, marshal(t, patch))
+
+			patched, err := Apply(from, patch)
+			require.NoError(t, err)
+			assert.True(t, ast.Equal(to, patched), marshal(t, patched))
+		})
+	}
+}
+
+func TestDiff_RoundTrip(t *testing.T) {
+	pairs := [][2]string{
+		{`[1, 2, 3, 4, 5, 6]`, `[6, 5, 4, 3, 2, 1]`},
+		{`[[1, 2], [3], {"a": [4]}]`, `[[1], {"a": [4, 5]}, [3, 0], 7]`},
+		{`{"a": {"b": {"c": [1, 2, {"d": null}]}}, "x": true}`, `{"x": false, "a": {"b": {"c": [2, {"d": 0}]}}}`},
+		{`[]`, `[1, [2], {}]`},
+		{`{"a": [1, 2, 3]}`, `{}`},
+	}
+	for _, p := range pairs {
+		from, to := parse(t, p[0]), parse(t, p[1])
+		patched, err := Apply(from, Diff(from, to))
+		require.NoError(t, err)
+		assert.True(t, ast.Equal(to, patched), p[0]+" → "+p[1])
+	}
+}
+
+// TestApply covers examples of RFC 6902, appendix A
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name          string
+		doc, patch    string
+		expected      string
+		expectedError string
+	}{
+		{"add member", `{"foo": "bar"}`, `[{"op": "add", "path": "/baz", "value": "qux"}]`, `{"foo":"bar","baz":"qux"}`, ""},
+		{"add item", `{"foo": ["bar", "baz"]}`, `[{"op": "add", "path": "/foo/1", "value": "qux"}]`, `{"foo":["bar","qux","baz"]}`, ""},
+		{"append item", `[1]`, `[{"op": "add", "path": "/-", "value": 2}]`, `[1,2]`, ""},
+		{"remove member", `{"baz": "qux", "foo": "bar"}`, `[{"op": "remove", "path": "/baz"}]`, `{"foo":"bar"}`, ""},
+		{"remove item", `{"foo": ["bar", "qux", "baz"]}`, `[{"op": "remove", "path": "/foo/1"}]`, `{"foo":["bar","baz"]}`, ""},
+		{"replace", `{"baz": "qux", "foo": "bar"}`, `[{"op": "replace", "path": "/baz", "value": "boo"}]`, `{"baz":"boo","foo":"bar"}`, ""},
+		{"move member", `{"foo": {"bar": "baz", "waldo": "fred"}, "qux": {"corge": "grault"}}`,
+			`[{"op": "move", "from": "/foo/waldo", "path": "/qux/thud"}]`,
+			`{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`, ""},
+		{"move item", `{"foo": ["all", "grass", "cows", "eat"]}`, `[{"op": "move", "from": "/foo/1", "path": "/foo/3"}]`,
+			`{"foo":["all","cows","eat","grass"]}`, ""},
+		{"copy", `{"a": {"b": 1}}`, `[{"op": "copy", "from": "/a", "path": "/c"}]`, `{"a":{"b":1},"c":{"b":1}}`, ""},
+		{"test", `{"baz": "qux", "foo": ["a", 2, "c"]}`,
+			`[{"op": "test", "path": "/baz", "value": "qux"}, {"op": "test", "path": "/foo/1", "value": 2.0}]`,
+			`{"baz":"qux","foo":["a",2,"c"]}`, ""},
+		{"escaped pointer", `{"/": 9, "~1": 10}`, `[{"op": "test", "path": "/~01", "value": 10}, {"op": "remove", "path": "/~1"}]`, `{"~1":10}`, ""},
+		{"test fails", `{"baz": "qux"}`, `[{"op": "test", "path": "/baz", "value": "bar"}]`, "", "test failed"},
+		{"missing parent", `{"foo": "bar"}`, `[{"op": "add", "path": "/baz/bat", "value": "qux"}]`, "", `property "baz" not found`},
+		{"leading zero", `[1, 2]`, `[{"op": "remove", "path": "/01"}]`, "", `invalid array index: "01"`},
+		{"out of bounds", `[1, 2]`, `[{"op": "add", "path": "/3", "value": 0}]`, "", "out of bounds"},
+		{"move into itself", `{"a": {"b": {}}}`, `[{"op": "move", "from": "/a", "path": "/a/b/c"}]`, "", "into itself"},
+		{"atomic", `{"a": 1}`, `[{"op": "remove", "path": "/a"}, {"op": "remove", "path": "/a"}]`, "", "operation 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := parse(t, tt.doc)
+			patch, err := ParsePatch(parse(t, tt.patch))
+			require.NoError(t, err)
+
+			patched, err := Apply(doc, patch)
+			if tt.expectedError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, marshal(t, patched))
+		})
+	}
+}
+
+func TestApply_MissingValue(t *testing.T) {
+	doc := ast.NewObject().Set("a", nil)
+	for _, path := range []string{"/a/b", "/a/b/c"} {
+		for _, op := range []Op{OpAdd, OpRemove, OpReplace} {
+			_, err := Apply(doc, Patch{{Op: op, Path: path, Value: ast.NewNullNode()}})
+			assert.ErrorContains(t, err, "a missing value", "%s %s", op, path)
+		}
+	}
+}
+
+func TestParsePatch_Invalid(t *testing.T) {
+	for _, raw := range []string{
+		`{}`,
+		`[1]`,
+		`[{"op": "jump", "path": "/a"}]`,
+		`[{"op": "add", "path": "/a"}]`,
+		`[{"op": "remove"}]`,
+		`[{"op": "remove", "path": "a"}]`,
+		`[{"op": "remove", "path": "/a~2"}]`,
+		`[{"op": "move", "from": 1, "path": "/a"}]`,
+	} {
+		_, err := ParsePatch(parse(t, raw))
+		assert.Error(t, err, raw)
+	}
+}
+
+// TestMergePatch covers the examples of RFC 7396, appendix A
+func TestMergePatch(t *testing.T) {
+	tests := [][3]string{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for _, tt := range tests {
+		merged := MergePatch(parse(t, tt[0]), parse(t, tt[1]))
+		assert.Equal(t, tt[2], marshal(t, merged), tt[0]+" + "+tt[1])
+	}
+}
+
+func TestFormat(t *testing.T) {
+	patch := Diff(parse(t, `{"a": 1, "b": [1, 2], "c": "x"}`), parse(t, `{"a": 2, "b": [1, 2, 3]}`))
+
+	var out bytes.Buffer
+	require.NoError(t, Format(&out, patch, false))
+	assert.Equal(t, "~ /a: 1 → 2\n+ /b/2: 3\n- /c: \"x\"\n", out.String())
+
+	out.Reset()
+	require.NoError(t, Format(&out, patch[:1], true))
+	assert.Equal(t, "\033[33m~ /a: 1 → 2\033[0m\n", out.String())
+}
+
+func TestIndexBy(t *testing.T) {
+	yesterday := []ast.Node{parse(t, `{"id": 1, "v": "a"}`), parse(t, `{"id": 2, "v": "b"}`), parse(t, `{"id": 3, "v": "c"}`)}
+	today := []ast.Node{parse(t, `{"id": 3, "v": "c"}`), parse(t, `{"id": 1, "v": "A"}`), parse(t, `{"id": 4, "v": "d"}`)}
+
+	from, err := IndexBy(yesterday, "id")
+	require.NoError(t, err)
+	to, err := IndexBy(today, "id")
+	require.NoError(t, err)
+
+	assert.Equal(t, `[{"op":"replace","path":"/1/v","value":"A"},{"op":"remove","path":"/2"},{"op":"add","path":"/4","value":{"id":4,"v":"d"}}]`,
+		marshal(t, Diff(from, to)))
+
+	_, err = IndexBy([]ast.Node{parse(t, `{"id": 1}`), parse(t, `{"id": 1}`)}, "id")
+	assert.ErrorContains(t, err, "duplicate key")
+	_, err = IndexBy([]ast.Node{parse(t, `{"id": null}`)}, "id")
+	assert.Error(t, err)
+	_, err = IndexBy([]ast.Node{parse(t, `{"name": "x"}`)}, "id")
+	assert.Error(t, err)
+}
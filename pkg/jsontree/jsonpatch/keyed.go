@@ -0,0 +1,43 @@
+package jsonpatch
+
+import (
+	"fmt"
+
+	"github.com/trichner/toolbox/pkg/jsontree/ast"
+)
+
+// IndexBy turns a list of records, e.g. read from NDJSON, into an object of the records keyed by the value of
+// their key property. Diffing indexed lists pairs records by key rather than by position, hence the paths of
+// the patch start with the key, e.g. /42/price. Keys must be unique texts or numbers.
+func IndexBy(records []ast.Node, key string) (ast.ObjectNode, error) {
+	properties := make([]*ast.Property, 0, len(records))
+	seen := make(map[string]bool, len(records))
+	for i, r := range records {
+		obj, ok := r.(ast.ObjectNode)
+		if !ok {
+			return nil, fmt.Errorf("record %d is not an object: %s", i, r.Type())
+		}
+
+		v, ok := lookup(obj.Properties(), key)
+		if !ok {
+			return nil, fmt.Errorf("record %d has no key %q", i, key)
+		}
+
+		var id string
+		switch typed := v.(type) {
+		case ast.TextNode:
+			id = typed.Value()
+		case ast.NumberNode:
+			id = typed.Value()
+		default:
+			return nil, fmt.Errorf("key %q of record %d must be a text or number but got: %s", key, i, v.Type())
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("duplicate key %q in record %d", id, i)
+		}
+		seen[id] = true
+
+		properties = append(properties, &ast.Property{Name: id, Value: r})
+	}
+	return ast.NewObjectNode(properties), nil
+}
@@ -0,0 +1,26 @@
+package jsonpatch
+
+import "github.com/trichner/toolbox/pkg/jsontree/ast"
+
+// MergePatch applies a JSON Merge Patch as of RFC 7396, properties set to null in the patch are removed. New
+// properties are appended, existing ones keep their position.
+func MergePatch(target, patch ast.Node) ast.Node {
+	p, ok := patch.(ast.ObjectNode)
+	if !ok {
+		return patch
+	}
+
+	obj, ok := target.(ast.ObjectNode)
+	if !ok {
+		obj = ast.NewObjectNode(nil)
+	}
+	for _, prop := range p.Properties() {
+		if prop.Value.Type() == ast.NodeTypeNull {
+			obj = deleteProperty(obj, prop.Name)
+			continue
+		}
+		current, _ := lookup(obj.Properties(), prop.Name)
+		obj = setProperty(obj, prop.Name, MergePatch(current, prop.Value))
+	}
+	return obj
+}
@@ -0,0 +1,137 @@
+// Package jsonpatch computes structural differences between JSON trees as JSON Patch (RFC 6902) and applies
+// JSON Patch as well as JSON Merge Patch (RFC 7396) documents.
+package jsonpatch
+
+import (
+	"fmt"
+
+	"github.com/trichner/toolbox/pkg/jsontree"
+	"github.com/trichner/toolbox/pkg/jsontree/ast"
+)
+
+type Op string
+
+const (
+	OpAdd     Op = "add"
+	OpRemove  Op = "remove"
+	OpReplace Op = "replace"
+	OpMove    Op = "move"
+	OpCopy    Op = "copy"
+	OpTest    Op = "test"
+)
+
+// Operation is a single operation of a JSON Patch, paths are JSON Pointers as of RFC 6901
+type Operation struct {
+	Op    Op
+	Path  string
+	From  string
+	Value ast.Node
+
+	// Old is the removed or replaced value, it is set by Diff for display but is no part of the patch
+	Old ast.Node
+}
+
+// Patch is a JSON Patch, its operations apply in order
+type Patch []Operation
+
+func (p Patch) MarshalJSON() ([]byte, error) {
+	return p.Node().MarshalJSON()
+}
+
+// Node converts the patch to its JSON representation
+func (p Patch) Node() ast.Node {
+	items := make([]ast.Node, len(p))
+	for i, op := range p {
+		items[i] = op.Node()
+	}
+	return ast.NewArrayNode(items)
+}
+
+func (o Operation) MarshalJSON() ([]byte, error) {
+	return o.Node().MarshalJSON()
+}
+
+// Node converts the operation to its JSON representation
+func (o Operation) Node() ast.Node {
+	properties := []*ast.Property{{Name: "op", Value: ast.NewTextNode(string(o.Op))}}
+	if o.Op == OpMove || o.Op == OpCopy {
+		properties = append(properties, &ast.Property{Name: "from", Value: ast.NewTextNode(o.From)})
+	}
+	properties = append(properties, &ast.Property{Name: "path", Value: ast.NewTextNode(o.Path)})
+	if o.Op == OpAdd || o.Op == OpReplace || o.Op == OpTest {
+		value := o.Value
+		if value == nil {
+			value = ast.NewNullNode()
+		}
+		properties = append(properties, &ast.Property{Name: "value", Value: value})
+	}
+	return ast.NewObjectNode(properties)
+}
+
+// ParsePatch reads a JSON Patch document, i.e. an array of operations
+func ParsePatch(n ast.Node) (Patch, error) {
+	arr, ok := n.(ast.ArrayNode)
+	if !ok {
+		return nil, fmt.Errorf("a JSON patch must be an array but got: %s", n.Type())
+	}
+
+	patch := make(Patch, 0, len(arr.Items()))
+	for i, item := range arr.Items() {
+		op, err := parseOperation(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid operation %d: %w", i, err)
+		}
+		patch = append(patch, op)
+	}
+	return patch, nil
+}
+
+func parseOperation(n ast.Node) (Operation, error) {
+	obj, ok := n.(ast.ObjectNode)
+	if !ok {
+		return Operation{}, fmt.Errorf("expected an object but got: %s", n.Type())
+	}
+
+	var op Operation
+	var hasPath, hasValue bool
+	for _, p := range obj.Properties() {
+		switch p.Name {
+		case "op", "path", "from":
+			text, ok := p.Value.(ast.TextNode)
+			if !ok {
+				return Operation{}, fmt.Errorf("%q must be a string", p.Name)
+			}
+			switch p.Name {
+			case "op":
+				op.Op = Op(text.Value())
+			case "path":
+				op.Path, hasPath = text.Value(), true
+			case "from":
+				op.From = text.Value()
+			}
+		case "value":
+			op.Value, hasValue = p.Value, true
+		}
+	}
+
+	switch op.Op {
+	case OpAdd, OpReplace, OpTest:
+		if !hasValue {
+			return Operation{}, fmt.Errorf("%q requires a value", op.Op)
+		}
+	case OpMove, OpCopy:
+		if _, err := jsontree.ParsePointer(op.From); err != nil {
+			return Operation{}, err
+		}
+	case OpRemove:
+	default:
+		return Operation{}, fmt.Errorf("unknown op: %q", op.Op)
+	}
+	if !hasPath {
+		return Operation{}, fmt.Errorf("%q requires a path", op.Op)
+	}
+	if _, err := jsontree.ParsePointer(op.Path); err != nil {
+		return Operation{}, err
+	}
+	return op, nil
+}
@@ -0,0 +1,142 @@
+package jsonpath
+
+import (
+	"github.com/trichner/toolbox/pkg/jsontree"
+	"github.com/trichner/toolbox/pkg/jsontree/ast"
+)
+
+// filterExpr is the logical expression of a filter selector, tested against each child of the filtered node
+type filterExpr interface {
+	test(root, current ast.Node) bool
+}
+
+type orExpr struct {
+	left, right filterExpr
+}
+
+func (e orExpr) test(root, current ast.Node) bool {
+	return e.left.test(root, current) || e.right.test(root, current)
+}
+
+type andExpr struct {
+	left, right filterExpr
+}
+
+func (e andExpr) test(root, current ast.Node) bool {
+	return e.left.test(root, current) && e.right.test(root, current)
+}
+
+type notExpr struct {
+	expr filterExpr
+}
+
+func (e notExpr) test(root, current ast.Node) bool {
+	return !e.expr.test(root, current)
+}
+
+// existsExpr is true if the query selects at least one node, e.g. [?@.email]
+type existsExpr struct {
+	query *subquery
+}
+
+func (e existsExpr) test(root, current ast.Node) bool {
+	return len(e.query.find(root, current)) > 0
+}
+
+type comparisonOp string
+
+const (
+	opEqual          comparisonOp = "=="
+	opNotEqual       comparisonOp = "!="
+	opLess           comparisonOp = "<"
+	opLessOrEqual    comparisonOp = "<="
+	opGreater        comparisonOp = ">"
+	opGreaterOrEqual comparisonOp = ">="
+)
+
+// comparisonOps is ordered such that no operator is shadowed by a prefix of it
+var comparisonOps = []comparisonOp{opEqual, opNotEqual, opLessOrEqual, opGreaterOrEqual, opLess, opGreater}
+
+type comparisonExpr struct {
+	op          comparisonOp
+	left, right operand
+}
+
+func (e comparisonExpr) test(root, current ast.Node) bool {
+	left, right := e.left.value(root, current), e.right.value(root, current)
+	switch e.op {
+	case opEqual:
+		return ast.Equal(left, right)
+	case opNotEqual:
+		return !ast.Equal(left, right)
+	case opLess:
+		return less(left, right)
+	case opLessOrEqual:
+		return less(left, right) || ast.Equal(left, right)
+	case opGreater:
+		return less(right, left)
+	case opGreaterOrEqual:
+		return less(right, left) || ast.Equal(left, right)
+	}
+	return false
+}
+
+// operand is either a literal or a singular query, a nil value means that the query selected nothing
+type operand interface {
+	value(root, current ast.Node) ast.Node
+}
+
+type literal struct {
+	node ast.Node
+}
+
+func (l literal) value(_, _ ast.Node) ast.Node {
+	return l.node
+}
+
+// subquery is a query embedded in a filter, relative ones start at the current node '@' instead of the root
+type subquery struct {
+	relative bool
+	segments []*segment
+}
+
+func (q *subquery) find(root, current ast.Node) []Match {
+	start := root
+	if q.relative {
+		start = current
+	}
+	return evaluate(root, Match{Path: jsontree.Path{}, Node: start}, q.segments)
+}
+
+func (q *subquery) value(root, current ast.Node) ast.Node {
+	matches := q.find(root, current)
+	if len(matches) == 0 {
+		return nil
+	}
+	return matches[0].Node
+}
+
+func (q *subquery) singular() bool {
+	for _, s := range q.segments {
+		if !s.singular() {
+			return false
+		}
+	}
+	return true
+}
+
+// less orders numbers and texts, any other values are not ordered
+func less(a, b ast.Node) bool {
+	if a == nil || b == nil || a.Type() != b.Type() {
+		return false
+	}
+	switch a.Type() {
+	case ast.NodeTypeNumber:
+		cmp, ok := ast.CompareNumbers(a.(ast.NumberNode), b.(ast.NumberNode))
+		return ok && cmp < 0
+	case ast.NodeTypeText:
+		// comparing UTF-8 bytes orders by code points
+		return a.(ast.TextNode).Value() < b.(ast.TextNode).Value()
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+// Package jsonpath selects nodes of a JSON tree with JSONPath expressions as described in RFC 9535, e.g.
+// `$.items[*].user.name`, `$..book[?@.price < 10].title` or `$.rows[-1]`. Function extensions like
+// length() are not supported.
+package jsonpath
+
+import (
+	"fmt"
+
+	"github.com/trichner/toolbox/pkg/jsontree"
+	"github.com/trichner/toolbox/pkg/jsontree/ast"
+)
+
+// Query is a compiled JSONPath expression, it is safe for concurrent use
+type Query struct {
+	expr     string
+	segments []*segment
+}
+
+// Match is a node selected by a query and its location within the queried value
+type Match struct {
+	Path jsontree.Path
+	Node ast.Node
+}
+
+// Error describes an invalid JSONPath expression
+type Error struct {
+	Expr string
+	// Offset is the zero based byte offset of the problem within Expr
+	Offset int
+	Msg    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("invalid jsonpath %q at offset %d: %s", e.Expr, e.Offset, e.Msg)
+}
+
+// Compile parses a JSONPath expression, invalid expressions result in an *Error
+func Compile(expr string) (*Query, error) {
+	p := &parser{expr: expr}
+	segments, err := p.parseQuery()
+	if err != nil {
+		return nil, err
+	}
+	return &Query{expr: expr, segments: segments}, nil
+}
+
+// MustCompile is like Compile but panics if the expression is invalid
+func MustCompile(expr string) *Query {
+	q, err := Compile(expr)
+	if err != nil {
+		panic(err)
+	}
+	return q
+}
+
+func (q *Query) String() string {
+	return q.expr
+}
+
+// Select returns the nodes matching the query in document order, objects keep the order of their properties
+func (q *Query) Select(root ast.Node) []ast.Node {
+	matches := q.Find(root)
+	nodes := make([]ast.Node, len(matches))
+	for i, m := range matches {
+		nodes[i] = m.Node
+	}
+	return nodes
+}
+
+// Find is like Select but also returns where the nodes are located
+func (q *Query) Find(root ast.Node) []Match {
+	return evaluate(root, Match{Path: jsontree.Path{}, Node: root}, q.segments)
+}
+
+func evaluate(root ast.Node, start Match, segments []*segment) []Match {
+	matches := []Match{start}
+	for _, s := range segments {
+		var next []Match
+		for _, m := range matches {
+			s.apply(root, m, func(m Match) {
+				next = append(next, m)
+			})
+		}
+		matches = next
+	}
+	return matches
+}
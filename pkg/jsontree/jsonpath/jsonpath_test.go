@@ -0,0 +1,193 @@
+package jsonpath
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trichner/toolbox/pkg/jsontree"
+	"github.com/trichner/toolbox/pkg/jsontree/ast"
+	"github.com/trichner/toolbox/pkg/jsontree/lexer"
+)
+
+const store = `{
+	"store": {
+		"book": [
+			{"category": "reference", "author": "Nigel Rees", "title": "Sayings of the Century", "price": 8.95},
+			{"category": "fiction", "author": "Evelyn Waugh", "title": "Sword of Honour", "price": 12.99},
+			{"category": "fiction", "author": "Herman Melville", "title": "Moby Dick", "isbn": "0-553-21311-3", "price": 8.99},
+			{"category": "fiction", "author": "J. R. R. Tolkien", "title": "The Lord of the Rings", "isbn": "0-395-19395-8", "price": 22.99}
+		],
+		"bicycle": {"color": "red", "price": 399}
+	}
+}`
+
+func TestQuery_Select(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected []string
+	}{
+		{`$`, []string{`{"a":[1,2,{"b c":true}],"z":null}`}},
+		{`$.a`, []string{`[1,2,{"b c":true}]`}},
+		{`$.a[*]`, []string{`1`, `2`, `{"b c":true}`}},
+		{`$.a[2]["b c"]`, []string{`true`}},
+		{`$['a'][-1]['b c']`, []string{`true`}},
+		{`$.a[5]`, nil},
+		{`$.*`, []string{`[1,2,{"b c":true}]`, `null`}},
+		{`$.a[0,2,0]`, []string{`1`, `{"b c":true}`, `1`}},
+		{`$.a[1:]`, []string{`2`, `{"b c":true}`}},
+		{`$.a[::-1]`, []string{`{"b c":true}`, `2`, `1`}},
+		{`$.a[:-1]`, []string{`1`, `2`}},
+		{`$.a[0:3:2]`, []string{`1`, `{"b c":true}`}},
+		{`$.a[::0]`, nil},
+		{`$..*`, []string{`[1,2,{"b c":true}]`, `null`, `1`, `2`, `{"b c":true}`, `true`}},
+		{`$..["b c"]`, []string{`true`}},
+		{`$.z.a`, nil},
+	}
+	root := parse(t, `{"a": [1, 2, {"b c": true}], "z": null}`)
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			q, err := Compile(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, marshal(t, q.Select(root)))
+		})
+	}
+}
+
+func TestQuery_Select_Store(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected []string
+	}{
+		{`$.store.book[*].author`, []string{`"Nigel Rees"`, `"Evelyn Waugh"`, `"Herman Melville"`, `"J. R. R. Tolkien"`}},
+		{`$..author`, []string{`"Nigel Rees"`, `"Evelyn Waugh"`, `"Herman Melville"`, `"J. R. R. Tolkien"`}},
+		{`$.store..price`, []string{`8.95`, `12.99`, `8.99`, `22.99`, `399`}},
+		{`$..book[2].title`, []string{`"Moby Dick"`}},
+		{`$..book[-1:].title`, []string{`"The Lord of the Rings"`}},
+		{`$..book[?@.isbn].title`, []string{`"Moby Dick"`, `"The Lord of the Rings"`}},
+		{`$..book[?(@.price < 10)].title`, []string{`"Sayings of the Century"`, `"Moby Dick"`}},
+		{`$..book[?@.price >= 12.99 && @.category == 'fiction'].title`, []string{`"Sword of Honour"`, `"The Lord of the Rings"`}},
+		{`$..book[?!@.isbn || @.price == 8.99E0].title`, []string{`"Sayings of the Century"`, `"Sword of Honour"`, `"Moby Dick"`}},
+		{`$..book[?@.author != "Nigel Rees" && !(@.price > 10)].title`, []string{`"Moby Dick"`}},
+		{`$..book[?@.price > $.store.bicycle.price]`, nil},
+		{`$..book[?@.missing == $.nothing].title`, []string{`"Sayings of the Century"`, `"Sword of Honour"`, `"Moby Dick"`, `"The Lord of the Rings"`}},
+		{`$.store[?@.color == "red"]`, []string{`{"color":"red","price":399}`}},
+		{`$..[?@.price > 100].color`, []string{`"red"`}},
+		{`$.store.book[?@.title > 'S'].author`, []string{`"Nigel Rees"`, `"Evelyn Waugh"`, `"J. R. R. Tolkien"`}},
+	}
+	root := parse(t, store)
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			q, err := Compile(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, marshal(t, q.Select(root)))
+		})
+	}
+}
+
+func TestQuery_Select_KeepsOrder(t *testing.T) {
+	root := parse(t, `{"items": [{"z": 1, "a": 2, "m": {"y": 3, "b": 4}}]}`)
+
+	nodes := MustCompile(`$.items[*]`).Select(root)
+	assert.Equal(t, []string{`{"z":1,"a":2,"m":{"y":3,"b":4}}`}, marshal(t, nodes))
+}
+
+func TestQuery_Select_Equality(t *testing.T) {
+	root := parse(t, `[{"v": {"a": 1, "b": [true, null]}}, {"v": 100}, {"v": 1e2}, {"v": "1e2"}]`)
+
+	nodes := MustCompile(`$[?@.v == 1.00e2]`).Select(root)
+	assert.Equal(t, []string{`{"v":100}`, `{"v":1e2}`}, marshal(t, nodes))
+
+	nodes = MustCompile(`$[?@.v == $[0].v]`).Select(root)
+	assert.Equal(t, []string{`{"v":{"a":1,"b":[true,null]}}`}, marshal(t, nodes))
+}
+
+func TestQuery_Find(t *testing.T) {
+	root := parse(t, store)
+
+	matches := MustCompile(`$..book[?@.price > 20]["title", "price"]`).Find(root)
+	require.Len(t, matches, 2)
+	assert.Equal(t, `$.store.book[3].title`, matches[0].Path.String())
+	assert.Equal(t, `$.store.book[3].price`, matches[1].Path.String())
+
+	// paths can be used as queries again
+	for _, m := range MustCompile(`$..*`).Find(root) {
+		found := MustCompile(m.Path.String()).Select(root)
+		require.Len(t, found, 1, m.Path.String())
+		assert.Same(t, m.Node, found[0])
+	}
+}
+
+func TestCompile_Strings(t *testing.T) {
+	root := parse(t, `{"it's": 1, "say \"hi\"": 2, "tab\there": 3, "😀": 4}`)
+
+	tests := map[string]string{
+		`$['it\'s']`:         `1`,
+		`$["it's"]`:          `1`,
+		`$['say "hi"']`:      `2`,
+		`$["say \"hi\""]`:    `2`,
+		`$["tab\there"]`:     `3`,
+		`$["tab\u0009here"]`: `3`,
+		`$["😀"]`:             `4`,
+		`$.😀`:                `4`,
+	}
+	for expr, expected := range tests {
+		t.Run(expr, func(t *testing.T) {
+			q, err := Compile(expr)
+			require.NoError(t, err)
+			assert.Equal(t, []string{expected}, marshal(t, q.Select(root)))
+		})
+	}
+}
+
+func TestCompile_Invalid(t *testing.T) {
+	tests := []struct {
+		expr   string
+		offset int
+	}{
+		{``, 0},
+		{`a`, 0},
+		{`$.`, 2},
+		{`$.1a`, 2},
+		{`$[`, 2},
+		{`$[1`, 3},
+		{`$[01]`, 2},
+		{`$[-0]`, 2},
+		{`$['a`, 4},
+		{`$['\x']`, 4},
+		{`$[?@.a ==]`, 9},
+		{`$[?1]`, 3},
+		{`$[?@.* == 1]`, 3},
+		{`$[?@.a == @..b]`, 10},
+		{`$[?(@.a]`, 7},
+		{`$[?@.a == 1.]`, 10},
+		{`$.a b`, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := Compile(tt.expr)
+			var perr *Error
+			require.ErrorAs(t, err, &perr)
+			assert.Equal(t, tt.offset, perr.Offset, perr.Error())
+		})
+	}
+}
+
+func parse(t *testing.T, raw string) ast.Node {
+	n, err := jsontree.Parse(lexer.NewLexer(strings.NewReader(raw)))
+	require.NoError(t, err)
+	return n
+}
+
+func marshal(t *testing.T, nodes []ast.Node) []string {
+	var values []string
+	for _, n := range nodes {
+		b, err := n.MarshalJSON()
+		require.NoError(t, err)
+		values = append(values, string(b))
+	}
+	return values
+}
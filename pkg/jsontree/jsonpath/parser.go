@@ -0,0 +1,482 @@
+package jsonpath
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/trichner/toolbox/pkg/jsontree/ast"
+)
+
+var numberPattern = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// parser is a recursive descent parser for the JSONPath grammar of RFC 9535
+type parser struct {
+	expr string
+	pos  int
+}
+
+func (p *parser) parseQuery() ([]*segment, error) {
+	p.skipWhitespace()
+	if !p.consume("$") {
+		return nil, p.errorf("expected '$' at the start of the query")
+	}
+	segments, err := p.parseSegments()
+	if err != nil {
+		return nil, err
+	}
+	p.skipWhitespace()
+	if !p.eof() {
+		return nil, p.errorf("unexpected %q", p.peek())
+	}
+	return segments, nil
+}
+
+func (p *parser) parseSegments() ([]*segment, error) {
+	var segments []*segment
+	for {
+		start := p.pos
+		p.skipWhitespace()
+		if !p.lookingAt(".") && !p.lookingAt("[") {
+			p.pos = start
+			return segments, nil
+		}
+
+		s, err := p.parseSegment()
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, s)
+	}
+}
+
+func (p *parser) parseSegment() (*segment, error) {
+	if p.consume("..") {
+		s := &segment{descendant: true}
+		if p.lookingAt("[") {
+			selectors, err := p.parseBracket()
+			if err != nil {
+				return nil, err
+			}
+			s.selectors = selectors
+			return s, nil
+		}
+		sel, err := p.parseShorthand()
+		if err != nil {
+			return nil, err
+		}
+		s.selectors = []selector{sel}
+		return s, nil
+	}
+
+	if p.consume(".") {
+		sel, err := p.parseShorthand()
+		if err != nil {
+			return nil, err
+		}
+		return &segment{selectors: []selector{sel}}, nil
+	}
+
+	selectors, err := p.parseBracket()
+	if err != nil {
+		return nil, err
+	}
+	return &segment{selectors: selectors}, nil
+}
+
+// parseShorthand parses the wildcard or member name following a dot, e.g. .* or .name
+func (p *parser) parseShorthand() (selector, error) {
+	if p.consume("*") {
+		return wildcardSelector{}, nil
+	}
+
+	start := p.pos
+	for !p.eof() {
+		r, size := utf8.DecodeRuneInString(p.expr[p.pos:])
+		if !isNameChar(r) || (p.pos == start && r >= '0' && r <= '9') {
+			break
+		}
+		p.pos += size
+	}
+	if p.pos == start {
+		return nil, p.errorf("expected a property name or '*'")
+	}
+	return nameSelector{name: p.expr[start:p.pos]}, nil
+}
+
+func isNameChar(r rune) bool {
+	return r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') ||
+		(r >= 0x80 && r != utf8.RuneError)
+}
+
+func (p *parser) parseBracket() ([]selector, error) {
+	if !p.consume("[") {
+		return nil, p.errorf("expected '['")
+	}
+
+	var selectors []selector
+	for {
+		p.skipWhitespace()
+		sel, err := p.parseSelector()
+		if err != nil {
+			return nil, err
+		}
+		selectors = append(selectors, sel)
+
+		p.skipWhitespace()
+		if p.consume("]") {
+			return selectors, nil
+		}
+		if !p.consume(",") {
+			return nil, p.errorf("expected ',' or ']'")
+		}
+	}
+}
+
+func (p *parser) parseSelector() (selector, error) {
+	switch c := p.peek(); {
+	case c == '\'' || c == '"':
+		name, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+		return nameSelector{name: name}, nil
+	case c == '*':
+		p.pos++
+		return wildcardSelector{}, nil
+	case c == '?':
+		p.pos++
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return filterSelector{expr: expr}, nil
+	case c == '-' || c == ':' || (c >= '0' && c <= '9'):
+		return p.parseIndexOrSlice()
+	}
+	return nil, p.errorf("expected a selector")
+}
+
+func (p *parser) parseIndexOrSlice() (selector, error) {
+	start, err := p.parseOptionalInt()
+	if err != nil {
+		return nil, err
+	}
+	p.skipWhitespace()
+	if !p.consume(":") {
+		if start == nil {
+			return nil, p.errorf("expected an index")
+		}
+		return indexSelector{index: *start}, nil
+	}
+
+	p.skipWhitespace()
+	end, err := p.parseOptionalInt()
+	if err != nil {
+		return nil, err
+	}
+
+	step := 1
+	p.skipWhitespace()
+	if p.consume(":") {
+		p.skipWhitespace()
+		s, err := p.parseOptionalInt()
+		if err != nil {
+			return nil, err
+		}
+		if s != nil {
+			step = *s
+		}
+	}
+	return sliceSelector{start: start, end: end, step: step}, nil
+}
+
+func (p *parser) parseOptionalInt() (*int, error) {
+	start := p.pos
+	p.consume("-")
+	digits := p.pos
+	for !p.eof() && p.peek() >= '0' && p.peek() <= '9' {
+		p.pos++
+	}
+	if p.pos == digits {
+		if p.pos != start {
+			return nil, p.errorf("expected a digit")
+		}
+		return nil, nil
+	}
+
+	literal := p.expr[start:p.pos]
+	if (p.expr[digits] == '0' && p.pos-digits > 1) || literal == "-0" {
+		return nil, &Error{Expr: p.expr, Offset: start, Msg: fmt.Sprintf("invalid integer %q", literal)}
+	}
+	i, err := strconv.Atoi(literal)
+	if err != nil {
+		return nil, &Error{Expr: p.expr, Offset: start, Msg: fmt.Sprintf("invalid integer %q", literal)}
+	}
+	return &i, nil
+}
+
+func (p *parser) parseOr() (filterExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		p.skipWhitespace()
+		if !p.consume("||") {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orExpr{left: left, right: right}
+	}
+}
+
+func (p *parser) parseAnd() (filterExpr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		p.skipWhitespace()
+		if !p.consume("&&") {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andExpr{left: left, right: right}
+	}
+}
+
+func (p *parser) parseUnary() (filterExpr, error) {
+	p.skipWhitespace()
+	if p.lookingAt("!") && !p.lookingAt("!=") {
+		p.pos++
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{expr: expr}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (filterExpr, error) {
+	if p.consume("(") {
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		p.skipWhitespace()
+		if !p.consume(")") {
+			return nil, p.errorf("expected ')'")
+		}
+		return expr, nil
+	}
+
+	start := p.pos
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipWhitespace()
+	op, ok := p.parseComparisonOp()
+	if !ok {
+		q, isQuery := left.(*subquery)
+		if !isQuery {
+			return nil, &Error{Expr: p.expr, Offset: start, Msg: "expected a query or a comparison"}
+		}
+		return existsExpr{query: q}, nil
+	}
+
+	p.skipWhitespace()
+	rightStart := p.pos
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	if q, ok := left.(*subquery); ok && !q.singular() {
+		return nil, &Error{Expr: p.expr, Offset: start, Msg: "only queries selecting a single node can be compared"}
+	}
+	if q, ok := right.(*subquery); ok && !q.singular() {
+		return nil, &Error{Expr: p.expr, Offset: rightStart, Msg: "only queries selecting a single node can be compared"}
+	}
+	return comparisonExpr{op: op, left: left, right: right}, nil
+}
+
+func (p *parser) parseComparisonOp() (comparisonOp, bool) {
+	for _, op := range comparisonOps {
+		if p.consume(string(op)) {
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) parseOperand() (operand, error) {
+	switch c := p.peek(); {
+	case c == '@' || c == '$':
+		p.pos++
+		segments, err := p.parseSegments()
+		if err != nil {
+			return nil, err
+		}
+		return &subquery{relative: c == '@', segments: segments}, nil
+	case c == '\'' || c == '"':
+		s, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+		return literal{node: ast.NewTextNode(s)}, nil
+	case c == '-' || (c >= '0' && c <= '9'):
+		return p.parseNumber()
+	case p.consume("true"):
+		return literal{node: ast.NewBooleanNode(true)}, nil
+	case p.consume("false"):
+		return literal{node: ast.NewBooleanNode(false)}, nil
+	case p.consume("null"):
+		return literal{node: ast.NewNullNode()}, nil
+	}
+	return nil, p.errorf("expected a query or a literal")
+}
+
+func (p *parser) parseNumber() (operand, error) {
+	start := p.pos
+	for !p.eof() && strings.IndexByte("0123456789+-.eE", p.peek()) >= 0 {
+		p.pos++
+	}
+	literalValue := p.expr[start:p.pos]
+	if !numberPattern.MatchString(literalValue) {
+		return nil, &Error{Expr: p.expr, Offset: start, Msg: fmt.Sprintf("invalid number %q", literalValue)}
+	}
+	return literal{node: ast.NewNumberNode(literalValue)}, nil
+}
+
+// parseString parses a single or double-quoted string with JSON style escapes
+func (p *parser) parseString() (string, error) {
+	quote := p.peek()
+	p.pos++
+
+	var b strings.Builder
+	for {
+		if p.eof() {
+			return "", p.errorf("unterminated string")
+		}
+		r, size := utf8.DecodeRuneInString(p.expr[p.pos:])
+		switch {
+		case r == rune(quote):
+			p.pos += size
+			return b.String(), nil
+		case r < 0x20:
+			return "", p.errorf("invalid control character in string")
+		case r == '\\':
+			p.pos++
+			r, err := p.parseEscape(quote)
+			if err != nil {
+				return "", err
+			}
+			b.WriteRune(r)
+		default:
+			p.pos += size
+			b.WriteRune(r)
+		}
+	}
+}
+
+func (p *parser) parseEscape(quote byte) (rune, error) {
+	if p.eof() {
+		return 0, p.errorf("unterminated string")
+	}
+	c := p.peek()
+	p.pos++
+	switch c {
+	case quote, '\\', '/':
+		return rune(c), nil
+	case 'b':
+		return '\b', nil
+	case 'f':
+		return '\f', nil
+	case 'n':
+		return '\n', nil
+	case 'r':
+		return '\r', nil
+	case 't':
+		return '\t', nil
+	case 'u':
+		r, err := p.parseHex()
+		if err != nil {
+			return 0, err
+		}
+		if !utf16.IsSurrogate(r) {
+			return r, nil
+		}
+		if !p.consume(`\u`) {
+			return 0, p.errorf("expected a low surrogate")
+		}
+		low, err := p.parseHex()
+		if err != nil {
+			return 0, err
+		}
+		decoded := utf16.DecodeRune(r, low)
+		if decoded == utf8.RuneError {
+			return 0, p.errorf("invalid surrogate pair")
+		}
+		return decoded, nil
+	}
+	p.pos--
+	return 0, p.errorf("invalid escape sequence")
+}
+
+func (p *parser) parseHex() (rune, error) {
+	if p.pos+4 > len(p.expr) {
+		return 0, p.errorf("expected 4 hex digits")
+	}
+	v, err := strconv.ParseUint(p.expr[p.pos:p.pos+4], 16, 32)
+	if err != nil {
+		return 0, p.errorf("expected 4 hex digits")
+	}
+	p.pos += 4
+	return rune(v), nil
+}
+
+func (p *parser) skipWhitespace() {
+	for !p.eof() && strings.IndexByte(" \t\n\r", p.peek()) >= 0 {
+		p.pos++
+	}
+}
+
+func (p *parser) consume(s string) bool {
+	if !p.lookingAt(s) {
+		return false
+	}
+	p.pos += len(s)
+	return true
+}
+
+func (p *parser) lookingAt(s string) bool {
+	return strings.HasPrefix(p.expr[p.pos:], s)
+}
+
+func (p *parser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.expr[p.pos]
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.expr)
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return &Error{Expr: p.expr, Offset: p.pos, Msg: fmt.Sprintf(format, args...)}
+}
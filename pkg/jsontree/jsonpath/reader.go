@@ -0,0 +1,32 @@
+package jsonpath
+
+import (
+	"github.com/trichner/toolbox/pkg/jsontree"
+	"github.com/trichner/toolbox/pkg/jsontree/ast"
+)
+
+// NewReader reads the nodes the query selects from each of the values read from nodes, e.g. to select the
+// items of an array nested in every value of a NDJSON stream
+func NewReader(q *Query, nodes jsontree.NodeReader) jsontree.NodeReader {
+	return &reader{q: q, nodes: nodes}
+}
+
+type reader struct {
+	q       *Query
+	nodes   jsontree.NodeReader
+	pending []ast.Node
+}
+
+func (r *reader) Next() (ast.Node, error) {
+	for len(r.pending) == 0 {
+		n, err := r.nodes.Next()
+		if err != nil {
+			return nil, err
+		}
+		r.pending = r.q.Select(n)
+	}
+
+	n := r.pending[0]
+	r.pending = r.pending[1:]
+	return n, nil
+}
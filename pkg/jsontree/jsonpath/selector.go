@@ -0,0 +1,169 @@
+package jsonpath
+
+import (
+	"github.com/trichner/toolbox/pkg/jsontree"
+	"github.com/trichner/toolbox/pkg/jsontree/ast"
+)
+
+// segment applies its selectors to the input nodes or, for descendant segments, to the input nodes and all
+// their descendants
+type segment struct {
+	descendant bool
+	selectors  []selector
+}
+
+type selector interface {
+	apply(root ast.Node, m Match, emit func(Match))
+}
+
+func (s *segment) apply(root ast.Node, m Match, emit func(Match)) {
+	for _, sel := range s.selectors {
+		sel.apply(root, m, emit)
+	}
+	if s.descendant {
+		children(m, func(child Match) {
+			s.apply(root, child, emit)
+		})
+	}
+}
+
+// singular reports whether the segment selects at most one node
+func (s *segment) singular() bool {
+	if s.descendant || len(s.selectors) != 1 {
+		return false
+	}
+	switch s.selectors[0].(type) {
+	case nameSelector, indexSelector:
+		return true
+	}
+	return false
+}
+
+type nameSelector struct {
+	name string
+}
+
+func (s nameSelector) apply(_ ast.Node, m Match, emit func(Match)) {
+	obj, ok := m.Node.(ast.ObjectNode)
+	if !ok {
+		return
+	}
+	for _, p := range obj.Properties() {
+		if p.Name == s.name {
+			emit(property(m, p))
+			return
+		}
+	}
+}
+
+type wildcardSelector struct{}
+
+func (wildcardSelector) apply(_ ast.Node, m Match, emit func(Match)) {
+	children(m, emit)
+}
+
+type indexSelector struct {
+	index int
+}
+
+func (s indexSelector) apply(_ ast.Node, m Match, emit func(Match)) {
+	arr, ok := m.Node.(ast.ArrayNode)
+	if !ok {
+		return
+	}
+	items := arr.Items()
+	i := s.index
+	if i < 0 {
+		i += len(items)
+	}
+	if i >= 0 && i < len(items) {
+		emit(item(m, items, i))
+	}
+}
+
+// sliceSelector selects items like Python slices, absent bounds default depending on the direction of step
+type sliceSelector struct {
+	start, end *int
+	step       int
+}
+
+func (s sliceSelector) apply(_ ast.Node, m Match, emit func(Match)) {
+	arr, ok := m.Node.(ast.ArrayNode)
+	if !ok || s.step == 0 {
+		return
+	}
+	items := arr.Items()
+	n := len(items)
+
+	normalize := func(i int) int {
+		if i < 0 {
+			return n + i
+		}
+		return i
+	}
+
+	if s.step > 0 {
+		start, end := 0, n
+		if s.start != nil {
+			start = min(max(normalize(*s.start), 0), n)
+		}
+		if s.end != nil {
+			end = min(max(normalize(*s.end), 0), n)
+		}
+		for i := start; i < end; i += s.step {
+			emit(item(m, items, i))
+		}
+		return
+	}
+
+	start, end := n-1, -1
+	if s.start != nil {
+		start = min(max(normalize(*s.start), -1), n-1)
+	}
+	if s.end != nil {
+		end = min(max(normalize(*s.end), -1), n-1)
+	}
+	for i := start; i > end; i += s.step {
+		emit(item(m, items, i))
+	}
+}
+
+type filterSelector struct {
+	expr filterExpr
+}
+
+func (s filterSelector) apply(root ast.Node, m Match, emit func(Match)) {
+	children(m, func(child Match) {
+		if s.expr.test(root, child.Node) {
+			emit(child)
+		}
+	})
+}
+
+// children emits the property values of objects or the items of arrays in document order
+func children(m Match, emit func(Match)) {
+	switch n := m.Node.(type) {
+	case ast.ObjectNode:
+		for _, p := range n.Properties() {
+			emit(property(m, p))
+		}
+	case ast.ArrayNode:
+		items := n.Items()
+		for i := range items {
+			emit(item(m, items, i))
+		}
+	}
+}
+
+func property(m Match, p *ast.Property) Match {
+	return Match{Path: appendPath(m.Path, jsontree.PathElement{Key: p.Name, Index: -1}), Node: p.Value}
+}
+
+func item(m Match, items []ast.Node, i int) Match {
+	return Match{Path: appendPath(m.Path, jsontree.PathElement{Index: i}), Node: items[i]}
+}
+
+func appendPath(p jsontree.Path, e jsontree.PathElement) jsontree.Path {
+	// never share the backing array between siblings
+	return append(p[:len(p):len(p)], e)
+}
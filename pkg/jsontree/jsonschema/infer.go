@@ -0,0 +1,193 @@
+package jsonschema
+
+import (
+	"io"
+	"math/big"
+	"strconv"
+
+	"github.com/trichner/toolbox/pkg/jsontree"
+	"github.com/trichner/toolbox/pkg/jsontree/ast"
+)
+
+// Draft is the JSON Schema dialect of inferred schemas
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// typeOrder is the order of types within an inferred "type" keyword
+var typeOrder = []string{"object", "array", "string", "integer", "number", "boolean", "null"}
+
+// Inferrer derives a schema from sample values, e.g. the records of an NDJSON stream. Types are merged across all
+// values, properties present in every object are required and arrays are bounded by the item counts seen.
+type Inferrer struct {
+	root   *shape
+	counts bool
+}
+
+type InferOption func(i *Inferrer)
+
+// WithCounts annotates every schema with the number of values it was inferred from as "x-count", e.g. to tell
+// how often an optional property is present
+func WithCounts() InferOption {
+	return func(i *Inferrer) {
+		i.counts = true
+	}
+}
+
+func NewInferrer(opts ...InferOption) *Inferrer {
+	i := &Inferrer{root: newShape()}
+	for _, opt := range opts {
+		opt(i)
+	}
+	return i
+}
+
+// Infer reads all values and returns their schema
+func Infer(r jsontree.NodeReader, opts ...InferOption) (ast.Node, error) {
+	i := NewInferrer(opts...)
+	for {
+		n, err := r.Next()
+		if err == io.EOF {
+			return i.Schema(), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		i.Add(n)
+	}
+}
+
+// Add merges a value into the schema
+func (i *Inferrer) Add(n ast.Node) {
+	i.root.add(n)
+}
+
+// Schema returns the schema of all values added so far, without any value it accepts nothing
+func (i *Inferrer) Schema() ast.Node {
+	if i.root.count == 0 {
+		return ast.NewObject().Set("$schema", ast.NewTextNode(Draft)).Set("not", ast.NewObject())
+	}
+	return i.root.schema(ast.NewObject(&ast.Property{Name: "$schema", Value: ast.NewTextNode(Draft)}), i.counts)
+}
+
+// shape accumulates what was seen at one location
+type shape struct {
+	count int
+	types map[string]bool
+
+	// objects is the number of objects seen, properties their properties in order of appearance
+	objects    int
+	properties []*propertyShape
+	index      map[string]*propertyShape
+
+	items              *shape
+	minItems, maxItems int
+	arrays             int
+}
+
+type propertyShape struct {
+	name  string
+	shape *shape
+}
+
+func newShape() *shape {
+	return &shape{types: make(map[string]bool), index: make(map[string]*propertyShape)}
+}
+
+func (s *shape) add(n ast.Node) {
+	s.count++
+	switch n.Type() {
+	case ast.NodeTypeObject:
+		s.types["object"] = true
+		s.objects++
+		for _, p := range n.(ast.ObjectNode).Properties() {
+			ps, ok := s.index[p.Name]
+			if !ok {
+				ps = &propertyShape{name: p.Name, shape: newShape()}
+				s.index[p.Name] = ps
+				s.properties = append(s.properties, ps)
+			}
+			if p.Value == nil {
+				ps.shape.add(ast.NewNullNode())
+				continue
+			}
+			ps.shape.add(p.Value)
+		}
+	case ast.NodeTypeArray:
+		s.types["array"] = true
+		items := n.(ast.ArrayNode).Items()
+		if s.arrays == 0 || len(items) < s.minItems {
+			s.minItems = len(items)
+		}
+		if len(items) > s.maxItems {
+			s.maxItems = len(items)
+		}
+		s.arrays++
+		if len(items) > 0 && s.items == nil {
+			s.items = newShape()
+		}
+		for _, item := range items {
+			s.items.add(item)
+		}
+	case ast.NodeTypeText:
+		s.types["string"] = true
+	case ast.NodeTypeNumber:
+		if isInteger(n.(ast.NumberNode).Value()) {
+			s.types["integer"] = true
+		} else {
+			s.types["number"] = true
+		}
+	case ast.NodeTypeBoolean:
+		s.types["boolean"] = true
+	case ast.NodeTypeNull:
+		s.types["null"] = true
+	}
+}
+
+// schema sets the keywords describing the shape on the given schema object
+func (s *shape) schema(schema *ast.Object, counts bool) *ast.Object {
+	var types []ast.Node
+	for _, t := range typeOrder {
+		// integers are numbers too
+		if s.types[t] && !(t == "integer" && s.types["number"]) {
+			types = append(types, ast.NewTextNode(t))
+		}
+	}
+	if len(types) == 1 {
+		schema.Set("type", types[0])
+	} else {
+		schema.Set("type", ast.NewArrayNode(types))
+	}
+
+	if s.objects > 0 {
+		properties := ast.NewObject()
+		var required []ast.Node
+		for _, p := range s.properties {
+			properties.Set(p.name, p.shape.schema(ast.NewObject(), counts))
+			if p.shape.count == s.objects {
+				required = append(required, ast.NewTextNode(p.name))
+			}
+		}
+		schema.Set("properties", properties)
+		if len(required) > 0 {
+			schema.Set("required", ast.NewArrayNode(required))
+		}
+	}
+
+	if s.arrays > 0 {
+		if s.items != nil {
+			schema.Set("items", s.items.schema(ast.NewObject(), counts))
+		}
+		schema.Set("minItems", ast.NewNumberNode(strconv.Itoa(s.minItems)))
+		schema.Set("maxItems", ast.NewNumberNode(strconv.Itoa(s.maxItems)))
+	}
+
+	if counts {
+		schema.Set("x-count", ast.NewNumberNode(strconv.Itoa(s.count)))
+	}
+	return schema
+}
+
+// isInteger tells whether a number has no fractional part, as of JSON Schema 1.0 is an integer
+func isInteger(number string) bool {
+	f, _, err := big.ParseFloat(number, 10, 256, big.ToNearestEven)
+	return err == nil && f.IsInt()
+}
@@ -0,0 +1,151 @@
+package jsonschema
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trichner/toolbox/pkg/jsontree"
+	"github.com/trichner/toolbox/pkg/jsontree/ast"
+	"github.com/trichner/toolbox/pkg/jsontree/lexer"
+)
+
+func parse(t *testing.T, raw string) ast.Node {
+	n, err := jsontree.Parse(lexer.NewLexer(strings.NewReader(raw)))
+	require.NoError(t, err)
+	return n
+}
+
+func TestInfer(t *testing.T) {
+	records := `{"id": 1, "name": "a", "tags": ["x"], "score": 1.5, "address": {"city": "Zurich"}}
+{"id": 2, "name": null, "tags": [], "score": 2}
+{"id": 3, "name": "c", "tags": ["y", "z"], "score": 3, "address": {"city": "Bern", "zip": "3000"}}`
+
+	schema, err := Infer(jsontree.NewValueReader(lexer.NewLexer(strings.NewReader(records))))
+	require.NoError(t, err)
+
+	txt, err := schema.MarshalJSON()
+	require.NoError(t, err)
+	assert.Equal(t, `{"$schema":"https://json-schema.org/draft/2020-12/schema","type":"object","properties":{`+
+		`"id":{"type":"integer"},`+
+		`"name":{"type":["string","null"]},`+
+		`"tags":{"type":"array","items":{"type":"string"},"minItems":0,"maxItems":2},`+
+		`"score":{"type":"number"},`+
+		`"address":{"type":"object","properties":{"city":{"type":"string"},"zip":{"type":"string"}},"required":["city"]}},`+
+		`"required":["id","name","tags","score"]}`, string(txt))
+
+	// every record is valid against the inferred schema
+	s, err := Compile(schema)
+	require.NoError(t, err)
+	r := jsontree.NewValueReader(lexer.NewLexer(strings.NewReader(records)))
+	for {
+		n, err := r.Next()
+		if err != nil {
+			break
+		}
+		assert.Empty(t, s.Validate(n))
+	}
+}
+
+func TestInfer_Counts(t *testing.T) {
+	i := NewInferrer(WithCounts())
+	i.Add(parse(t, `{"a": 1}`))
+	i.Add(parse(t, `{"b": true}`))
+	i.Add(parse(t, `{"a": 2.0}`))
+
+	txt, err := i.Schema().MarshalJSON()
+	require.NoError(t, err)
+	assert.Equal(t, `{"$schema":"https://json-schema.org/draft/2020-12/schema","type":"object","properties":{`+
+		`"a":{"type":"integer","x-count":2},"b":{"type":"boolean","x-count":1}},"x-count":3}`, string(txt))
+
+	txt, err = NewInferrer().Schema().MarshalJSON()
+	require.NoError(t, err)
+	assert.Equal(t, `{"$schema":"https://json-schema.org/draft/2020-12/schema","not":{}}`, string(txt))
+}
+
+func TestValidate(t *testing.T) {
+	s := MustCompile(parse(t, `{
+		"$defs": {"positive": {"type": "number", "exclusiveMinimum": 0}},
+		"type": "object",
+		"required": ["id", "price"],
+		"properties": {
+			"id": {"type": "integer"},
+			"price": {"$ref": "#/$defs/positive"},
+			"currency": {"enum": ["CHF", "EUR"]},
+			"sku": {"type": "string", "pattern": "^[A-Z]{3}-[0-9]+$", "maxLength": 10},
+			"tags": {"type": "array", "items": {"type": "string"}, "uniqueItems": true, "maxItems": 3},
+			"qty": {"type": "integer", "minimum": 1, "multipleOf": 0.5}
+		},
+		"patternProperties": {"^x-": true},
+		"additionalProperties": false
+	}`))
+
+	tests := []struct {
+		record   string
+		expected []string
+	}{
+		{`{"id": 1, "price": 9.95, "currency": "CHF", "sku": "ABC-1", "tags": ["a"], "qty": 2, "x-note": 1}`, nil},
+		{`{"id": 1.0, "price": 1e2}`, nil},
+		{`{"id": "1"}`, []string{`$: missing required property "price"`, `$.id: expected integer but got string`}},
+		{`{"id": 1, "price": 0, "currency": "USD"}`, []string{`$.price: 0 must be greater than 0`, `$.currency: "USD" is not one of the allowed values`}},
+		{`{"id": 1, "price": 1, "sku": "abc-12345678"}`, []string{`$.sku: string is longer than 10 characters`, `$.sku: string does not match the pattern "^[A-Z]{3}-[0-9]+$"`}},
+		{`{"id": 1, "price": 1, "tags": ["a", 1, "a", "b"]}`, []string{`$.tags: array has more than 3 items`, `$.tags[1]: expected string but got integer`, `$.tags: items 0 and 2 are equal`}},
+		{`{"id": 1, "price": 1, "qty": 0.25}`, []string{`$.qty: 0.25 is less than the minimum of 1`, `$.qty: expected integer but got number`, `$.qty: 0.25 is not a multiple of 1/2`}},
+		{`{"id": 1, "price": 1, "color": "red"}`, []string{`$.color: property "color" is not allowed`}},
+		{`[]`, []string{`$: expected object but got array`}},
+	}
+	for _, tt := range tests {
+		var actual []string
+		for _, err := range s.Validate(parse(t, tt.record)) {
+			actual = append(actual, err.Error())
+		}
+		assert.ElementsMatch(t, tt.expected, actual, tt.record)
+	}
+}
+
+func TestValidate_Applicators(t *testing.T) {
+	s := MustCompile(parse(t, `{
+		"oneOf": [{"type": "string"}, {"type": "integer"}, {"type": "number", "minimum": 10}],
+		"not": {"const": "forbidden"},
+		"if": {"type": "string"},
+		"then": {"minLength": 2}
+	}`))
+
+	valid := func(raw string) bool { return len(s.Validate(parse(t, raw))) == 0 }
+	assert.True(t, valid(`"ok"`))
+	assert.True(t, valid(`12.5`))
+	assert.False(t, valid(`1.5`))
+	assert.False(t, valid(`"x"`))
+	assert.False(t, valid(`"forbidden"`))
+	assert.False(t, valid(`12`))
+	assert.False(t, valid(`null`))
+
+	tree := MustCompile(parse(t, `{"$defs": {"node": {"type": "object", "properties": {"children": {"items": {"$ref": "#/$defs/node"}}}, "required": ["name"]}}, "$ref": "#/$defs/node"}`))
+	errs := tree.Validate(parse(t, `{"name": "root", "children": [{"name": "a"}, {"children": []}]}`))
+	require.Len(t, errs, 1)
+	assert.Equal(t, `$.children[1]: missing required property "name"`, errs[0].Error())
+	assert.Equal(t, "required", errs[0].Keyword)
+
+	assert.Empty(t, MustCompile(parse(t, `true`)).Validate(parse(t, `{}`)))
+	assert.Len(t, MustCompile(parse(t, `false`)).Validate(parse(t, `{}`)), 1)
+}
+
+func TestCompile_Invalid(t *testing.T) {
+	for _, raw := range []string{
+		`1`,
+		`{"type": "text"}`,
+		`{"minLength": -1}`,
+		`{"minLength": 1.5}`,
+		`{"pattern": "("}`,
+		`{"multipleOf": 0}`,
+		`{"allOf": []}`,
+		`{"$ref": "https://example.com/schema.json"}`,
+		`{"$ref": "#/$defs/missing"}`,
+		`{"$defs": {"a~b": true}, "$ref": "#/$defs/a~b"}`,
+		`{"properties": {"a": 1}}`,
+	} {
+		_, err := Compile(parse(t, raw))
+		assert.Error(t, err, raw)
+	}
+}
@@ -0,0 +1,417 @@
+// Package jsonschema infers JSON Schemas from sample values and validates values against a schema.
+//
+// Validation supports the assertions of JSON Schema draft 2020-12 that do not need external resources:
+// type, enum, const, the numeric, string, array and object assertions, allOf, anyOf, oneOf, not,
+// if/then/else and $ref to locations within the same schema, e.g. "#/$defs/address". Annotations like
+// "format" or "description" and unknown keywords are ignored.
+package jsonschema
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/trichner/toolbox/pkg/jsontree"
+	"github.com/trichner/toolbox/pkg/jsontree/ast"
+)
+
+// Schema is a compiled JSON Schema, it is safe for concurrent use
+type Schema struct {
+	root *schema
+}
+
+// schema holds the keywords of a schema object, keywords that are absent are nil
+type schema struct {
+	// always is set for the boolean schemas true and false
+	always *bool
+
+	ref *schema
+
+	types    []string
+	enum     []ast.Node
+	constant ast.Node
+
+	minimum, maximum                   ast.NumberNode
+	exclusiveMinimum, exclusiveMaximum ast.NumberNode
+	multipleOf                         *big.Rat
+
+	minLength, maxLength *int
+	pattern              *regexp.Regexp
+
+	prefixItems          []*schema
+	items                *schema
+	contains             *schema
+	minItems, maxItems   *int
+	minContains          *int
+	maxContains          *int
+	uniqueItems          bool
+	properties           []*namedSchema
+	patternProperties    []*patternSchema
+	additionalProperties *schema
+	propertyNames        *schema
+	required             []string
+	dependentRequired    map[string][]string
+	minProperties        *int
+	maxProperties        *int
+
+	allOf, anyOf, oneOf []*schema
+	not                 *schema
+	if_, then, else_    *schema
+}
+
+type namedSchema struct {
+	name   string
+	schema *schema
+}
+
+type patternSchema struct {
+	pattern *regexp.Regexp
+	schema  *schema
+}
+
+// Compile checks a schema and prepares it for validation
+func Compile(root ast.Node) (*Schema, error) {
+	c := &compiler{root: root, refs: make(map[string]*schema)}
+	s, err := c.compile(root, "#")
+	if err != nil {
+		return nil, err
+	}
+	return &Schema{root: s}, nil
+}
+
+// MustCompile is like Compile but panics if the schema is invalid
+func MustCompile(root ast.Node) *Schema {
+	s, err := Compile(root)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+type compiler struct {
+	root ast.Node
+
+	// refs are the schemas referenced by $ref by their location, entries are added before compiling the schema to
+	// support recursive references
+	refs map[string]*schema
+}
+
+func (c *compiler) compile(n ast.Node, location string) (*schema, error) {
+	s := &schema{}
+	switch n.Type() {
+	case ast.NodeTypeBoolean:
+		b := n.(ast.BooleanNode).Value()
+		s.always = &b
+		return s, nil
+	case ast.NodeTypeObject:
+	default:
+		return nil, fmt.Errorf("%s: schema must be an object or a boolean but got: %s", location, n.Type())
+	}
+
+	for _, p := range n.(ast.ObjectNode).Properties() {
+		keyword := jsontree.AppendPointer(location, p.Name)
+		var err error
+		switch p.Name {
+		case "$ref":
+			s.ref, err = c.resolve(p.Value, keyword)
+		case "type":
+			s.types, err = compileTypes(p.Value, keyword)
+		case "enum":
+			arr, ok := p.Value.(ast.ArrayNode)
+			if !ok {
+				return nil, fmt.Errorf("%s: must be an array", keyword)
+			}
+			s.enum = arr.Items()
+		case "const":
+			s.constant = p.Value
+		case "minimum":
+			s.minimum, err = compileNumber(p.Value, keyword)
+		case "maximum":
+			s.maximum, err = compileNumber(p.Value, keyword)
+		case "exclusiveMinimum":
+			s.exclusiveMinimum, err = compileNumber(p.Value, keyword)
+		case "exclusiveMaximum":
+			s.exclusiveMaximum, err = compileNumber(p.Value, keyword)
+		case "multipleOf":
+			s.multipleOf, err = compileMultipleOf(p.Value, keyword)
+		case "minLength":
+			s.minLength, err = compileCount(p.Value, keyword)
+		case "maxLength":
+			s.maxLength, err = compileCount(p.Value, keyword)
+		case "pattern":
+			s.pattern, err = compilePattern(p.Value, keyword)
+		case "prefixItems":
+			s.prefixItems, err = c.compileList(p.Value, keyword)
+		case "items":
+			s.items, err = c.compile(p.Value, keyword)
+		case "contains":
+			s.contains, err = c.compile(p.Value, keyword)
+		case "minItems":
+			s.minItems, err = compileCount(p.Value, keyword)
+		case "maxItems":
+			s.maxItems, err = compileCount(p.Value, keyword)
+		case "minContains":
+			s.minContains, err = compileCount(p.Value, keyword)
+		case "maxContains":
+			s.maxContains, err = compileCount(p.Value, keyword)
+		case "uniqueItems":
+			b, ok := p.Value.(ast.BooleanNode)
+			if !ok {
+				return nil, fmt.Errorf("%s: must be a boolean", keyword)
+			}
+			s.uniqueItems = b.Value()
+		case "properties":
+			s.properties, err = c.compileProperties(p.Value, keyword)
+		case "patternProperties":
+			s.patternProperties, err = c.compilePatternProperties(p.Value, keyword)
+		case "additionalProperties":
+			s.additionalProperties, err = c.compile(p.Value, keyword)
+		case "propertyNames":
+			s.propertyNames, err = c.compile(p.Value, keyword)
+		case "required":
+			s.required, err = compileNames(p.Value, keyword)
+		case "dependentRequired":
+			s.dependentRequired, err = compileDependentRequired(p.Value, keyword)
+		case "minProperties":
+			s.minProperties, err = compileCount(p.Value, keyword)
+		case "maxProperties":
+			s.maxProperties, err = compileCount(p.Value, keyword)
+		case "allOf":
+			s.allOf, err = c.compileList(p.Value, keyword)
+		case "anyOf":
+			s.anyOf, err = c.compileList(p.Value, keyword)
+		case "oneOf":
+			s.oneOf, err = c.compileList(p.Value, keyword)
+		case "not":
+			s.not, err = c.compile(p.Value, keyword)
+		case "if":
+			s.if_, err = c.compile(p.Value, keyword)
+		case "then":
+			s.then, err = c.compile(p.Value, keyword)
+		case "else":
+			s.else_, err = c.compile(p.Value, keyword)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// resolve compiles the target of a $ref, only references within the schema are supported
+func (c *compiler) resolve(n ast.Node, location string) (*schema, error) {
+	if n.Type() != ast.NodeTypeText {
+		return nil, fmt.Errorf("%s: must be a string", location)
+	}
+	target := n.(ast.TextNode).Value()
+	if s, ok := c.refs[target]; ok {
+		return s, nil
+	}
+	if !strings.HasPrefix(target, "#") {
+		return nil, fmt.Errorf("%s: unsupported reference %q, only references within the schema are supported", location, target)
+	}
+
+	fragment, err := url.PathUnescape(target[1:])
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid reference %q: %w", location, target, err)
+	}
+	resolved, err := lookupPointer(c.root, fragment)
+	if err != nil {
+		return nil, fmt.Errorf("%s: cannot resolve %q: %w", location, target, err)
+	}
+
+	// register the schema before compiling it, a schema may refer to itself
+	s := &schema{}
+	c.refs[target] = s
+	compiled, err := c.compile(resolved, target)
+	if err != nil {
+		return nil, err
+	}
+	*s = *compiled
+	return s, nil
+}
+
+func (c *compiler) compileList(n ast.Node, location string) ([]*schema, error) {
+	arr, ok := n.(ast.ArrayNode)
+	if !ok || len(arr.Items()) == 0 {
+		return nil, fmt.Errorf("%s: must be a non-empty array", location)
+	}
+	schemas := make([]*schema, len(arr.Items()))
+	for i, item := range arr.Items() {
+		s, err := c.compile(item, location+"/"+strconv.Itoa(i))
+		if err != nil {
+			return nil, err
+		}
+		schemas[i] = s
+	}
+	return schemas, nil
+}
+
+func (c *compiler) compileProperties(n ast.Node, location string) ([]*namedSchema, error) {
+	obj, ok := n.(ast.ObjectNode)
+	if !ok {
+		return nil, fmt.Errorf("%s: must be an object", location)
+	}
+	var properties []*namedSchema
+	for _, p := range obj.Properties() {
+		s, err := c.compile(p.Value, jsontree.AppendPointer(location, p.Name))
+		if err != nil {
+			return nil, err
+		}
+		properties = append(properties, &namedSchema{name: p.Name, schema: s})
+	}
+	return properties, nil
+}
+
+func (c *compiler) compilePatternProperties(n ast.Node, location string) ([]*patternSchema, error) {
+	obj, ok := n.(ast.ObjectNode)
+	if !ok {
+		return nil, fmt.Errorf("%s: must be an object", location)
+	}
+	var properties []*patternSchema
+	for _, p := range obj.Properties() {
+		keyword := jsontree.AppendPointer(location, p.Name)
+		pattern, err := regexp.Compile(p.Name)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid pattern: %w", keyword, err)
+		}
+		s, err := c.compile(p.Value, keyword)
+		if err != nil {
+			return nil, err
+		}
+		properties = append(properties, &patternSchema{pattern: pattern, schema: s})
+	}
+	return properties, nil
+}
+
+var validTypes = map[string]bool{
+	"null": true, "boolean": true, "object": true, "array": true, "number": true, "integer": true, "string": true,
+}
+
+func compileTypes(n ast.Node, location string) ([]string, error) {
+	if n.Type() == ast.NodeTypeText {
+		n = ast.NewArrayNode([]ast.Node{n})
+	}
+	types, err := compileNames(n, location)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range types {
+		if !validTypes[t] {
+			return nil, fmt.Errorf("%s: unknown type %q", location, t)
+		}
+	}
+	return types, nil
+}
+
+func compileNames(n ast.Node, location string) ([]string, error) {
+	arr, ok := n.(ast.ArrayNode)
+	if !ok {
+		return nil, fmt.Errorf("%s: must be an array of strings", location)
+	}
+	names := make([]string, len(arr.Items()))
+	for i, item := range arr.Items() {
+		if item.Type() != ast.NodeTypeText {
+			return nil, fmt.Errorf("%s: must be an array of strings", location)
+		}
+		names[i] = item.(ast.TextNode).Value()
+	}
+	return names, nil
+}
+
+func compileDependentRequired(n ast.Node, location string) (map[string][]string, error) {
+	obj, ok := n.(ast.ObjectNode)
+	if !ok {
+		return nil, fmt.Errorf("%s: must be an object", location)
+	}
+	dependent := make(map[string][]string, len(obj.Properties()))
+	for _, p := range obj.Properties() {
+		names, err := compileNames(p.Value, jsontree.AppendPointer(location, p.Name))
+		if err != nil {
+			return nil, err
+		}
+		dependent[p.Name] = names
+	}
+	return dependent, nil
+}
+
+func compileNumber(n ast.Node, location string) (ast.NumberNode, error) {
+	if n.Type() != ast.NodeTypeNumber {
+		return nil, fmt.Errorf("%s: must be a number", location)
+	}
+	return n.(ast.NumberNode), nil
+}
+
+func compileMultipleOf(n ast.Node, location string) (*big.Rat, error) {
+	num, err := compileNumber(n, location)
+	if err != nil {
+		return nil, err
+	}
+	r, ok := new(big.Rat).SetString(num.Value())
+	if !ok || r.Sign() <= 0 {
+		return nil, fmt.Errorf("%s: must be a number greater than 0", location)
+	}
+	return r, nil
+}
+
+func compileCount(n ast.Node, location string) (*int, error) {
+	if n.Type() == ast.NodeTypeNumber {
+		f, _, err := big.ParseFloat(n.(ast.NumberNode).Value(), 10, 256, big.ToNearestEven)
+		if err == nil && f.IsInt() {
+			if i, acc := f.Int64(); acc == big.Exact && i >= 0 && i <= math.MaxInt {
+				count := int(i)
+				return &count, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%s: must be a non-negative integer", location)
+}
+
+func compilePattern(n ast.Node, location string) (*regexp.Regexp, error) {
+	if n.Type() != ast.NodeTypeText {
+		return nil, fmt.Errorf("%s: must be a string", location)
+	}
+	pattern, err := regexp.Compile(n.(ast.TextNode).Value())
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid pattern: %w", location, err)
+	}
+	return pattern, nil
+}
+
+// lookupPointer resolves a JSON Pointer as of RFC 6901
+func lookupPointer(n ast.Node, pointer string) (ast.Node, error) {
+	tokens, err := jsontree.ParsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, token := range tokens {
+		switch n.Type() {
+		case ast.NodeTypeObject:
+			found := false
+			for _, p := range n.(ast.ObjectNode).Properties() {
+				if p.Name == token {
+					n, found = p.Value, true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("property %q not found", token)
+			}
+		case ast.NodeTypeArray:
+			items := n.(ast.ArrayNode).Items()
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(items) {
+				return nil, fmt.Errorf("invalid array index %q", token)
+			}
+			n = items[i]
+		default:
+			return nil, fmt.Errorf("cannot resolve %q in %s", token, n.Type())
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,306 @@
+package jsonschema
+
+import (
+	"fmt"
+	"math/big"
+	"slices"
+	"unicode/utf8"
+
+	"github.com/trichner/toolbox/pkg/jsontree"
+	"github.com/trichner/toolbox/pkg/jsontree/ast"
+)
+
+// ValidationError describes a value violating a keyword of the schema
+type ValidationError struct {
+	// Path locates the invalid value
+	Path    jsontree.Path
+	Keyword string
+	Msg     string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Msg)
+}
+
+// Validate checks a value against the schema and returns all violations, none if the value is valid
+func (s *Schema) Validate(n ast.Node) []*ValidationError {
+	v := &validator{}
+	v.validate(s.root, n, nil)
+	return v.errs
+}
+
+type validator struct {
+	errs []*ValidationError
+}
+
+func (v *validator) fail(path jsontree.Path, keyword, format string, args ...any) {
+	v.errs = append(v.errs, &ValidationError{Path: slices.Clone(path), Keyword: keyword, Msg: fmt.Sprintf(format, args...)})
+}
+
+// matches tells whether a value is valid without reporting any violation
+func matches(s *schema, n ast.Node) bool {
+	v := &validator{}
+	v.validate(s, n, nil)
+	return len(v.errs) == 0
+}
+
+func (v *validator) validate(s *schema, n ast.Node, path jsontree.Path) {
+	if n == nil {
+		n = ast.NewNullNode()
+	}
+	if s.always != nil {
+		if !*s.always {
+			v.fail(path, "false", "no value is allowed here")
+		}
+		return
+	}
+
+	if s.ref != nil {
+		v.validate(s.ref, n, path)
+	}
+	v.validateGeneric(s, n, path)
+	v.validateApplicators(s, n, path)
+
+	switch n.Type() {
+	case ast.NodeTypeNumber:
+		v.validateNumber(s, n.(ast.NumberNode), path)
+	case ast.NodeTypeText:
+		v.validateString(s, n.(ast.TextNode).Value(), path)
+	case ast.NodeTypeArray:
+		v.validateArray(s, n.(ast.ArrayNode).Items(), path)
+	case ast.NodeTypeObject:
+		v.validateObject(s, n.(ast.ObjectNode).Properties(), path)
+	}
+}
+
+func (v *validator) validateGeneric(s *schema, n ast.Node, path jsontree.Path) {
+	if s.types != nil {
+		actual := typeOf(n)
+		if !slices.Contains(s.types, actual) && !(actual == "integer" && slices.Contains(s.types, "number")) {
+			if len(s.types) == 1 {
+				v.fail(path, "type", "expected %s but got %s", s.types[0], actual)
+			} else {
+				v.fail(path, "type", "expected one of %v but got %s", s.types, actual)
+			}
+		}
+	}
+	if s.enum != nil && !slices.ContainsFunc(s.enum, func(e ast.Node) bool { return ast.Equal(e, n) }) {
+		v.fail(path, "enum", "%s is not one of the allowed values", excerpt(n))
+	}
+	if s.constant != nil && !ast.Equal(s.constant, n) {
+		v.fail(path, "const", "%s is not %s", excerpt(n), excerpt(s.constant))
+	}
+}
+
+func (v *validator) validateApplicators(s *schema, n ast.Node, path jsontree.Path) {
+	for _, sub := range s.allOf {
+		v.validate(sub, n, path)
+	}
+	if s.anyOf != nil && !slices.ContainsFunc(s.anyOf, func(sub *schema) bool { return matches(sub, n) }) {
+		v.fail(path, "anyOf", "value matches none of the schemas of anyOf")
+	}
+	if s.oneOf != nil {
+		count := 0
+		for _, sub := range s.oneOf {
+			if matches(sub, n) {
+				count++
+			}
+		}
+		if count != 1 {
+			v.fail(path, "oneOf", "value matches %d of the schemas of oneOf, expected exactly one", count)
+		}
+	}
+	if s.not != nil && matches(s.not, n) {
+		v.fail(path, "not", "value must not match the schema of not")
+	}
+	if s.if_ != nil {
+		if matches(s.if_, n) {
+			if s.then != nil {
+				v.validate(s.then, n, path)
+			}
+		} else if s.else_ != nil {
+			v.validate(s.else_, n, path)
+		}
+	}
+}
+
+func (v *validator) validateNumber(s *schema, n ast.NumberNode, path jsontree.Path) {
+	compare := func(bound ast.NumberNode) int {
+		cmp, ok := ast.CompareNumbers(n, bound)
+		if !ok {
+			// e.g. a NaN number node built in code, treat as in range
+			return 0
+		}
+		return cmp
+	}
+
+	if s.minimum != nil && compare(s.minimum) < 0 {
+		v.fail(path, "minimum", "%s is less than the minimum of %s", n.Value(), s.minimum.Value())
+	}
+	if s.maximum != nil && compare(s.maximum) > 0 {
+		v.fail(path, "maximum", "%s is greater than the maximum of %s", n.Value(), s.maximum.Value())
+	}
+	if s.exclusiveMinimum != nil && compare(s.exclusiveMinimum) <= 0 {
+		v.fail(path, "exclusiveMinimum", "%s must be greater than %s", n.Value(), s.exclusiveMinimum.Value())
+	}
+	if s.exclusiveMaximum != nil && compare(s.exclusiveMaximum) >= 0 {
+		v.fail(path, "exclusiveMaximum", "%s must be less than %s", n.Value(), s.exclusiveMaximum.Value())
+	}
+	if s.multipleOf != nil {
+		r, ok := new(big.Rat).SetString(n.Value())
+		if ok && !new(big.Rat).Quo(r, s.multipleOf).IsInt() {
+			v.fail(path, "multipleOf", "%s is not a multiple of %s", n.Value(), s.multipleOf.RatString())
+		}
+	}
+}
+
+func (v *validator) validateString(s *schema, str string, path jsontree.Path) {
+	length := utf8.RuneCountInString(str)
+	if s.minLength != nil && length < *s.minLength {
+		v.fail(path, "minLength", "string is shorter than %d characters", *s.minLength)
+	}
+	if s.maxLength != nil && length > *s.maxLength {
+		v.fail(path, "maxLength", "string is longer than %d characters", *s.maxLength)
+	}
+	if s.pattern != nil && !s.pattern.MatchString(str) {
+		v.fail(path, "pattern", "string does not match the pattern %q", s.pattern)
+	}
+}
+
+func (v *validator) validateArray(s *schema, items []ast.Node, path jsontree.Path) {
+	if s.minItems != nil && len(items) < *s.minItems {
+		v.fail(path, "minItems", "array has fewer than %d items", *s.minItems)
+	}
+	if s.maxItems != nil && len(items) > *s.maxItems {
+		v.fail(path, "maxItems", "array has more than %d items", *s.maxItems)
+	}
+
+	for i, item := range items {
+		itemPath := append(path, jsontree.PathElement{Index: i})
+		if i < len(s.prefixItems) {
+			v.validate(s.prefixItems[i], item, itemPath)
+		} else if s.items != nil {
+			v.validate(s.items, item, itemPath)
+		}
+	}
+
+	if s.contains != nil {
+		count := 0
+		for _, item := range items {
+			if matches(s.contains, item) {
+				count++
+			}
+		}
+		minContains := 1
+		if s.minContains != nil {
+			minContains = *s.minContains
+		}
+		if count < minContains {
+			v.fail(path, "contains", "array contains %d matching items, expected at least %d", count, minContains)
+		}
+		if s.maxContains != nil && count > *s.maxContains {
+			v.fail(path, "maxContains", "array contains %d matching items, expected at most %d", count, *s.maxContains)
+		}
+	}
+
+	if s.uniqueItems {
+	unique:
+		for i := range items {
+			for j := i + 1; j < len(items); j++ {
+				if ast.Equal(items[i], items[j]) {
+					v.fail(path, "uniqueItems", "items %d and %d are equal", i, j)
+					break unique
+				}
+			}
+		}
+	}
+}
+
+func (v *validator) validateObject(s *schema, properties []*ast.Property, path jsontree.Path) {
+	if s.minProperties != nil && len(properties) < *s.minProperties {
+		v.fail(path, "minProperties", "object has fewer than %d properties", *s.minProperties)
+	}
+	if s.maxProperties != nil && len(properties) > *s.maxProperties {
+		v.fail(path, "maxProperties", "object has more than %d properties", *s.maxProperties)
+	}
+
+	present := make(map[string]bool, len(properties))
+	for _, p := range properties {
+		present[p.Name] = true
+	}
+	for _, name := range s.required {
+		if !present[name] {
+			v.fail(path, "required", "missing required property %q", name)
+		}
+	}
+	for name, dependencies := range s.dependentRequired {
+		if !present[name] {
+			continue
+		}
+		for _, dependency := range dependencies {
+			if !present[dependency] {
+				v.fail(path, "dependentRequired", "property %q requires property %q", name, dependency)
+			}
+		}
+	}
+
+	for _, p := range properties {
+		propertyPath := append(path, jsontree.PathElement{Key: p.Name, Index: -1})
+		if s.propertyNames != nil && !matches(s.propertyNames, ast.NewTextNode(p.Name)) {
+			v.fail(propertyPath, "propertyNames", "invalid property name %q", p.Name)
+		}
+
+		evaluated := false
+		for _, ps := range s.properties {
+			if ps.name == p.Name {
+				v.validate(ps.schema, p.Value, propertyPath)
+				evaluated = true
+			}
+		}
+		for _, ps := range s.patternProperties {
+			if ps.pattern.MatchString(p.Name) {
+				v.validate(ps.schema, p.Value, propertyPath)
+				evaluated = true
+			}
+		}
+		if !evaluated && s.additionalProperties != nil {
+			if s.additionalProperties.always != nil && !*s.additionalProperties.always {
+				v.fail(propertyPath, "additionalProperties", "property %q is not allowed", p.Name)
+				continue
+			}
+			v.validate(s.additionalProperties, p.Value, propertyPath)
+		}
+	}
+}
+
+// typeOf returns the JSON Schema type of a value, numbers without a fraction are integers
+func typeOf(n ast.Node) string {
+	switch n.Type() {
+	case ast.NodeTypeObject:
+		return "object"
+	case ast.NodeTypeArray:
+		return "array"
+	case ast.NodeTypeText:
+		return "string"
+	case ast.NodeTypeNumber:
+		if isInteger(n.(ast.NumberNode).Value()) {
+			return "integer"
+		}
+		return "number"
+	case ast.NodeTypeBoolean:
+		return "boolean"
+	}
+	return "null"
+}
+
+// excerpt renders a value for messages, long values are shortened
+func excerpt(n ast.Node) string {
+	b, err := n.MarshalJSON()
+	if err != nil {
+		return typeOf(n)
+	}
+	if s := []rune(string(b)); len(s) > 40 {
+		return string(s[:39]) + "…"
+	}
+	return string(b)
+}
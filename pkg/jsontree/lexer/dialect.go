@@ -0,0 +1,276 @@
+package lexer
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Dialect selects the flavour of JSON to accept
+type Dialect string
+
+const (
+	// DialectJSON is strict JSON as of RFC 8259
+	DialectJSON Dialect = "json"
+
+	// DialectJSONC additionally accepts comments and trailing commas, as in VS Code's settings.json
+	DialectJSONC Dialect = "jsonc"
+
+	// DialectJSON5 accepts JSON5, i.e. JSONC plus single-quoted strings, unquoted keys, hexadecimal numbers,
+	// and more, see https://spec.json5.org. Lenient numbers are normalized to JSON numbers. NaN and Infinity
+	// have no JSON equivalent and are rejected.
+	DialectJSON5 Dialect = "json5"
+)
+
+// Dialects lists all supported dialects, strict first
+var Dialects = []Dialect{DialectJSON, DialectJSONC, DialectJSON5}
+
+// ParseDialect parses the name of a dialect, e.g. from a command line flag
+func ParseDialect(s string) (Dialect, error) {
+	for _, d := range Dialects {
+		if strings.EqualFold(s, string(d)) {
+			return d, nil
+		}
+	}
+	return "", fmt.Errorf("unknown JSON dialect: %q", s)
+}
+
+// UnmarshalText parses the name of a dialect with ParseDialect, e.g. when decoding a command line flag
+func (d *Dialect) UnmarshalText(text []byte) error {
+	parsed, err := ParseDialect(string(text))
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// DialectFlag is the --dialect command line flag shared by the JSON commands, embed it into a kong CLI struct
+type DialectFlag struct {
+	Dialect Dialect `help:"JSON dialect of the input, 'jsonc' and 'json5' allow comments, trailing commas and more" enum:"json,jsonc,json5" default:"json"`
+}
+
+type Option func(l *lexer)
+
+// WithDialect makes the lexer accept a lenient dialect rather than strict JSON
+func WithDialect(d Dialect) Option {
+	return func(l *lexer) {
+		l.dialect = d
+	}
+}
+
+const bom = '\ufeff'
+
+// skipComment skips a '//' or '/* */' comment if there is one at the current position
+func (l *lexer) skipComment() (bool, error) {
+	start := l.r.pos
+	switch string(l.r.Peek(2)) {
+	case "//":
+		for {
+			r, _, err := l.r.ReadRune()
+			if err == io.EOF || r == '\n' {
+				return true, nil
+			}
+			if err != nil {
+				return false, err
+			}
+		}
+	case "/*":
+		l.r.advance(2)
+		for {
+			r, _, err := l.r.ReadRune()
+			if err == io.EOF {
+				return false, errorAt(start, "unterminated comment")
+			}
+			if err != nil {
+				return false, err
+			}
+			if r == '*' && string(l.r.Peek(1)) == "/" {
+				l.r.advance(1)
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// followedByClosing reports whether the next token closes an object or array
+func (l *lexer) followedByClosing() (bool, error) {
+	err := l.skipWhitespace()
+	if err == io.EOF {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	next, err := l.r.peekByte()
+	if err == io.EOF {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return next == '}' || next == ']', nil
+}
+
+func (l *lexer) lexJSON5Number() (Token, error) {
+	start := l.r.pos
+	v, err := l.readLiteral()
+	if err != nil {
+		return unknownToken, err
+	}
+
+	if s := strings.TrimLeft(v, "+-"); s == "NaN" || s == "Infinity" {
+		return unknownToken, nonFiniteError(start, v)
+	}
+	n, ok := normalizeJSON5Number(v)
+	if !ok {
+		return unknownToken, errorAt(start, "invalid number literal: %q", v)
+	}
+	return Token{Type: TokenTypePrimitiveNumber, Value: n}, nil
+}
+
+// nonFiniteError rejects NaN and Infinity, the output would not be valid JSON otherwise
+func nonFiniteError(at Position, literal string) error {
+	return errorAt(at, "%s has no JSON equivalent", literal)
+}
+
+// normalizeJSON5Number converts a finite JSON5 number to a JSON number
+func normalizeJSON5Number(s string) (string, bool) {
+	sign := ""
+	switch {
+	case strings.HasPrefix(s, "-"):
+		sign, s = "-", s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		i, ok := new(big.Int).SetString(s[2:], 16)
+		if !ok || strings.ContainsAny(s[2:], "+-_") {
+			return "", false
+		}
+		if i.Sign() == 0 {
+			sign = ""
+		}
+		return sign + i.String(), true
+	}
+
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		return "", false
+	}
+
+	// leading or trailing decimal points
+	if len(s) > 1 && s[0] == '.' && isDigit(s[1]) {
+		s = "0" + s
+	}
+	if i := strings.IndexByte(s, '.'); i > 0 && isDigit(s[i-1]) && (i+1 == len(s) || s[i+1] == 'e' || s[i+1] == 'E') {
+		s = s[:i] + s[i+1:]
+	}
+
+	if !isValidNumber(s) {
+		return "", false
+	}
+	return sign + s, true
+}
+
+// lexIdentifier lexes an unquoted JSON5 identifier, it is a property name if followed by a colon and
+// otherwise a literal like true
+func (l *lexer) lexIdentifier() (Token, error) {
+	start := l.r.pos
+	k, err := l.literalLength()
+	if err != nil {
+		return unknownToken, err
+	}
+
+	raw := l.r.buf[l.r.i : l.r.i+k]
+	for j := 0; j < len(raw); {
+		r, size := utf8.DecodeRune(raw[j:])
+		if !isIdentifierPart(r) {
+			return unknownToken, errorAt(start, "invalid identifier: '%s%c'", raw[:j], r)
+		}
+		j += size
+	}
+	v := l.intern(raw)
+	l.r.advance(k)
+	end := l.r.pos
+
+	err = l.skipWhitespace()
+	if err != nil && err != io.EOF {
+		return unknownToken, err
+	}
+	if string(l.r.Peek(1)) == ":" {
+		return Token{Type: TokenTypeText, Value: v, End: end}, nil
+	}
+
+	if v == "NaN" || v == "Infinity" {
+		return unknownToken, nonFiniteError(start, v)
+	}
+	return Token{Type: TokenTypePrimitiveText, Value: v, End: end}, nil
+}
+
+// readJSON5Escape decodes the escape sequences JSON5 adds to JSON and appends them to text
+func (l *lexer) readJSON5Escape(text []byte, r rune, at Position) ([]byte, error) {
+	switch {
+	case r == '\'':
+		text = utf8.AppendRune(text, r)
+	case r == 'v':
+		text = append(text, '\v')
+	case r == '0':
+		if next := l.r.Peek(1); len(next) > 0 && '0' <= next[0] && next[0] <= '9' {
+			return text, errorAt(at, "invalid escape sequence in text: '\\0%c'", next[0])
+		}
+		text = append(text, 0)
+	case r == 'x':
+		hi, err := l.readHexDigit()
+		if err != nil {
+			return text, err
+		}
+		lo, err := l.readHexDigit()
+		if err != nil {
+			return text, err
+		}
+		text = utf8.AppendRune(text, hi<<4|lo)
+	case r == '\r':
+		// line continuations
+		if string(l.r.Peek(1)) == "\n" {
+			l.r.advance(1)
+		}
+	case r == '\n' || r == '\u2028' || r == '\u2029':
+	case '1' <= r && r <= '9':
+		return text, errorAt(at, "invalid escape sequence in text: '\\%c'", r)
+	default:
+		// any other character is escaped to itself
+		text = utf8.AppendRune(text, r)
+	}
+	return text, nil
+}
+
+func isDigit(b byte) bool {
+	return '0' <= b && b <= '9'
+}
+
+func isJSON5NumberLiteral(r rune) bool {
+	return isNumberLiteral(r) || r == '+' || r == '.'
+}
+
+func isIdentifierStart(r rune) bool {
+	return r == '_' || r == '$' || unicode.IsLetter(r) || unicode.Is(unicode.Nl, r)
+}
+
+func isIdentifierPart(r rune) bool {
+	return isIdentifierStart(r) || unicode.IsDigit(r) || unicode.In(r, unicode.Mn, unicode.Mc, unicode.Pc) ||
+		r == '\u200c' || r == '\u200d'
+}
+
+// isJSON5Whitespace accepts the whitespace of ECMAScript 5.1
+func isJSON5Whitespace(r rune) bool {
+	switch r {
+	case '\t', '\n', '\v', '\f', '\r', ' ', '\u00a0', '\u2028', '\u2029', bom:
+		return true
+	}
+	return unicode.Is(unicode.Zs, r)
+}
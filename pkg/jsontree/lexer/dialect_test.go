@@ -0,0 +1,183 @@
+package lexer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func lexAll(t *testing.T, raw string, d Dialect) ([]Token, error) {
+	lex := newLexer(strings.NewReader(raw), WithDialect(d))
+	var tokens []Token
+	for {
+		token, err := lex.Token()
+		if err != nil {
+			return tokens, err
+		}
+		// positions are covered separately
+		tokens = append(tokens, Token{Type: token.Type, Value: token.Value})
+		if token.Type == TokenTypeEOF {
+			return tokens, nil
+		}
+	}
+}
+
+func TestLexer_Token_JSONC(t *testing.T) {
+	raw := "\ufeff// settings\n{\n\t\"a\": [1, 2,], /* inline */ \"b\": true, // trailing\n}\n/* end */"
+
+	tokens, err := lexAll(t, raw, DialectJSONC)
+	require.NoError(t, err)
+	assert.Equal(t, []Token{
+		{Type: TokenTypeOpeningBrace},
+		{Type: TokenTypeText, Value: "a"},
+		{Type: TokenTypeColon},
+		{Type: TokenTypeOpeningBracket},
+		{Type: TokenTypePrimitiveNumber, Value: "1"},
+		{Type: TokenTypeComma},
+		{Type: TokenTypePrimitiveNumber, Value: "2"},
+		{Type: TokenTypeClosingBracket},
+		{Type: TokenTypeComma},
+		{Type: TokenTypeText, Value: "b"},
+		{Type: TokenTypeColon},
+		{Type: TokenTypePrimitiveText, Value: "true"},
+		{Type: TokenTypeClosingBrace},
+		{Type: TokenTypeEOF},
+	}, tokens)
+
+	// strict JSON stays the default
+	_, err = lexAll(t, raw, DialectJSON)
+	assert.Error(t, err)
+	_, err = lexAll(t, `[1,]`, DialectJSON)
+	assert.NoError(t, err, "trailing commas are rejected by the parser")
+}
+
+func TestLexer_Token_JSON5(t *testing.T) {
+	raw := `{
+		// comment
+		unquoted: 'single "quoted"',
+		$id_1: 0x1F, neg: -0XA,
+		lead: .5, trail: 5., plus: +1e3,
+		true: null,
+		multi: 'a\
+b\x41\v\0\q',
+		"list": [1, 2,],
+	}`
+
+	tokens, err := lexAll(t, raw, DialectJSON5)
+	require.NoError(t, err)
+
+	var values []string
+	for _, tkn := range tokens {
+		if tkn.Value != "" {
+			values = append(values, tkn.Type.String()+":"+tkn.Value)
+		}
+	}
+	assert.Equal(t, []string{
+		"TokenTypeText:unquoted", "TokenTypeText:single \"quoted\"",
+		"TokenTypeText:$id_1", "TokenTypePrimitiveNumber:31",
+		"TokenTypeText:neg", "TokenTypePrimitiveNumber:-10",
+		"TokenTypeText:lead", "TokenTypePrimitiveNumber:0.5",
+		"TokenTypeText:trail", "TokenTypePrimitiveNumber:5",
+		"TokenTypeText:plus", "TokenTypePrimitiveNumber:1e3",
+		"TokenTypeText:true", "TokenTypePrimitiveText:null",
+		"TokenTypeText:multi", "TokenTypeText:abA\v\x00q",
+		"TokenTypeText:list", "TokenTypePrimitiveNumber:1", "TokenTypePrimitiveNumber:2",
+	}, values)
+	assert.Equal(t, TokenTypeClosingBrace, tokens[len(tokens)-2].Type)
+	assert.Equal(t, TokenTypeClosingBracket, tokens[len(tokens)-3].Type)
+}
+
+func TestLexer_Token_JSON5_Positions(t *testing.T) {
+	lex := newLexer(strings.NewReader("{key /* c */ : 1}"), WithDialect(DialectJSON5))
+
+	_, err := lex.Token()
+	require.NoError(t, err)
+	key, err := lex.Token()
+	require.NoError(t, err)
+	assert.Equal(t, Position{Offset: 1, Line: 1, Column: 2}, key.Pos)
+	assert.Equal(t, Position{Offset: 4, Line: 1, Column: 5}, key.End)
+}
+
+func TestLexer_Token_DialectErrors(t *testing.T) {
+	tests := []struct {
+		raw              string
+		dialect          Dialect
+		expectedErrorMsg string
+	}{
+		{`/* open`, DialectJSONC, "unterminated comment"},
+		{`'single'`, DialectJSONC, "unrecognized token: '''"},
+		{`KEY`, DialectJSONC, "unrecognized token: 'K'"},
+		{`0x`, DialectJSON5, `invalid number literal: "0x"`},
+		{`0x1G`, DialectJSON5, `invalid number literal: "0x1G"`},
+		{`01`, DialectJSON5, `invalid number literal: "01"`},
+		{`'a\1'`, DialectJSON5, `invalid escape sequence in text: '\1'`},
+		{`'a\01'`, DialectJSON5, `invalid escape sequence in text: '\01'`},
+		{"'a\nb'", DialectJSON5, "unescaped line break in text"},
+		{`a-b`, DialectJSON5, "invalid identifier: 'a-'"},
+	}
+	for _, test := range tests {
+		t.Run(string(test.dialect)+" "+test.raw, func(t *testing.T) {
+			_, err := lexAll(t, test.raw, test.dialect)
+			assert.EqualError(t, err, test.expectedErrorMsg)
+		})
+	}
+}
+
+func TestLexer_Token_JSON5_NonFinite(t *testing.T) {
+	for _, tt := range []struct {
+		raw     string
+		literal string
+		pos     Position
+	}{
+		{"[1, NaN]", "NaN", Position{Offset: 4, Line: 1, Column: 5}},
+		{"{a: -Infinity}", "-Infinity", Position{Offset: 4, Line: 1, Column: 5}},
+		{"{a: 1,\n b: +NaN}", "+NaN", Position{Offset: 11, Line: 2, Column: 5}},
+		{"Infinity", "Infinity", Position{Offset: 0, Line: 1, Column: 1}},
+	} {
+		_, err := lexAll(t, tt.raw, DialectJSON5)
+		var lexErr *Error
+		require.ErrorAs(t, err, &lexErr, tt.raw)
+		assert.Equal(t, tt.pos, lexErr.Pos, tt.raw)
+		assert.Equal(t, tt.literal+" has no JSON equivalent", lexErr.Msg, tt.raw)
+	}
+
+	// as property names they are fine
+	_, err := lexAll(t, `{NaN: 1, Infinity: 2}`, DialectJSON5)
+	assert.NoError(t, err)
+}
+
+func TestNormalizeJSON5Number(t *testing.T) {
+	tests := map[string]string{
+		"0":                    "0",
+		"-0x0":                 "0",
+		"0xFFFFFFFFFFFFFFFFFF": "4722366482869645213695",
+		"+.5e-3":               "0.5e-3",
+		"-5.e2":                "-5e2",
+	}
+	for raw, expected := range tests {
+		n, ok := normalizeJSON5Number(raw)
+		assert.True(t, ok, raw)
+		assert.Equal(t, expected, n, raw)
+	}
+
+	for _, raw := range []string{"", "+", ".", "1..2", "0x-1", "Inf", "--1", "Infinity", "-NaN"} {
+		_, ok := normalizeJSON5Number(raw)
+		assert.False(t, ok, raw)
+	}
+}
+
+func TestParseDialect(t *testing.T) {
+	d, err := ParseDialect("JSON5")
+	require.NoError(t, err)
+	assert.Equal(t, DialectJSON5, d)
+
+	_, err = ParseDialect("yaml")
+	assert.Error(t, err)
+
+	var flag DialectFlag
+	require.NoError(t, flag.Dialect.UnmarshalText([]byte("JSONC")))
+	assert.Equal(t, DialectJSONC, flag.Dialect)
+	assert.Error(t, flag.Dialect.UnmarshalText([]byte("yaml")))
+}
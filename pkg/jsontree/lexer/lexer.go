@@ -1,11 +1,11 @@
 package lexer
 
 import (
-	"bufio"
 	"fmt"
 	"io"
-	"strings"
 	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
 )
 
 //go:generate stringer -type=TokenType
@@ -20,23 +20,25 @@ const (
 	TokenTypeComma
 	TokenTypeColon
 
-	// TokenTypeText is a token of escaped text in double quotes
+	// TokenTypeText is a token of text in double quotes, the value holds the unescaped text
 	TokenTypeText
 
 	// TokenTypePrimitiveText is a token of unescaped, lower-case alpha text
 	TokenTypePrimitiveText
 
-	// TokenTypePrimitiveNumber is a token of a json number
+	// TokenTypePrimitiveNumber is a token of a json number, the value holds the literal as is
 	TokenTypePrimitiveNumber
 
 	TokenTypeEOF
 )
 
-const delimiters = ",{}[]:"
-
 type Token struct {
 	Type  TokenType
 	Value string
+
+	// Pos is the position of the first character of the token, End the one right after it
+	Pos Position
+	End Position
 }
 
 var (
@@ -62,50 +64,132 @@ type Lexer interface {
 	Peek() (Token, error)
 }
 
+const (
+	// maxInterned bounds the number of distinct texts a lexer interns, maxInternedLength their length
+	maxInterned       = 1024
+	maxInternedLength = 32
+)
+
 type lexer struct {
-	r *bufio.Reader
+	r       *source
+	dialect Dialect
+
+	// scratch is reused to unescape text
+	scratch []byte
+
+	// interned holds short texts seen before, e.g. the property names repeated in every record of NDJSON
+	interned map[string]string
 }
 
-func NewLexer(r io.Reader) Lexer {
+// NewLexer lexes strict JSON unless a lenient dialect is selected with WithDialect
+func NewLexer(r io.Reader, opts ...Option) Lexer {
 	return &peekable{
-		tokenizer: &lexer{
-			r: bufio.NewReader(r),
-		},
+		tokenizer: newLexer(r, opts...),
 	}
 }
 
+func newLexer(r io.Reader, opts ...Option) *lexer {
+	l := &lexer{r: newSource(r), dialect: DialectJSON}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
 func (l *lexer) Token() (Token, error) {
+	for {
+		token, err := l.nextToken()
+		if err != nil || token.Type != TokenTypeComma || l.dialect == DialectJSON {
+			return token, err
+		}
+
+		// lenient dialects drop trailing commas
+		trailing, err := l.followedByClosing()
+		if err != nil {
+			return unknownToken, err
+		}
+		if !trailing {
+			return token, nil
+		}
+	}
+}
+
+func (l *lexer) nextToken() (Token, error) {
 	err := l.skipWhitespace()
+	if err != nil && err != io.EOF {
+		return unknownToken, err
+	}
+
+	start := l.r.pos
+	token, err := l.token(err == io.EOF)
 	if err != nil {
-		if err == io.EOF {
-			return eofToken, nil
-		}
 		return unknownToken, err
 	}
 
-	r, _, err := l.r.ReadRune()
-	switch r {
+	token.Pos = start
+	if token.End.Line == 0 {
+		token.End = l.r.pos
+	}
+	return token, nil
+}
+
+func (l *lexer) Excerpt(pos Position) (string, int, bool) {
+	return l.r.Excerpt(pos)
+}
+
+func (l *lexer) token(eof bool) (Token, error) {
+	if eof {
+		return eofToken, nil
+	}
+
+	start := l.r.pos
+	b, err := l.r.peekByte()
+	if err != nil {
+		return unknownToken, err
+	}
+
+	token := unknownToken
+	switch b {
 	case '"':
-		return l.lexText()
+		l.r.advance(1)
+		return l.lexText('"')
+	case '\'':
+		if l.dialect == DialectJSON5 {
+			l.r.advance(1)
+			return l.lexText('\'')
+		}
 	case '{':
-		return openingBraceToken, nil
+		token = openingBraceToken
 	case '}':
-		return closingBraceToken, nil
+		token = closingBraceToken
 	case '[':
-		return openingBracketToken, nil
+		token = openingBracketToken
 	case ']':
-		return closingBracketToken, nil
+		token = closingBracketToken
 	case ':':
-		return colonToken, nil
+		token = colonToken
 	case ',':
-		return commaToken, nil
+		token = commaToken
+	}
+	if token.Type != TokenTypeUnknown {
+		l.r.advance(1)
+		return token, nil
 	}
 
-	err = l.r.UnreadRune()
+	r, _, err := l.r.peekRune()
 	if err != nil {
 		return unknownToken, err
 	}
 
+	if l.dialect == DialectJSON5 {
+		if isJSON5NumberLiteral(r) {
+			return l.lexJSON5Number()
+		}
+		if isIdentifierStart(r) {
+			return l.lexIdentifier()
+		}
+	}
+
 	if isNumberLiteral(r) {
 		return l.lexPrimitiveNumber()
 	}
@@ -114,137 +198,403 @@ func (l *lexer) Token() (Token, error) {
 		return l.lexPrimitiveText()
 	}
 
-	return unknownToken, fmt.Errorf("unrecognized token: '%c'", r)
+	return unknownToken, errorAt(start, "unrecognized token: '%c'", r)
 }
 
 func (l *lexer) skipWhitespace() error {
 	for {
-		r, _, err := l.r.ReadRune()
+		l.r.skipSpace()
+		if l.dialect != DialectJSON {
+			skipped, err := l.skipComment()
+			if err != nil {
+				return err
+			}
+			if skipped {
+				continue
+			}
+		}
+
+		r, size, err := l.r.peekRune()
 		if err != nil {
 			return err
 		}
-		if !unicode.IsSpace(r) {
-			return l.r.UnreadRune()
+		if !l.isWhitespace(r) {
+			return nil
+		}
+		l.r.advance(size)
+	}
+}
+
+// literalLength scans the bytes of an unquoted literal up to the next delimiter without consuming them
+func (l *lexer) literalLength() (int, error) {
+	s := l.r
+	k := 0
+	for {
+		if s.i+k == s.n && !s.fill() {
+			if err := s.readErr(); err != io.EOF {
+				return 0, err
+			}
+			return k, nil
+		}
+
+		b := s.buf[s.i+k]
+		if b < utf8.RuneSelf {
+			if l.isDelimiter(rune(b)) {
+				return k, nil
+			}
+			k++
+			continue
+		}
+
+		if !utf8.FullRune(s.buf[s.i+k : s.n]) {
+			s.ensure(k + utf8.UTFMax)
+		}
+		r, size := utf8.DecodeRune(s.buf[s.i+k : s.n])
+		if l.isDelimiter(r) {
+			return k, nil
 		}
+		k += size
 	}
 }
 
+// readLiteral consumes an unquoted literal
+func (l *lexer) readLiteral() (string, error) {
+	k, err := l.literalLength()
+	if err != nil {
+		return "", err
+	}
+	v := literalString(l.r.buf[l.r.i : l.r.i+k])
+	l.r.advance(k)
+	return v, nil
+}
+
+// literalString converts the literals of JSON without allocating
+func literalString(b []byte) string {
+	switch string(b) {
+	case "true":
+		return "true"
+	case "false":
+		return "false"
+	case "null":
+		return "null"
+	case "0":
+		return "0"
+	case "1":
+		return "1"
+	}
+	return string(b)
+}
+
 func (l *lexer) lexPrimitiveNumber() (Token, error) {
-	v, err := l.readPrimitiveNumber()
+	start := l.r.pos
+	v, err := l.readLiteral()
 	if err != nil {
 		return unknownToken, err
 	}
 
-	// TODO: Validate literal?
+	if !isValidNumber(v) {
+		return unknownToken, errorAt(start, "invalid number literal: %q", v)
+	}
 
 	return Token{Type: TokenTypePrimitiveNumber, Value: v}, nil
 }
 
-func (l *lexer) readPrimitiveNumber() (string, error) {
-	var s strings.Builder
-	for {
-		r, _, err := l.r.ReadRune()
-		if err != nil {
-			if err == io.EOF {
-				return s.String(), nil
-			}
-			return "", err
-		}
-
-		if isDelimiter(r) {
-			return s.String(), l.r.UnreadRune()
-		}
+func (l *lexer) lexPrimitiveText() (Token, error) {
+	start := l.r.pos
+	k, err := l.literalLength()
+	if err != nil {
+		return unknownToken, err
+	}
 
-		_, err = s.WriteRune(r)
-		if err != nil {
-			return "", err
+	raw := l.r.buf[l.r.i : l.r.i+k]
+	for j := 0; j < len(raw); {
+		r, size := utf8.DecodeRune(raw[j:])
+		if !isAlpha(r) {
+			return unknownToken, errorAt(start, "invalid primitive text: '%s%c'", raw[:j], r)
 		}
+		j += size
 	}
+
+	v := literalString(raw)
+	l.r.advance(k)
+	return Token{Type: TokenTypePrimitiveText, Value: v}, nil
 }
 
-func (l *lexer) lexPrimitiveText() (Token, error) {
-	v, err := l.readPrimitiveText()
+func (l *lexer) lexText(quote byte) (Token, error) {
+	v, err := l.readText(quote)
 	if err != nil {
 		return unknownToken, err
 	}
 
-	return Token{Type: TokenTypePrimitiveText, Value: v}, nil
+	return Token{Type: TokenTypeText, Value: v}, nil
 }
 
-func (l *lexer) readPrimitiveText() (string, error) {
-	var s strings.Builder
+// readText reads the remainder of a quoted text. Text without escapes is taken straight from the buffer,
+// anything else is unescaped rune by rune.
+func (l *lexer) readText(quote byte) (string, error) {
+	s := l.r
+	k := 0
+	for {
+		if s.i+k == s.n && !s.fill() {
+			break
+		}
+		b := s.buf[s.i+k]
+		if b == quote {
+			raw := s.buf[s.i : s.i+k]
+			if !utf8.Valid(raw) {
+				// replace invalid UTF-8 rune by rune
+				k = 0
+				break
+			}
+			v := l.intern(raw)
+			s.advance(k + 1)
+			return v, nil
+		}
+		if b == '\\' || b < 0x20 {
+			if !utf8.Valid(s.buf[s.i : s.i+k]) {
+				k = 0
+			}
+			break
+		}
+		k++
+	}
+
+	text := append(l.scratch[:0], s.buf[s.i:s.i+k]...)
+	s.advance(k)
+	defer func() {
+		l.scratch = text[:0]
+	}()
+
 	for {
-		r, _, err := l.r.ReadRune()
+		at := s.pos
+		r, _, err := s.ReadRune()
 		if err != nil {
 			if err == io.EOF {
-				return s.String(), nil
+				return "", errorAt(at, "unexpected EOF in text")
 			}
 			return "", err
 		}
 
-		if isDelimiter(r) {
-			return s.String(), l.r.UnreadRune()
+		switch {
+		case r == rune(quote):
+			return l.intern(text), nil
+		case r == '\\':
+			text, err = l.readEscape(text, at)
+			if err != nil {
+				return "", err
+			}
+		case l.dialect == DialectJSON5 && (r == '\n' || r == '\r'):
+			return "", errorAt(at, "unescaped line break in text")
+		case r < 0x20 && l.dialect != DialectJSON5:
+			return "", errorAt(at, "invalid control character in text: %U", r)
+		default:
+			text = utf8.AppendRune(text, r)
 		}
+	}
+}
 
-		if !isAlpha(r) {
-			return "", fmt.Errorf("invalid primitive text: '%s%c'", s.String(), r)
-		}
+// intern converts text to a string, short texts seen before are not allocated again
+func (l *lexer) intern(b []byte) string {
+	if len(b) > maxInternedLength {
+		return string(b)
+	}
+	if v, ok := l.interned[string(b)]; ok {
+		return v
+	}
 
-		_, err = s.WriteRune(r)
-		if err != nil {
-			return "", err
+	v := string(b)
+	if l.interned == nil {
+		l.interned = make(map[string]string)
+	}
+	if len(l.interned) < maxInterned {
+		l.interned[v] = v
+	}
+	return v
+}
+
+// readEscape decodes the escape sequence following the backslash at the given position and appends it to text
+func (l *lexer) readEscape(text []byte, at Position) ([]byte, error) {
+	r, _, err := l.r.ReadRune()
+	if err != nil {
+		if err == io.EOF {
+			return text, errorAt(l.r.pos, "unexpected EOF in text")
 		}
+		return text, err
+	}
+
+	switch r {
+	case '"', '\\', '/':
+		return append(text, byte(r)), nil
+	case 'b':
+		return append(text, '\b'), nil
+	case 'f':
+		return append(text, '\f'), nil
+	case 'n':
+		return append(text, '\n'), nil
+	case 'r':
+		return append(text, '\r'), nil
+	case 't':
+		return append(text, '\t'), nil
+	case 'u':
+		return l.readUnicodeEscape(text)
+	}
+	if l.dialect == DialectJSON5 {
+		return l.readJSON5Escape(text, r, at)
 	}
+	return text, errorAt(at, "invalid escape sequence in text: '\\%c'", r)
 }
 
-func (l *lexer) lexText() (Token, error) {
-	v, err := l.readText()
+// readUnicodeEscape decodes a '\uXXXX' escape, combining UTF-16 surrogate pairs. Like encoding/json, unpaired
+// surrogates are replaced with U+FFFD.
+func (l *lexer) readUnicodeEscape(text []byte) ([]byte, error) {
+	r, err := l.readHex()
 	if err != nil {
-		return unknownToken, err
+		return text, err
 	}
 
-	return Token{Type: TokenTypeText, Value: v}, nil
+	for utf16.IsSurrogate(r) {
+		if r >= 0xdc00 {
+			// a low surrogate without a high one
+			break
+		}
+
+		if string(l.r.Peek(2)) != `\u` {
+			break
+		}
+		l.r.advance(2)
+
+		low, err := l.readHex()
+		if err != nil {
+			return text, err
+		}
+		if 0xdc00 <= low && low <= 0xdfff {
+			return utf8.AppendRune(text, utf16.DecodeRune(r, low)), nil
+		}
+
+		// the high surrogate is unpaired, continue with the next escape
+		text = utf8.AppendRune(text, unicode.ReplacementChar)
+		r = low
+	}
+
+	if utf16.IsSurrogate(r) {
+		r = unicode.ReplacementChar
+	}
+	return utf8.AppendRune(text, r), nil
 }
 
-func (l *lexer) readText() (string, error) {
-	var s strings.Builder
-	for {
-		r, _, err := l.r.ReadRune()
+func (l *lexer) readHex() (rune, error) {
+	var r rune
+	for i := 0; i < 4; i++ {
+		v, err := l.readHexDigit()
 		if err != nil {
-			if err == io.EOF {
-				return "", fmt.Errorf("unexpected EOF in text")
-			}
-			return "", err
+			return 0, err
 		}
+		r = r<<4 | v
+	}
+	return r, nil
+}
 
-		if r == '"' {
-			return s.String(), nil
+func (l *lexer) readHexDigit() (rune, error) {
+	at := l.r.pos
+	b, _, err := l.r.ReadRune()
+	if err != nil {
+		if err == io.EOF {
+			return 0, errorAt(at, "unexpected EOF in text")
 		}
-		if r == '\\' {
-			_, err = s.WriteRune(r)
-			if err != nil {
-				return "", err
-			}
+		return 0, err
+	}
 
-			r, _, err = l.r.ReadRune()
-			if err != nil {
-				return "", err
-			}
+	switch {
+	case '0' <= b && b <= '9':
+		return b - '0', nil
+	case 'a' <= b && b <= 'f':
+		return b - 'a' + 10, nil
+	case 'A' <= b && b <= 'F':
+		return b - 'A' + 10, nil
+	}
+	return 0, errorAt(at, "invalid unicode escape in text, expected hex digit but got: '%c'", b)
+}
+
+// isValidNumber checks the number grammar of RFC 8259:
+//
+//	number = [ minus ] int [ frac ] [ exp ]
+func isValidNumber[T string | []byte](s T) bool {
+	i := 0
+	if i < len(s) && s[i] == '-' {
+		i++
+	}
+
+	// int, no leading zeros
+	switch {
+	case i < len(s) && s[i] == '0':
+		i++
+	case i < len(s) && '1' <= s[i] && s[i] <= '9':
+		i = skipDigits(s, i)
+	default:
+		return false
+	}
+
+	// frac
+	if i < len(s) && s[i] == '.' {
+		start := i + 1
+		i = skipDigits(s, start)
+		if i == start {
+			return false
 		}
+	}
 
-		_, err = s.WriteRune(r)
-		if err != nil {
-			return "", err
+	// exp
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		i++
+		if i < len(s) && (s[i] == '+' || s[i] == '-') {
+			i++
+		}
+		start := i
+		i = skipDigits(s, start)
+		if i == start {
+			return false
 		}
 	}
+
+	return i == len(s)
+}
+
+func skipDigits[T string | []byte](s T, i int) int {
+	for i < len(s) && '0' <= s[i] && s[i] <= '9' {
+		i++
+	}
+	return i
+}
+
+func errorAt(pos Position, format string, args ...any) error {
+	return &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (l *lexer) isDelimiter(r rune) bool {
+	switch r {
+	case ',', '{', '}', '[', ']', ':', ' ', '\t', '\n', '\r':
+		return true
+	}
+	return l.dialect != DialectJSON && (r == '/' || l.isWhitespace(r))
+}
+
+func (l *lexer) isWhitespace(r rune) bool {
+	switch l.dialect {
+	case DialectJSONC:
+		return isWhitespace(r) || r == bom
+	case DialectJSON5:
+		return isJSON5Whitespace(r)
+	}
+	return isWhitespace(r)
 }
 
-func isDelimiter(r rune) bool {
-	return unicode.IsSpace(r) || strings.ContainsRune(delimiters, r)
+// isWhitespace only accepts the whitespace of RFC 8259, other unicode spaces are invalid outside of text
+func isWhitespace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r'
 }
 
 func isNumberLiteral(r rune) bool {
-	return unicode.IsDigit(r) || r == '-'
+	return ('0' <= r && r <= '9') || r == '-'
 }
 
 func isAlpha(r rune) bool {
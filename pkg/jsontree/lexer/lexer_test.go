@@ -1,16 +1,17 @@
 package lexer
 
 import (
-	"bufio"
+	"io"
 	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/stretchr/testify/assert"
 )
 
 func TestLexer_Token_SkipWhitespace(t *testing.T) {
 	raw := "   \t "
-	lex := &lexer{r: bufio.NewReader(strings.NewReader(raw))}
+	lex := newLexer(strings.NewReader(raw))
 
 	_, err := lex.Token()
 	assert.NoError(t, err)
@@ -96,7 +97,7 @@ func TestLexer_Token_WithSuccess(t *testing.T) {
 			name: "string 3",
 			raw:  `   "hi\n"`,
 			expected: []Token{
-				{Type: TokenTypeText, Value: "hi\\n"},
+				{Type: TokenTypeText, Value: "hi\n"},
 				{Type: TokenTypeEOF},
 			},
 		},
@@ -104,15 +105,15 @@ func TestLexer_Token_WithSuccess(t *testing.T) {
 			name: "string 4 - escaping",
 			raw:  `   "hi\""`,
 			expected: []Token{
-				{Type: TokenTypeText, Value: "hi\\\""},
+				{Type: TokenTypeText, Value: "hi\""},
 				{Type: TokenTypeEOF},
 			},
 		},
 		{
 			name: "string 5 - escaping",
-			raw:  `   "hi\n"`,
+			raw:  `   "\\\/\b\f\n\r\t"`,
 			expected: []Token{
-				{Type: TokenTypeText, Value: "hi\\n"},
+				{Type: TokenTypeText, Value: "\\/\b\f\n\r\t"},
 				{Type: TokenTypeEOF},
 			},
 		},
@@ -141,10 +142,10 @@ func TestLexer_Token_WithSuccess(t *testing.T) {
 			},
 		},
 		{
-			name: "number 2",
-			raw:  "   -01.23 ",
+			name: "number 3",
+			raw:  "   -10.23e-4 ",
 			expected: []Token{
-				{Type: TokenTypePrimitiveNumber, Value: "-01.23"},
+				{Type: TokenTypePrimitiveNumber, Value: "-10.23e-4"},
 				{Type: TokenTypeEOF},
 			},
 		},
@@ -171,7 +172,7 @@ func TestLexer_Token_WithSuccess(t *testing.T) {
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			lex := &lexer{r: bufio.NewReader(strings.NewReader(test.raw))}
+			lex := newLexer(strings.NewReader(test.raw))
 			var tokens []Token
 			for {
 				token, err := lex.Token()
@@ -179,7 +180,8 @@ func TestLexer_Token_WithSuccess(t *testing.T) {
 					assert.FailNow(t, "error lexing token", err)
 				}
 				assert.NoError(t, err)
-				tokens = append(tokens, token)
+				// positions are covered separately
+				tokens = append(tokens, Token{Type: token.Type, Value: token.Value})
 				if token.Type == TokenTypeEOF {
 					break
 				}
@@ -189,6 +191,159 @@ func TestLexer_Token_WithSuccess(t *testing.T) {
 	}
 }
 
+func TestLexer_LexText_Unicode(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		expected string
+	}{
+		{
+			name:     "latin",
+			raw:      `"caf\u00e9"`,
+			expected: "café",
+		},
+		{
+			name:     "upper case hex",
+			raw:      `"\u00E9\u00e9"`,
+			expected: "éé",
+		},
+		{
+			name:     "verbatim utf8",
+			raw:      `"café 日本"`,
+			expected: "café 日本",
+		},
+		{
+			name:     "null character",
+			raw:      `"a\u0000b"`,
+			expected: "a\x00b",
+		},
+		{
+			name:     "surrogate pair",
+			raw:      `"\ud83d\ude00"`,
+			expected: "😀",
+		},
+		{
+			name:     "lone high surrogate",
+			raw:      `"\ud83dx"`,
+			expected: "\ufffdx",
+		},
+		{
+			name:     "lone low surrogate",
+			raw:      `"\ude00"`,
+			expected: "\ufffd",
+		},
+		{
+			name:     "high surrogate followed by escape",
+			raw:      `"\ud83d\u00e9"`,
+			expected: "\ufffdé",
+		},
+		{
+			name:     "two high surrogates and a low one",
+			raw:      `"\ud83d\ud83d\ude00"`,
+			expected: "\ufffd😀",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lex := newLexer(strings.NewReader(test.raw))
+			token, err := lex.Token()
+			assert.NoError(t, err)
+			assert.Equal(t, TokenTypeText, token.Type)
+			assert.Equal(t, test.expected, token.Value)
+		})
+	}
+}
+
+func TestLexer_Token_WithError(t *testing.T) {
+	tests := []struct {
+		name             string
+		raw              string
+		expectedErrorMsg string
+	}{
+		{
+			name:             "invalid escape",
+			raw:              `"\x41"`,
+			expectedErrorMsg: `invalid escape sequence in text: '\x'`,
+		},
+		{
+			name:             "short unicode escape",
+			raw:              `"\u12"`,
+			expectedErrorMsg: `invalid unicode escape in text, expected hex digit but got: '"'`,
+		},
+		{
+			name:             "unterminated escape",
+			raw:              `"\`,
+			expectedErrorMsg: "unexpected EOF in text",
+		},
+		{
+			name:             "raw newline",
+			raw:              "\"a\nb\"",
+			expectedErrorMsg: "invalid control character in text: U+000A",
+		},
+		{
+			name:             "raw tab",
+			raw:              "\"a\tb\"",
+			expectedErrorMsg: "invalid control character in text: U+0009",
+		},
+		{
+			name:             "garbage number",
+			raw:              "-1-2abc",
+			expectedErrorMsg: `invalid number literal: "-1-2abc"`,
+		},
+		{
+			name:             "leading zero",
+			raw:              "012",
+			expectedErrorMsg: `invalid number literal: "012"`,
+		},
+		{
+			name:             "missing fraction",
+			raw:              "1.",
+			expectedErrorMsg: `invalid number literal: "1."`,
+		},
+		{
+			name:             "missing exponent",
+			raw:              "1e+",
+			expectedErrorMsg: `invalid number literal: "1e+"`,
+		},
+		{
+			name:             "minus only",
+			raw:              "-",
+			expectedErrorMsg: `invalid number literal: "-"`,
+		},
+		{
+			name:             "leading plus",
+			raw:              "+1",
+			expectedErrorMsg: "unrecognized token: '+'",
+		},
+		{
+			name:             "non-ascii digit",
+			raw:              "١",
+			expectedErrorMsg: "unrecognized token: '١'",
+		},
+		{
+			name:             "form feed is no whitespace",
+			raw:              "\f1",
+			expectedErrorMsg: "unrecognized token: '\f'",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lex := newLexer(strings.NewReader(test.raw))
+			_, err := lex.Token()
+			assert.EqualError(t, err, test.expectedErrorMsg)
+		})
+	}
+}
+
+func TestIsValidNumber(t *testing.T) {
+	for _, valid := range []string{"0", "-0", "1", "-1", "10", "0.5", "-0.5", "1e3", "1E3", "1e+3", "1e-3", "1.5e-10", "123456789012345678901234567890"} {
+		assert.True(t, isValidNumber(valid), valid)
+	}
+	for _, invalid := range []string{"", "-", "00", "01", "-01", ".5", "1.", "1.e3", "1e", "1e+", "0x10", "1_000", "Infinity", "NaN", "1.5.5", "--1"} {
+		assert.False(t, isValidNumber(invalid), invalid)
+	}
+}
+
 func TestLexer_LexPrimitiveText(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -228,10 +383,109 @@ func TestLexer_LexPrimitiveText(t *testing.T) {
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			lex := &lexer{r: bufio.NewReader(strings.NewReader(test.raw))}
+			lex := newLexer(strings.NewReader(test.raw))
 			token, err := lex.lexPrimitiveText()
 			assert.NoError(t, err)
 			assert.Equal(t, test.expected, token.Value)
 		})
 	}
 }
+
+func TestLexer_Token_Positions(t *testing.T) {
+	raw := "{\"a\": [1,\n  \"é\", true]}"
+	lex := newLexer(strings.NewReader(raw))
+
+	expected := []struct {
+		typ      TokenType
+		pos, end Position
+	}{
+		{TokenTypeOpeningBrace, Position{0, 1, 1}, Position{1, 1, 2}},
+		{TokenTypeText, Position{1, 1, 2}, Position{4, 1, 5}},
+		{TokenTypeColon, Position{4, 1, 5}, Position{5, 1, 6}},
+		{TokenTypeOpeningBracket, Position{6, 1, 7}, Position{7, 1, 8}},
+		{TokenTypePrimitiveNumber, Position{7, 1, 8}, Position{8, 1, 9}},
+		{TokenTypeComma, Position{8, 1, 9}, Position{9, 1, 10}},
+		{TokenTypeText, Position{12, 2, 3}, Position{16, 2, 6}},
+		{TokenTypeComma, Position{16, 2, 6}, Position{17, 2, 7}},
+		{TokenTypePrimitiveText, Position{18, 2, 8}, Position{22, 2, 12}},
+		{TokenTypeClosingBracket, Position{22, 2, 12}, Position{23, 2, 13}},
+		{TokenTypeClosingBrace, Position{23, 2, 13}, Position{24, 2, 14}},
+		{TokenTypeEOF, Position{24, 2, 14}, Position{24, 2, 14}},
+	}
+	for _, e := range expected {
+		token, err := lex.Token()
+		assert.NoError(t, err)
+		assert.Equal(t, e.typ, token.Type)
+		assert.Equal(t, e.pos, token.Pos, "start of %s", token.Type)
+		assert.Equal(t, e.end, token.End, "end of %s", token.Type)
+	}
+}
+
+func TestLexer_Token_ErrorPosition(t *testing.T) {
+	lex := newLexer(strings.NewReader("[1,\n \"ab\\x\"]"))
+	for i := 0; i < 3; i++ {
+		_, err := lex.Token()
+		assert.NoError(t, err)
+	}
+
+	_, err := lex.Token()
+	var lexErr *Error
+	assert.ErrorAs(t, err, &lexErr)
+	assert.Equal(t, Position{Offset: 8, Line: 2, Column: 5}, lexErr.Pos)
+
+	line, column, ok := lex.Excerpt(lexErr.Pos)
+	assert.True(t, ok)
+	assert.Equal(t, ` "ab\x"]`, line)
+	assert.Equal(t, 4, column)
+
+	line, column, ok = lex.Excerpt(Position{Offset: 1, Line: 1, Column: 2})
+	assert.True(t, ok)
+	assert.Equal(t, "[1,", line)
+	assert.Equal(t, 1, column)
+}
+
+func TestLexer_Excerpt_LongLine(t *testing.T) {
+	raw := "[" + strings.Repeat(`"abcdefghi",`, 100) + "*]"
+	lex := newLexer(strings.NewReader(raw))
+
+	var err error
+	for err == nil {
+		_, err = lex.Token()
+	}
+	var lexErr *Error
+	assert.ErrorAs(t, err, &lexErr)
+	assert.Equal(t, 1202, lexErr.Pos.Column)
+
+	line, column, ok := lex.Excerpt(lexErr.Pos)
+	assert.True(t, ok)
+	assert.True(t, strings.HasPrefix(line, "..."))
+	assert.Equal(t, "*]", line[column:])
+	assert.LessOrEqual(t, len(line), maxExcerpt)
+}
+
+func TestLexer_Token_SplitReads(t *testing.T) {
+	long := strings.Repeat("\u00e4bc\u2603", 20000)
+	raw := `{"short":"x","long":"` + long + `","escaped":"a\"\u00e4\ud83d\ude00` + long + `",` +
+		"\"invalid\":\"a\xffb\",\"number\":-12.5e3,\"ok\":true}\n" + strings.Repeat(`[null, 1, "\u00e4"]`+"\n", 1000)
+
+	lexAll := func(r io.Reader) []Token {
+		lex := newLexer(r)
+		var tokens []Token
+		for {
+			tkn, err := lex.Token()
+			assert.NoError(t, err)
+			tokens = append(tokens, tkn)
+			if err != nil || tkn.Type == TokenTypeEOF {
+				return tokens
+			}
+		}
+	}
+
+	expected := lexAll(strings.NewReader(raw))
+	assert.Equal(t, long, expected[7].Value)
+	assert.Equal(t, "a\"\u00e4\U0001f600"+long, expected[11].Value)
+	assert.Equal(t, "a\ufffdb", expected[15].Value)
+
+	assert.Equal(t, expected, lexAll(iotest.OneByteReader(strings.NewReader(raw))))
+	assert.Equal(t, expected, lexAll(iotest.HalfReader(strings.NewReader(raw))))
+}
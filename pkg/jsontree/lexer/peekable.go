@@ -4,7 +4,8 @@ import "fmt"
 
 type peekable struct {
 	tokenizer Tokenizer
-	peeked    *Token
+	peeked    Token
+	hasPeeked bool
 	err       error
 }
 
@@ -13,12 +14,12 @@ func (p *peekable) Peek() (Token, error) {
 		return unknownToken, p.err
 	}
 
-	if p.peeked != nil {
-		return *p.peeked, nil
+	if p.hasPeeked {
+		return p.peeked, nil
 	}
 
 	token, err := p.Token()
-	p.peeked = &token
+	p.peeked, p.hasPeeked = token, true
 
 	return token, err
 }
@@ -28,10 +29,9 @@ func (p *peekable) Token() (Token, error) {
 		return unknownToken, p.err
 	}
 
-	if p.peeked != nil {
-		t := p.peeked
-		p.peeked = nil
-		return *t, nil
+	if p.hasPeeked {
+		p.hasPeeked = false
+		return p.peeked, nil
 	}
 
 	token, err := p.tokenizer.Token()
@@ -43,3 +43,10 @@ func (p *peekable) Token() (Token, error) {
 
 	return token, nil
 }
+
+func (p *peekable) Excerpt(pos Position) (string, int, bool) {
+	if e, ok := p.tokenizer.(Excerpter); ok {
+		return e.Excerpt(pos)
+	}
+	return "", 0, false
+}
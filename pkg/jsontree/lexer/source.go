@@ -0,0 +1,252 @@
+package lexer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// maxExcerpt is the number of bytes kept around a position to render an excerpt of the source
+const maxExcerpt = 80
+
+// Position is a location in the source, lines and columns start at 1 and columns count characters
+type Position struct {
+	// Offset is the zero based byte offset
+	Offset int64
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("line %d, column %d", p.Line, p.Column)
+}
+
+// Error is a lexing error at a specific position of the source
+type Error struct {
+	Pos Position
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return e.Msg
+}
+
+// Excerpter is implemented by lexers able to show the source around a position, e.g. to point at errors
+type Excerpter interface {
+	// Excerpt returns the line of the source containing the position, possibly shortened, and the zero based
+	// column of the position within the returned line. Only recent lines are still available.
+	Excerpt(pos Position) (line string, column int, ok bool)
+}
+
+const (
+	// minBufferSize is the initial size of the read buffer, it grows up to maxBufferSize as more input is read
+	minBufferSize = 4 << 10
+	maxBufferSize = 64 << 10
+
+	// keepBehind is the number of bytes kept before the read position to render excerpts of recent lines
+	keepBehind = 4 * maxExcerpt
+)
+
+// source is a byte-oriented reader keeping track of the position. Tokens are scanned directly in the buffer,
+// bytes are only consumed with advance. Unconsumed bytes are never discarded, hence a token may be scanned ahead
+// by an offset relative to the read position while the buffer is refilled.
+type source struct {
+	r   io.Reader
+	err error
+
+	// buf[i:n] is buffered but not yet consumed, buf[0] is at offset base of the input. Some bytes before i are
+	// kept for excerpts.
+	buf  []byte
+	i, n int
+	base int64
+
+	// pos is the position of buf[i]
+	pos Position
+
+	// lineStart and prevLineStart are the offsets of the current and the previous line, -1 if there is none
+	lineStart, prevLineStart int64
+}
+
+func newSource(r io.Reader) *source {
+	return &source{r: r, pos: Position{Line: 1, Column: 1}, prevLineStart: -1}
+}
+
+// fill reads more input, it returns false if there is none left or reading failed
+func (s *source) fill() bool {
+	if s.err != nil {
+		return false
+	}
+
+	if s.n == len(s.buf) {
+		s.compact()
+	}
+	for {
+		m, err := s.r.Read(s.buf[s.n:])
+		s.n += m
+		if err != nil {
+			s.err = err
+		}
+		if m > 0 || err != nil {
+			return m > 0
+		}
+	}
+}
+
+// compact makes room at the end of the buffer, keeping the unconsumed bytes and the bytes needed for excerpts
+func (s *source) compact() {
+	keep := s.i
+	if from := max(s.prevLineStart, s.base+int64(s.i)-keepBehind); from < s.base+int64(s.i) {
+		keep = int(max(from-s.base, 0))
+	}
+
+	// grow while small or if the kept bytes take up most of the buffer, e.g. for a long token
+	buf := s.buf
+	if len(s.buf) < maxBufferSize || s.n-keep > len(s.buf)/2 {
+		buf = make([]byte, max(2*len(s.buf), minBufferSize))
+	}
+	s.n = copy(buf, s.buf[keep:s.n])
+	s.buf = buf
+	s.i -= keep
+	s.base += int64(keep)
+}
+
+// ensure tries to buffer at least k unconsumed bytes, it reports whether there are enough
+func (s *source) ensure(k int) bool {
+	for s.n-s.i < k {
+		if !s.fill() {
+			return false
+		}
+	}
+	return true
+}
+
+// readErr is the error at the end of the input, usually io.EOF
+func (s *source) readErr() error {
+	if s.err == nil {
+		return io.EOF
+	}
+	return s.err
+}
+
+func (s *source) peekByte() (byte, error) {
+	if s.i == s.n && !s.fill() {
+		return 0, s.readErr()
+	}
+	return s.buf[s.i], nil
+}
+
+// peekRune decodes the next rune without consuming it, invalid UTF-8 results in utf8.RuneError of size 1
+func (s *source) peekRune() (rune, int, error) {
+	if s.i == s.n && !s.fill() {
+		return 0, 0, s.readErr()
+	}
+	if b := s.buf[s.i]; b < utf8.RuneSelf {
+		return rune(b), 1, nil
+	}
+	if !utf8.FullRune(s.buf[s.i:s.n]) {
+		s.ensure(utf8.UTFMax)
+	}
+	r, size := utf8.DecodeRune(s.buf[s.i:s.n])
+	return r, size, nil
+}
+
+func (s *source) ReadRune() (rune, int, error) {
+	r, size, err := s.peekRune()
+	if err == nil {
+		s.advance(size)
+	}
+	return r, size, err
+}
+
+// Peek returns the next bytes without consuming them
+func (s *source) Peek(k int) []byte {
+	s.ensure(k)
+	return s.buf[s.i:min(s.i+k, s.n)]
+}
+
+// advance consumes k buffered bytes
+func (s *source) advance(k int) {
+	consumed := s.buf[s.i : s.i+k]
+	s.i += k
+	for {
+		nl := bytes.IndexByte(consumed, '\n')
+		if nl < 0 {
+			break
+		}
+		s.pos.Offset += int64(nl + 1)
+		s.pos.Line++
+		s.pos.Column = 1
+		s.prevLineStart, s.lineStart = s.lineStart, s.pos.Offset
+		consumed = consumed[nl+1:]
+	}
+	s.pos.Offset += int64(len(consumed))
+	s.pos.Column += utf8.RuneCount(consumed)
+}
+
+// skipSpace consumes the whitespace of RFC 8259, which is whitespace in all dialects
+func (s *source) skipSpace() {
+	for {
+		k := s.i
+		for k < s.n && (s.buf[k] == ' ' || s.buf[k] == '\n' || s.buf[k] == '\t' || s.buf[k] == '\r') {
+			k++
+		}
+		s.advance(k - s.i)
+		if s.i < s.n || !s.fill() {
+			return
+		}
+	}
+}
+
+func (s *source) Excerpt(pos Position) (string, int, bool) {
+	// complete the current line with what can be buffered ahead
+	s.ensure(maxExcerpt)
+
+	var start int64
+	switch pos.Line {
+	case s.pos.Line:
+		start = s.lineStart
+	case s.pos.Line - 1:
+		start = s.prevLineStart
+	default:
+		return "", 0, false
+	}
+	if start < 0 || pos.Offset < start || pos.Offset < s.base {
+		return "", 0, false
+	}
+
+	truncated := start < s.base
+	start = max(start, s.base)
+	text := s.buf[start-s.base : s.n]
+	if end := bytes.IndexByte(text, '\n'); end >= 0 {
+		text = text[:end]
+	}
+
+	idx := int(pos.Offset - start)
+	if idx > len(text) {
+		return "", 0, false
+	}
+
+	prefix := ""
+	if truncated {
+		prefix = "..."
+	}
+	if idx > maxExcerpt/2 {
+		start := idx - maxExcerpt/2
+		for start < idx && !utf8.RuneStart(text[start]) {
+			start++
+		}
+		text, idx, prefix = text[start:], idx-start, "..."
+	}
+	suffix := ""
+	if len(text) > idx+maxExcerpt/2 {
+		end := idx + maxExcerpt/2
+		for end > idx && !utf8.RuneStart(text[end]) {
+			end--
+		}
+		text, suffix = text[:end], "..."
+	}
+
+	line := prefix + string(bytes.TrimRight(text, "\r")) + suffix
+	return line, len(prefix) + utf8.RuneCount(text[:idx]), true
+}
@@ -1,6 +1,7 @@
 package jsontree
 
 import (
+	"errors"
 	"fmt"
 	"io"
 
@@ -8,7 +9,46 @@ import (
 	"github.com/trichner/toolbox/pkg/jsontree/lexer"
 )
 
+// Parse reads the next JSON value from the lexer, at the end of the input it returns io.EOF. Invalid input
+// results in a *SyntaxError. Every call starts a new arena, NewValueReader reads a stream of values, e.g. NDJSON,
+// with a single one.
 func Parse(l lexer.Lexer) (ast.Node, error) {
+	return newParser(l).parse()
+}
+
+// parser builds nodes in an arena, a reader parsing many values keeps its parser to share the arena and the
+// scratch space between them
+type parser struct {
+	l     lexer.Lexer
+	arena *ast.Arena
+
+	// items and properties are stacks of the containers being parsed, nested containers push on top
+	items      []ast.Node
+	properties []ast.Property
+}
+
+func newParser(l lexer.Lexer) *parser {
+	return &parser{l: l, arena: ast.NewArena()}
+}
+
+func (p *parser) parse() (ast.Node, error) {
+	token, err := p.l.Peek()
+	if err != nil {
+		return nil, newSyntaxError(p.l, err)
+	}
+	if token.Type == lexer.TokenTypeEOF {
+		return nil, io.EOF
+	}
+
+	n, err := p.parseValue()
+	if err != nil {
+		return nil, newSyntaxError(p.l, err)
+	}
+	return n, nil
+}
+
+func (p *parser) parseValue() (ast.Node, error) {
+	l := p.l
 	token, err := l.Peek()
 	if err != nil {
 		return nil, err
@@ -16,23 +56,24 @@ func Parse(l lexer.Lexer) (ast.Node, error) {
 
 	switch token.Type {
 	case lexer.TokenTypeEOF:
-		return nil, io.EOF
+		return nil, errorAt(token, io.ErrUnexpectedEOF)
 	case lexer.TokenTypeOpeningBrace:
-		return parseObject(l)
+		return p.parseObject()
 	case lexer.TokenTypeOpeningBracket:
-		return parseArray(l)
+		return p.parseArray()
 	case lexer.TokenTypePrimitiveNumber:
-		return parseNumber(l)
+		return p.parseNumber()
 	case lexer.TokenTypePrimitiveText:
-		return parsePrimitiveText(l)
+		return p.parsePrimitiveText()
 	case lexer.TokenTypeText:
-		return parseText(l)
+		return p.parseText()
 	}
-	return nil, fmt.Errorf("unexpected token: %v", token)
+	return nil, errorAt(token, fmt.Errorf("unexpected token: %v", token.Type))
 }
 
-func parseArray(l lexer.Lexer) (ast.Node, error) {
-	err := skipToken(l, lexer.TokenTypeOpeningBracket)
+func (p *parser) parseArray() (ast.Node, error) {
+	l := p.l
+	start, err := skipToken(l, lexer.TokenTypeOpeningBracket)
 	if err != nil {
 		return nil, err
 	}
@@ -44,72 +85,76 @@ func parseArray(l lexer.Lexer) (ast.Node, error) {
 	}
 	if peeked.Type == lexer.TokenTypeClosingBracket {
 		// discard closing bracket
-		_, err = l.Token()
-		return ast.NewArrayNode(nil), err
+		end, err := l.Token()
+		return p.arena.NewArray(nil, spanOf(start, end)), err
 	}
 
-	var items []ast.Node
+	base := len(p.items)
+	defer func() {
+		clear(p.items[base:])
+		p.items = p.items[:base]
+	}()
 	for {
-		item, err := Parse(l)
+		item, err := p.parseValue()
 		if err != nil {
 			return nil, fmt.Errorf("unexpected error parsing array item: %w", err)
 		}
-		items = append(items, item)
+		p.items = append(p.items, item)
 
 		sep, err := l.Token()
 		if err != nil {
 			return nil, fmt.Errorf("unexpected error parsing array: %w", err)
 		}
 		if sep.Type == lexer.TokenTypeClosingBracket {
-			break
+			return p.arena.NewArray(p.items[base:], spanOf(start, sep)), nil
 		}
 		if sep.Type != lexer.TokenTypeComma {
-			return nil, fmt.Errorf("unexpected error parsing array, expected comma but got: %v", sep.Type)
+			return nil, errorAt(sep, fmt.Errorf("unexpected error parsing array, expected comma but got: %v", sep.Type))
 		}
 	}
-
-	return ast.NewArrayNode(items), err
 }
 
-func parseText(l lexer.Lexer) (ast.TextNode, error) {
-	token, err := l.Token()
+func (p *parser) parseText() (ast.TextNode, error) {
+	token, err := p.l.Token()
 	if err != nil {
 		return nil, err
 	}
 
-	return ast.NewTextNode(token.Value), nil
+	return p.arena.NewText(token.Value, spanOf(token, token)), nil
 }
 
-func parsePrimitiveText(l lexer.Lexer) (ast.Node, error) {
-	token, err := l.Token()
+func (p *parser) parsePrimitiveText() (ast.Node, error) {
+	token, err := p.l.Token()
 	if err != nil {
 		return nil, err
 	}
 
+	span := spanOf(token, token)
 	switch token.Value {
 	case "true":
-		return ast.NewBooleanNode(true), nil
+		return p.arena.NewBoolean(true, span), nil
 	case "false":
-		return ast.NewBooleanNode(false), nil
+		return p.arena.NewBoolean(false, span), nil
 	case "null":
-		return ast.NewNullNode(), nil
+		return p.arena.NewNull(span), nil
 	}
-	return nil, fmt.Errorf("unrecognized literal: %q", token.Value)
+	return nil, errorAt(token, fmt.Errorf("unrecognized literal: %q", token.Value))
 }
 
-func parseNumber(l lexer.Lexer) (ast.Node, error) {
-	tkn, err := l.Token()
+func (p *parser) parseNumber() (ast.Node, error) {
+	tkn, err := p.l.Token()
 	if err != nil {
 		return nil, fmt.Errorf("unexpected token parsing number: %w", err)
 	}
 	if tkn.Type != lexer.TokenTypePrimitiveNumber {
-		return nil, fmt.Errorf("unexpected token, expected %s: %s", lexer.TokenTypePrimitiveNumber, tkn.Type)
+		return nil, errorAt(tkn, fmt.Errorf("unexpected token, expected %s: %s", lexer.TokenTypePrimitiveNumber, tkn.Type))
 	}
-	return ast.NewNumberNode(tkn.Value), nil
+	return p.arena.NewNumber(tkn.Value, spanOf(tkn, tkn)), nil
 }
 
-func parseObject(l lexer.Lexer) (ast.Node, error) {
-	err := skipToken(l, lexer.TokenTypeOpeningBrace)
+func (p *parser) parseObject() (ast.Node, error) {
+	l := p.l
+	start, err := skipToken(l, lexer.TokenTypeOpeningBrace)
 	if err != nil {
 		return nil, wrapUnexpectedObjectParseException(err)
 	}
@@ -119,29 +164,32 @@ func parseObject(l lexer.Lexer) (ast.Node, error) {
 		return nil, wrapUnexpectedObjectParseException(err)
 	}
 	if tkn.Type == lexer.TokenTypeClosingBrace {
-		err := skipToken(l, lexer.TokenTypeClosingBrace)
-		return ast.NewObjectNode(nil), err
+		end, err := skipToken(l, lexer.TokenTypeClosingBrace)
+		return p.arena.NewObject(nil, spanOf(start, end)), err
 	}
 
-	var properties []*ast.Property
-
+	base := len(p.properties)
+	defer func() {
+		clear(p.properties[base:])
+		p.properties = p.properties[:base]
+	}()
 	for {
 		property, err := parseObjectPropertyName(l)
 		if err != nil {
 			return nil, err
 		}
 
-		err = skipToken(l, lexer.TokenTypeColon)
+		_, err = skipToken(l, lexer.TokenTypeColon)
 		if err != nil {
 			return nil, wrapUnexpectedObjectParseException(err)
 		}
 
-		val, err := Parse(l)
+		val, err := p.parseValue()
 		if err != nil {
 			return nil, fmt.Errorf("unexpected error parsing object value: %w", err)
 		}
 
-		properties = append(properties, &ast.Property{
+		p.properties = append(p.properties, ast.Property{
 			Name:  property,
 			Value: val,
 		})
@@ -151,14 +199,12 @@ func parseObject(l lexer.Lexer) (ast.Node, error) {
 			return nil, wrapUnexpectedObjectParseException(err)
 		}
 		if tkn.Type == lexer.TokenTypeClosingBrace {
-			break
+			return p.arena.NewObject(p.properties[base:], spanOf(start, tkn)), nil
 		}
 		if tkn.Type != lexer.TokenTypeComma {
-			return nil, fmt.Errorf("unexpected token parsing object, expected %q but got: %v", lexer.TokenTypeComma, tkn)
+			return nil, errorAt(tkn, fmt.Errorf("unexpected token parsing object, expected %q but got: %v", lexer.TokenTypeComma, tkn.Type))
 		}
 	}
-
-	return ast.NewObjectNode(properties), nil
 }
 
 func parseObjectPropertyName(l lexer.Lexer) (string, error) {
@@ -167,22 +213,57 @@ func parseObjectPropertyName(l lexer.Lexer) (string, error) {
 		return "", wrapUnexpectedObjectParseException(err)
 	}
 	if tkn.Type != lexer.TokenTypeText {
-		return "", fmt.Errorf("unexpected token parsing object, expected %q but got: %q", lexer.TokenTypeText, tkn.Type)
+		return "", errorAt(tkn, fmt.Errorf("unexpected token parsing object, expected %q but got: %q", lexer.TokenTypeText, tkn.Type))
 	}
 	return tkn.Value, nil
 }
 
-func skipToken(l lexer.Lexer, t lexer.TokenType) error {
+func skipToken(l lexer.Lexer, t lexer.TokenType) (lexer.Token, error) {
 	tkn, err := l.Token()
 	if err != nil {
-		return fmt.Errorf("expected token %q but got: %w", t, err)
+		return tkn, fmt.Errorf("expected token %q but got: %w", t, err)
 	}
 	if tkn.Type != t {
-		return fmt.Errorf("unexpected token, expected %q but got: %v", t, tkn)
+		return tkn, errorAt(tkn, fmt.Errorf("unexpected token, expected %q but got: %v", t, tkn.Type))
 	}
-	return nil
+	return tkn, nil
+}
+
+func spanOf(start, end lexer.Token) ast.Span {
+	return ast.Span{Start: start.Pos, End: end.End}
 }
 
 func wrapUnexpectedObjectParseException(err error) error {
 	return fmt.Errorf("unexpected error parsing object: %w", err)
 }
+
+// positionedError attaches the position of the offending token to an error
+type positionedError struct {
+	pos lexer.Position
+	err error
+}
+
+func errorAt(tkn lexer.Token, err error) error {
+	return &positionedError{pos: tkn.Pos, err: err}
+}
+
+func (e *positionedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *positionedError) Unwrap() error {
+	return e.err
+}
+
+// findPosition looks for the position of a lexer or parser error within the chain
+func findPosition(err error) (lexer.Position, bool) {
+	var perr *positionedError
+	if errors.As(err, &perr) {
+		return perr.pos, true
+	}
+	var lerr *lexer.Error
+	if errors.As(err, &lerr) {
+		return lerr.Pos, true
+	}
+	return lexer.Position{}, false
+}
@@ -1,11 +1,15 @@
 package jsontree
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trichner/toolbox/pkg/jsontree/ast"
 	"github.com/trichner/toolbox/pkg/jsontree/lexer"
 )
 
@@ -67,7 +71,7 @@ func TestParse(t *testing.T) {
 		{
 			name:             "bad 2",
 			raw:              "[",
-			expectedErrorMsg: "unexpected error parsing array item: EOF",
+			expectedErrorMsg: "unexpected error parsing array item: unexpected EOF",
 		},
 		{
 			name:             "bad 3",
@@ -95,8 +99,9 @@ func TestParse(t *testing.T) {
 			l := lexer.NewLexer(strings.NewReader(test.raw))
 			n, err := Parse(l)
 			if test.expectedErrorMsg != "" {
-				assert.Error(t, err)
-				assert.Equal(t, test.expectedErrorMsg, err.Error())
+				var serr *SyntaxError
+				assert.ErrorAs(t, err, &serr)
+				assert.Equal(t, test.expectedErrorMsg, serr.Msg)
 			} else {
 				assert.NoError(t, err)
 				assert.NotNil(t, n)
@@ -132,3 +137,89 @@ func TestStream(t *testing.T) {
 	actual, _ = n.MarshalJSON()
 	fmt.Println(string(actual))
 }
+
+func TestParse_SyntaxError(t *testing.T) {
+	raw := "{\"a\": 1}\n{\"b\": 2,, \"c\": 3}\n"
+
+	l := lexer.NewLexer(strings.NewReader(raw))
+	_, err := Parse(l)
+	require.NoError(t, err)
+
+	_, err = Parse(l)
+	var serr *SyntaxError
+	require.ErrorAs(t, err, &serr)
+	assert.Equal(t, lexer.Position{Offset: 17, Line: 2, Column: 9}, serr.Pos)
+	assert.Equal(t, `syntax error at line 2, column 9: unexpected token parsing object, expected "TokenTypeText" but got: "TokenTypeComma"`, err.Error())
+	assert.Equal(t, "2 | {\"b\": 2,, \"c\": 3}\n  |         ^", serr.Snippet())
+	assert.Equal(t, err.Error()+"\n"+serr.Snippet(), FormatError(err))
+
+	// truncated input is no clean end of the stream
+	_, err = Parse(lexer.NewLexer(strings.NewReader(`[1,`)))
+	assert.ErrorAs(t, err, &serr)
+	assert.False(t, errors.Is(err, io.EOF))
+}
+
+func TestParse_LexerErrorPosition(t *testing.T) {
+	_, err := Parse(lexer.NewLexer(strings.NewReader("[\n\t\"a\\qb\"]")))
+	var serr *SyntaxError
+	require.ErrorAs(t, err, &serr)
+	assert.Equal(t, lexer.Position{Offset: 5, Line: 2, Column: 4}, serr.Pos)
+	assert.Equal(t, "2 | \t\"a\\qb\"]\n  | \t  ^", serr.Snippet())
+}
+
+func TestParse_Spans(t *testing.T) {
+	n, err := Parse(lexer.NewLexer(strings.NewReader(" {\"a\": [true, \"x\"]}")))
+	require.NoError(t, err)
+
+	span, ok := ast.SpanOf(n)
+	require.True(t, ok)
+	assert.Equal(t, ast.Span{Start: lexer.Position{Offset: 1, Line: 1, Column: 2}, End: lexer.Position{Offset: 19, Line: 1, Column: 20}}, span)
+
+	arr := n.(ast.ObjectNode).Properties()[0].Value
+	span, ok = ast.SpanOf(arr)
+	require.True(t, ok)
+	assert.Equal(t, int64(7), span.Start.Offset)
+	assert.Equal(t, int64(18), span.End.Offset)
+
+	text := arr.(ast.ArrayNode).Items()[1]
+	span, ok = ast.SpanOf(text)
+	require.True(t, ok)
+	assert.Equal(t, int64(14), span.Start.Offset)
+	assert.Equal(t, int64(17), span.End.Offset)
+
+	_, ok = ast.SpanOf(ast.NewNullNode())
+	assert.False(t, ok)
+}
+
+func TestParse_Dialects(t *testing.T) {
+	raw := `// exported settings
+{
+	name: 'toolbox', // unquoted keys and single quotes
+	"tags": ["cli", "json",],
+	limits: {max: 0x10, ratio: .5,},
+}`
+
+	_, err := Parse(lexer.NewLexer(strings.NewReader(raw)))
+	assert.Error(t, err, "strict JSON is the default")
+
+	n, err := Parse(lexer.NewLexer(strings.NewReader(raw), lexer.WithDialect(lexer.DialectJSON5)))
+	require.NoError(t, err)
+
+	b, err := n.MarshalJSON()
+	require.NoError(t, err)
+	assert.Equal(t, `{"name":"toolbox","tags":["cli","json"],"limits":{"max":16,"ratio":0.5}}`, string(b))
+
+	_, err = Parse(lexer.NewLexer(strings.NewReader(`{"a": [1, 2,], /* c */ "b": {},}`), lexer.WithDialect(lexer.DialectJSONC)))
+	assert.NoError(t, err)
+
+	_, err = Parse(lexer.NewLexer(strings.NewReader(`[1, 2,,]`), lexer.WithDialect(lexer.DialectJSONC)))
+	var serr *SyntaxError
+	require.ErrorAs(t, err, &serr)
+	assert.Equal(t, 8, serr.Pos.Column)
+
+	// NaN and Infinity would not be valid JSON in the output
+	_, err = Parse(lexer.NewLexer(strings.NewReader("{\n  ratio: -Infinity,\n}"), lexer.WithDialect(lexer.DialectJSON5)))
+	require.ErrorAs(t, err, &serr)
+	assert.Equal(t, lexer.Position{Offset: 11, Line: 2, Column: 10}, serr.Pos)
+	assert.ErrorContains(t, err, "-Infinity has no JSON equivalent")
+}
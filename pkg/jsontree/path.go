@@ -0,0 +1,68 @@
+package jsontree
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// PathElement is either the name of an object property or, if Index is not negative, the index of an array item
+type PathElement struct {
+	Key   string
+	Index int
+}
+
+func (e PathElement) IsIndex() bool {
+	return e.Index >= 0
+}
+
+// Path locates a value within its top-level value
+type Path []PathElement
+
+// String renders the path as a JSONPath expression selecting exactly this location, e.g. $.items[3]["first name"]
+func (p Path) String() string {
+	var b strings.Builder
+	b.WriteByte('$')
+	for _, e := range p {
+		switch {
+		case e.IsIndex():
+			b.WriteByte('[')
+			b.WriteString(strconv.Itoa(e.Index))
+			b.WriteByte(']')
+		case identifierPattern.MatchString(e.Key):
+			b.WriteByte('.')
+			b.WriteString(e.Key)
+		default:
+			b.WriteByte('[')
+			writeQuoted(&b, e.Key)
+			b.WriteByte(']')
+		}
+	}
+	return b.String()
+}
+
+// writeQuoted writes a double-quoted string with JSON escapes, which is also a valid JSONPath string literal
+func writeQuoted(b *strings.Builder, s string) {
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r < 0x20:
+			fmt.Fprintf(b, `\u%04x`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+}
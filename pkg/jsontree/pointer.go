@@ -0,0 +1,43 @@
+package jsontree
+
+import (
+	"fmt"
+	"strings"
+)
+
+var (
+	pointerEscaper   = strings.NewReplacer("~", "~0", "/", "~1")
+	pointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+)
+
+// AppendPointer appends a reference token to a JSON Pointer as of RFC 6901, e.g. the property 'a/b' to '/x'
+// is '/x/a~1b'
+func AppendPointer(pointer, token string) string {
+	return pointer + "/" + pointerEscaper.Replace(token)
+}
+
+// ParsePointer splits a JSON Pointer as of RFC 6901 into its unescaped reference tokens, the empty pointer
+// refers to the whole document
+func ParsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q: must start with '/'", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, t := range tokens {
+		for j := 0; j < len(t); j++ {
+			if t[j] != '~' {
+				continue
+			}
+			if j+1 >= len(t) || (t[j+1] != '0' && t[j+1] != '1') {
+				return nil, fmt.Errorf("invalid JSON pointer %q: invalid escape in %q", pointer, t)
+			}
+			j++
+		}
+		tokens[i] = pointerUnescaper.Replace(t)
+	}
+	return tokens, nil
+}
@@ -0,0 +1,31 @@
+package jsontree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPointer(t *testing.T) {
+	pointer := AppendPointer(AppendPointer("", "a/b"), "~c")
+	assert.Equal(t, "/a~1b/~0c", pointer)
+
+	tokens, err := ParsePointer(pointer)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a/b", "~c"}, tokens)
+
+	// '~01' is '~1' rather than '/'
+	tokens, err = ParsePointer("/~01//")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"~1", "", ""}, tokens)
+
+	tokens, err = ParsePointer("")
+	require.NoError(t, err)
+	assert.Empty(t, tokens)
+
+	for _, invalid := range []string{"a", "/a~b", "/a~"} {
+		_, err := ParsePointer(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
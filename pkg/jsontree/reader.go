@@ -0,0 +1,74 @@
+package jsontree
+
+import (
+	"io"
+
+	"github.com/trichner/toolbox/pkg/jsontree/ast"
+	"github.com/trichner/toolbox/pkg/jsontree/lexer"
+)
+
+// NodeReader reads JSON values one at a time, at the end of the input Next returns io.EOF
+type NodeReader interface {
+	Next() (ast.Node, error)
+}
+
+// NewValueReader reads a stream of top-level values, e.g. NDJSON
+func NewValueReader(l lexer.Lexer) NodeReader {
+	return &valueReader{p: newParser(l)}
+}
+
+type valueReader struct {
+	p *parser
+}
+
+func (r *valueReader) Next() (ast.Node, error) {
+	return r.p.parse()
+}
+
+// NewItemReader reads the items of top-level arrays one at a time without holding the whole array in memory.
+// Any other top-level value is read as is, hence both a huge array of objects and a stream of objects,
+// e.g. NDJSON, result in the same sequence of objects.
+func NewItemReader(l lexer.Lexer) NodeReader {
+	return &itemReader{d: NewDecoder(l)}
+}
+
+type itemReader struct {
+	d       *Decoder
+	inArray bool
+}
+
+func (r *itemReader) Next() (ast.Node, error) {
+	for {
+		if r.inArray {
+			more, err := r.d.More()
+			if err != nil {
+				return nil, err
+			}
+			if more {
+				return r.d.Node()
+			}
+
+			// consume the end of the array
+			if _, err := r.d.Next(); err != nil {
+				return nil, err
+			}
+			r.inArray = false
+		}
+
+		tkn, err := r.d.l.Peek()
+		if err != nil {
+			return nil, newSyntaxError(r.d.l, err)
+		}
+		switch tkn.Type {
+		case lexer.TokenTypeEOF:
+			return nil, io.EOF
+		case lexer.TokenTypeOpeningBracket:
+			if _, err := r.d.Next(); err != nil {
+				return nil, err
+			}
+			r.inArray = true
+		default:
+			return r.d.Node()
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package serializer
+
+import (
+	"unicode/utf8"
+)
+
+const hex = "0123456789abcdef"
+
+// writeString writes a quoted string. Control characters, quotes and backslashes are escaped as well as, unless
+// canonical, U+2028 and U+2029 which break JavaScript. Invalid UTF-8 is replaced with U+FFFD.
+func (e *encoder) writeString(s string) {
+	e.w.WriteByte('"')
+
+	start := 0
+	for i := 0; i < len(s); {
+		b := s[i]
+		if b >= 0x20 && b != '"' && b != '\\' && b < utf8.RuneSelf {
+			i++
+			continue
+		}
+
+		if b < utf8.RuneSelf {
+			e.w.WriteString(s[start:i])
+			e.writeEscapedByte(b)
+			i++
+			start = i
+			continue
+		}
+
+		c, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case c == utf8.RuneError && size == 1:
+			e.w.WriteString(s[start:i])
+			if e.canonical {
+				e.w.WriteRune(utf8.RuneError)
+			} else {
+				e.w.WriteString(`\ufffd`)
+			}
+		case (c == '\u2028' || c == '\u2029') && !e.canonical:
+			e.w.WriteString(s[start:i])
+			e.w.WriteString(`\u202`)
+			e.w.WriteByte(hex[c&0xF])
+		default:
+			i += size
+			continue
+		}
+		i += size
+		start = i
+	}
+
+	e.w.WriteString(s[start:])
+	e.w.WriteByte('"')
+}
+
+func (e *encoder) writeEscapedByte(b byte) {
+	e.w.WriteByte('\\')
+	switch b {
+	case '\\', '"':
+		e.w.WriteByte(b)
+	case '\b':
+		e.w.WriteByte('b')
+	case '\f':
+		e.w.WriteByte('f')
+	case '\n':
+		e.w.WriteByte('n')
+	case '\r':
+		e.w.WriteByte('r')
+	case '\t':
+		e.w.WriteByte('t')
+	default:
+		e.w.WriteString(`u00`)
+		e.w.WriteByte(hex[b>>4])
+		e.w.WriteByte(hex[b&0xF])
+	}
+}
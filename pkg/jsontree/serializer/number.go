@@ -0,0 +1,65 @@
+package serializer
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// formatES6Number formats a number literal like ECMAScript's Number.prototype.toString, as required by RFC 8785.
+// The literal is converted to the closest IEEE 754 double first, hence precision beyond it is lost.
+func formatES6Number(literal string) (string, error) {
+	f, err := strconv.ParseFloat(literal, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return "", fmt.Errorf("number cannot be represented in canonical JSON: %s", literal)
+	}
+	if f == 0 {
+		// also covers negative zero
+		return "0", nil
+	}
+
+	sign := ""
+	if f < 0 {
+		sign, f = "-", -f
+	}
+
+	// shortest digits that round trip, e.g. "1.2345e+02"
+	e := strconv.FormatFloat(f, 'e', -1, 64)
+	mantissa, exponent, _ := strings.Cut(e, "e")
+	digits := strings.Replace(mantissa, ".", "", 1)
+	exp, err := strconv.Atoi(exponent)
+	if err != nil {
+		return "", err
+	}
+
+	// the value is 0.digits * 10^n
+	k, n := len(digits), exp+1
+	var s string
+	switch {
+	case k <= n && n <= 21:
+		s = digits + strings.Repeat("0", n-k)
+	case 0 < n && n <= 21:
+		s = digits[:n] + "." + digits[n:]
+	case -6 < n && n <= 0:
+		s = "0." + strings.Repeat("0", -n) + digits
+	default:
+		s = digits[:1]
+		if k > 1 {
+			s += "." + digits[1:]
+		}
+		expSign := "+"
+		if n-1 < 0 {
+			expSign = "-"
+		}
+		s += "e" + expSign + strconv.Itoa(abs(n-1))
+	}
+	return sign + s, nil
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
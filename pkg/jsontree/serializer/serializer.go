@@ -0,0 +1,225 @@
+// Package serializer writes JSON trees either compact or indented, optionally with sorted keys or as canonical
+// JSON according to RFC 8785. Unless canonical, property order and number literals are preserved as parsed.
+package serializer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/trichner/toolbox/pkg/jsontree/ast"
+)
+
+type config struct {
+	prefix    string
+	indent    string
+	sortKeys  bool
+	canonical bool
+}
+
+type Option func(c *config)
+
+// WithIndent puts every property and item on a new line, starting with prefix followed by one copy of
+// indent per nesting level
+func WithIndent(prefix, indent string) Option {
+	return func(c *config) {
+		c.prefix = prefix
+		c.indent = indent
+	}
+}
+
+// WithSortedKeys orders properties by name rather than keeping their original order
+func WithSortedKeys() Option {
+	return func(c *config) {
+		c.sortKeys = true
+	}
+}
+
+// WithCanonical writes the JSON Canonicalization Scheme of RFC 8785, i.e. compact output with properties
+// sorted by their UTF-16 code units, numbers formatted like ECMAScript and minimal escaping. Indentation does
+// not apply.
+func WithCanonical() Option {
+	return func(c *config) {
+		c.canonical = true
+	}
+}
+
+// Marshal serializes the node
+func Marshal(n ast.Node, opts ...Option) ([]byte, error) {
+	var buf bytes.Buffer
+	err := Write(&buf, n, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Write serializes the node to w
+func Write(w io.Writer, n ast.Node, opts ...Option) error {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.canonical {
+		c.prefix, c.indent = "", ""
+	}
+
+	bw := bufio.NewWriter(w)
+	e := &encoder{w: bw, config: c}
+	err := e.writeNode(n, 0)
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+type encoder struct {
+	w *bufio.Writer
+	*config
+}
+
+func (e *encoder) writeNode(n ast.Node, depth int) error {
+	if n == nil {
+		_, err := e.w.WriteString("null")
+		return err
+	}
+
+	switch n.Type() {
+	case ast.NodeTypeObject:
+		return e.writeObject(n.(ast.ObjectNode), depth)
+	case ast.NodeTypeArray:
+		return e.writeArray(n.(ast.ArrayNode), depth)
+	case ast.NodeTypeText:
+		e.writeString(n.(ast.TextNode).Value())
+	case ast.NodeTypeNumber:
+		return e.writeNumber(n.(ast.NumberNode).Value())
+	case ast.NodeTypeBoolean:
+		if n.(ast.BooleanNode).Value() {
+			e.w.WriteString("true")
+		} else {
+			e.w.WriteString("false")
+		}
+	case ast.NodeTypeNull:
+		e.w.WriteString("null")
+	default:
+		return fmt.Errorf("cannot serialize node of type %s", n.Type())
+	}
+	return nil
+}
+
+func (e *encoder) writeObject(n ast.ObjectNode, depth int) error {
+	properties, err := e.orderProperties(n.Properties())
+	if err != nil {
+		return err
+	}
+	if len(properties) == 0 {
+		_, err := e.w.WriteString("{}")
+		return err
+	}
+
+	e.w.WriteByte('{')
+	for i, p := range properties {
+		if i > 0 {
+			e.w.WriteByte(',')
+		}
+		e.writeNewline(depth + 1)
+		e.writeString(p.Name)
+		e.w.WriteByte(':')
+		if e.indented() {
+			e.w.WriteByte(' ')
+		}
+		if err := e.writeNode(p.Value, depth+1); err != nil {
+			return err
+		}
+	}
+	e.writeNewline(depth)
+	return e.w.WriteByte('}')
+}
+
+func (e *encoder) writeArray(n ast.ArrayNode, depth int) error {
+	items := n.Items()
+	if len(items) == 0 {
+		_, err := e.w.WriteString("[]")
+		return err
+	}
+
+	e.w.WriteByte('[')
+	for i, item := range items {
+		if i > 0 {
+			e.w.WriteByte(',')
+		}
+		e.writeNewline(depth + 1)
+		if err := e.writeNode(item, depth+1); err != nil {
+			return err
+		}
+	}
+	e.writeNewline(depth)
+	return e.w.WriteByte(']')
+}
+
+func (e *encoder) orderProperties(properties []*ast.Property) ([]*ast.Property, error) {
+	if !e.sortKeys && !e.canonical {
+		return properties, nil
+	}
+
+	sorted := make([]*ast.Property, len(properties))
+	copy(sorted, properties)
+	if !e.canonical {
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return sorted[i].Name < sorted[j].Name
+		})
+		return sorted, nil
+	}
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return lessUTF16(sorted[i].Name, sorted[j].Name)
+	})
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i].Name == sorted[i-1].Name {
+			return nil, fmt.Errorf("canonical JSON must not contain duplicate property names: %q", sorted[i].Name)
+		}
+	}
+	return sorted, nil
+}
+
+func (e *encoder) writeNumber(literal string) error {
+	if !e.canonical {
+		_, err := e.w.WriteString(literal)
+		return err
+	}
+
+	formatted, err := formatES6Number(literal)
+	if err != nil {
+		return err
+	}
+	_, err = e.w.WriteString(formatted)
+	return err
+}
+
+func (e *encoder) writeNewline(depth int) {
+	if !e.indented() {
+		return
+	}
+	e.w.WriteByte('\n')
+	e.w.WriteString(e.prefix)
+	e.w.WriteString(strings.Repeat(e.indent, depth))
+}
+
+func (e *encoder) indented() bool {
+	return e.prefix != "" || e.indent != ""
+}
+
+// lessUTF16 orders strings by their UTF-16 code units as required by RFC 8785
+func lessUTF16(a, b string) bool {
+	x, y := utf16.Encode([]rune(a)), utf16.Encode([]rune(b))
+	for i := 0; i < len(x) && i < len(y); i++ {
+		if x[i] != y[i] {
+			return x[i] < y[i]
+		}
+	}
+	return len(x) < len(y)
+}
@@ -0,0 +1,122 @@
+package serializer
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trichner/toolbox/pkg/jsontree"
+	"github.com/trichner/toolbox/pkg/jsontree/ast"
+	"github.com/trichner/toolbox/pkg/jsontree/lexer"
+)
+
+func parse(t *testing.T, raw string) ast.Node {
+	n, err := jsontree.Parse(lexer.NewLexer(strings.NewReader(raw)))
+	require.NoError(t, err)
+	return n
+}
+
+const sample = `{"z": 1.50, "a": [true, null, {}, [], "x\"y"], "m": {"b": 10E2, "a": -0}}`
+
+func TestMarshal_Compact(t *testing.T) {
+	out, err := Marshal(parse(t, sample))
+	require.NoError(t, err)
+	assert.Equal(t, `{"z":1.50,"a":[true,null,{},[],"x\"y"],"m":{"b":10E2,"a":-0}}`, string(out))
+}
+
+func TestMarshal_Indent(t *testing.T) {
+	out, err := Marshal(parse(t, sample), WithIndent("", "  "))
+	require.NoError(t, err)
+	assert.Equal(t, `{
+  "z": 1.50,
+  "a": [
+    true,
+    null,
+    {},
+    [],
+    "x\"y"
+  ],
+  "m": {
+    "b": 10E2,
+    "a": -0
+  }
+}`, string(out))
+
+	// same layout as encoding/json
+	var expected bytes.Buffer
+	require.NoError(t, json.Indent(&expected, []byte(sample), "> ", "\t"))
+	out, err = Marshal(parse(t, sample), WithIndent("> ", "\t"))
+	require.NoError(t, err)
+	assert.Equal(t, expected.String(), string(out))
+}
+
+func TestMarshal_SortedKeys(t *testing.T) {
+	out, err := Marshal(parse(t, sample), WithSortedKeys())
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":[true,null,{},[],"x\"y"],"m":{"a":-0,"b":10E2},"z":1.50}`, string(out))
+}
+
+func TestMarshal_EscapesNames(t *testing.T) {
+	n := ast.NewObjectNode([]*ast.Property{
+		{Name: "quote\"", Value: ast.NewTextNode("tab\t\u2028\x01")},
+		{Name: "bad\xffutf8", Value: ast.NewNullNode()},
+	})
+
+	out, err := Marshal(n)
+	require.NoError(t, err)
+	assert.Equal(t, `{"quote\"":"tab\t\u2028\u0001","bad\ufffdutf8":null}`, string(out))
+	assert.True(t, json.Valid(out))
+}
+
+// TestMarshal_Canonical uses the examples of RFC 8785
+func TestMarshal_Canonical(t *testing.T) {
+	raw := `{
+		"numbers": [333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001],
+		"string": "\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/",
+		"literals": [null, true, false]
+	}`
+
+	out, err := Marshal(parse(t, raw), WithCanonical(), WithIndent("", "  "))
+	require.NoError(t, err)
+	assert.Equal(t, `{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],"string":"€$\u000f\nA'B\"\\\\\"/"}`, string(out))
+
+	raw = `{"\u20ac": 1, "\r": 2, "\ufb33": 3, "1": 4, "\ud83d\ude00": 5, "\u0080": 6, "\u00f6": 7}`
+	out, err = Marshal(parse(t, raw), WithCanonical())
+	require.NoError(t, err)
+	assert.Equal(t, "{\"\\r\":2,\"1\":4,\"\u0080\":6,\"ö\":7,\"€\":1,\"😀\":5,\"\ufb33\":3}", string(out))
+}
+
+func TestMarshal_Canonical_Errors(t *testing.T) {
+	_, err := Marshal(parse(t, `{"a": 1, "a": 2}`), WithCanonical())
+	assert.Error(t, err)
+
+	_, err = Marshal(parse(t, `[1e400]`), WithCanonical())
+	assert.Error(t, err)
+}
+
+func TestFormatES6Number(t *testing.T) {
+	tests := map[string]string{
+		"0":                      "0",
+		"-0.0":                   "0",
+		"1":                      "1",
+		"-1.50":                  "-1.5",
+		"1e21":                   "1e+21",
+		"1e20":                   "100000000000000000000",
+		"123456789012345680000":  "123456789012345680000",
+		"0.000001":               "0.000001",
+		"1e-7":                   "1e-7",
+		"9007199254740992":       "9007199254740992",
+		"9007199254740993":       "9007199254740992",
+		"1.7976931348623157e308": "1.7976931348623157e+308",
+		"5e-324":                 "5e-324",
+		"123.456e-2":             "1.23456",
+	}
+	for literal, expected := range tests {
+		actual, err := formatES6Number(literal)
+		require.NoError(t, err, literal)
+		assert.Equal(t, expected, actual, literal)
+	}
+}
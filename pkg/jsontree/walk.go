@@ -0,0 +1,103 @@
+package jsontree
+
+import (
+	"errors"
+
+	"github.com/trichner/toolbox/pkg/jsontree/ast"
+)
+
+// SkipChildren is returned by a WalkFunc to not descend into the current object or array
+var SkipChildren = errors.New("skip children")
+
+// WalkFunc is called for every node with its location, the path is reused between calls, copy it to retain it
+type WalkFunc func(path Path, n ast.Node) error
+
+// Walk visits all nodes depth-first in document order, parents before their children. Any error other than
+// SkipChildren stops the walk and is returned.
+func Walk(n ast.Node, fn WalkFunc) error {
+	err := walk(nil, n, fn)
+	if err == SkipChildren {
+		return nil
+	}
+	return err
+}
+
+func walk(path Path, n ast.Node, fn WalkFunc) error {
+	err := fn(path, n)
+	if err != nil {
+		return err
+	}
+
+	switch typed := n.(type) {
+	case ast.ObjectNode:
+		for _, p := range typed.Properties() {
+			err = walk(append(path, PathElement{Key: p.Name, Index: -1}), p.Value, fn)
+			if err != nil && err != SkipChildren {
+				return err
+			}
+		}
+	case ast.ArrayNode:
+		for i, item := range typed.Items() {
+			err = walk(append(path, PathElement{Index: i}), item, fn)
+			if err != nil && err != SkipChildren {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// TransformFunc returns the replacement of a node, returning nil removes the node from its parent
+type TransformFunc func(path Path, n ast.Node) (ast.Node, error)
+
+// Transform rebuilds a tree bottom-up, the children of an object or array are transformed before it is passed to
+// fn. The original tree is left untouched, unchanged subtrees are shared with it. Removing the top-level node
+// returns nil.
+func Transform(n ast.Node, fn TransformFunc) (ast.Node, error) {
+	return transform(nil, n, fn)
+}
+
+func transform(path Path, n ast.Node, fn TransformFunc) (ast.Node, error) {
+	switch typed := n.(type) {
+	case ast.ObjectNode:
+		var properties []*ast.Property
+		changed := false
+		for _, p := range typed.Properties() {
+			v, err := transform(append(path, PathElement{Key: p.Name, Index: -1}), p.Value, fn)
+			if err != nil {
+				return nil, err
+			}
+			changed = changed || v != p.Value
+			if v != nil {
+				properties = append(properties, &ast.Property{Name: p.Name, Value: v})
+			}
+		}
+		if changed {
+			n = ast.NewObjectNode(properties, spanOption(n)...)
+		}
+	case ast.ArrayNode:
+		var items []ast.Node
+		changed := false
+		for i, item := range typed.Items() {
+			v, err := transform(append(path, PathElement{Index: i}), item, fn)
+			if err != nil {
+				return nil, err
+			}
+			changed = changed || v != item
+			if v != nil {
+				items = append(items, v)
+			}
+		}
+		if changed {
+			n = ast.NewArrayNode(items, spanOption(n)...)
+		}
+	}
+	return fn(path, n)
+}
+
+func spanOption(n ast.Node) []ast.NodeOption {
+	if span, ok := ast.SpanOf(n); ok {
+		return []ast.NodeOption{ast.WithSpan(span)}
+	}
+	return nil
+}
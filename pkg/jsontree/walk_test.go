@@ -0,0 +1,65 @@
+package jsontree
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trichner/toolbox/pkg/jsontree/ast"
+	"github.com/trichner/toolbox/pkg/jsontree/lexer"
+)
+
+func TestWalk(t *testing.T) {
+	n, err := Parse(lexer.NewLexer(strings.NewReader(`{"a": [1, {"b": 2}], "skip": {"c": 3}, "d": null}`)))
+	require.NoError(t, err)
+
+	var visited []string
+	err = Walk(n, func(path Path, n ast.Node) error {
+		visited = append(visited, fmt.Sprintf("%s %s", path, n.Type()))
+		if len(path) == 1 && path[0].Key == "skip" {
+			return SkipChildren
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"$ NodeTypeObject", "$.a NodeTypeArray", "$.a[0] NodeTypeNumber", "$.a[1] NodeTypeObject", "$.a[1].b NodeTypeNumber",
+		"$.skip NodeTypeObject", "$.d NodeTypeNull",
+	}, visited)
+
+	stop := fmt.Errorf("stop")
+	err = Walk(n, func(path Path, n ast.Node) error {
+		if n.Type() == ast.NodeTypeNumber {
+			return stop
+		}
+		return nil
+	})
+	assert.Equal(t, stop, err)
+}
+
+func TestTransform(t *testing.T) {
+	original, err := Parse(lexer.NewLexer(strings.NewReader(`{"user": {"name": "a", "password": "s3cret"}, "ids": [1, null, 3]}`)))
+	require.NoError(t, err)
+
+	// redact passwords, drop nulls and double the numbers
+	transformed, err := Transform(original, func(path Path, n ast.Node) (ast.Node, error) {
+		switch {
+		case len(path) > 0 && path[len(path)-1].Key == "password":
+			return ast.NewTextNode("***"), nil
+		case n.Type() == ast.NodeTypeNull:
+			return nil, nil
+		case n.Type() == ast.NodeTypeNumber:
+			i, err := n.(ast.NumberNode).ToInt()
+			return ast.NewNumberNode(fmt.Sprint(2 * i)), err
+		}
+		return n, nil
+	})
+	require.NoError(t, err)
+
+	txt, _ := transformed.MarshalJSON()
+	assert.Equal(t, `{"user":{"name":"a","password":"***"},"ids":[2,6]}`, string(txt))
+	txt, _ = original.MarshalJSON()
+	assert.Equal(t, `{"user":{"name":"a","password":"s3cret"},"ids":[1,null,3]}`, string(txt))
+}
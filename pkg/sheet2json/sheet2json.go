@@ -1,7 +1,6 @@
 package sheet2json
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -11,18 +10,8 @@ import (
 	"github.com/trichner/toolbox/pkg/sheets"
 )
 
-func ReadFromSheet(ctx context.Context, spreadsheetId string, sheetId int64, w io.Writer) error {
-	svc, err := sheets.NewSheetService(ctx)
-	if err != nil {
-		return err
-	}
-
-	ss, err := svc.GetSpreadSheet(spreadsheetId)
-	if err != nil {
-		return err
-	}
-
-	sheet, err := ss.SheetById(sheetId)
+func ReadFromSheet(svc sheets.SheetsService, ref *sheets.SheetRef, w io.Writer) error {
+	sheet, err := sheets.OpenSheet(svc, ref)
 	if err != nil {
 		return err
 	}
@@ -35,6 +24,16 @@ func ReadFromSheet(ctx context.Context, spreadsheetId string, sheetId int64, w i
 	return nil
 }
 
+// SheetReader reads all values of a sheet
+type SheetReader interface {
+	Values() ([][]any, error)
+}
+
+// ReadObjectsFrom writes every row of the sheet as a JSON object to w, the first row holds the property names
+func ReadObjectsFrom(sheet SheetReader, w io.Writer) error {
+	return writeSheetToJsonObjects(sheet, newJsonWriter(w))
+}
+
 type JsonWriter func(n any) error
 
 func newJsonWriter(w io.Writer) JsonWriter {
@@ -44,7 +43,7 @@ func newJsonWriter(w io.Writer) JsonWriter {
 	}
 }
 
-func writeSheetToJsonObjects(sheet sheets.SheetOps, w JsonWriter) error {
+func writeSheetToJsonObjects(sheet SheetReader, w JsonWriter) error {
 	values, err := sheet.Values()
 	if err != nil {
 		return fmt.Errorf("failed to fetch sheet values: %w", err)
@@ -0,0 +1,33 @@
+package sheet2json
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trichner/toolbox/pkg/json2sheet"
+	"github.com/trichner/toolbox/pkg/sheets"
+)
+
+func TestReadFromSheet(t *testing.T) {
+	svc, err := sheets.NewLocalSheetService(t.TempDir())
+	require.NoError(t, err)
+
+	url, err := json2sheet.WriteToNewSheet(svc, strings.NewReader(`{"a":"hello","b":"world"}
+{"b":"2","a":"1"}
+`))
+	require.NoError(t, err)
+
+	ref, err := sheets.ParseSheetRef(url.String())
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	err = ReadFromSheet(svc, ref, &buf)
+	require.NoError(t, err)
+
+	assert.Equal(t, `{"a":"hello","b":"world"}
+{"a":"1","b":"2"}
+`, buf.String())
+}
@@ -0,0 +1,26 @@
+package sheets
+
+import (
+	"context"
+	"fmt"
+)
+
+type Backend string
+
+const (
+	// BackendGoogle talks to the Google Sheets API
+	BackendGoogle Backend = "google"
+	// BackendLocal stores spreadsheets as CSV files in a local directory
+	BackendLocal Backend = "local"
+)
+
+// NewSheetServiceForBackend creates a SheetsService for the given backend, localDir is only used by BackendLocal
+func NewSheetServiceForBackend(ctx context.Context, backend Backend, localDir string) (SheetsService, error) {
+	switch backend {
+	case BackendGoogle, "":
+		return NewSheetService(ctx)
+	case BackendLocal:
+		return NewLocalSheetService(localDir)
+	}
+	return nil, fmt.Errorf("unknown sheets backend %q, expected one of: %s, %s", backend, BackendGoogle, BackendLocal)
+}
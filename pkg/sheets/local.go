@@ -0,0 +1,693 @@
+package sheets
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	localManifestFile = "spreadsheet.json"
+	localSheetExt     = ".csv"
+	defaultSheetTitle = "Sheet1"
+)
+
+// localSheetsService stores spreadsheets on the local filesystem. Every spreadsheet is a directory named after its ID
+// with a manifest describing the sheets and a CSV file per sheet:
+//
+//	<dir>/<spreadsheetId>/spreadsheet.json
+//	<dir>/<spreadsheetId>/<sheetId>.csv
+//
+// A spreadsheet directory without a manifest is read as one sheet per CSV file, ordered by file name.
+type localSheetsService struct {
+	dir string
+	mu  sync.Mutex
+}
+
+type localManifest struct {
+	Id          string               `json:"id"`
+	Title       string               `json:"title"`
+	Sheets      []*localSheetDetails `json:"sheets"`
+	Permissions []*localPermission   `json:"permissions,omitempty"`
+}
+
+// localPermission records a share, there is nobody to actually notify locally
+type localPermission struct {
+	Email  string    `json:"email,omitempty"`
+	Anyone bool      `json:"anyone,omitempty"`
+	Role   ShareRole `json:"role"`
+}
+
+type localSheetDetails struct {
+	Id    int64  `json:"id"`
+	Title string `json:"title"`
+	Index int64  `json:"index"`
+	File  string `json:"file"`
+}
+
+// NewLocalSheetService creates a SheetsService backed by CSV files within the given directory, useful for tests
+// and dry runs without access to the Google Sheets API.
+func NewLocalSheetService(dir string) (SheetsService, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("cannot create local sheets directory %q: %w", dir, err)
+	}
+	return &localSheetsService{dir: dir}, nil
+}
+
+func (s *localSheetsService) CreateSpreadSheet(title string) (SpreadsheetOps, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, err := newLocalSpreadsheetId()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Join(s.dir, id), 0o755); err != nil {
+		return nil, fmt.Errorf("cannot create spreadsheet: %w", err)
+	}
+
+	m := &localManifest{
+		Id:    id,
+		Title: title,
+		Sheets: []*localSheetDetails{{
+			Id:    0,
+			Title: defaultSheetTitle,
+			Index: 0,
+			File:  localSheetFile(0),
+		}},
+	}
+	ops := &localSpreadsheetOps{service: s, id: id}
+	if err := ops.saveManifest(m); err != nil {
+		return nil, fmt.Errorf("cannot create spreadsheet: %w", err)
+	}
+	if err := ops.saveValues(m.Sheets[0], nil); err != nil {
+		return nil, fmt.Errorf("cannot create spreadsheet: %w", err)
+	}
+	return ops, nil
+}
+
+func (s *localSheetsService) GetSpreadSheet(id string) (SpreadsheetOps, error) {
+	if !spreadsheetIdPattern.MatchString(id) {
+		return nil, fmt.Errorf("invalid spreadsheet id %q", id)
+	}
+
+	info, err := os.Stat(filepath.Join(s.dir, id))
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && !info.IsDir()) {
+		return nil, fmt.Errorf("spreadsheet %q: %w", id, ErrNotFound)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &localSpreadsheetOps{service: s, id: id}, nil
+}
+
+type localSpreadsheetOps struct {
+	service *localSheetsService
+	id      string
+}
+
+func (s *localSpreadsheetOps) CreateSheet(opts *CreateSheetOptions) (SheetOps, error) {
+	s.service.mu.Lock()
+	defer s.service.mu.Unlock()
+
+	m, err := s.loadManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sheet := range m.Sheets {
+		if sheet.Title == opts.Title {
+			return nil, fmt.Errorf("unable to add sheet %q to %q: a sheet with that title already exists", opts.Title, s.id)
+		}
+	}
+
+	id, err := newLocalSheetId(m)
+	if err != nil {
+		return nil, err
+	}
+
+	// like the Google Sheets API, new sheets are inserted in front
+	for _, sheet := range m.Sheets {
+		sheet.Index++
+	}
+	sheet := &localSheetDetails{Id: id, Title: opts.Title, Index: 0, File: localSheetFile(id)}
+	m.Sheets = append(m.Sheets, sheet)
+
+	if err := s.saveValues(sheet, nil); err != nil {
+		return nil, err
+	}
+	if err := s.saveManifest(m); err != nil {
+		return nil, err
+	}
+
+	return &localSheetOps{spreadsheet: s, sheetId: id}, nil
+}
+
+func (s *localSpreadsheetOps) FirstSheet() (SheetOps, error) {
+	return s.SheetByIndex(0)
+}
+
+func (s *localSpreadsheetOps) SheetByIndex(index int64) (SheetOps, error) {
+	return s.filteredSheet(func(sheet *localSheetDetails) bool {
+		return sheet.Index == index
+	})
+}
+
+func (s *localSpreadsheetOps) SheetById(id int64) (SheetOps, error) {
+	return s.filteredSheet(func(sheet *localSheetDetails) bool {
+		return sheet.Id == id
+	})
+}
+
+func (s *localSpreadsheetOps) SheetByTitle(title string) (SheetOps, error) {
+	return s.filteredSheet(func(sheet *localSheetDetails) bool {
+		return sheet.Title == title
+	})
+}
+
+func (s *localSpreadsheetOps) Get() (*SpreadSheet, error) {
+	s.service.mu.Lock()
+	defer s.service.mu.Unlock()
+
+	m, err := s.loadManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	sts := make([]*Sheet, 0, len(m.Sheets))
+	for _, details := range m.Sheets {
+		sheet, err := s.toSheet(details)
+		if err != nil {
+			return nil, err
+		}
+		sts = append(sts, sheet)
+	}
+	sort.Slice(sts, func(i, j int) bool {
+		return sts[i].Index < sts[j].Index
+	})
+
+	return &SpreadSheet{Id: s.id, Title: m.Title, Sheets: sts}, nil
+}
+
+func (s *localSpreadsheetOps) Share(opts *ShareOptions) error {
+	s.service.mu.Lock()
+	defer s.service.mu.Unlock()
+
+	m, err := s.loadManifest()
+	if err != nil {
+		return err
+	}
+
+	perm := &localPermission{Email: opts.Email, Anyone: opts.Anyone, Role: opts.Role}
+	if opts.Anyone {
+		perm.Email = ""
+	}
+	m.Permissions = append(m.Permissions, perm)
+	return s.saveManifest(m)
+}
+
+func (s *localSpreadsheetOps) filteredSheet(predicate func(sheet *localSheetDetails) bool) (SheetOps, error) {
+	s.service.mu.Lock()
+	defer s.service.mu.Unlock()
+
+	sheet, err := s.findSheet(predicate)
+	if err != nil {
+		return nil, err
+	}
+	return &localSheetOps{spreadsheet: s, sheetId: sheet.Id}, nil
+}
+
+func (s *localSpreadsheetOps) findSheet(predicate func(sheet *localSheetDetails) bool) (*localSheetDetails, error) {
+	m, err := s.loadManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sheet := range m.Sheets {
+		if predicate(sheet) {
+			return sheet, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *localSpreadsheetOps) toSheet(details *localSheetDetails) (*Sheet, error) {
+	values, err := s.loadValues(details)
+	if err != nil {
+		return nil, err
+	}
+
+	var columns int
+	for _, row := range values {
+		columns = max(columns, len(row))
+	}
+
+	return &Sheet{
+		Id:          details.Id,
+		Title:       details.Title,
+		Index:       details.Index,
+		RowCount:    int64(len(values)),
+		ColumnCount: int64(columns),
+	}, nil
+}
+
+func (s *localSpreadsheetOps) path(name string) string {
+	return filepath.Join(s.service.dir, s.id, name)
+}
+
+func (s *localSpreadsheetOps) loadManifest() (*localManifest, error) {
+	data, err := os.ReadFile(s.path(localManifestFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return s.deriveManifest()
+	} else if err != nil {
+		return nil, fmt.Errorf("cannot read spreadsheet %q: %w", s.id, err)
+	}
+
+	var m localManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid manifest for spreadsheet %q: %w", s.id, err)
+	}
+	return &m, nil
+}
+
+// deriveManifest builds a manifest for a plain directory of CSV files, one sheet per file
+func (s *localSpreadsheetOps) deriveManifest() (*localManifest, error) {
+	files, err := filepath.Glob(s.path("*" + localSheetExt))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	m := &localManifest{Id: s.id, Title: s.id}
+	for i, f := range files {
+		name := filepath.Base(f)
+		m.Sheets = append(m.Sheets, &localSheetDetails{
+			Id:    int64(i),
+			Title: strings.TrimSuffix(name, localSheetExt),
+			Index: int64(i),
+			File:  name,
+		})
+	}
+	return m, nil
+}
+
+func (s *localSpreadsheetOps) saveManifest(m *localManifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path(localManifestFile), data, 0o644)
+}
+
+func (s *localSpreadsheetOps) loadValues(sheet *localSheetDetails) ([][]string, error) {
+	f, err := os.Open(s.path(sheet.File))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("cannot read sheet %q: %w", sheet.Title, err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	values, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read sheet %q: %w", sheet.Title, err)
+	}
+	return values, nil
+}
+
+// loadHeader reads the first row of a sheet only, it is nil if the sheet is empty
+func (s *localSpreadsheetOps) loadHeader(sheet *localSheetDetails) ([]string, error) {
+	f, err := os.Open(s.path(sheet.File))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("cannot read sheet %q: %w", sheet.Title, err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	header, err := r.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read sheet %q: %w", sheet.Title, err)
+	}
+	return header, nil
+}
+
+func (s *localSpreadsheetOps) saveValues(sheet *localSheetDetails, values [][]string) error {
+	f, err := os.Create(s.path(sheet.File))
+	if err != nil {
+		return fmt.Errorf("cannot write sheet %q: %w", sheet.Title, err)
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	if err := w.WriteAll(values); err != nil {
+		return fmt.Errorf("cannot write sheet %q: %w", sheet.Title, err)
+	}
+	return f.Close()
+}
+
+type localSheetOps struct {
+	spreadsheet *localSpreadsheetOps
+	sheetId     int64
+}
+
+func (s *localSheetOps) UpdateValues(data [][]string) error {
+	return s.modify(func(values [][]string) [][]string {
+		for i, row := range data {
+			if i >= len(values) {
+				values = append(values, nil)
+			}
+			for j, cell := range row {
+				for j >= len(values[i]) {
+					values[i] = append(values[i], "")
+				}
+				values[i][j] = cell
+			}
+		}
+		return values
+	})
+}
+
+func (s *localSheetOps) AppendValues(data [][]string) error {
+	return s.modify(func(values [][]string) [][]string {
+		// like the Google Sheets API, append after the last row with data
+		for len(values) > 0 && isEmptyRow(values[len(values)-1]) {
+			values = values[:len(values)-1]
+		}
+		return append(values, data...)
+	})
+}
+
+func (s *localSheetOps) Values() ([][]any, error) {
+	s.spreadsheet.service.mu.Lock()
+	defer s.spreadsheet.service.mu.Unlock()
+
+	sheet, err := s.details()
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := s.spreadsheet.loadValues(sheet)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(values) == 0 {
+		return nil, ErrEmptySheet
+	}
+
+	return toValues(values), nil
+}
+
+func (s *localSheetOps) HeaderValues() ([]any, error) {
+	s.spreadsheet.service.mu.Lock()
+	defer s.spreadsheet.service.mu.Unlock()
+
+	sheet, err := s.details()
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := s.spreadsheet.loadHeader(sheet)
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, ErrEmptySheet
+	}
+	return toValues([][]string{header})[0], nil
+}
+
+func (s *localSheetOps) Get() (*Sheet, error) {
+	s.spreadsheet.service.mu.Lock()
+	defer s.spreadsheet.service.mu.Unlock()
+
+	sheet, err := s.details()
+	if err != nil {
+		return nil, err
+	}
+	return s.spreadsheet.toSheet(sheet)
+}
+
+func (s *localSheetOps) Rename(title string) error {
+	s.spreadsheet.service.mu.Lock()
+	defer s.spreadsheet.service.mu.Unlock()
+
+	m, err := s.spreadsheet.loadManifest()
+	if err != nil {
+		return err
+	}
+
+	sheet := findDetails(m, s.sheetId)
+	if sheet == nil {
+		return ErrNotFound
+	}
+	if other := findDetailsByTitle(m, title); other != nil && other != sheet {
+		return fmt.Errorf("unable to rename sheet %q: a sheet with the title %q already exists", sheet.Title, title)
+	}
+
+	sheet.Title = title
+	return s.spreadsheet.saveManifest(m)
+}
+
+func (s *localSheetOps) Clear() error {
+	return s.modify(func(values [][]string) [][]string {
+		return nil
+	})
+}
+
+func (s *localSheetOps) Delete() error {
+	s.spreadsheet.service.mu.Lock()
+	defer s.spreadsheet.service.mu.Unlock()
+
+	m, err := s.spreadsheet.loadManifest()
+	if err != nil {
+		return err
+	}
+
+	sheet := findDetails(m, s.sheetId)
+	if sheet == nil {
+		return ErrNotFound
+	}
+	if len(m.Sheets) == 1 {
+		return fmt.Errorf("unable to delete sheet %q: a spreadsheet must contain at least one sheet", sheet.Title)
+	}
+
+	remaining := make([]*localSheetDetails, 0, len(m.Sheets)-1)
+	for _, other := range m.Sheets {
+		if other == sheet {
+			continue
+		}
+		if other.Index > sheet.Index {
+			other.Index--
+		}
+		remaining = append(remaining, other)
+	}
+	m.Sheets = remaining
+
+	if err := s.spreadsheet.saveManifest(m); err != nil {
+		return err
+	}
+	if err := os.Remove(s.spreadsheet.path(sheet.File)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("cannot delete sheet %q: %w", sheet.Title, err)
+	}
+	return nil
+}
+
+func (s *localSheetOps) Duplicate(title string) (SheetOps, error) {
+	s.spreadsheet.service.mu.Lock()
+	defer s.spreadsheet.service.mu.Unlock()
+
+	m, err := s.spreadsheet.loadManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	source := findDetails(m, s.sheetId)
+	if source == nil {
+		return nil, ErrNotFound
+	}
+
+	values, err := s.spreadsheet.loadValues(source)
+	if err != nil {
+		return nil, err
+	}
+
+	// like the Google Sheets API, the copy is placed right after its source
+	for _, other := range m.Sheets {
+		if other.Index > source.Index {
+			other.Index++
+		}
+	}
+	sheet, err := s.spreadsheet.copySheet(m, source.Title, title, source.Index+1, values)
+	if err != nil {
+		return nil, err
+	}
+
+	return &localSheetOps{spreadsheet: s.spreadsheet, sheetId: sheet.Id}, nil
+}
+
+func (s *localSheetOps) CopyTo(spreadsheetId string) (*Sheet, error) {
+	if !spreadsheetIdPattern.MatchString(spreadsheetId) {
+		return nil, fmt.Errorf("invalid spreadsheet id %q", spreadsheetId)
+	}
+
+	s.spreadsheet.service.mu.Lock()
+	defer s.spreadsheet.service.mu.Unlock()
+
+	source, err := s.details()
+	if err != nil {
+		return nil, err
+	}
+
+	dest := &localSpreadsheetOps{service: s.spreadsheet.service, id: spreadsheetId}
+	if _, err := os.Stat(dest.path("")); errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("spreadsheet %q: %w", spreadsheetId, ErrNotFound)
+	}
+
+	m, err := dest.loadManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := s.spreadsheet.loadValues(source)
+	if err != nil {
+		return nil, err
+	}
+
+	// like the Google Sheets API, copies to another spreadsheet are appended at the end
+	sheet, err := dest.copySheet(m, source.Title, "", int64(len(m.Sheets)), values)
+	if err != nil {
+		return nil, err
+	}
+	return dest.toSheet(sheet)
+}
+
+func (s *localSheetOps) details() (*localSheetDetails, error) {
+	return s.spreadsheet.findSheet(func(sheet *localSheetDetails) bool {
+		return sheet.Id == s.sheetId
+	})
+}
+
+func (s *localSheetOps) modify(fn func(values [][]string) [][]string) error {
+	s.spreadsheet.service.mu.Lock()
+	defer s.spreadsheet.service.mu.Unlock()
+
+	sheet, err := s.details()
+	if err != nil {
+		return err
+	}
+
+	values, err := s.spreadsheet.loadValues(sheet)
+	if err != nil {
+		return err
+	}
+
+	return s.spreadsheet.saveValues(sheet, fn(values))
+}
+
+// copySheet adds a sheet with the given values to the manifest. An empty title is replaced with a unique
+// "Copy of ..." title derived from the source title.
+func (s *localSpreadsheetOps) copySheet(m *localManifest, sourceTitle, title string, index int64, values [][]string) (*localSheetDetails, error) {
+	if title == "" {
+		title = "Copy of " + sourceTitle
+		for i := 2; findDetailsByTitle(m, title) != nil; i++ {
+			title = fmt.Sprintf("Copy of %s %d", sourceTitle, i)
+		}
+	} else if findDetailsByTitle(m, title) != nil {
+		return nil, fmt.Errorf("unable to copy sheet %q: a sheet with the title %q already exists", sourceTitle, title)
+	}
+
+	id, err := newLocalSheetId(m)
+	if err != nil {
+		return nil, err
+	}
+
+	sheet := &localSheetDetails{Id: id, Title: title, Index: index, File: localSheetFile(id)}
+	m.Sheets = append(m.Sheets, sheet)
+
+	if err := s.saveValues(sheet, values); err != nil {
+		return nil, err
+	}
+	if err := s.saveManifest(m); err != nil {
+		return nil, err
+	}
+	return sheet, nil
+}
+
+func findDetails(m *localManifest, id int64) *localSheetDetails {
+	for _, sheet := range m.Sheets {
+		if sheet.Id == id {
+			return sheet
+		}
+	}
+	return nil
+}
+
+func findDetailsByTitle(m *localManifest, title string) *localSheetDetails {
+	for _, sheet := range m.Sheets {
+		if sheet.Title == title {
+			return sheet
+		}
+	}
+	return nil
+}
+
+func isEmptyRow(row []string) bool {
+	for _, cell := range row {
+		if cell != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func localSheetFile(id int64) string {
+	return fmt.Sprintf("%d%s", id, localSheetExt)
+}
+
+// newLocalSpreadsheetId generates a random ID looking like the ones of Google Sheets
+func newLocalSpreadsheetId() (string, error) {
+	buf := make([]byte, 33)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("cannot generate spreadsheet id: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func newLocalSheetId(m *localManifest) (int64, error) {
+	for {
+		var buf [4]byte
+		if _, err := rand.Read(buf[:]); err != nil {
+			return 0, fmt.Errorf("cannot generate sheet id: %w", err)
+		}
+		id := int64(binary.BigEndian.Uint32(buf[:]) >> 1)
+
+		taken := false
+		for _, sheet := range m.Sheets {
+			taken = taken || sheet.Id == id
+		}
+		if !taken {
+			return id, nil
+		}
+	}
+}
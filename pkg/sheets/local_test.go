@@ -0,0 +1,197 @@
+package sheets
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalSheetService_RoundTrip(t *testing.T) {
+	svc, err := NewLocalSheetService(t.TempDir())
+	require.NoError(t, err)
+
+	ss, err := svc.CreateSpreadSheet("test")
+	require.NoError(t, err)
+
+	sheet, err := ss.FirstSheet()
+	require.NoError(t, err)
+
+	_, err = sheet.Values()
+	assert.ErrorIs(t, err, ErrEmptySheet)
+	_, err = sheet.HeaderValues()
+	assert.ErrorIs(t, err, ErrEmptySheet)
+
+	err = sheet.UpdateValues([][]string{{"a", "b"}, {"1", "2"}})
+	require.NoError(t, err)
+
+	err = sheet.AppendValues([][]string{{"3", "4", "5"}})
+	require.NoError(t, err)
+
+	values, err := sheet.Values()
+	require.NoError(t, err)
+	assert.Equal(t, [][]any{{"a", "b"}, {"1", "2"}, {"3", "4", "5"}}, values)
+
+	header, err := sheet.HeaderValues()
+	require.NoError(t, err)
+	assert.Equal(t, []any{"a", "b"}, header)
+
+	info, err := sheet.Get()
+	require.NoError(t, err)
+	assert.Equal(t, &Sheet{Id: 0, Title: "Sheet1", Index: 0, RowCount: 3, ColumnCount: 3}, info)
+}
+
+func TestLocalSheetService_UpdateValuesKeepsRemainder(t *testing.T) {
+	svc, err := NewLocalSheetService(t.TempDir())
+	require.NoError(t, err)
+
+	ss, err := svc.CreateSpreadSheet("test")
+	require.NoError(t, err)
+	sheet, err := ss.FirstSheet()
+	require.NoError(t, err)
+
+	require.NoError(t, sheet.UpdateValues([][]string{{"a", "b", "c"}, {"1", "2", "3"}, {"4", "5", "6"}}))
+	require.NoError(t, sheet.UpdateValues([][]string{{"x"}, {"y", "z"}}))
+
+	values, err := sheet.Values()
+	require.NoError(t, err)
+	assert.Equal(t, [][]any{{"x", "b", "c"}, {"y", "z", "3"}, {"4", "5", "6"}}, values)
+}
+
+func TestLocalSheetService_CreateSheet(t *testing.T) {
+	dir := t.TempDir()
+	svc, err := NewLocalSheetService(dir)
+	require.NoError(t, err)
+
+	ss, err := svc.CreateSpreadSheet("test")
+	require.NoError(t, err)
+
+	created, err := ss.CreateSheet(&CreateSheetOptions{Title: "second"})
+	require.NoError(t, err)
+	require.NoError(t, created.UpdateValues([][]string{{"hello"}}))
+
+	_, err = ss.CreateSheet(&CreateSheetOptions{Title: "second"})
+	assert.Error(t, err)
+
+	info, err := ss.Get()
+	require.NoError(t, err)
+	require.Len(t, info.Sheets, 2)
+	assert.Equal(t, "second", info.Sheets[0].Title)
+	assert.Equal(t, int64(0), info.Sheets[0].Index)
+	assert.Equal(t, "Sheet1", info.Sheets[1].Title)
+	assert.Equal(t, int64(1), info.Sheets[1].Index)
+
+	// a new service instance sees the same state
+	svc, err = NewLocalSheetService(dir)
+	require.NoError(t, err)
+	ss, err = svc.GetSpreadSheet(info.Id)
+	require.NoError(t, err)
+
+	byTitle, err := ss.SheetByTitle("second")
+	require.NoError(t, err)
+	values, err := byTitle.Values()
+	require.NoError(t, err)
+	assert.Equal(t, [][]any{{"hello"}}, values)
+
+	byId, err := ss.SheetById(info.Sheets[0].Id)
+	require.NoError(t, err)
+	_, err = byId.Values()
+	assert.NoError(t, err)
+}
+
+func TestLocalSheetService_ManageSheets(t *testing.T) {
+	svc, err := NewLocalSheetService(t.TempDir())
+	require.NoError(t, err)
+
+	ss, err := svc.CreateSpreadSheet("test")
+	require.NoError(t, err)
+
+	first, err := ss.FirstSheet()
+	require.NoError(t, err)
+	require.NoError(t, first.UpdateValues([][]string{{"a"}}))
+
+	// the last sheet cannot be deleted
+	assert.Error(t, first.Delete())
+
+	copied, err := first.Duplicate("")
+	require.NoError(t, err)
+	info, err := copied.Get()
+	require.NoError(t, err)
+	assert.Equal(t, "Copy of Sheet1", info.Title)
+	assert.Equal(t, int64(1), info.Index)
+
+	_, err = first.Duplicate("Copy of Sheet1")
+	assert.Error(t, err)
+
+	assert.Error(t, copied.Rename("Sheet1"))
+	require.NoError(t, copied.Rename("renamed"))
+
+	require.NoError(t, first.Delete())
+
+	renamed, err := ss.SheetByTitle("renamed")
+	require.NoError(t, err)
+	info, err = renamed.Get()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), info.Index)
+
+	values, err := renamed.Values()
+	require.NoError(t, err)
+	assert.Equal(t, [][]any{{"a"}}, values)
+
+	require.NoError(t, renamed.Clear())
+	_, err = renamed.Values()
+	assert.ErrorIs(t, err, ErrEmptySheet)
+
+	other, err := svc.CreateSpreadSheet("other")
+	require.NoError(t, err)
+	otherInfo, err := other.Get()
+	require.NoError(t, err)
+
+	info, err = renamed.CopyTo(otherInfo.Id)
+	require.NoError(t, err)
+	assert.Equal(t, "Copy of renamed", info.Title)
+	assert.Equal(t, int64(1), info.Index)
+
+	_, err = renamed.CopyTo("missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, ss.Share(&ShareOptions{Email: "bob@example.com", Role: ShareRoleWriter}))
+}
+
+func TestLocalSheetService_PlainCsvDirectory(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "fixture"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "fixture", "a.csv"), []byte("name,age\nbob,42\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "fixture", "b.csv"), []byte("x\n"), 0o644))
+
+	svc, err := NewLocalSheetService(dir)
+	require.NoError(t, err)
+
+	ss, err := svc.GetSpreadSheet("fixture")
+	require.NoError(t, err)
+
+	sheet, err := ss.FirstSheet()
+	require.NoError(t, err)
+	values, err := sheet.Values()
+	require.NoError(t, err)
+	assert.Equal(t, [][]any{{"name", "age"}, {"bob", "42"}}, values)
+
+	sheet, err = ss.SheetByTitle("b")
+	require.NoError(t, err)
+	info, err := sheet.Get()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), info.Index)
+}
+
+func TestLocalSheetService_NotFound(t *testing.T) {
+	svc, err := NewLocalSheetService(t.TempDir())
+	require.NoError(t, err)
+
+	_, err = svc.GetSpreadSheet("missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = svc.GetSpreadSheet("../escape")
+	assert.Error(t, err)
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/trichner/oauthflows"
 	"golang.org/x/oauth2/google"
+	"google.golang.org/api/drive/v3"
 	. "google.golang.org/api/option"
 	googlesheets "google.golang.org/api/sheets/v4"
 )
@@ -33,17 +34,21 @@ type SheetsService interface {
 
 type sheetsService struct {
 	service *googlesheets.Service
+	drive   *drive.Service
 }
 
 type SpreadSheet struct {
-	Id     string
-	Sheets []*Sheet
+	Id     string   `json:"id"`
+	Title  string   `json:"title"`
+	Sheets []*Sheet `json:"sheets"`
 }
 
 type Sheet struct {
-	Id    int64
-	Title string
-	Index int64
+	Id          int64  `json:"id"`
+	Title       string `json:"title"`
+	Index       int64  `json:"index"`
+	RowCount    int64  `json:"rowCount"`
+	ColumnCount int64  `json:"columnCount"`
 }
 
 func NewSheetService(ctx context.Context) (SheetsService, error) {
@@ -65,21 +70,35 @@ func NewSheetService(ctx context.Context) (SheetsService, error) {
 		return nil, fmt.Errorf("cannot initialize oauth client: %w", err)
 	}
 
-	service, err := googlesheets.NewService(ctx, WithHTTPClient(client))
+	return NewSheetServiceWithOptions(ctx, WithHTTPClient(client))
+}
+
+// NewSheetServiceWithOptions creates a service talking to the Google Sheets API with the given client options, e.g.
+// to point it to a different endpoint
+func NewSheetServiceWithOptions(ctx context.Context, opts ...ClientOption) (SheetsService, error) {
+	service, err := googlesheets.NewService(ctx, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("cannot create service: %w", err)
 	}
 
-	return &sheetsService{service: service}, nil
+	// sharing is managed by the Drive API
+	driveService, err := drive.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create drive service: %w", err)
+	}
+
+	return &sheetsService{service: service, drive: driveService}, nil
 }
 
 func (s *sheetsService) GetSpreadSheet(id string) (SpreadsheetOps, error) {
-	res, err := s.service.Spreadsheets.Get(id).Do()
+	res, err := s.service.Spreadsheets.Get(id).Fields(spreadsheetFields).Do()
 	if err != nil {
 		return nil, err
 	}
 	return &spreadsheetOps{
 		service:     s.service,
+		drive:       s.drive,
+		id:          res.SpreadsheetId,
 		spreadsheet: res,
 	}, nil
 }
@@ -88,12 +107,14 @@ func (s *sheetsService) CreateSpreadSheet(title string) (SpreadsheetOps, error)
 	ss := &googlesheets.Spreadsheet{
 		Properties: &googlesheets.SpreadsheetProperties{Title: title},
 	}
-	res, err := s.service.Spreadsheets.Create(ss).Do()
+	res, err := s.service.Spreadsheets.Create(ss).Fields(spreadsheetFields).Do()
 	if err != nil {
 		return nil, fmt.Errorf("cannot create spreadsheet: %w", err)
 	}
 	return &spreadsheetOps{
 		service:     s.service,
+		drive:       s.drive,
+		id:          res.SpreadsheetId,
 		spreadsheet: res,
 	}, nil
 }
@@ -12,7 +12,17 @@ type SheetOps interface {
 	UpdateValues(data [][]string) error
 	AppendValues(data [][]string) error
 	Values() ([][]any, error)
+	// HeaderValues reads the values of the first row only, e.g. the header of a table
+	HeaderValues() ([]any, error)
 	Get() (*Sheet, error)
+	Rename(title string) error
+	// Clear removes all values but keeps the sheet
+	Clear() error
+	Delete() error
+	// Duplicate copies the sheet within the same spreadsheet, an empty title picks a default one
+	Duplicate(title string) (SheetOps, error)
+	// CopyTo copies the sheet into another spreadsheet
+	CopyTo(spreadsheetId string) (*Sheet, error)
 }
 
 type sheetOps struct {
@@ -30,42 +40,29 @@ func (s *sheetOps) Get() (*Sheet, error) {
 	return toSheet(sheet), nil
 }
 
+// UpdateValues writes the data starting at the top-left cell. Growing the sheet to fit the data and writing the
+// values happens in a single batch update.
 func (s *sheetOps) UpdateValues(data [][]string) error {
-	values := toValues(data)
+	if len(data) == 0 {
+		return nil
+	}
 
-	if err := s.grow(values); err != nil {
+	requests, err := s.growRequests(data)
+	if err != nil {
 		return err
 	}
 
-	filterRange := &googlesheets.DataFilterValueRange{
-		DataFilter: &googlesheets.DataFilter{
-			GridRange: &googlesheets.GridRange{
-				EndColumnIndex:   int64(len(values[0])),
-				EndRowIndex:      int64(len(values)),
-				SheetId:          s.sheetId,
-				StartColumnIndex: 0,
-				StartRowIndex:    0,
-				ForceSendFields:  nil,
-				NullFields:       nil,
-			},
+	requests = append(requests, &googlesheets.Request{UpdateCells: &googlesheets.UpdateCellsRequest{
+		Start: &googlesheets.GridCoordinate{
+			SheetId:     s.sheetId,
+			RowIndex:    0,
+			ColumnIndex: 0,
 		},
-		MajorDimension:  "ROWS",
-		Values:          values,
-		ForceSendFields: nil,
-		NullFields:      nil,
-	}
-
-	req := &googlesheets.BatchUpdateValuesByDataFilterRequest{
-		Data:                         []*googlesheets.DataFilterValueRange{filterRange},
-		IncludeValuesInResponse:      false,
-		ResponseDateTimeRenderOption: "",
-		ResponseValueRenderOption:    "",
-		ValueInputOption:             "RAW",
-		ForceSendFields:              nil,
-		NullFields:                   nil,
-	}
+		Rows:   toRowData(data),
+		Fields: "userEnteredValue",
+	}})
 
-	_, err := s.service.Spreadsheets.Values.BatchUpdateByDataFilter(s.spreadsheetId(), req).Do()
+	_, err = s.batchUpdate(requests)
 	if err != nil {
 		return fmt.Errorf("unable to update data from sheet: %w", err)
 	}
@@ -73,70 +70,58 @@ func (s *sheetOps) UpdateValues(data [][]string) error {
 	return nil
 }
 
-func (s *sheetOps) grow(values [][]any) error {
+// growRequests returns the requests necessary to expand the sheet to fit the data, if any
+func (s *sheetOps) growRequests(data [][]string) ([]*googlesheets.Request, error) {
 	sheet, err := s.filteredSheets(func(p *googlesheets.SheetProperties) bool {
 		return p.SheetId == s.sheetId
 	})
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	var curColumns, curRows int64
+	if sheet.GridProperties != nil {
+		curColumns = sheet.GridProperties.ColumnCount
+		curRows = sheet.GridProperties.RowCount
 	}
-	curColumns := sheet.GridProperties.ColumnCount
-	curRows := sheet.GridProperties.RowCount
 
-	var appendDimensions []*googlesheets.AppendDimensionRequest
-	missingColumns := max(len(values[0])-int(curColumns), 0)
+	columns := 0
+	for _, row := range data {
+		columns = max(columns, len(row))
+	}
+
+	var requests []*googlesheets.Request
+	missingColumns := max(columns-int(curColumns), 0)
 	if missingColumns > 0 {
-		appendDimensions = append(appendDimensions, &googlesheets.AppendDimensionRequest{
+		requests = append(requests, &googlesheets.Request{AppendDimension: &googlesheets.AppendDimensionRequest{
 			Dimension: "COLUMNS",
 			Length:    int64(missingColumns),
 			SheetId:   s.sheetId,
-		})
+		}})
 	}
-	missingRows := max(len(values)-int(curRows), 0)
+	missingRows := max(len(data)-int(curRows), 0)
 	if missingRows > 0 {
-		appendDimensions = append(appendDimensions, &googlesheets.AppendDimensionRequest{
+		requests = append(requests, &googlesheets.Request{AppendDimension: &googlesheets.AppendDimensionRequest{
 			Dimension: "ROWS",
 			Length:    int64(missingRows),
 			SheetId:   s.sheetId,
-		})
+		}})
 	}
 
-	if len(appendDimensions) == 0 {
-		return nil
-	}
-
-	var requests []*googlesheets.Request
-	for _, r := range appendDimensions {
-		requests = append(requests, &googlesheets.Request{AppendDimension: r})
-	}
-	req := &googlesheets.BatchUpdateSpreadsheetRequest{
-		Requests: requests,
-	}
-	_, err = s.service.Spreadsheets.BatchUpdate(s.spreadsheetId(), req).Do()
-	if err != nil {
-		return fmt.Errorf("failed to expand data range to fit data: %w", err)
-	}
-	return nil
+	return requests, nil
 }
 
+// AppendValues appends the data after the last row with data, the sheet grows automatically
 func (s *sheetOps) AppendValues(data [][]string) error {
-	sheet, err := s.Get()
-	if err != nil {
-		return fmt.Errorf("unable to append data, spreadsheet='%s' sheetId='%d': %w", s.spreadsheetId(), s.sheetId, err)
-	}
-
-	insertRange := fmt.Sprintf("'%s'!A:A", sheet.Title)
-	values := toValues(data)
-	valueRange := &googlesheets.ValueRange{
-		MajorDimension: "ROWS",
-		Values:         values,
+	if len(data) == 0 {
+		return nil
 	}
 
-	_, err = s.service.Spreadsheets.Values.Append(s.spreadsheetId(), insertRange, valueRange).
-		ValueInputOption("RAW").
-		InsertDataOption("INSERT_ROWS").
-		Do()
-
+	_, err := s.batchUpdate([]*googlesheets.Request{{AppendCells: &googlesheets.AppendCellsRequest{
+		SheetId: s.sheetId,
+		Rows:    toRowData(data),
+		Fields:  "userEnteredValue",
+	}}})
 	if err != nil {
 		return fmt.Errorf("unable to append data, spreadsheet='%s' sheetId='%d': %w", s.spreadsheetId(), s.sheetId, err)
 	}
@@ -160,17 +145,111 @@ func (s *sheetOps) Values() ([][]any, error) {
 		return nil, fmt.Errorf("unable to retrieve data from sheet: %w", err)
 	}
 
-	if len(resp.ValueRanges) == 0 {
-		return nil, fmt.Errorf("empty spreadsheet")
+	if len(resp.ValueRanges) == 0 || resp.ValueRanges[0].ValueRange == nil {
+		return nil, ErrEmptySheet
 	}
 
 	values := resp.ValueRanges[0].ValueRange.Values
 	if len(values) == 0 {
-		return nil, fmt.Errorf("empty spreadsheet, no values found")
+		return nil, ErrEmptySheet
 	}
 	return values, nil
 }
 
+func (s *sheetOps) HeaderValues() ([]any, error) {
+	// the range '1:1' of the sheet
+	resp, err := s.service.Spreadsheets.Values.BatchGetByDataFilter(s.spreadsheetId(), &googlesheets.BatchGetValuesByDataFilterRequest{
+		DataFilters: []*googlesheets.DataFilter{{GridRange: &googlesheets.GridRange{
+			SheetId:     s.sheetId,
+			EndRowIndex: 1,
+		}}},
+	}).Do()
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve header from sheet: %w", err)
+	}
+
+	if len(resp.ValueRanges) == 0 || resp.ValueRanges[0].ValueRange == nil || len(resp.ValueRanges[0].ValueRange.Values) == 0 {
+		return nil, ErrEmptySheet
+	}
+	return resp.ValueRanges[0].ValueRange.Values[0], nil
+}
+
+func (s *sheetOps) Rename(title string) error {
+	_, err := s.batchUpdate([]*googlesheets.Request{{UpdateSheetProperties: &googlesheets.UpdateSheetPropertiesRequest{
+		Properties: &googlesheets.SheetProperties{SheetId: s.sheetId, Title: title},
+		Fields:     "title",
+	}}})
+	if err != nil {
+		return fmt.Errorf("unable to rename sheet, spreadsheet='%s' sheetId='%d': %w", s.spreadsheetId(), s.sheetId, err)
+	}
+	return nil
+}
+
+func (s *sheetOps) Clear() error {
+	_, err := s.batchUpdate([]*googlesheets.Request{{UpdateCells: &googlesheets.UpdateCellsRequest{
+		Range:  &googlesheets.GridRange{SheetId: s.sheetId},
+		Fields: "userEnteredValue",
+	}}})
+	if err != nil {
+		return fmt.Errorf("unable to clear sheet, spreadsheet='%s' sheetId='%d': %w", s.spreadsheetId(), s.sheetId, err)
+	}
+	return nil
+}
+
+func (s *sheetOps) Delete() error {
+	_, err := s.batchUpdate([]*googlesheets.Request{{DeleteSheet: &googlesheets.DeleteSheetRequest{
+		SheetId: s.sheetId,
+	}}})
+	if err != nil {
+		return fmt.Errorf("unable to delete sheet, spreadsheet='%s' sheetId='%d': %w", s.spreadsheetId(), s.sheetId, err)
+	}
+	return nil
+}
+
+func (s *sheetOps) Duplicate(title string) (SheetOps, error) {
+	sheet, err := s.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.batchUpdate([]*googlesheets.Request{{DuplicateSheet: &googlesheets.DuplicateSheetRequest{
+		SourceSheetId:    s.sheetId,
+		NewSheetName:     title,
+		InsertSheetIndex: sheet.Index + 1,
+	}}})
+	if err != nil {
+		return nil, fmt.Errorf("unable to duplicate sheet, spreadsheet='%s' sheetId='%d': %w", s.spreadsheetId(), s.sheetId, err)
+	}
+
+	return s.toSheetOps(res.Replies[0].DuplicateSheet.Properties), nil
+}
+
+func (s *sheetOps) CopyTo(spreadsheetId string) (*Sheet, error) {
+	req := &googlesheets.CopySheetToAnotherSpreadsheetRequest{DestinationSpreadsheetId: spreadsheetId}
+	props, err := s.service.Spreadsheets.Sheets.CopyTo(s.spreadsheetId(), s.sheetId, req).Do()
+	if err != nil {
+		return nil, fmt.Errorf("unable to copy sheet, spreadsheet='%s' sheetId='%d': %w", s.spreadsheetId(), s.sheetId, err)
+	}
+	if spreadsheetId == s.spreadsheetId() {
+		s.invalidate()
+	}
+	return toSheet(props), nil
+}
+
+// toRowData maps the data to raw string values, like the 'RAW' value input option they are not parsed
+func toRowData(data [][]string) []*googlesheets.RowData {
+	rows := make([]*googlesheets.RowData, len(data))
+	for i, row := range data {
+		cells := make([]*googlesheets.CellData, len(row))
+		for j, cell := range row {
+			value := cell
+			cells[j] = &googlesheets.CellData{UserEnteredValue: &googlesheets.ExtendedValue{StringValue: &value}}
+		}
+		rows[i] = &googlesheets.RowData{Values: cells}
+	}
+	return rows
+}
+
 func toValues(data [][]string) [][]interface{} {
 	values := make([][]interface{}, len(data))
 	for i, row := range data {
@@ -0,0 +1,136 @@
+package sheets
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// NoSheetId marks a SheetRef without an explicit sheet, it references the first sheet of the spreadsheet.
+const NoSheetId int64 = -1
+
+// SheetRef references a single sheet (tab) within a spreadsheet.
+type SheetRef struct {
+	SpreadsheetId string
+	// SheetId is the 'gid' of the sheet or NoSheetId for the first sheet
+	SheetId int64
+}
+
+var (
+	spreadsheetIdPattern   = regexp.MustCompile("^[-_A-Za-z0-9]+$")
+	spreadsheetPathPattern = regexp.MustCompile("^(?:/a/[^/]+)?/spreadsheets(?:/u/[0-9]+)?/d/([-_A-Za-z0-9]+)(?:/.*)?$")
+	publishedPathPattern   = regexp.MustCompile("^(?:/a/[^/]+)?/spreadsheets(?:/u/[0-9]+)?/d/e/")
+)
+
+// ParseSheetRef parses a reference to a sheet, either a plain spreadsheet ID or any form of Google Sheets URL such as:
+//
+//	https://docs.google.com/spreadsheets/d/1dAN8MO9NDVPqVIoOxC9H_j4Ir5c1viQ97igGdXOyXsU/edit#gid=886605725
+//	https://docs.google.com/spreadsheets/u/1/d/1dAN8MO9NDVPqVIoOxC9H_j4Ir5c1viQ97igGdXOyXsU/edit?usp=sharing
+//	https://docs.google.com/spreadsheets/d/1dAN8MO9NDVPqVIoOxC9H_j4Ir5c1viQ97igGdXOyXsU
+//
+// If the URL does not specify a 'gid' the reference points to the first sheet.
+func ParseSheetRef(s string) (*SheetRef, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, fmt.Errorf("empty spreadsheet reference")
+	}
+
+	if spreadsheetIdPattern.MatchString(s) {
+		return &SheetRef{SpreadsheetId: s, SheetId: NoSheetId}, nil
+	}
+
+	if !strings.Contains(s, "://") {
+		s = "https://" + s
+	}
+
+	parsed, err := url.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid spreadsheet URL %q: %w", s, err)
+	}
+
+	if parsed.Scheme != "https" && parsed.Scheme != "http" {
+		return nil, fmt.Errorf("unexpected scheme '%s', expected 'https'", parsed.Scheme)
+	}
+
+	const googleDocsHost = "docs.google.com"
+	if parsed.Hostname() != googleDocsHost {
+		return nil, fmt.Errorf("unexpected host '%s', expected '%s'", parsed.Host, googleDocsHost)
+	}
+
+	if publishedPathPattern.MatchString(parsed.Path) {
+		return nil, fmt.Errorf("published spreadsheet URLs are not supported, use the URL of the spreadsheet itself: '%s'", parsed.Path)
+	}
+
+	matches := spreadsheetPathPattern.FindStringSubmatch(parsed.Path)
+	if matches == nil {
+		return nil, fmt.Errorf("can't find spreadsheetId in path: '%s'", parsed.Path)
+	}
+
+	sheetId, err := parseGid(parsed)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SheetRef{SpreadsheetId: matches[1], SheetId: sheetId}, nil
+}
+
+// parseGid finds the sheet ID of a URL, the fragment takes precedence over the query as it reflects the currently
+// selected sheet
+func parseGid(u *url.URL) (int64, error) {
+	const paramGid = "gid"
+
+	rawSheetId := ""
+	if fragment, err := url.ParseQuery(u.Fragment); err == nil {
+		rawSheetId = fragment.Get(paramGid)
+	}
+	if rawSheetId == "" {
+		rawSheetId = u.Query().Get(paramGid)
+	}
+	if rawSheetId == "" {
+		return NoSheetId, nil
+	}
+
+	sheetId, err := strconv.ParseInt(rawSheetId, 10, 64)
+	if err != nil || sheetId < 0 {
+		return NoSheetId, fmt.Errorf("invalid '%s' in URL: '%s'", paramGid, rawSheetId)
+	}
+	return sheetId, nil
+}
+
+// HasSheetId returns true if the reference points to a specific sheet rather than the first one
+func (r *SheetRef) HasSheetId() bool {
+	return r.SheetId != NoSheetId
+}
+
+// URL returns the canonical URL to edit the referenced sheet
+func (r *SheetRef) URL() string {
+	u := fmt.Sprintf("https://docs.google.com/spreadsheets/d/%s/edit", r.SpreadsheetId)
+	if r.HasSheetId() {
+		u += fmt.Sprintf("#gid=%d", r.SheetId)
+	}
+	return u
+}
+
+func (r *SheetRef) String() string {
+	return r.URL()
+}
+
+// OpenSheet opens the referenced sheet, falls back to the first sheet if the reference has no sheet ID
+func OpenSheet(svc SheetsService, ref *SheetRef) (SheetOps, error) {
+	ss, err := svc.GetSpreadSheet(ref.SpreadsheetId)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open spreadsheet %q: %w", ref.SpreadsheetId, err)
+	}
+
+	if !ref.HasSheetId() {
+		return ss.FirstSheet()
+	}
+
+	sheet, err := ss.SheetById(ref.SheetId)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open sheet %d in spreadsheet %q: %w", ref.SheetId, ref.SpreadsheetId, err)
+	}
+	return sheet, nil
+}
@@ -0,0 +1,152 @@
+package sheets
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSheetRef(t *testing.T) {
+	const id = "1dAN8MO9NDVPqVIoOxC9H_j4Ir5c1viQ97igGdXOyXsU"
+
+	tests := []struct {
+		name             string
+		raw              string
+		expected         *SheetRef
+		expectedErrorMsg string
+	}{
+		{
+			name:     "edit with gid fragment",
+			raw:      "https://docs.google.com/spreadsheets/d/" + id + "/edit#gid=886605725",
+			expected: &SheetRef{SpreadsheetId: id, SheetId: 886605725},
+		},
+		{
+			name:     "edit with gid in query and fragment",
+			raw:      "https://docs.google.com/spreadsheets/d/" + id + "/edit?gid=12#gid=12",
+			expected: &SheetRef{SpreadsheetId: id, SheetId: 12},
+		},
+		{
+			name:     "fragment takes precedence over query",
+			raw:      "https://docs.google.com/spreadsheets/d/" + id + "/edit?gid=12#gid=13",
+			expected: &SheetRef{SpreadsheetId: id, SheetId: 13},
+		},
+		{
+			name:     "gid in query only",
+			raw:      "https://docs.google.com/spreadsheets/d/" + id + "/edit?gid=0",
+			expected: &SheetRef{SpreadsheetId: id, SheetId: 0},
+		},
+		{
+			name:     "sharing link",
+			raw:      "https://docs.google.com/spreadsheets/d/" + id + "/edit?usp=sharing",
+			expected: &SheetRef{SpreadsheetId: id, SheetId: NoSheetId},
+		},
+		{
+			name:     "edit without gid",
+			raw:      "https://docs.google.com/spreadsheets/d/" + id + "/edit",
+			expected: &SheetRef{SpreadsheetId: id, SheetId: NoSheetId},
+		},
+		{
+			name:     "without edit",
+			raw:      "https://docs.google.com/spreadsheets/d/" + id,
+			expected: &SheetRef{SpreadsheetId: id, SheetId: NoSheetId},
+		},
+		{
+			name:     "without edit but trailing slash",
+			raw:      "https://docs.google.com/spreadsheets/d/" + id + "/",
+			expected: &SheetRef{SpreadsheetId: id, SheetId: NoSheetId},
+		},
+		{
+			name:     "account prefix",
+			raw:      "https://docs.google.com/spreadsheets/u/1/d/" + id + "/edit#gid=42",
+			expected: &SheetRef{SpreadsheetId: id, SheetId: 42},
+		},
+		{
+			name:     "domain prefix",
+			raw:      "https://docs.google.com/a/example.com/spreadsheets/d/" + id + "/edit#gid=42",
+			expected: &SheetRef{SpreadsheetId: id, SheetId: 42},
+		},
+		{
+			name:     "fragment with range",
+			raw:      "https://docs.google.com/spreadsheets/d/" + id + "/edit#gid=42&range=A1:C3",
+			expected: &SheetRef{SpreadsheetId: id, SheetId: 42},
+		},
+		{
+			name:     "export link",
+			raw:      "https://docs.google.com/spreadsheets/d/" + id + "/export?format=csv&gid=7",
+			expected: &SheetRef{SpreadsheetId: id, SheetId: 7},
+		},
+		{
+			name:     "without scheme",
+			raw:      "docs.google.com/spreadsheets/d/" + id + "/edit#gid=1",
+			expected: &SheetRef{SpreadsheetId: id, SheetId: 1},
+		},
+		{
+			name:     "surrounding whitespace",
+			raw:      "  https://docs.google.com/spreadsheets/d/" + id + "/edit#gid=1\n",
+			expected: &SheetRef{SpreadsheetId: id, SheetId: 1},
+		},
+		{
+			name:     "plain spreadsheet id",
+			raw:      id,
+			expected: &SheetRef{SpreadsheetId: id, SheetId: NoSheetId},
+		},
+		{
+			name:             "empty",
+			raw:              "",
+			expectedErrorMsg: "empty spreadsheet reference",
+		},
+		{
+			name:             "wrong host",
+			raw:              "https://example.com/spreadsheets/d/" + id + "/edit",
+			expectedErrorMsg: "unexpected host 'example.com', expected 'docs.google.com'",
+		},
+		{
+			name:             "wrong scheme",
+			raw:              "ftp://docs.google.com/spreadsheets/d/" + id + "/edit",
+			expectedErrorMsg: "unexpected scheme 'ftp', expected 'https'",
+		},
+		{
+			name:             "document instead of spreadsheet",
+			raw:              "https://docs.google.com/document/d/" + id + "/edit",
+			expectedErrorMsg: "can't find spreadsheetId in path: '/document/d/" + id + "/edit'",
+		},
+		{
+			name:             "published spreadsheet",
+			raw:              "https://docs.google.com/spreadsheets/d/e/2PACX-1vQ/pubhtml",
+			expectedErrorMsg: "published spreadsheet URLs are not supported, use the URL of the spreadsheet itself: '/spreadsheets/d/e/2PACX-1vQ/pubhtml'",
+		},
+		{
+			name:             "bad gid",
+			raw:              "https://docs.google.com/spreadsheets/d/" + id + "/edit#gid=abc",
+			expectedErrorMsg: "invalid 'gid' in URL: 'abc'",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ref, err := ParseSheetRef(test.raw)
+			if test.expectedErrorMsg != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.expectedErrorMsg, err.Error())
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, ref)
+			}
+		})
+	}
+}
+
+func TestSheetRef_URL(t *testing.T) {
+	ref := &SheetRef{SpreadsheetId: "abc", SheetId: 0}
+	assert.Equal(t, "https://docs.google.com/spreadsheets/d/abc/edit#gid=0", ref.URL())
+
+	ref = &SheetRef{SpreadsheetId: "abc", SheetId: NoSheetId}
+	assert.Equal(t, "https://docs.google.com/spreadsheets/d/abc/edit", ref.URL())
+}
+
+func TestSheetRef_RoundTrip(t *testing.T) {
+	expected := &SheetRef{SpreadsheetId: "1dAN8MO9NDVPqVIoOxC9H_j4Ir5c1viQ97igGdXOyXsU", SheetId: 886605725}
+
+	actual, err := ParseSheetRef(expected.URL())
+	assert.NoError(t, err)
+	assert.Equal(t, expected, actual)
+}
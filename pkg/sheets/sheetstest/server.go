@@ -0,0 +1,504 @@
+// Package sheetstest provides a fake of the Google Sheets REST API for tests.
+package sheetstest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/trichner/toolbox/pkg/sheets"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/option"
+	googlesheets "google.golang.org/api/sheets/v4"
+)
+
+const (
+	spreadsheetsPath = "/v4/spreadsheets"
+	filesPath        = "/files"
+)
+
+// Server fakes the subset of the Google Sheets REST API used by pkg/sheets, all requests are served from the
+// backing SheetsService, usually a local one. Sharing via the Drive API permissions endpoint is faked as well.
+type Server struct {
+	*httptest.Server
+	backend sheets.SheetsService
+
+	mu       sync.Mutex
+	requests []string
+}
+
+type httpError struct {
+	code int
+	err  error
+}
+
+func (e *httpError) Error() string {
+	return e.err.Error()
+}
+
+// NewServer starts a fake Sheets API serving from the given backend, callers should Close it when done.
+func NewServer(backend sheets.SheetsService) *Server {
+	s := &Server{backend: backend}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// SheetsService returns a client of the Google Sheets API talking to this fake
+func (s *Server) SheetsService(ctx context.Context) (sheets.SheetsService, error) {
+	return sheets.NewSheetServiceWithOptions(ctx,
+		option.WithEndpoint(s.URL+"/"),
+		option.WithHTTPClient(s.Client()),
+	)
+}
+
+// Requests lists all requests served so far as '<method> <path>'
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+	s.mu.Unlock()
+
+	res, err := s.route(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		writeError(w, err)
+	}
+}
+
+func (s *Server) route(r *http.Request) (any, error) {
+	p := r.URL.Path
+	if strings.HasPrefix(p, filesPath+"/") {
+		return s.routeFiles(r)
+	}
+	if !strings.HasPrefix(p, spreadsheetsPath) {
+		return nil, notFound("unknown path %q", p)
+	}
+	p = strings.TrimPrefix(p, spreadsheetsPath)
+
+	if p == "" {
+		return s.handle(r, http.MethodPost, s.createSpreadsheet)
+	}
+
+	p = strings.TrimPrefix(p, "/")
+	id, rest, _ := strings.Cut(p, "/")
+	id, action, _ := strings.Cut(id, ":")
+
+	switch {
+	case rest == "" && action == "":
+		return s.handleWithId(r, http.MethodGet, id, s.getSpreadsheet)
+	case rest == "" && action == "batchUpdate":
+		return s.handleWithId(r, http.MethodPost, id, s.batchUpdate)
+	case rest == "values:batchUpdateByDataFilter":
+		return s.handleWithId(r, http.MethodPost, id, s.batchUpdateValuesByDataFilter)
+	case rest == "values:batchGetByDataFilter":
+		return s.handleWithId(r, http.MethodPost, id, s.batchGetValuesByDataFilter)
+	case strings.HasPrefix(rest, "values/") && strings.HasSuffix(rest, ":append"):
+		a1Range := strings.TrimSuffix(strings.TrimPrefix(rest, "values/"), ":append")
+		return s.handleWithId(r, http.MethodPost, id, func(r *http.Request, ss sheets.SpreadsheetOps) (any, error) {
+			return s.appendValues(r, ss, a1Range)
+		})
+	case strings.HasPrefix(rest, "sheets/") && strings.HasSuffix(rest, ":copyTo"):
+		sheetId, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimPrefix(rest, "sheets/"), ":copyTo"), 10, 64)
+		if err != nil {
+			return nil, badRequest("invalid sheet id: %s", err)
+		}
+		return s.handleWithId(r, http.MethodPost, id, func(r *http.Request, ss sheets.SpreadsheetOps) (any, error) {
+			return s.copySheetTo(r, ss, sheetId)
+		})
+	}
+	return nil, notFound("unknown path %q", r.URL.Path)
+}
+
+func (s *Server) routeFiles(r *http.Request) (any, error) {
+	id, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, filesPath+"/"), "/")
+	if rest != "permissions" {
+		return nil, notFound("unknown path %q", r.URL.Path)
+	}
+	return s.handleWithId(r, http.MethodPost, id, s.createPermission)
+}
+
+func (s *Server) handle(r *http.Request, method string, fn func(r *http.Request) (any, error)) (any, error) {
+	if r.Method != method {
+		return nil, &httpError{code: http.StatusMethodNotAllowed, err: fmt.Errorf("method %s not allowed", r.Method)}
+	}
+	return fn(r)
+}
+
+func (s *Server) handleWithId(r *http.Request, method string, id string, fn func(r *http.Request, ss sheets.SpreadsheetOps) (any, error)) (any, error) {
+	return s.handle(r, method, func(r *http.Request) (any, error) {
+		ss, err := s.backend.GetSpreadSheet(id)
+		if err != nil {
+			return nil, err
+		}
+		return fn(r, ss)
+	})
+}
+
+func (s *Server) createSpreadsheet(r *http.Request) (any, error) {
+	var req googlesheets.Spreadsheet
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+
+	title := ""
+	if req.Properties != nil {
+		title = req.Properties.Title
+	}
+
+	ss, err := s.backend.CreateSpreadSheet(title)
+	if err != nil {
+		return nil, err
+	}
+	return toSpreadsheet(ss)
+}
+
+func (s *Server) getSpreadsheet(_ *http.Request, ss sheets.SpreadsheetOps) (any, error) {
+	return toSpreadsheet(ss)
+}
+
+func (s *Server) batchUpdate(r *http.Request, ss sheets.SpreadsheetOps) (any, error) {
+	var req googlesheets.BatchUpdateSpreadsheetRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+
+	info, err := ss.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	res := &googlesheets.BatchUpdateSpreadsheetResponse{SpreadsheetId: info.Id}
+	for _, op := range req.Requests {
+		reply, err := s.applyRequest(ss, op)
+		if err != nil {
+			return nil, err
+		}
+		res.Replies = append(res.Replies, reply)
+	}
+
+	if req.IncludeSpreadsheetInResponse {
+		res.UpdatedSpreadsheet, err = toSpreadsheet(ss)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+func (s *Server) applyRequest(ss sheets.SpreadsheetOps, req *googlesheets.Request) (*googlesheets.Response, error) {
+	switch {
+	case req.AddSheet != nil:
+		title := ""
+		if req.AddSheet.Properties != nil {
+			title = req.AddSheet.Properties.Title
+		}
+		sheet, err := ss.CreateSheet(&sheets.CreateSheetOptions{Title: title})
+		if err != nil {
+			return nil, err
+		}
+		props, err := sheet.Get()
+		if err != nil {
+			return nil, err
+		}
+		return &googlesheets.Response{AddSheet: &googlesheets.AddSheetResponse{Properties: toSheetProperties(props)}}, nil
+	case req.AppendDimension != nil:
+		// the backend grows on demand, nothing to do
+		return &googlesheets.Response{}, nil
+	case req.UpdateCells != nil && req.UpdateCells.Start != nil:
+		start := req.UpdateCells.Start
+		if start.RowIndex != 0 || start.ColumnIndex != 0 {
+			return nil, badRequest("only updating cells starting at A1 is supported")
+		}
+		sheet, err := ss.SheetById(start.SheetId)
+		if err != nil {
+			return nil, err
+		}
+		return &googlesheets.Response{}, sheet.UpdateValues(fromRowData(req.UpdateCells.Rows))
+	case req.UpdateCells != nil && req.UpdateCells.Range != nil:
+		// a range without rows clears the whole sheet
+		if len(req.UpdateCells.Rows) > 0 {
+			return nil, badRequest("only clearing cells of a range is supported")
+		}
+		sheet, err := ss.SheetById(req.UpdateCells.Range.SheetId)
+		if err != nil {
+			return nil, err
+		}
+		return &googlesheets.Response{}, sheet.Clear()
+	case req.AppendCells != nil:
+		sheet, err := ss.SheetById(req.AppendCells.SheetId)
+		if err != nil {
+			return nil, err
+		}
+		return &googlesheets.Response{}, sheet.AppendValues(fromRowData(req.AppendCells.Rows))
+	case req.UpdateSheetProperties != nil:
+		props := req.UpdateSheetProperties.Properties
+		if props == nil || req.UpdateSheetProperties.Fields != "title" {
+			return nil, badRequest("only updating the title of a sheet is supported")
+		}
+		sheet, err := ss.SheetById(props.SheetId)
+		if err != nil {
+			return nil, err
+		}
+		return &googlesheets.Response{}, sheet.Rename(props.Title)
+	case req.DeleteSheet != nil:
+		sheet, err := ss.SheetById(req.DeleteSheet.SheetId)
+		if err != nil {
+			return nil, err
+		}
+		return &googlesheets.Response{}, sheet.Delete()
+	case req.DuplicateSheet != nil:
+		sheet, err := ss.SheetById(req.DuplicateSheet.SourceSheetId)
+		if err != nil {
+			return nil, err
+		}
+		duplicate, err := sheet.Duplicate(req.DuplicateSheet.NewSheetName)
+		if err != nil {
+			return nil, err
+		}
+		props, err := duplicate.Get()
+		if err != nil {
+			return nil, err
+		}
+		return &googlesheets.Response{DuplicateSheet: &googlesheets.DuplicateSheetResponse{Properties: toSheetProperties(props)}}, nil
+	}
+	return nil, badRequest("unsupported batch update request")
+}
+
+func (s *Server) batchUpdateValuesByDataFilter(r *http.Request, ss sheets.SpreadsheetOps) (any, error) {
+	var req googlesheets.BatchUpdateValuesByDataFilterRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+
+	res := &googlesheets.BatchUpdateValuesByDataFilterResponse{}
+	for _, data := range req.Data {
+		if data.DataFilter == nil || data.DataFilter.GridRange == nil {
+			return nil, badRequest("only grid range data filters are supported")
+		}
+		gr := data.DataFilter.GridRange
+		if gr.StartRowIndex != 0 || gr.StartColumnIndex != 0 {
+			return nil, badRequest("only grid ranges starting at A1 are supported")
+		}
+
+		sheet, err := ss.SheetById(gr.SheetId)
+		if err != nil {
+			return nil, err
+		}
+		if err := sheet.UpdateValues(fromValues(data.Values)); err != nil {
+			return nil, err
+		}
+		res.TotalUpdatedRows += int64(len(data.Values))
+	}
+	return res, nil
+}
+
+func (s *Server) batchGetValuesByDataFilter(r *http.Request, ss sheets.SpreadsheetOps) (any, error) {
+	var req googlesheets.BatchGetValuesByDataFilterRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+
+	res := &googlesheets.BatchGetValuesByDataFilterResponse{}
+	for _, filter := range req.DataFilters {
+		if filter.GridRange == nil {
+			return nil, badRequest("only grid range data filters are supported")
+		}
+		sheet, err := ss.SheetById(filter.GridRange.SheetId)
+		if err != nil {
+			return nil, err
+		}
+		values, err := sheet.Values()
+		if err != nil && !errors.Is(err, sheets.ErrEmptySheet) {
+			return nil, err
+		}
+		res.ValueRanges = append(res.ValueRanges, &googlesheets.MatchedValueRange{
+			DataFilters: []*googlesheets.DataFilter{filter},
+			ValueRange:  &googlesheets.ValueRange{MajorDimension: "ROWS", Values: values},
+		})
+	}
+	return res, nil
+}
+
+func (s *Server) appendValues(r *http.Request, ss sheets.SpreadsheetOps, a1Range string) (any, error) {
+	var req googlesheets.ValueRange
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+
+	title, _, ok := strings.Cut(a1Range, "!")
+	if !ok {
+		return nil, badRequest("range without sheet title: %q", a1Range)
+	}
+	title = strings.Trim(title, "'")
+
+	sheet, err := ss.SheetByTitle(title)
+	if err != nil {
+		return nil, err
+	}
+	if err := sheet.AppendValues(fromValues(req.Values)); err != nil {
+		return nil, err
+	}
+	return &googlesheets.AppendValuesResponse{TableRange: a1Range}, nil
+}
+
+func (s *Server) copySheetTo(r *http.Request, ss sheets.SpreadsheetOps, sheetId int64) (any, error) {
+	var req googlesheets.CopySheetToAnotherSpreadsheetRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+
+	sheet, err := ss.SheetById(sheetId)
+	if err != nil {
+		return nil, err
+	}
+	props, err := sheet.CopyTo(req.DestinationSpreadsheetId)
+	if err != nil {
+		return nil, err
+	}
+	return toSheetProperties(props), nil
+}
+
+func (s *Server) createPermission(r *http.Request, ss sheets.SpreadsheetOps) (any, error) {
+	var req drive.Permission
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+
+	opts := &sheets.ShareOptions{
+		Email:  req.EmailAddress,
+		Anyone: req.Type == "anyone",
+		Role:   sheets.ShareRole(req.Role),
+		Notify: r.URL.Query().Get("sendNotificationEmail") == "true",
+	}
+	if err := ss.Share(opts); err != nil {
+		return nil, err
+	}
+	return &drive.Permission{Kind: "drive#permission", Type: req.Type, Role: req.Role, EmailAddress: req.EmailAddress}, nil
+}
+
+func toSpreadsheet(ss sheets.SpreadsheetOps) (*googlesheets.Spreadsheet, error) {
+	info, err := ss.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	res := &googlesheets.Spreadsheet{
+		SpreadsheetId:  info.Id,
+		Properties:     &googlesheets.SpreadsheetProperties{Title: info.Title},
+		SpreadsheetUrl: (&sheets.SheetRef{SpreadsheetId: info.Id, SheetId: sheets.NoSheetId}).URL(),
+	}
+	for _, sheet := range info.Sheets {
+		res.Sheets = append(res.Sheets, &googlesheets.Sheet{Properties: toSheetProperties(sheet)})
+	}
+	return res, nil
+}
+
+func toSheetProperties(sheet *sheets.Sheet) *googlesheets.SheetProperties {
+	return &googlesheets.SheetProperties{
+		SheetId:   sheet.Id,
+		Title:     sheet.Title,
+		Index:     sheet.Index,
+		SheetType: "GRID",
+		GridProperties: &googlesheets.GridProperties{
+			RowCount:    sheet.RowCount,
+			ColumnCount: sheet.ColumnCount,
+		},
+		ForceSendFields: []string{"SheetId", "Index"},
+	}
+}
+
+func fromRowData(rows []*googlesheets.RowData) [][]string {
+	data := make([][]string, len(rows))
+	for i, row := range rows {
+		data[i] = make([]string, len(row.Values))
+		for j, cell := range row.Values {
+			data[i][j] = cellToString(cell)
+		}
+	}
+	return data
+}
+
+func cellToString(cell *googlesheets.CellData) string {
+	if cell == nil || cell.UserEnteredValue == nil {
+		return ""
+	}
+	v := cell.UserEnteredValue
+	switch {
+	case v.StringValue != nil:
+		return *v.StringValue
+	case v.NumberValue != nil:
+		return strconv.FormatFloat(*v.NumberValue, 'f', -1, 64)
+	case v.BoolValue != nil:
+		return strings.ToUpper(strconv.FormatBool(*v.BoolValue))
+	case v.FormulaValue != nil:
+		return *v.FormulaValue
+	}
+	return ""
+}
+
+func fromValues(values [][]any) [][]string {
+	data := make([][]string, len(values))
+	for i, row := range values {
+		data[i] = make([]string, len(row))
+		for j, cell := range row {
+			if cell != nil {
+				data[i][j] = fmt.Sprint(cell)
+			}
+		}
+	}
+	return data
+}
+
+func decode(r *http.Request, v any) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return badRequest("invalid request body: %s", err)
+	}
+	return nil
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	var herr *httpError
+	if errors.As(err, &herr) {
+		code = herr.code
+	} else if errors.Is(err, sheets.ErrNotFound) {
+		code = http.StatusNotFound
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	var body struct {
+		Error struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	body.Error.Code = code
+	body.Error.Message = err.Error()
+	_ = json.NewEncoder(w).Encode(&body)
+}
+
+func badRequest(format string, args ...any) error {
+	return &httpError{code: http.StatusBadRequest, err: fmt.Errorf(format, args...)}
+}
+
+func notFound(format string, args ...any) error {
+	return &httpError{code: http.StatusNotFound, err: fmt.Errorf(format, args...)}
+}
@@ -0,0 +1,171 @@
+package sheetstest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trichner/toolbox/pkg/sheets"
+)
+
+func newTestService(t *testing.T) sheets.SheetsService {
+	backend, err := sheets.NewLocalSheetService(t.TempDir())
+	require.NoError(t, err)
+
+	srv := NewServer(backend)
+	t.Cleanup(srv.Close)
+
+	svc, err := srv.SheetsService(context.Background())
+	require.NoError(t, err)
+	return svc
+}
+
+func TestServer_RoundTrip(t *testing.T) {
+	svc := newTestService(t)
+
+	ss, err := svc.CreateSpreadSheet("test")
+	require.NoError(t, err)
+
+	sheet, err := ss.FirstSheet()
+	require.NoError(t, err)
+
+	require.NoError(t, sheet.UpdateValues([][]string{{"a", "b"}, {"1", "2"}}))
+	require.NoError(t, sheet.AppendValues([][]string{{"3", "4"}}))
+
+	values, err := sheet.Values()
+	require.NoError(t, err)
+	assert.Equal(t, [][]any{{"a", "b"}, {"1", "2"}, {"3", "4"}}, values)
+
+	info, err := sheet.Get()
+	require.NoError(t, err)
+	assert.Equal(t, "Sheet1", info.Title)
+	assert.Equal(t, int64(3), info.RowCount)
+}
+
+func TestServer_CreateSheet(t *testing.T) {
+	svc := newTestService(t)
+
+	ss, err := svc.CreateSpreadSheet("test")
+	require.NoError(t, err)
+
+	created, err := ss.CreateSheet(&sheets.CreateSheetOptions{Title: "other"})
+	require.NoError(t, err)
+
+	_, err = created.Values()
+	assert.ErrorIs(t, err, sheets.ErrEmptySheet)
+
+	require.NoError(t, created.AppendValues([][]string{{"x"}}))
+
+	byTitle, err := ss.SheetByTitle("other")
+	require.NoError(t, err)
+	values, err := byTitle.Values()
+	require.NoError(t, err)
+	assert.Equal(t, [][]any{{"x"}}, values)
+
+	info, err := ss.Get()
+	require.NoError(t, err)
+	assert.Len(t, info.Sheets, 2)
+}
+
+func TestServer_NotFound(t *testing.T) {
+	svc := newTestService(t)
+
+	_, err := svc.GetSpreadSheet("missing")
+	assert.Error(t, err)
+}
+
+func TestServer_CachesMetadata(t *testing.T) {
+	backend, err := sheets.NewLocalSheetService(t.TempDir())
+	require.NoError(t, err)
+
+	srv := NewServer(backend)
+	defer srv.Close()
+
+	svc, err := srv.SheetsService(context.Background())
+	require.NoError(t, err)
+
+	created, err := svc.CreateSpreadSheet("test")
+	require.NoError(t, err)
+	info, err := created.Get()
+	require.NoError(t, err)
+
+	ss, err := svc.GetSpreadSheet(info.Id)
+	require.NoError(t, err)
+
+	sheet, err := ss.SheetById(info.Sheets[0].Id)
+	require.NoError(t, err)
+	_, err = ss.SheetByTitle("Sheet1")
+	require.NoError(t, err)
+	_, err = sheet.Get()
+	require.NoError(t, err)
+
+	require.NoError(t, sheet.UpdateValues([][]string{{"a", "b", "c"}, {"1", "2"}}))
+	require.NoError(t, sheet.AppendValues([][]string{{"3"}}))
+	_, err = ss.Get()
+	require.NoError(t, err)
+	_, err = sheet.Get()
+	require.NoError(t, err)
+
+	values, err := sheet.Values()
+	require.NoError(t, err)
+	assert.Equal(t, [][]any{{"a", "b", "c"}, {"1", "2"}, {"3"}}, values)
+
+	path := "/v4/spreadsheets/" + info.Id
+	assert.Equal(t, []string{
+		"POST /v4/spreadsheets",
+		"GET " + path,
+		// growing and writing values happens in one batch
+		"POST " + path + ":batchUpdate",
+		"POST " + path + ":batchUpdate",
+		"POST " + path + "/values:batchGetByDataFilter",
+	}, srv.Requests())
+}
+
+func TestServer_ManageSheets(t *testing.T) {
+	svc := newTestService(t)
+
+	ss, err := svc.CreateSpreadSheet("test")
+	require.NoError(t, err)
+
+	first, err := ss.FirstSheet()
+	require.NoError(t, err)
+	require.NoError(t, first.UpdateValues([][]string{{"a", "b"}}))
+
+	copied, err := first.Duplicate("copy")
+	require.NoError(t, err)
+	values, err := copied.Values()
+	require.NoError(t, err)
+	assert.Equal(t, [][]any{{"a", "b"}}, values)
+
+	require.NoError(t, copied.Rename("renamed"))
+	require.NoError(t, first.Clear())
+	_, err = first.Values()
+	assert.ErrorIs(t, err, sheets.ErrEmptySheet)
+
+	require.NoError(t, first.Delete())
+
+	info, err := ss.Get()
+	require.NoError(t, err)
+	assert.Equal(t, "test", info.Title)
+	require.Len(t, info.Sheets, 1)
+	assert.Equal(t, "renamed", info.Sheets[0].Title)
+
+	other, err := svc.CreateSpreadSheet("other")
+	require.NoError(t, err)
+	otherInfo, err := other.Get()
+	require.NoError(t, err)
+
+	copiedInfo, err := copied.CopyTo(otherInfo.Id)
+	require.NoError(t, err)
+	assert.Equal(t, "Copy of renamed", copiedInfo.Title)
+
+	// other caches its metadata, re-open it to see the copy
+	other, err = svc.GetSpreadSheet(otherInfo.Id)
+	require.NoError(t, err)
+	otherInfo, err = other.Get()
+	require.NoError(t, err)
+	assert.Len(t, otherInfo.Sheets, 2)
+
+	require.NoError(t, ss.Share(&sheets.ShareOptions{Anyone: true, Role: sheets.ShareRoleReader}))
+}
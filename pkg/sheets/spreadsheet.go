@@ -4,15 +4,38 @@ import (
 	"errors"
 	"fmt"
 
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
 	googlesheets "google.golang.org/api/sheets/v4"
 )
 
-var ErrNotFound = errors.New("not found")
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrEmptySheet = errors.New("empty spreadsheet, no values found")
+)
 
 type CreateSheetOptions struct {
 	Title string
 }
 
+type ShareRole string
+
+const (
+	ShareRoleReader    ShareRole = "reader"
+	ShareRoleCommenter ShareRole = "commenter"
+	ShareRoleWriter    ShareRole = "writer"
+)
+
+type ShareOptions struct {
+	// Email of the user or group to share with, ignored if Anyone is set
+	Email string
+	// Anyone shares with anyone having the link
+	Anyone bool
+	Role   ShareRole
+	// Notify sends a notification email to the user or group
+	Notify bool
+}
+
 type SpreadsheetOps interface {
 	CreateSheet(opts *CreateSheetOptions) (SheetOps, error)
 	FirstSheet() (SheetOps, error)
@@ -20,10 +43,18 @@ type SpreadsheetOps interface {
 	SheetById(id int64) (SheetOps, error)
 	SheetByTitle(name string) (SheetOps, error)
 	Get() (*SpreadSheet, error)
+	Share(opts *ShareOptions) error
 }
 
+// spreadsheetFields is the field mask to fetch only the metadata of a spreadsheet and its sheets but no cell data
+const spreadsheetFields = "spreadsheetId,properties.title,sheets.properties"
+
 type spreadsheetOps struct {
-	service     *googlesheets.Service
+	service *googlesheets.Service
+	drive   *drive.Service
+	id      string
+
+	// spreadsheet caches the metadata of the spreadsheet, nil if it needs to be refetched
 	spreadsheet *googlesheets.Spreadsheet
 }
 
@@ -36,11 +67,9 @@ func (s *spreadsheetOps) CreateSheet(opts *CreateSheetOptions) (SheetOps, error)
 		},
 	}
 
-	breq := &googlesheets.BatchUpdateSpreadsheetRequest{Requests: []*googlesheets.Request{{AddSheet: req}}}
-
-	res, err := s.service.Spreadsheets.BatchUpdate(s.spreadsheet.SpreadsheetId, breq).Do()
+	res, err := s.batchUpdate([]*googlesheets.Request{{AddSheet: req}})
 	if err != nil {
-		return nil, fmt.Errorf("unable to add sheet %q to %q: %w", s.spreadsheet.SpreadsheetId, opts.Title, err)
+		return nil, fmt.Errorf("unable to add sheet %q to %q: %w", opts.Title, s.id, err)
 	}
 
 	props := res.Replies[0].AddSheet.Properties
@@ -71,12 +100,38 @@ func (s *spreadsheetOps) SheetByTitle(title string) (SheetOps, error) {
 }
 
 func (s *spreadsheetOps) Get() (*SpreadSheet, error) {
-	err := s.refresh()
+	sheets, err := s.getSheets()
 	if err != nil {
 		return nil, err
 	}
 
-	return &SpreadSheet{Id: s.spreadsheet.SpreadsheetId, Sheets: mapSheets(s.spreadsheet.Sheets)}, nil
+	var title string
+	if s.spreadsheet.Properties != nil {
+		title = s.spreadsheet.Properties.Title
+	}
+
+	return &SpreadSheet{Id: s.id, Title: title, Sheets: mapSheets(sheets)}, nil
+}
+
+func (s *spreadsheetOps) Share(opts *ShareOptions) error {
+	perm := &drive.Permission{
+		Type:         "user",
+		Role:         string(opts.Role),
+		EmailAddress: opts.Email,
+	}
+	if opts.Anyone {
+		perm.Type = "anyone"
+		perm.EmailAddress = ""
+	}
+
+	_, err := s.drive.Permissions.Create(s.id, perm).
+		SendNotificationEmail(opts.Notify && !opts.Anyone).
+		SupportsAllDrives(true).
+		Do()
+	if err != nil {
+		return fmt.Errorf("unable to share spreadsheet %q: %w", s.id, err)
+	}
+	return nil
 }
 
 func (s *spreadsheetOps) filteredSheets(predicate func(p *googlesheets.SheetProperties) bool) (*googlesheets.SheetProperties, error) {
@@ -95,21 +150,27 @@ func (s *spreadsheetOps) filteredSheets(predicate func(p *googlesheets.SheetProp
 }
 
 func (s *spreadsheetOps) spreadsheetId() string {
-	return s.spreadsheet.SpreadsheetId
+	return s.id
 }
 
 func (s *spreadsheetOps) getSheets() ([]*googlesheets.Sheet, error) {
-	err := s.refresh()
-	if err != nil {
-		return nil, err
+	if s.spreadsheet == nil {
+		if err := s.refresh(); err != nil {
+			return nil, err
+		}
 	}
 	return s.spreadsheet.Sheets, nil
 }
 
+// invalidate drops the cached metadata, it is refetched on next use
+func (s *spreadsheetOps) invalidate() {
+	s.spreadsheet = nil
+}
+
 func (s *spreadsheetOps) refresh() error {
-	res, err := s.service.Spreadsheets.Get(s.spreadsheet.SpreadsheetId).Do()
+	res, err := s.service.Spreadsheets.Get(s.id).Fields(spreadsheetFields).Do()
 	if err != nil {
-		return fmt.Errorf("cannot create spreadsheet: %w", err)
+		return fmt.Errorf("cannot fetch spreadsheet %q: %w", s.id, err)
 	}
 
 	s.spreadsheet = res
@@ -117,6 +178,30 @@ func (s *spreadsheetOps) refresh() error {
 	return nil
 }
 
+// batchUpdate applies all requests in a single call and updates the cached metadata from the response
+func (s *spreadsheetOps) batchUpdate(requests []*googlesheets.Request) (*googlesheets.BatchUpdateSpreadsheetResponse, error) {
+	req := &googlesheets.BatchUpdateSpreadsheetRequest{
+		Requests:                     requests,
+		IncludeSpreadsheetInResponse: true,
+		ResponseIncludeGridData:      false,
+	}
+
+	// updates may fail half-way, in any case the cached metadata cannot be trusted anymore
+	s.invalidate()
+
+	res, err := s.service.Spreadsheets.BatchUpdate(s.id, req).
+		Fields(googleapi.Field("replies,updatedSpreadsheet(" + spreadsheetFields + ")")).
+		Do()
+	if err != nil {
+		return nil, err
+	}
+
+	if res.UpdatedSpreadsheet != nil && res.UpdatedSpreadsheet.SpreadsheetId != "" {
+		s.spreadsheet = res.UpdatedSpreadsheet
+	}
+	return res, nil
+}
+
 func (s *spreadsheetOps) toSheetOpsWithErr(sheet *googlesheets.SheetProperties, err error) (*sheetOps, error) {
 	return s.toSheetOps(sheet), err
 }
@@ -144,9 +229,14 @@ func toSheet(sheet *googlesheets.SheetProperties) *Sheet {
 		return nil
 	}
 
-	return &Sheet{
+	s := &Sheet{
 		Id:    sheet.SheetId,
 		Title: sheet.Title,
 		Index: sheet.Index,
 	}
+	if sheet.GridProperties != nil {
+		s.RowCount = sheet.GridProperties.RowCount
+		s.ColumnCount = sheet.GridProperties.ColumnCount
+	}
+	return s
 }
@@ -0,0 +1,60 @@
+package sqlconn
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// TLSOptions secure the connection to the database server beyond what the URI can express, e.g. with a
+// custom certificate authority
+type TLSOptions struct {
+	// CAFile is a PEM file of the certificate authorities to trust instead of those of the system
+	CAFile string
+
+	// CertFile and KeyFile are the PEM files of a client certificate
+	CertFile string
+	KeyFile  string
+
+	// ServerName verifies the certificate of the server, it defaults to the host of the URI
+	ServerName string
+
+	// SkipVerify accepts any certificate of the server
+	SkipVerify bool
+}
+
+// NewTLSConfig loads the files of the options, it returns nil if no option is set
+func NewTLSConfig(o TLSOptions) (*tls.Config, error) {
+	if o == (TLSOptions{}) {
+		return nil, nil
+	}
+
+	cfg := &tls.Config{
+		ServerName:         o.ServerName,
+		InsecureSkipVerify: o.SkipVerify,
+	}
+
+	if o.CAFile != "" {
+		pem, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read CA file: %w", err)
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in CA file %s", o.CAFile)
+		}
+	}
+
+	if o.CertFile != "" || o.KeyFile != "" {
+		if o.CertFile == "" || o.KeyFile == "" {
+			return nil, fmt.Errorf("a client certificate needs both a certificate and a key file")
+		}
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
@@ -0,0 +1,142 @@
+package sqlconn
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCertificate writes a self-signed certificate for the name and its key as PEM files
+func writeCertificate(t *testing.T, name string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		DNSNames:              []string{name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := writeFile(t, name+".crt", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	keyFile := writeFile(t, name+".key", pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+	return certFile, keyFile
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	cfg, err := NewTLSConfig(TLSOptions{})
+	require.NoError(t, err)
+	assert.Nil(t, cfg)
+
+	caFile, _ := writeCertificate(t, "ca")
+	certFile, keyFile := writeCertificate(t, "client")
+	cfg, err = NewTLSConfig(TLSOptions{CAFile: caFile, CertFile: certFile, KeyFile: keyFile, ServerName: "db.internal"})
+	require.NoError(t, err)
+	assert.NotNil(t, cfg.RootCAs)
+	assert.Len(t, cfg.Certificates, 1)
+	assert.Equal(t, "db.internal", cfg.ServerName)
+	assert.False(t, cfg.InsecureSkipVerify)
+
+	cfg, err = NewTLSConfig(TLSOptions{SkipVerify: true})
+	require.NoError(t, err)
+	assert.True(t, cfg.InsecureSkipVerify)
+	assert.Nil(t, cfg.RootCAs)
+}
+
+func TestNewTLSConfig_Invalid(t *testing.T) {
+	certFile, keyFile := writeCertificate(t, "client")
+
+	_, err := NewTLSConfig(TLSOptions{CertFile: certFile})
+	assert.ErrorContains(t, err, "both a certificate and a key")
+
+	_, err = NewTLSConfig(TLSOptions{CAFile: keyFile})
+	assert.ErrorContains(t, err, "no certificates in CA file")
+
+	_, err = NewTLSConfig(TLSOptions{CertFile: keyFile, KeyFile: certFile})
+	assert.ErrorContains(t, err, "cannot load client certificate")
+}
+
+// startPostgresTLSServer accepts the TLS handshake of Postgres clients and reports its outcome
+func startPostgresTLSServer(t *testing.T, certFile, keyFile string) (string, <-chan error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	require.NoError(t, err)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	handshakes := make(chan error, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		// the SSLRequest message is answered with 'S' before the handshake
+		request := make([]byte, 8)
+		if _, err := io.ReadFull(conn, request); err != nil {
+			handshakes <- err
+			return
+		}
+		if _, err := conn.Write([]byte("S")); err != nil {
+			handshakes <- err
+			return
+		}
+		handshakes <- tls.Server(conn, &tls.Config{Certificates: []tls.Certificate{cert}}).Handshake()
+	}()
+	return l.Addr().String(), handshakes
+}
+
+func TestConfig_Open_Postgres_TLS(t *testing.T) {
+	certFile, keyFile := writeCertificate(t, "db.internal")
+	otherCA, _ := writeCertificate(t, "db.internal")
+
+	for _, tt := range []struct {
+		caFile string
+		valid  bool
+	}{
+		{certFile, true},
+		{otherCA, false},
+	} {
+		addr, handshakes := startPostgresTLSServer(t, certFile, keyFile)
+
+		cfg, err := Parse("postgres://reporting@db.internal:5432/shop?sslmode=disable", Overrides{})
+		require.NoError(t, err)
+		cfg.TLS, err = NewTLSConfig(TLSOptions{CAFile: tt.caFile})
+		require.NoError(t, err)
+		cfg.Dial = func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		}
+
+		// the server hangs up after the handshake
+		_, err = cfg.Open(context.Background())
+		assert.Error(t, err)
+		if tt.valid {
+			assert.NoError(t, <-handshakes)
+		} else {
+			assert.Error(t, <-handshakes)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package sqlconn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// SSHOptions configure a tunnel through an SSH server, e.g. a bastion in front of the database
+type SSHOptions struct {
+	// Host is the SSH server as '[user@]host[:port]', the user defaults to the current one and the port to 22
+	Host string
+
+	// KeyFile is the private key to authenticate with, the keys of the SSH agent are used if it is empty
+	KeyFile string
+
+	// KnownHostsFile verifies the key of the server, it defaults to '~/.ssh/known_hosts'
+	KnownHostsFile string
+
+	// InsecureIgnoreHostKey accepts any key of the server
+	InsecureIgnoreHostKey bool
+}
+
+// Tunnel is a connection to an SSH server which dials the database on behalf of the client
+type Tunnel struct {
+	client *ssh.Client
+}
+
+// DialSSH connects and authenticates to the SSH server of the options
+func DialSSH(ctx context.Context, o SSHOptions) (*Tunnel, error) {
+	username, addr, err := splitSSHHost(o.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	auth, err := sshAuth(o.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	hostKeyCallback, err := sshHostKeyCallback(o)
+	if err != nil {
+		return nil, err
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to SSH server %s: %w", addr, err)
+	}
+
+	// the handshake does not take a context, hence cancelling it closes the connection instead
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, &ssh.ClientConfig{
+		User:              username,
+		Auth:              []ssh.AuthMethod{auth},
+		HostKeyCallback:   hostKeyCallback,
+		HostKeyAlgorithms: knownHostKeyAlgorithms(hostKeyCallback, addr, conn.RemoteAddr()),
+	})
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("cannot connect to SSH server %s: %w", addr, err)
+	}
+	return &Tunnel{client: ssh.NewClient(c, chans, reqs)}, nil
+}
+
+// DialContext connects to an address as seen from the SSH server, it is a Config.Dial
+func (t *Tunnel) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return t.client.DialContext(ctx, network, addr)
+}
+
+// Close closes the tunnel and all connections through it
+func (t *Tunnel) Close() error {
+	return t.client.Close()
+}
+
+func splitSSHHost(host string) (string, string, error) {
+	username, addr, ok := strings.Cut(host, "@")
+	if !ok {
+		addr = host
+		u, err := user.Current()
+		if err != nil {
+			return "", "", fmt.Errorf("no SSH user given and cannot get the current one: %w", err)
+		}
+		username = u.Username
+	}
+	if addr == "" {
+		return "", "", fmt.Errorf("invalid SSH host %q, expected '[user@]host[:port]'", host)
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "22")
+	}
+	return username, addr, nil
+}
+
+func sshAuth(keyFile string) (ssh.AuthMethod, error) {
+	if keyFile == "" {
+		socket := os.Getenv("SSH_AUTH_SOCK")
+		if socket == "" {
+			return nil, fmt.Errorf("no SSH key given and no SSH agent running")
+		}
+		conn, err := net.Dial("unix", socket)
+		if err != nil {
+			return nil, fmt.Errorf("cannot connect to SSH agent: %w", err)
+		}
+		return ssh.PublicKeysCallback(agent.NewClient(conn).Signers), nil
+	}
+
+	pem, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read SSH key: %w", err)
+	}
+	signer, err := ssh.ParsePrivateKey(pem)
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		return nil, fmt.Errorf("SSH key %s is protected by a passphrase, add it to the SSH agent instead", keyFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse SSH key %s: %w", keyFile, err)
+	}
+	return ssh.PublicKeys(signer), nil
+}
+
+func sshHostKeyCallback(o SSHOptions) (ssh.HostKeyCallback, error) {
+	if o.InsecureIgnoreHostKey {
+		return ssh.InsecureIgnoreHostKey(), nil
+	}
+
+	file := o.KnownHostsFile
+	if file == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("cannot find known hosts file: %w", err)
+		}
+		file = filepath.Join(home, ".ssh", "known_hosts")
+	}
+	callback, err := knownhosts.New(file)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read known hosts: %w", err)
+	}
+	return callback, nil
+}
+
+// knownHostKeyAlgorithms returns the algorithms of the known keys of the host, otherwise the server may present
+// a key of another algorithm than the known ones and fail the verification. It returns nil for unknown hosts,
+// i.e. the default algorithms.
+func knownHostKeyAlgorithms(callback ssh.HostKeyCallback, addr string, remote net.Addr) []string {
+	// a key no host has lists the known keys of the host in the error
+	var keyErr *knownhosts.KeyError
+	if !errors.As(callback(addr, remote, probeKey{}), &keyErr) {
+		return nil
+	}
+
+	var algorithms []string
+	for _, k := range keyErr.Want {
+		if k.Key.Type() == ssh.KeyAlgoRSA {
+			// the same RSA key signs with any of its algorithms
+			algorithms = append(algorithms, ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256)
+		}
+		algorithms = append(algorithms, k.Key.Type())
+	}
+	return algorithms
+}
+
+// probeKey is a host key which is never known
+type probeKey struct{}
+
+func (probeKey) Type() string {
+	return "probe"
+}
+
+func (probeKey) Marshal() []byte {
+	return []byte("probe")
+}
+
+func (probeKey) Verify([]byte, *ssh.Signature) error {
+	return errors.New("probe key cannot verify signatures")
+}
@@ -0,0 +1,264 @@
+package sqlconn
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// sshServer forwards 'direct-tcpip' channels like 'ssh -L' to clients authenticated with its client key
+type sshServer struct {
+	addr      string
+	hostKey   ssh.Signer
+	clientKey ssh.Signer
+}
+
+func newSigner(t *testing.T) (ssh.Signer, ed25519.PrivateKey) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer, err := ssh.NewSignerFromKey(key)
+	require.NoError(t, err)
+	return signer, key
+}
+
+func startSSHServer(t *testing.T) (*sshServer, ed25519.PrivateKey) {
+	hostKey, _ := newSigner(t)
+	clientKey, clientPrivateKey := newSigner(t)
+
+	cfg := &ssh.ServerConfig{
+		PublicKeyCallback: func(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if string(key.Marshal()) != string(clientKey.PublicKey().Marshal()) {
+				return nil, errors.New("unknown key")
+			}
+			return nil, nil
+		},
+	}
+	cfg.AddHostKey(hostKey)
+
+	// the client prefers ECDSA to Ed25519, it has to pick the algorithm of the known host key
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ecdsaHostKey, err := ssh.NewSignerFromKey(ecdsaKey)
+	require.NoError(t, err)
+	cfg.AddHostKey(ecdsaHostKey)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serveSSH(conn, cfg)
+		}
+	}()
+	return &sshServer{addr: l.Addr().String(), hostKey: hostKey, clientKey: clientKey}, clientPrivateKey
+}
+
+func serveSSH(conn net.Conn, cfg *ssh.ServerConfig) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, cfg)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+
+	for ch := range chans {
+		if ch.ChannelType() != "direct-tcpip" {
+			ch.Reject(ssh.UnknownChannelType, "only port forwarding")
+			continue
+		}
+		var target struct {
+			Host       string
+			Port       uint32
+			OriginHost string
+			OriginPort uint32
+		}
+		if err := ssh.Unmarshal(ch.ExtraData(), &target); err != nil {
+			ch.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+		upstream, err := net.Dial("tcp", net.JoinHostPort(target.Host, strconv.Itoa(int(target.Port))))
+		if err != nil {
+			ch.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+		channel, requests, err := ch.Accept()
+		if err != nil {
+			upstream.Close()
+			continue
+		}
+		go ssh.DiscardRequests(requests)
+		go func() {
+			io.Copy(channel, upstream)
+			channel.Close()
+		}()
+		go func() {
+			io.Copy(upstream, channel)
+			upstream.Close()
+		}()
+	}
+}
+
+// startEchoServer returns the address of a server writing back whatever it reads
+func startEchoServer(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				io.Copy(conn, conn)
+				conn.Close()
+			}()
+		}
+	}()
+	return l.Addr().String()
+}
+
+func writeFile(t *testing.T, name string, content []byte) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, content, 0o600))
+	return path
+}
+
+func writeSSHKey(t *testing.T, key ed25519.PrivateKey) string {
+	block, err := ssh.MarshalPrivateKey(key, "")
+	require.NoError(t, err)
+	return writeFile(t, "id_ed25519", pem.EncodeToMemory(block))
+}
+
+func writeKnownHosts(t *testing.T, addr string, key ssh.PublicKey) string {
+	return writeFile(t, "known_hosts", []byte(knownhosts.Line([]string{knownhosts.Normalize(addr)}, key)+"\n"))
+}
+
+func TestDialSSH(t *testing.T) {
+	server, clientKey := startSSHServer(t)
+	echo := startEchoServer(t)
+
+	tunnel, err := DialSSH(context.Background(), SSHOptions{
+		Host:           "tester@" + server.addr,
+		KeyFile:        writeSSHKey(t, clientKey),
+		KnownHostsFile: writeKnownHosts(t, server.addr, server.hostKey.PublicKey()),
+	})
+	require.NoError(t, err)
+	defer tunnel.Close()
+
+	conn, err := tunnel.DialContext(context.Background(), "tcp", echo)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("ping"))
+	require.NoError(t, err)
+	reply := make([]byte, 4)
+	_, err = io.ReadFull(conn, reply)
+	require.NoError(t, err)
+	assert.Equal(t, "ping", string(reply))
+}
+
+func TestDialSSH_Invalid(t *testing.T) {
+	server, clientKey := startSSHServer(t)
+	keyFile := writeSSHKey(t, clientKey)
+	otherHostKey, otherKey := newSigner(t)
+
+	_, err := DialSSH(context.Background(), SSHOptions{
+		Host:           "tester@" + server.addr,
+		KeyFile:        keyFile,
+		KnownHostsFile: writeKnownHosts(t, server.addr, otherHostKey.PublicKey()),
+	})
+	assert.ErrorContains(t, err, "key mismatch")
+
+	_, err = DialSSH(context.Background(), SSHOptions{
+		Host:           "tester@" + server.addr,
+		KeyFile:        keyFile,
+		KnownHostsFile: writeFile(t, "known_hosts", nil),
+	})
+	assert.ErrorContains(t, err, "key is unknown")
+
+	_, err = DialSSH(context.Background(), SSHOptions{
+		Host:                  "tester@" + server.addr,
+		KeyFile:               writeSSHKey(t, otherKey),
+		InsecureIgnoreHostKey: true,
+	})
+	assert.ErrorContains(t, err, "unable to authenticate")
+
+	_, err = DialSSH(context.Background(), SSHOptions{Host: "tester@", KeyFile: keyFile})
+	assert.ErrorContains(t, err, "invalid SSH host")
+}
+
+func TestConfig_Open_Tunnel(t *testing.T) {
+	server, clientKey := startSSHServer(t)
+	tunnel, err := DialSSH(context.Background(), SSHOptions{
+		Host:                  "tester@" + server.addr,
+		KeyFile:               writeSSHKey(t, clientKey),
+		InsecureIgnoreHostKey: true,
+	})
+	require.NoError(t, err)
+	defer tunnel.Close()
+
+	// a database server which hangs up right away, it is reached through the tunnel only
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	_, port, err := net.SplitHostPort(l.Addr().String())
+	require.NoError(t, err)
+
+	for _, uri := range []string{
+		"postgres://reporting@db.internal:5432/shop?sslmode=disable",
+		"mysql://reporting@db.internal:3306/shop",
+	} {
+		var dialed []string
+		cfg, err := Parse(uri, Overrides{})
+		require.NoError(t, err)
+		cfg.Dial = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			dialed = append(dialed, network+" "+addr)
+			return tunnel.DialContext(ctx, "tcp", net.JoinHostPort("127.0.0.1", port))
+		}
+
+		_, err = cfg.Open(context.Background())
+		assert.Error(t, err, uri)
+		require.NotEmpty(t, dialed, uri)
+		assert.Regexp(t, `^tcp db\.internal:(5432|3306)$`, dialed[0], uri)
+	}
+}
+
+func TestConfig_Open_SQLite_Unsupported(t *testing.T) {
+	cfg, err := Parse("sqlite://"+filepath.Join(t.TempDir(), "test.db"), Overrides{})
+	require.NoError(t, err)
+	cfg.Dial = (&net.Dialer{}).DialContext
+
+	_, err = cfg.Open(context.Background())
+	assert.ErrorContains(t, err, "not supported by sqlite databases")
+}
@@ -0,0 +1,37 @@
+// Package tbconfig locates the configuration files of the tools, e.g. saved connections
+package tbconfig
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// DirEnv overrides the configuration directory
+const DirEnv = "TB_CONFIG_DIR"
+
+// Dir is the configuration directory, i.e. $TB_CONFIG_DIR or 'tb' in $XDG_CONFIG, which defaults to
+// '~/.config'
+func Dir() (string, error) {
+	if dir := os.Getenv(DirEnv); dir != "" {
+		return dir, nil
+	}
+	if dir := os.Getenv("XDG_CONFIG"); dir != "" {
+		return filepath.Join(dir, "tb"), nil
+	}
+
+	home := os.Getenv("HOME")
+	if home == "" {
+		return "", fmt.Errorf("cannot find configuration directory, neither $HOME nor $%s is set", DirEnv)
+	}
+	return filepath.Join(home, ".config", "tb"), nil
+}
+
+// Path is the path of a file in the configuration directory
+func Path(name string) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name), nil
+}
@@ -0,0 +1,35 @@
+package tbconfig
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPath(t *testing.T) {
+	t.Setenv(DirEnv, "/etc/tb")
+	path, err := Path("sql2json.json")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("/etc/tb", "sql2json.json"), path)
+}
+
+func TestDir(t *testing.T) {
+	t.Setenv(DirEnv, "")
+	t.Setenv("XDG_CONFIG", "")
+	t.Setenv("HOME", "/home/me")
+	dir, err := Dir()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("/home/me", ".config", "tb"), dir)
+
+	t.Setenv("XDG_CONFIG", "/home/me/conf")
+	dir, err = Dir()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("/home/me/conf", "tb"), dir)
+
+	t.Setenv("XDG_CONFIG", "")
+	t.Setenv("HOME", "")
+	_, err = Dir()
+	assert.ErrorContains(t, err, "cannot find configuration directory")
+}
@@ -0,0 +1,388 @@
+package workbook
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	odsMimeType = "application/vnd.oasis.opendocument.spreadsheet"
+
+	nsOffice = "urn:oasis:names:tc:opendocument:xmlns:office:1.0"
+	nsTable  = "urn:oasis:names:tc:opendocument:xmlns:table:1.0"
+	nsText   = "urn:oasis:names:tc:opendocument:xmlns:text:1.0"
+
+	defaultOdsSheetName = "Sheet1"
+)
+
+const odsManifest = `<?xml version="1.0" encoding="UTF-8"?>
+<manifest:manifest xmlns:manifest="urn:oasis:names:tc:opendocument:xmlns:manifest:1.0" manifest:version="1.2">
+ <manifest:file-entry manifest:full-path="/" manifest:version="1.2" manifest:media-type="application/vnd.oasis.opendocument.spreadsheet"/>
+ <manifest:file-entry manifest:full-path="content.xml" manifest:media-type="text/xml"/>
+</manifest:manifest>
+`
+
+// odsWorkbook keeps an OpenDocument spreadsheet in memory, only the cell values are retained. Saving it
+// rewrites the whole document, formatting, formulas and other content of an existing file are lost.
+type odsWorkbook struct {
+	path   string
+	sheets []*odsSheet
+
+	// pristine is true for a new workbook whose default sheet was not touched yet
+	pristine bool
+}
+
+type odsSheet struct {
+	name string
+	rows [][]string
+}
+
+func newOds(path string) *odsWorkbook {
+	return &odsWorkbook{
+		path:     path,
+		sheets:   []*odsSheet{{name: defaultOdsSheetName}},
+		pristine: true,
+	}
+}
+
+func openOds(path string) (*odsWorkbook, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open workbook %q: %w", path, err)
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		if f.Name != "content.xml" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("cannot open workbook %q: %w", path, err)
+		}
+		defer rc.Close()
+
+		sheets, err := parseOdsContent(rc)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse workbook %q: %w", path, err)
+		}
+		return &odsWorkbook{path: path, sheets: sheets}, nil
+	}
+	return nil, fmt.Errorf("cannot open workbook %q: no content.xml found", path)
+}
+
+func (w *odsWorkbook) SheetNames() []string {
+	names := make([]string, len(w.sheets))
+	for i, s := range w.sheets {
+		names[i] = s.name
+	}
+	return names
+}
+
+func (w *odsWorkbook) FirstSheet() (Sheet, error) {
+	if len(w.sheets) == 0 {
+		return nil, fmt.Errorf("workbook has no sheets: %w", ErrNotFound)
+	}
+	w.pristine = false
+	return w.sheets[0], nil
+}
+
+func (w *odsWorkbook) SheetByName(name string) (Sheet, error) {
+	for _, s := range w.sheets {
+		if s.name == name {
+			w.pristine = false
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("sheet %q: %w", name, ErrNotFound)
+}
+
+func (w *odsWorkbook) AddSheet(name string) (Sheet, error) {
+	if w.pristine {
+		// re-use the default sheet of a new workbook instead of leaving it around empty
+		w.pristine = false
+		w.sheets[0].name = name
+		return w.sheets[0], nil
+	}
+
+	for _, s := range w.sheets {
+		if s.name == name {
+			return nil, fmt.Errorf("cannot add sheet %q: a sheet with that name already exists", name)
+		}
+	}
+
+	s := &odsSheet{name: name}
+	w.sheets = append(w.sheets, s)
+	return s, nil
+}
+
+func (w *odsWorkbook) Save() error {
+	var buf bytes.Buffer
+	if err := w.write(&buf); err != nil {
+		return fmt.Errorf("cannot save workbook %q: %w", w.path, err)
+	}
+	if err := os.WriteFile(w.path, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("cannot save workbook %q: %w", w.path, err)
+	}
+	return nil
+}
+
+func (w *odsWorkbook) Close() error {
+	return nil
+}
+
+func (w *odsWorkbook) write(out io.Writer) error {
+	zw := zip.NewWriter(out)
+
+	// the mimetype must come first and must not be compressed
+	mw, err := zw.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(mw, odsMimeType); err != nil {
+		return err
+	}
+
+	fw, err := zw.Create("META-INF/manifest.xml")
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(fw, odsManifest); err != nil {
+		return err
+	}
+
+	cw, err := zw.Create("content.xml")
+	if err != nil {
+		return err
+	}
+	if err := writeOdsContent(cw, w.sheets); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+func (s *odsSheet) Name() string {
+	return s.name
+}
+
+func (s *odsSheet) UpdateValues(data [][]string) error {
+	for i, row := range data {
+		for i >= len(s.rows) {
+			s.rows = append(s.rows, nil)
+		}
+		for j, cell := range row {
+			for j >= len(s.rows[i]) {
+				s.rows[i] = append(s.rows[i], "")
+			}
+			s.rows[i][j] = cell
+		}
+	}
+	return nil
+}
+
+func (s *odsSheet) AppendValues(data [][]string) error {
+	s.rows = s.rows[:rowCount(s.rows)]
+	for _, row := range data {
+		s.rows = append(s.rows, append([]string(nil), row...))
+	}
+	return nil
+}
+
+func (s *odsSheet) Values() ([][]any, error) {
+	return toValues(trimRows(s.rows)), nil
+}
+
+func (s *odsSheet) HeaderValues() ([]any, error) {
+	if len(s.rows) == 0 {
+		return nil, nil
+	}
+	return headerValues(s.rows[0]), nil
+}
+
+func (s *odsSheet) Clear() error {
+	s.rows = nil
+	return nil
+}
+
+func writeOdsContent(w io.Writer, sheets []*odsSheet) error {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.WriteString(`<office:document-content xmlns:office="` + nsOffice + `" xmlns:table="` + nsTable + `" xmlns:text="` + nsText + `" office:version="1.2">`)
+	buf.WriteString(`<office:body><office:spreadsheet>`)
+	for _, s := range sheets {
+		buf.WriteString(`<table:table table:name="`)
+		xml.EscapeText(&buf, []byte(s.name))
+		buf.WriteString(`">`)
+
+		rows := trimRows(s.rows)
+		columns := 0
+		for _, row := range rows {
+			columns = max(columns, len(row))
+		}
+		buf.WriteString(`<table:table-column table:number-columns-repeated="` + strconv.Itoa(max(columns, 1)) + `"/>`)
+
+		for _, row := range rows {
+			buf.WriteString(`<table:table-row>`)
+			for _, cell := range row {
+				if cell == "" {
+					buf.WriteString(`<table:table-cell/>`)
+					continue
+				}
+				if _, ok := numericValue(cell); ok {
+					buf.WriteString(`<table:table-cell office:value-type="float" office:value="` + cell + `">`)
+				} else {
+					buf.WriteString(`<table:table-cell office:value-type="string">`)
+				}
+				for _, line := range strings.Split(cell, "\n") {
+					buf.WriteString(`<text:p>`)
+					xml.EscapeText(&buf, []byte(line))
+					buf.WriteString(`</text:p>`)
+				}
+				buf.WriteString(`</table:table-cell>`)
+			}
+			buf.WriteString(`</table:table-row>`)
+		}
+		buf.WriteString(`</table:table>`)
+	}
+	buf.WriteString(`</office:spreadsheet></office:body></office:document-content>`)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// odsContentParser reads the cell values of all tables in a content.xml. Office suites pad tables with huge
+// runs of repeated empty rows and cells, these are only materialized if they are followed by actual values.
+type odsContentParser struct {
+	sheets []*odsSheet
+
+	sheet           *odsSheet
+	row             []string
+	rowRepeat       int
+	pendingRows     int
+	pendingCells    int
+	cell            *strings.Builder
+	cellRepeat      int
+	cellValue       string
+	paragraphs      int
+	inCellParagraph bool
+}
+
+func parseOdsContent(r io.Reader) ([]*odsSheet, error) {
+	p := &odsContentParser{}
+	d := xml.NewDecoder(r)
+	for {
+		tkn, err := d.Token()
+		if err == io.EOF {
+			return p.sheets, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tkn.(type) {
+		case xml.StartElement:
+			p.start(t)
+		case xml.EndElement:
+			p.end(t)
+		case xml.CharData:
+			if p.inCellParagraph {
+				p.cell.Write(t)
+			}
+		}
+	}
+}
+
+func (p *odsContentParser) start(e xml.StartElement) {
+	switch {
+	case e.Name.Space == nsTable && e.Name.Local == "table":
+		p.sheet = &odsSheet{name: attr(e, nsTable, "name")}
+		p.pendingRows = 0
+	case e.Name.Space == nsTable && e.Name.Local == "table-row" && p.sheet != nil:
+		p.row = nil
+		p.pendingCells = 0
+		p.rowRepeat = repeatAttr(e, "number-rows-repeated")
+	case e.Name.Space == nsTable && (e.Name.Local == "table-cell" || e.Name.Local == "covered-table-cell") && p.sheet != nil:
+		p.cell = &strings.Builder{}
+		p.cellRepeat = repeatAttr(e, "number-columns-repeated")
+		p.cellValue = attr(e, nsOffice, "value")
+		p.paragraphs = 0
+	case e.Name.Space == nsText && e.Name.Local == "p" && p.cell != nil:
+		if p.paragraphs > 0 {
+			p.cell.WriteByte('\n')
+		}
+		p.paragraphs++
+		p.inCellParagraph = true
+	case e.Name.Space == nsText && e.Name.Local == "s" && p.inCellParagraph:
+		p.cell.WriteString(strings.Repeat(" ", repeatAttr(e, "c")))
+	case e.Name.Space == nsText && e.Name.Local == "tab" && p.inCellParagraph:
+		p.cell.WriteByte('\t')
+	case e.Name.Space == nsText && e.Name.Local == "line-break" && p.inCellParagraph:
+		p.cell.WriteByte('\n')
+	}
+}
+
+func (p *odsContentParser) end(e xml.EndElement) {
+	switch {
+	case e.Name.Space == nsTable && e.Name.Local == "table" && p.sheet != nil:
+		p.sheets = append(p.sheets, p.sheet)
+		p.sheet = nil
+	case e.Name.Space == nsTable && e.Name.Local == "table-row" && p.sheet != nil:
+		if len(p.row) == 0 {
+			p.pendingRows += p.rowRepeat
+			return
+		}
+		for ; p.pendingRows > 0; p.pendingRows-- {
+			p.sheet.rows = append(p.sheet.rows, nil)
+		}
+		for i := 0; i < p.rowRepeat; i++ {
+			p.sheet.rows = append(p.sheet.rows, append([]string(nil), p.row...))
+		}
+	case e.Name.Space == nsTable && (e.Name.Local == "table-cell" || e.Name.Local == "covered-table-cell") && p.cell != nil:
+		value := p.cell.String()
+		if value == "" {
+			value = p.cellValue
+		}
+		p.cell = nil
+
+		if value == "" {
+			p.pendingCells += p.cellRepeat
+			return
+		}
+		for ; p.pendingCells > 0; p.pendingCells-- {
+			p.row = append(p.row, "")
+		}
+		for i := 0; i < p.cellRepeat; i++ {
+			p.row = append(p.row, value)
+		}
+	case e.Name.Space == nsText && e.Name.Local == "p":
+		p.inCellParagraph = false
+	}
+}
+
+func attr(e xml.StartElement, space, local string) string {
+	for _, a := range e.Attr {
+		if a.Name.Space == space && a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func repeatAttr(e xml.StartElement, local string) int {
+	for _, a := range e.Attr {
+		if a.Name.Local == local {
+			n, err := strconv.Atoi(a.Value)
+			if err == nil && n > 0 {
+				return n
+			}
+		}
+	}
+	return 1
+}
@@ -0,0 +1,196 @@
+// Package workbook reads and writes spreadsheet files such as Excel (.xlsx) or OpenDocument (.ods) workbooks.
+//
+// Sheets of a workbook implement the same update, append and read operations as the sheets of a Google
+// spreadsheet, hence they can be used with pkg/json2sheet and pkg/sheet2json.
+package workbook
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var ErrNotFound = errors.New("not found")
+
+type Format string
+
+const (
+	FormatXlsx Format = "xlsx"
+	FormatOds  Format = "ods"
+)
+
+type Workbook interface {
+	// SheetNames lists the names of all sheets in order
+	SheetNames() []string
+	FirstSheet() (Sheet, error)
+	SheetByName(name string) (Sheet, error)
+	// AddSheet appends a new, empty sheet
+	AddSheet(name string) (Sheet, error)
+	// Save writes the workbook back to the file it was opened from
+	Save() error
+	Close() error
+}
+
+type Sheet interface {
+	Name() string
+	// UpdateValues writes the data starting at the top-left cell, existing cells outside the data are kept.
+	// Values which are JSON numbers are written as numeric cells.
+	UpdateValues(data [][]string) error
+	// AppendValues writes the data after the last non-empty row
+	AppendValues(data [][]string) error
+	// Values reads all values as formatted strings, trailing empty rows and cells are omitted
+	Values() ([][]any, error)
+	// HeaderValues reads the values of the first row only, e.g. the header of a table. Trailing empty cells are
+	// omitted.
+	HeaderValues() ([]any, error)
+	// Clear removes all values
+	Clear() error
+}
+
+// KeepsFormatting tells whether saving an existing workbook of the format keeps its formatting, formulas and
+// styles. An .ods is written from the values of its cells only.
+func (f Format) KeepsFormatting() bool {
+	return f != FormatOds
+}
+
+// FormatFromPath derives the workbook format from the file extension
+func FormatFromPath(path string) (Format, error) {
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+	switch Format(ext) {
+	case FormatXlsx:
+		return FormatXlsx, nil
+	case FormatOds:
+		return FormatOds, nil
+	}
+	return "", fmt.Errorf("unsupported workbook format %q, expected one of: .%s, .%s", ext, FormatXlsx, FormatOds)
+}
+
+// Open opens an existing workbook, the format is derived from the file extension.
+func Open(path string) (Workbook, error) {
+	format, err := FormatFromPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case FormatXlsx:
+		return openXlsx(path)
+	case FormatOds:
+		return openOds(path)
+	}
+	panic(fmt.Errorf("unhandled format %q", format))
+}
+
+// OpenOrCreate opens the workbook at the given path or creates a new, empty one if the file does not exist yet.
+// A new workbook is only written to disk on Save.
+func OpenOrCreate(path string) (Workbook, error) {
+	format, err := FormatFromPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = os.Stat(path)
+	if err == nil {
+		return Open(path)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	switch format {
+	case FormatXlsx:
+		return newXlsx(path), nil
+	case FormatOds:
+		return newOds(path), nil
+	}
+	panic(fmt.Errorf("unhandled format %q", format))
+}
+
+// SheetOrCreate finds the sheet by its name and creates it if missing. An empty name selects the first sheet.
+func SheetOrCreate(wb Workbook, name string) (Sheet, error) {
+	if name == "" {
+		return wb.FirstSheet()
+	}
+
+	sheet, err := wb.SheetByName(name)
+	if errors.Is(err, ErrNotFound) {
+		return wb.AddSheet(name)
+	}
+	return sheet, err
+}
+
+func toValues(rows [][]string) [][]any {
+	values := make([][]any, len(rows))
+	for i, row := range rows {
+		values[i] = make([]any, len(row))
+		for j, cell := range row {
+			values[i][j] = cell
+		}
+	}
+	return values
+}
+
+// headerValues are the values of a row without its trailing empty cells
+func headerValues(row []string) []any {
+	rows := trimRows([][]string{row})
+	if len(rows) == 0 {
+		return nil
+	}
+	return toValues(rows)[0]
+}
+
+func trimRows(rows [][]string) [][]string {
+	for i, row := range rows {
+		end := len(row)
+		for end > 0 && row[end-1] == "" {
+			end--
+		}
+		rows[i] = row[:end]
+	}
+
+	end := len(rows)
+	for end > 0 && len(rows[end-1]) == 0 {
+		end--
+	}
+	return rows[:end]
+}
+
+// rowCount is the number of rows up to the last one with a non-empty cell
+func rowCount(rows [][]string) int {
+	for end := len(rows); end > 0; end-- {
+		for _, cell := range rows[end-1] {
+			if cell != "" {
+				return end
+			}
+		}
+	}
+	return 0
+}
+
+// maxNumberDigits are the significant digits spreadsheets keep of a number, e.g. IDs with more digits stay text
+// rather than being rounded
+const maxNumberDigits = 15
+
+// numericValue parses a value which is a JSON number, e.g. '42' or '-1.5e3', to write it as a numeric cell
+func numericValue(s string) (float64, bool) {
+	if s == "" || (s[0] != '-' && (s[0] < '0' || '9' < s[0])) || !json.Valid([]byte(s)) {
+		return 0, false
+	}
+
+	mantissa, _, _ := strings.Cut(strings.ToLower(strings.TrimPrefix(s, "-")), "e")
+	digits := strings.Trim(strings.Replace(mantissa, ".", "", 1), "0")
+	if len(digits) > maxNumberDigits {
+		return 0, false
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		// out of range
+		return 0, false
+	}
+	return f, true
+}
@@ -0,0 +1,211 @@
+package workbook
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestWorkbook_RoundTrip(t *testing.T) {
+	for _, ext := range []string{"xlsx", "ods"} {
+		t.Run(ext, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "test."+ext)
+
+			wb, err := OpenOrCreate(path)
+			require.NoError(t, err)
+
+			first, err := wb.AddSheet("first")
+			require.NoError(t, err)
+			require.NoError(t, first.UpdateValues([][]string{{"a", "b"}, {"1", "2"}}))
+			require.NoError(t, first.AppendValues([][]string{{"3", "", "5"}}))
+
+			second, err := wb.AddSheet("second & more")
+			require.NoError(t, err)
+			require.NoError(t, second.UpdateValues([][]string{{"multi\nline", "<tag>"}}))
+
+			_, err = wb.AddSheet("first")
+			assert.Error(t, err)
+
+			require.NoError(t, wb.Save())
+			require.NoError(t, wb.Close())
+
+			wb, err = Open(path)
+			require.NoError(t, err)
+			defer wb.Close()
+
+			assert.Equal(t, []string{"first", "second & more"}, wb.SheetNames())
+
+			sheet, err := wb.FirstSheet()
+			require.NoError(t, err)
+			values, err := sheet.Values()
+			require.NoError(t, err)
+			assert.Equal(t, [][]any{{"a", "b"}, {"1", "2"}, {"3", "", "5"}}, values)
+
+			header, err := sheet.HeaderValues()
+			require.NoError(t, err)
+			assert.Equal(t, []any{"a", "b"}, header)
+
+			sheet, err = wb.SheetByName("second & more")
+			require.NoError(t, err)
+			values, err = sheet.Values()
+			require.NoError(t, err)
+			assert.Equal(t, [][]any{{"multi\nline", "<tag>"}}, values)
+
+			require.NoError(t, sheet.Clear())
+			values, err = sheet.Values()
+			require.NoError(t, err)
+			assert.Empty(t, values)
+			header, err = sheet.HeaderValues()
+			require.NoError(t, err)
+			assert.Empty(t, header)
+
+			_, err = wb.SheetByName("missing")
+			assert.ErrorIs(t, err, ErrNotFound)
+		})
+	}
+}
+
+func TestSheet_AppendValues_Chunks(t *testing.T) {
+	for _, ext := range []string{"xlsx", "ods"} {
+		t.Run(ext, func(t *testing.T) {
+			wb, err := OpenOrCreate(filepath.Join(t.TempDir(), "test."+ext))
+			require.NoError(t, err)
+			defer wb.Close()
+
+			sheet, err := SheetOrCreate(wb, "data")
+			require.NoError(t, err)
+			require.NoError(t, sheet.AppendValues([][]string{{"a"}, {"1"}}))
+			// trailing empty rows of a chunk are overwritten by the next one
+			require.NoError(t, sheet.AppendValues([][]string{{"2"}, {""}, {}}))
+
+			// another handle of the same sheet appends after the rows of the first one
+			again, err := wb.SheetByName("data")
+			require.NoError(t, err)
+			require.NoError(t, again.AppendValues([][]string{{"3"}}))
+
+			require.NoError(t, sheet.UpdateValues([][]string{{"A"}, {"1"}, {"2"}, {"3"}, {"4"}}))
+			require.NoError(t, sheet.AppendValues([][]string{{"5"}}))
+
+			values, err := sheet.Values()
+			require.NoError(t, err)
+			assert.Equal(t, [][]any{{"A"}, {"1"}, {"2"}, {"3"}, {"4"}, {"5"}}, values)
+
+			require.NoError(t, sheet.Clear())
+			require.NoError(t, sheet.AppendValues([][]string{{"b"}}))
+			values, err = sheet.Values()
+			require.NoError(t, err)
+			assert.Equal(t, [][]any{{"b"}}, values)
+		})
+	}
+}
+
+func TestSheet_UpdateValues_Numbers(t *testing.T) {
+	row := []string{"42", "-1.5e3", "007", "1234567890123456789", "0x10", "text"}
+
+	path := filepath.Join(t.TempDir(), "test.xlsx")
+	wb, err := OpenOrCreate(path)
+	require.NoError(t, err)
+	sheet, err := wb.FirstSheet()
+	require.NoError(t, err)
+	require.NoError(t, sheet.UpdateValues([][]string{row}))
+	require.NoError(t, wb.Save())
+	require.NoError(t, wb.Close())
+
+	f, err := excelize.OpenFile(path)
+	require.NoError(t, err)
+	defer f.Close()
+	var types []excelize.CellType
+	for i := range row {
+		cell, err := excelize.CoordinatesToCellName(i+1, 1)
+		require.NoError(t, err)
+		typ, err := f.GetCellType(defaultXlsxSheetName, cell)
+		require.NoError(t, err)
+		types = append(types, typ)
+	}
+	// numbers have no explicit cell type
+	assert.Equal(t, []excelize.CellType{
+		excelize.CellTypeUnset, excelize.CellTypeUnset, excelize.CellTypeSharedString,
+		excelize.CellTypeSharedString, excelize.CellTypeSharedString, excelize.CellTypeSharedString,
+	}, types)
+
+	var content strings.Builder
+	ods := newOds("test.ods")
+	require.NoError(t, ods.sheets[0].UpdateValues([][]string{row}))
+	require.NoError(t, writeOdsContent(&content, ods.sheets))
+	assert.Contains(t, content.String(), `<table:table-cell office:value-type="float" office:value="-1.5e3"><text:p>-1.5e3</text:p>`)
+	assert.Contains(t, content.String(), `<table:table-cell office:value-type="string"><text:p>007</text:p>`)
+
+	sheets, err := parseOdsContent(strings.NewReader(content.String()))
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{row}, sheets[0].rows)
+}
+
+func TestSheetOrCreate(t *testing.T) {
+	wb, err := OpenOrCreate(filepath.Join(t.TempDir(), "test.xlsx"))
+	require.NoError(t, err)
+	defer wb.Close()
+
+	sheet, err := SheetOrCreate(wb, "")
+	require.NoError(t, err)
+	assert.Equal(t, "Sheet1", sheet.Name())
+
+	sheet, err = SheetOrCreate(wb, "data")
+	require.NoError(t, err)
+	assert.Equal(t, "data", sheet.Name())
+	assert.Equal(t, []string{"Sheet1", "data"}, wb.SheetNames())
+
+	sheet, err = SheetOrCreate(wb, "data")
+	require.NoError(t, err)
+	assert.Equal(t, "data", sheet.Name())
+}
+
+func TestFormatFromPath(t *testing.T) {
+	f, err := FormatFromPath("report.XLSX")
+	assert.NoError(t, err)
+	assert.Equal(t, FormatXlsx, f)
+	assert.True(t, f.KeepsFormatting())
+
+	f, err = FormatFromPath("report.ods")
+	assert.NoError(t, err)
+	assert.False(t, f.KeepsFormatting())
+
+	_, err = FormatFromPath("report.csv")
+	assert.Error(t, err)
+}
+
+func TestParseOdsContent_RepeatedCells(t *testing.T) {
+	content := `<?xml version="1.0" encoding="UTF-8"?>
+<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0" xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0">
+<office:body><office:spreadsheet>
+<table:table table:name="Tabelle1">
+ <table:table-column table:number-columns-repeated="1024"/>
+ <table:table-row>
+  <table:table-cell office:value-type="string"><text:p>name</text:p></table:table-cell>
+  <table:table-cell table:number-columns-repeated="2"/>
+  <table:table-cell office:value-type="float" office:value="1.5"><text:p>1,50</text:p></table:table-cell>
+  <table:table-cell table:number-columns-repeated="1020"/>
+ </table:table-row>
+ <table:table-row table:number-rows-repeated="2"><table:table-cell table:number-columns-repeated="1024"/></table:table-row>
+ <table:table-row>
+  <table:table-cell table:number-columns-repeated="2" office:value-type="string"><text:p>a<text:s text:c="2"/>b</text:p></table:table-cell>
+  <table:table-cell office:value-type="float" office:value="3"/>
+ </table:table-row>
+ <table:table-row table:number-rows-repeated="1048571"><table:table-cell table:number-columns-repeated="1024"/></table:table-row>
+</table:table>
+</office:spreadsheet></office:body></office:document-content>`
+
+	sheets, err := parseOdsContent(strings.NewReader(content))
+	require.NoError(t, err)
+	require.Len(t, sheets, 1)
+	assert.Equal(t, "Tabelle1", sheets[0].name)
+	assert.Equal(t, [][]string{
+		{"name", "", "", "1,50"},
+		nil,
+		nil,
+		{"a  b", "a  b", "3"},
+	}, sheets[0].rows)
+}
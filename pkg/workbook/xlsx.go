@@ -0,0 +1,204 @@
+package workbook
+
+import (
+	"fmt"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const defaultXlsxSheetName = "Sheet1"
+
+type xlsxWorkbook struct {
+	path string
+	file *excelize.File
+
+	// pristine is true for a new workbook whose default sheet was not touched yet
+	pristine bool
+
+	// appendRows are the indexes of the rows to append to by sheet, once they are known
+	appendRows map[string]int
+}
+
+func openXlsx(path string) (*xlsxWorkbook, error) {
+	f, err := excelize.OpenFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open workbook %q: %w", path, err)
+	}
+	return &xlsxWorkbook{path: path, file: f, appendRows: map[string]int{}}, nil
+}
+
+func newXlsx(path string) *xlsxWorkbook {
+	return &xlsxWorkbook{path: path, file: excelize.NewFile(), pristine: true, appendRows: map[string]int{}}
+}
+
+func (w *xlsxWorkbook) SheetNames() []string {
+	return w.file.GetSheetList()
+}
+
+func (w *xlsxWorkbook) FirstSheet() (Sheet, error) {
+	names := w.SheetNames()
+	if len(names) == 0 {
+		return nil, fmt.Errorf("workbook has no sheets: %w", ErrNotFound)
+	}
+	w.pristine = false
+	return &xlsxSheet{workbook: w, name: names[0]}, nil
+}
+
+func (w *xlsxWorkbook) SheetByName(name string) (Sheet, error) {
+	idx, err := w.file.GetSheetIndex(name)
+	if err != nil {
+		return nil, err
+	}
+	if idx < 0 {
+		return nil, fmt.Errorf("sheet %q: %w", name, ErrNotFound)
+	}
+	w.pristine = false
+	return &xlsxSheet{workbook: w, name: name}, nil
+}
+
+func (w *xlsxWorkbook) AddSheet(name string) (Sheet, error) {
+	if w.pristine {
+		// re-use the default sheet of a new workbook instead of leaving it around empty
+		w.pristine = false
+		if err := w.file.SetSheetName(defaultXlsxSheetName, name); err != nil {
+			return nil, fmt.Errorf("cannot add sheet %q: %w", name, err)
+		}
+		return &xlsxSheet{workbook: w, name: name}, nil
+	}
+
+	idx, err := w.file.GetSheetIndex(name)
+	if err != nil {
+		return nil, err
+	}
+	if idx >= 0 {
+		return nil, fmt.Errorf("cannot add sheet %q: a sheet with that name already exists", name)
+	}
+
+	if _, err := w.file.NewSheet(name); err != nil {
+		return nil, fmt.Errorf("cannot add sheet %q: %w", name, err)
+	}
+	return &xlsxSheet{workbook: w, name: name}, nil
+}
+
+func (w *xlsxWorkbook) Save() error {
+	if err := w.file.SaveAs(w.path); err != nil {
+		return fmt.Errorf("cannot save workbook %q: %w", w.path, err)
+	}
+	return nil
+}
+
+func (w *xlsxWorkbook) Close() error {
+	return w.file.Close()
+}
+
+type xlsxSheet struct {
+	workbook *xlsxWorkbook
+	name     string
+}
+
+func (s *xlsxSheet) Name() string {
+	return s.name
+}
+
+func (s *xlsxSheet) UpdateValues(data [][]string) error {
+	// the data may end with empty cells over existing ones, hence the rows to append to are not known anymore
+	delete(s.workbook.appendRows, s.name)
+	return s.writeRows(0, data)
+}
+
+// AppendValues writes after the last non-empty row, which is only looked up by the first append rather than
+// reading all rows again for every chunk
+func (s *xlsxSheet) AppendValues(data [][]string) error {
+	next, ok := s.workbook.appendRows[s.name]
+	if !ok {
+		rows, err := s.rows()
+		if err != nil {
+			return err
+		}
+		next = len(rows)
+	}
+
+	if err := s.writeRows(next, data); err != nil {
+		delete(s.workbook.appendRows, s.name)
+		return err
+	}
+	s.workbook.appendRows[s.name] = next + rowCount(data)
+	return nil
+}
+
+func (s *xlsxSheet) Values() ([][]any, error) {
+	rows, err := s.rows()
+	if err != nil {
+		return nil, err
+	}
+	return toValues(rows), nil
+}
+
+func (s *xlsxSheet) HeaderValues() ([]any, error) {
+	rows, err := s.workbook.file.Rows(s.name)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read sheet %q: %w", s.name, err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Error()
+	}
+	header, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read sheet %q: %w", s.name, err)
+	}
+	return headerValues(header), nil
+}
+
+func (s *xlsxSheet) Clear() error {
+	rows, err := s.rows()
+	if err != nil {
+		return err
+	}
+
+	for i, row := range rows {
+		empty := make([]any, len(row))
+		if err := s.setRow(i, &empty); err != nil {
+			return err
+		}
+	}
+	s.workbook.appendRows[s.name] = 0
+	return nil
+}
+
+func (s *xlsxSheet) rows() ([][]string, error) {
+	rows, err := s.workbook.file.GetRows(s.name)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read sheet %q: %w", s.name, err)
+	}
+	return trimRows(rows), nil
+}
+
+func (s *xlsxSheet) writeRows(offset int, data [][]string) error {
+	for i, row := range data {
+		cells := make([]any, len(row))
+		for j, cell := range row {
+			if f, ok := numericValue(cell); ok {
+				cells[j] = f
+			} else {
+				cells[j] = cell
+			}
+		}
+		if err := s.setRow(offset+i, &cells); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *xlsxSheet) setRow(i int, row any) error {
+	cell, err := excelize.CoordinatesToCellName(1, i+1)
+	if err != nil {
+		return err
+	}
+	if err := s.workbook.file.SetSheetRow(s.name, cell, row); err != nil {
+		return fmt.Errorf("cannot write row %d of sheet %q: %w", i+1, s.name, err)
+	}
+	return nil
+}